@@ -0,0 +1,45 @@
+// sync 只執行一次同步後結束的精簡執行檔，給平台的排程工作（cron job）使用。
+// 不含 HTTP 伺服器與靜態檔案，記憶體用量比完整服務小很多：
+//
+//	go build -o pxmark-sync ./cmd/sync
+//	./pxmark-sync            # 每日更新
+//	./pxmark-sync --monthly  # 完整同步並封存上個月的出貨
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/app"
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/scheduler"
+)
+
+func main() {
+	monthly := flag.Bool("monthly", false, "完整同步並封存上個月的出貨（與每月排程相同）")
+	flag.Parse()
+
+	app.LoadEnv()
+	cfg := config.Load()
+	cfg.LogSummary()
+	app.SetTimezone(cfg)
+	app.Configure(cfg)
+
+	app.CheckBlobStore()
+
+	db := app.ConnectDatabase(cfg, cfg.DBSyncMaxOpenConns)
+	defer db.Close()
+	app.CheckMigrations(db, cfg)
+
+	s := scheduler.NewScheduler(db, 0)
+	if err := s.RunSync(*monthly); err != nil {
+		db.Close()
+		os.Exit(1)
+	}
+	if *monthly {
+		s.RunMonthlyExport(time.Now().AddDate(0, -1, 0))
+	}
+	log.Println("[INFO] 同步結束")
+}
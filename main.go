@@ -1,28 +1,35 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"PXMarkMapBackEnd/pkg/app"
+	"PXMarkMapBackEnd/pkg/config"
 	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/demo"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/loadtest"
+	"PXMarkMapBackEnd/pkg/rpc"
 	"PXMarkMapBackEnd/pkg/scheduler"
-	"PXMarkMapBackEnd/pkg/sync"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"PXMarkMapBackEnd/pkg/server"
 )
 
 func init() {
-	if err := godotenv.Load(); err != nil {
-		if os.Getenv("GO_ENV") == "production" {
-			log.Println("[INFO] Running in production mode, using platform environment variables")
-		} else {
-			log.Println("[INFO] No .env file found, using system environment variables")
-		}
-	}
+	app.LoadEnv()
 }
 
 func main() {
@@ -32,18 +39,60 @@ func main() {
 	}
 	command := os.Args[1]
 
-	db := connectDatabase()
+	cfg := config.Load()
+	cfg.LogSummary()
+	app.SetTimezone(cfg)
+	app.Configure(cfg)
+
+	// 壓力測試只對執行中的服務送出請求，不需要資料庫
+	if command == "loadtest" {
+		handleLoadTest(os.Args[2:])
+		return
+	}
+	// 示範模式預設使用記憶體中的假資料，不需要資料庫、工作表或金鑰
+	if command == "demo" {
+		handleDemo(cfg, os.Args[2:])
+		return
+	}
+
+	app.CheckBlobStore()
+	db := app.ConnectDatabase(cfg, cfg.DBMaxOpenConns)
 	defer db.Close()
 
+	if command == "migrate" {
+		app.Migrate(db)
+		return
+	}
+	app.CheckMigrations(db, cfg)
+
+	// 同步專用的小型連線池，避免同步寫入佔滿 API 查詢的連線
+	syncDB := app.ConnectDatabase(cfg, cfg.DBSyncMaxOpenConns)
+	defer syncDB.Close()
+
 	switch command {
 	case "sync":
-		handleSync(db)
+		handleSync(syncDB, os.Args[2:])
 	case "serve":
-		handleServe(db)
+		handleServe(db, syncDB, cfg)
 	case "schedule":
-		handleSchedule(db)
+		handleSchedule(syncDB, cfg)
+		// 排程在背景執行，主程序等到收到停止訊號
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		<-ctx.Done()
+		stop()
+		waitForSync(cfg)
+	case "worker":
+		handleWorker(syncDB, cfg)
 	case "serve-schedule":
-		handleServeWithSchedule(db)
+		handleServeWithSchedule(db, syncDB, cfg)
+	case "verify":
+		handleVerify(db, os.Args[2:])
+	case "import-coordinates":
+		handleImportCoordinates(db, os.Args[2:])
+	case "index-advisor":
+		handleIndexAdvisor(db)
+	case "gc":
+		handleStoreGC(db, os.Args[2:])
 	default:
 		log.Printf("未知命令: %s\n", command)
 		printUsage()
@@ -51,220 +100,487 @@ func main() {
 	}
 }
 
-// connectDatabase 連接資料庫
-func connectDatabase() *sql.DB {
-	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
-	dbConfig := database.DBConfig{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     dbPort,
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", ""),
-		DBName:   getEnv("DB_NAME", "px_mark_map_db"),
-	}
-	db, err := database.ConnectDB(dbConfig)
+// handleSync 執行手動同步；與 cmd/sync 相同透過排程器執行，才會寫入 sync_logs 並保存執行日誌
+func handleSync(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	monthly := fs.Bool("monthly", false, "完整同步並封存上個月的出貨（與每月排程相同）")
+	fs.Parse(args)
+
+	log.Println("[INFO] 執行手動同步...")
+	s := scheduler.NewScheduler(db, 0)
+	s.Priority = google.PriorityManual
+	if err := s.RunSync(*monthly); err != nil {
+		db.Close()
+		os.Exit(1)
+	}
+	if *monthly {
+		s.RunMonthlyExport(time.Now().AddDate(0, -1, 0))
+	}
+}
+
+// handleVerify 檢查（並可選擇修復）資料完整性
+func handleVerify(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "同時修復發現的問題")
+	fs.Parse(args)
+
+	report, err := database.VerifyIntegrity(db, *repair)
 	if err != nil {
-		log.Fatalf("❌ 無法連接資料庫: %v", err)
+		log.Fatalf("[ERROR] 資料完整性檢查失敗: %v", err)
+	}
+
+	log.Println("[INFO] ===== 資料完整性檢查 =====")
+	for _, issue := range report.Issues {
+		status := "✓"
+		if issue.Count > 0 {
+			status = "⚠"
+		}
+		log.Printf("%s %-28s 問題 %d 筆，已修復 %d 筆", status, issue.Check, issue.Count, issue.Repaired)
+		log.Printf("    %s", issue.Detail)
+	}
+
+	if report.HasIssues() && !*repair {
+		log.Println("[WARN] 發現問題，可使用 --repair 修復")
+		os.Exit(1)
 	}
-	return db
 }
 
-// handleSync 執行手動同步
-func handleSync(db *sql.DB) {
-	log.Println("[INFO] 執行手動同步...")
-	if err := sync.SyncData(db); err != nil {
-		log.Fatalf("[ERROR] 同步失敗: %v", err)
+// handleStoreGC 列出沒有出貨、且近期沒有出現在工作表的孤兒店家；預設只列出，--apply 時停用（--delete 時刪除）
+func handleStoreGC(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	days := fs.Int("days", database.DefaultStoreGCDays, "超過幾天沒有出現在工作表")
+	apply := fs.Bool("apply", false, "實際停用列出的店家（未指定時只列出）")
+	remove := fs.Bool("delete", false, "與 --apply 一起使用，刪除而不是停用")
+	fs.Parse(args)
+
+	action := database.StoreGCDryRun
+	if *apply {
+		action = database.StoreGCDeactivate
+		if *remove {
+			action = database.StoreGCDelete
+		}
+	} else if *remove {
+		log.Println("[WARN] --delete 需要與 --apply 一起使用，本次只列出")
+	}
+
+	report, err := database.CollectOrphanedStores(db, *days, action, "gc")
+	if err != nil {
+		log.Fatalf("[ERROR] 清理孤兒店家失敗: %v", err)
+	}
+
+	log.Printf("[INFO] ===== 孤兒店家（沒有出貨、%d 天內未出現在工作表）=====", report.Days)
+	for _, s := range report.Stores {
+		lastSeen := "未知"
+		if s.LastSeenAt != nil {
+			lastSeen = s.LastSeenAt.Format("2006-01-02")
+		}
+		status := "啟用"
+		if !s.IsActive {
+			status = "停用"
+		}
+		log.Printf("  #%-6d %-30s 來源 %-12s %s，最後出現 %s", s.ID, s.StoreName, s.SourceID, status, lastSeen)
+	}
+
+	switch action {
+	case database.StoreGCDryRun:
+		if len(report.Stores) > 0 {
+			log.Printf("[INFO] 共 %d 家，確認後以 --apply 停用或 --apply --delete 刪除", len(report.Stores))
+		} else {
+			log.Println("[INFO] ✓ 沒有孤兒店家")
+		}
+	case database.StoreGCDeactivate:
+		log.Printf("[INFO] 共 %d 家，已停用 %d 家", len(report.Stores), report.Deactivated)
+	case database.StoreGCDelete:
+		log.Printf("[INFO] 共 %d 家，已刪除 %d 家", len(report.Stores), report.Deleted)
+	}
+}
+
+// handleImportCoordinates 從 CSV 批次匯入人工校正的座標（標記為 manual_import，之後同步不會覆蓋）
+func handleImportCoordinates(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("import-coordinates", flag.ExitOnError)
+	file := fs.String("file", "", "CSV 檔案（欄位: store_name 或 place_id、lat、lng）")
+	fs.Parse(args)
+
+	if *file == "" {
+		log.Fatal("[ERROR] 請以 --file 指定 CSV 檔案")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("[ERROR] 無法開啟 %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	fixes, err := parseCoordinateFixes(f)
+	if err != nil {
+		log.Fatalf("[ERROR] 解析 %s 失敗: %v", *file, err)
+	}
+	log.Printf("[INFO] 讀取到 %d 筆座標修正", len(fixes))
+
+	result, err := database.ImportCoordinates(db, fixes, "import-coordinates")
+	if err != nil {
+		log.Fatalf("[ERROR] 匯入座標失敗（已全部回復）: %v", err)
+	}
+	for _, nf := range result.NotFound {
+		log.Printf("[WARN] 找不到店家 %s", nf)
+	}
+	log.Printf("[INFO] 更新 %d 筆，未變更 %d 筆，找不到 %d 筆", result.Updated, result.Unchanged, len(result.NotFound))
+	if len(result.NotFound) > 0 {
+		os.Exit(1)
 	}
-	log.Println("[INFO] 同步完成")
+}
+
+// parseCoordinateFixes 解析座標修正 CSV，第一列為表頭（store_name / place_id 至少一欄，lat / lng 必填）
+func parseCoordinateFixes(r io.Reader) ([]database.CoordinateFix, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("讀取表頭失敗: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	latCol, hasLat := columns["lat"]
+	lngCol, hasLng := columns["lng"]
+	nameCol, hasName := columns["store_name"]
+	placeCol, hasPlace := columns["place_id"]
+	if !hasLat || !hasLng || (!hasName && !hasPlace) {
+		return nil, fmt.Errorf("表頭需要 lat、lng 以及 store_name 或 place_id")
+	}
+
+	var fixes []database.CoordinateFix
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", line, err)
+		}
+
+		fix := database.CoordinateFix{Line: line}
+		if hasName {
+			fix.StoreName = strings.TrimSpace(record[nameCol])
+		}
+		if hasPlace {
+			fix.PlaceID = strings.TrimSpace(record[placeCol])
+		}
+		if fix.StoreName == "" && fix.PlaceID == "" {
+			return nil, fmt.Errorf("第 %d 行缺少 store_name 或 place_id", line)
+		}
+
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(record[latCol]), 64)
+		lng, err2 := strconv.ParseFloat(strings.TrimSpace(record[lngCol]), 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("第 %d 行的 lat / lng 無效", line)
+		}
+		fix.Latitude, fix.Longitude = lat, lng
+		fixes = append(fixes, fix)
+	}
+	return fixes, nil
 }
 
 // handleServe 啟動 Gin API
-func handleServe(db *sql.DB) {
-	runGinServer(db)
+func handleServe(db, syncDB *sql.DB, cfg *config.Config) {
+	runGinServer(db, syncDB, cfg)
 }
 
-// handleSchedule 啟動排程器
-func handleSchedule(db *sql.DB) {
-	log.Println("[INFO] 啟動排程器模式")
-	// 每日同步設定
-	dailyHour, _ := strconv.Atoi(getEnv("DAILY_SYNC_HOUR", "0"))
-	dailyMinute, _ := strconv.Atoi(getEnv("DAILY_SYNC_MINUTE", "0"))
+// handleDemo 示範模式：預設以記憶體中的假資料（台南市 12 家虛構店家）提供地圖與 /api/v1/shopeMap，
+// 不需要資料庫或任何設定；--database 時改為建立 PostgreSQL 資料表並寫入示範資料，提供完整的 API
+func handleDemo(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	useDatabase := fs.Bool("database", false, "寫入 PostgreSQL（DB_HOST 等設定）並啟動完整的 API")
+	fs.Parse(args)
 
-	// 每月同步設定
-	monthlyDay, _ := strconv.Atoi(getEnv("MONTHLY_SYNC_DAY", "1"))
-	monthlyHour, _ := strconv.Atoi(getEnv("MONTHLY_SYNC_HOUR", "3"))
-	monthlyMinute, _ := strconv.Atoi(getEnv("MONTHLY_SYNC_MINUTE", "0"))
+	log.Println("[INFO] 啟動示範模式")
+	// 示範模式沒有工作表，關閉同步 API
+	cfg.EnableSync = false
 
-	// 啟動每日排程器（在背景執行）
-	go func() {
-		s := scheduler.NewScheduler(db, 0)
-		s.StartDaily(dailyHour, dailyMinute, false) // false = 每日更新
-	}()
+	if *useDatabase {
+		handleDemoDatabase(cfg)
+		return
+	}
 
-	// 啟動每月排程器（在背景執行）
+	router := server.NewDemoRouter(demo.NewMemory(time.Now()), cfg)
+	srv := &http.Server{Addr: ":" + cfg.APIPort, Handler: router}
 	go func() {
-		s := scheduler.NewScheduler(db, 0)
-		s.StartMonthly(monthlyDay, monthlyHour, monthlyMinute)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("[ERROR] API 伺服器啟動失敗: %v", err)
+		}
 	}()
+	log.Printf("[INFO] 已產生記憶體中的示範資料（近 %d 天的出貨），不使用資料庫", demo.Days)
+	log.Printf("[INFO] 開啟 http://localhost:%s%s/ 查看地圖", cfg.APIPort, cfg.BasePath)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
 }
 
-// handleServeWithSchedule 同時啟動 API + 排程
-func handleServeWithSchedule(db *sql.DB) {
-	log.Println("[INFO] 啟動 API + 排程器模式")
+// handleDemoDatabase 自動建立資料表並寫入示範資料（資料庫沒有任何店家時），再啟動完整的 API
+func handleDemoDatabase(cfg *config.Config) {
+	db := app.ConnectDatabase(cfg, cfg.DBMaxOpenConns)
+	defer db.Close()
+	app.Migrate(db)
 
-	
-	handleSchedule(db)
-	// 啟動 Gin API
-	runGinServer(db)
+	seeded, err := demo.Seed(db)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	if seeded {
+		log.Printf("[INFO] 已寫入示範資料（近 %d 天的出貨）", demo.Days)
+	} else {
+		log.Println("[INFO] 資料庫已有店家，不寫入示範資料，直接使用現有資料")
+	}
+
+	syncDB := app.ConnectDatabase(cfg, cfg.DBSyncMaxOpenConns)
+	defer syncDB.Close()
+
+	log.Printf("[INFO] 開啟 http://localhost:%s/ 查看地圖", cfg.APIPort)
+	runGinServer(db, syncDB, cfg)
 }
 
-// runGinServer Gin API 伺服器
-func runGinServer(db *sql.DB) {
-	port := getEnv("API_PORT", "8080")
-	corsOrigins := getEnv("CORS_ORIGINS", "*")
-	enableSync := getEnv("ENABLE_SYNC_API", "false") == "true"
-	syncSecret := getEnv("SYNC_SECRET", "")
+// handleSchedule 啟動排程器
+func handleSchedule(db *sql.DB, cfg *config.Config) {
+	log.Println("[INFO] 啟動排程器模式")
 
-	if enableSync && syncSecret == "" {
-		log.Fatal("[ERROR] 啟用同步 API 時必須設定 SYNC_SECRET")
-	}
+	s := scheduler.NewScheduler(db, 0)
 
-	router := gin.Default()
+	// 每日更新與每月完整同步（在背景執行，panic 時自動重新啟動）
+	scheduler.Register(s.DailySyncJob(cfg.DailySyncHour, cfg.DailySyncMinute, false)) // false = 每日更新
+	scheduler.Register(s.MonthlySyncJob(cfg.MonthlySyncDay, cfg.MonthlySyncHour, cfg.MonthlySyncMinute))
 
-	// CORS Middleware
-	router.Use(func(c *gin.Context) {
-		origin := c.GetHeader("Origin")
-		if corsOrigins == "*" {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		} else {
-			allowed := false
-			for _, o := range strings.Split(corsOrigins, ",") {
-				if strings.TrimSpace(o) == origin {
-					allowed = true
-					break
-				}
-			}
-			if allowed {
-				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-				c.Writer.Header().Set("Vary", "Origin")
-			}
+	// 孤兒店家清理（STORE_GC_INTERVAL_HOURS=0 時不排程）
+	if cfg.StoreGCIntervalHours > 0 {
+		if !database.IsValidStoreGCAction(cfg.StoreGCAction) || cfg.StoreGCDays <= 0 {
+			log.Fatalf("[ERROR] 無效的孤兒店家清理設定: STORE_GC_ACTION=%q STORE_GC_DAYS=%d", cfg.StoreGCAction, cfg.StoreGCDays)
 		}
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Sync-Secret")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(200)
-			return
-		}
-		c.Next()
-	})
+		scheduler.Register(s.StoreGCJob(time.Duration(cfg.StoreGCIntervalHours)*time.Hour, cfg.StoreGCDays, cfg.StoreGCAction))
+	}
+}
 
-	// 靜態 HTML
-	router.Static("/static", "./static")
-	router.GET("/", func(c *gin.Context) {
-		c.File("./static/index.html")
-	})
+// handleWorker 只執行排程與同步工作佇列（不啟動 HTTP），多個 worker 時只有取得主控權的會執行
+func handleWorker(db *sql.DB, cfg *config.Config) {
+	log.Println("[INFO] 啟動 worker 模式")
 
-	// /api/shopeMap
-	router.GET("/api/shopeMap", func(c *gin.Context) {
-		recentDays, err := strconv.Atoi(getEnv("RECENT_DAYS", "5"))
-		if err != nil {
-		recentDays = 5 // 若轉換失敗，預設為 5
-		}
-		data, err := database.GetRecentShipments(db, recentDays)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	leader, err := scheduler.AcquireLeadership(ctx, db)
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Println("[INFO] worker 已停止")
 			return
 		}
-		c.JSON(http.StatusOK, formatResponse(data))
+		log.Fatalf("[ERROR] 無法取得 worker 主控權: %v", err)
+	}
+	defer leader.Close()
+
+	// 主控權連線中斷時結束程序，交由平台重新啟動後再重新選主
+	go scheduler.WatchLeadership(ctx, leader, func() {
+		log.Fatal("[ERROR] 失去 worker 主控權，結束程序")
 	})
 
-	// /api/triggerSync
-	if enableSync {
-	router.POST("/api/triggerSync", func(c *gin.Context) {
-		secret := c.GetHeader("X-Sync-Secret")
-		if secret == "" {
-			secret = c.Query("secret")
-		}
-		if secret != syncSecret {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
-			return
+	handleSchedule(db, cfg)
+	jobs := scheduler.NewScheduler(db, 0)
+	jobs.CallbackSecret = cfg.SyncSecret
+	jobs.InstanceID = cfg.InstanceID
+	jobs.ProcessJobs(ctx)
+	waitForSync(cfg)
+	log.Println("[INFO] worker 已停止")
+}
+
+// handleServeWithSchedule 同時啟動 API + 排程
+func handleServeWithSchedule(db, syncDB *sql.DB, cfg *config.Config) {
+	log.Println("[INFO] 啟動 API + 排程器模式")
+
+	handleSchedule(syncDB, cfg)
+	// 啟動 Gin API
+	runGinServer(db, syncDB, cfg)
+}
+
+// runGinServer Gin API 伺服器（db 供查詢使用，syncDB 供同步寫入使用）
+func runGinServer(db, syncDB *sql.DB, cfg *config.Config) {
+	port := cfg.APIPort
+
+	if cfg.EnableSync && cfg.SyncSecret == "" {
+		log.Fatal("[ERROR] 啟用同步 API 時必須設定 SYNC_SECRET")
+	}
+
+	// 回應欄位一律使用 camelCase，新增的型別不符合時直接拒絕啟動
+	if violations := server.CheckJSONNaming(); len(violations) > 0 {
+		for _, v := range violations {
+			log.Printf("[ERROR] %s", v)
 		}
+		log.Fatal("[ERROR] JSON 欄位命名不符合 camelCase 規則")
+	}
 
-		syncType := c.Query("type")
-		if syncType == "" {
-			syncType = "daily" // 預設每日同步
+	readiness := &server.Readiness{}
+	router := server.NewRouter(db, syncDB, cfg, readiness)
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("[ERROR] API 伺服器啟動失敗: %v", err)
+	}
+	readiness.MarkReady()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Handler: router}
+	serve := func() error { return srv.Serve(listener) }
+	scheme := "http"
+
+	// HTTPS：憑證檔優先，其次是 Let's Encrypt 自動申請
+	var challengeSrv *http.Server
+	switch {
+	case cfg.TLSCertFile != "" || cfg.TLSKeyFile != "":
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			log.Fatal("[ERROR] TLS_CERT_FILE 與 TLS_KEY_FILE 必須同時設定")
 		}
+		serve = func() error { return srv.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile) }
+		scheme = "https"
+	case cfg.TLSAutocertDomains != "":
+		m := server.NewAutocertManager(server.ParseList(cfg.TLSAutocertDomains), cfg.TLSAutocertCache)
+		srv.TLSConfig = m.TLSConfig()
+		serve = func() error { return srv.ServeTLS(listener, "", "") }
+		scheme = "https"
 
+		challengeSrv = server.NewACMEChallengeServer(":"+cfg.TLSHTTPPort, m)
 		go func() {
-			var err error
-			switch syncType {
-			case "daily":
-				log.Println("[INFO] 觸發每日同步 (SyncDataDaily)")
-				err = sync.SyncDataDaily(db)
-			case "monthly":
-				log.Println("[INFO] 觸發每月完整同步 (SyncData)")
-				err = sync.SyncData(db)
-			default:
-				log.Printf("[WARN] 未知的同步類型: %s", syncType)
-				return
+			if err := challengeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("[ERROR] Let's Encrypt 驗證伺服器啟動失敗: %v", err)
 			}
+		}()
+	}
 
-			if err != nil {
-				log.Printf("[ERROR] %s 同步失敗: %v", syncType, err)
-			} else {
-				log.Printf("[INFO] %s 同步完成", syncType)
+	go func() {
+		if err := serve(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("[ERROR] API 伺服器啟動失敗: %v", err)
+		}
+	}()
+	log.Printf("[INFO] API 伺服器啟動於 %s://localhost:%s%s/", scheme, port, cfg.BasePath)
+
+	// 內部服務用的 gRPC（另一個連接埠）
+	var grpcSrv *http.Server
+	if cfg.GRPCPort != "" {
+		grpcSrv = rpc.NewServer(db, cfg).HTTPServer(":" + cfg.GRPCPort)
+		go func() {
+			if err := grpcSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("[ERROR] gRPC 伺服器啟動失敗: %v", err)
 			}
 		}()
-
-		c.JSON(http.StatusAccepted, gin.H{
-			"status":  "triggered",
-			"type":    syncType,
-			"message": "同步任務已觸發，正在背景執行",
-			})
-		})
+		log.Printf("[INFO] gRPC 伺服器啟動於 localhost:%s（%s）", cfg.GRPCPort, rpc.ServiceName)
 	}
 
-	log.Printf("[INFO] API 伺服器啟動於 http://localhost:%s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("[ERROR] API 伺服器啟動失敗: %v", err)
+	<-ctx.Done()
+	stop()
+	log.Println("[INFO] 收到停止訊號，停止接受新連線並等待進行中的請求...")
+	readiness.MarkNotReady()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[WARN] 部分請求未在時限內完成: %v", err)
+	}
+	if challengeSrv != nil {
+		challengeSrv.Shutdown(shutdownCtx)
+	}
+	if grpcSrv != nil {
+		grpcSrv.Shutdown(shutdownCtx)
 	}
+	waitForSync(cfg)
+	log.Println("[INFO] API 伺服器已停止")
 }
 
-// formatResponse 將資料整理成前端需要格式
-func formatResponse(data []map[string]interface{}) []map[string]interface{} {
-	storeMap := make(map[string]map[string]interface{})
-	for _, record := range data {
-		name := record["store_name"].(string)
-		if _, exists := storeMap[name]; !exists {
-			storeMap[name] = map[string]interface{}{
-				"storeName": name,
-				"address":   record["address"].(string),
-				"latitude":  record["latitude"].(float64),
-				"longitude": record["longitude"].(float64),
-				"shipments": []map[string]string{},
-			}
+// handleIndexAdvisor 輸出資料表掃描統計、缺少的索引與慢查詢，有建議時以狀態碼 1 結束
+func handleIndexAdvisor(db *sql.DB) {
+	report, err := database.AdviseIndexes(db)
+	if err != nil {
+		log.Fatalf("[ERROR] 產生索引建議失敗: %v", err)
+	}
+
+	log.Println("[INFO] ===== 索引建議 =====")
+	if report.StatsSince != nil {
+		log.Printf("[INFO] 統計自 %s 起累計", report.StatsSince.Format("2006-01-02 15:04"))
+	}
+	suspects := 0
+	for _, t := range report.Tables {
+		if !t.SeqScanSuspect {
+			continue
 		}
-		store := storeMap[name]
-		shipments := store["shipments"].([]map[string]string)
-		shipments = append(shipments, map[string]string{
-			"productType": record["product_type"].(string),
-			"date":        record["shipment_date"].(string),
-			"quantity":    record["quantity"].(string),
-		})
-		store["shipments"] = shipments
-	}
-	response := []map[string]interface{}{}
-	for _, v := range storeMap {
-		response = append(response, v)
-	}
-	return response
+		suspects++
+		log.Printf("[WARN] %s（%d 列，%s）以循序掃描為主: seq_scan=%d（共讀取 %d 列）idx_scan=%d",
+			t.Table, t.LiveRows, google.FormatBytes(int(t.SizeBytes)), t.SeqScans, t.SeqRowsRead, t.IndexScans)
+	}
+	for _, m := range report.MissingIndexes {
+		log.Printf("[WARN] %s 缺少 (%s) 索引，用於 %s", m.Table, strings.Join(m.Columns, ", "), m.UsedBy)
+		log.Printf("[WARN]   %s", m.Suggestion)
+	}
+	for _, s := range report.SlowStatements {
+		log.Printf("[WARN] 慢查詢 平均 %.1f ms × %d 次: %s", s.MeanMs, s.Calls, s.Query)
+	}
+	for _, note := range report.Notes {
+		log.Printf("[INFO] %s", note)
+	}
+
+	if suspects == 0 && len(report.MissingIndexes) == 0 && len(report.SlowStatements) == 0 {
+		log.Println("[INFO] ✓ 沒有需要處理的項目")
+		return
+	}
+	os.Exit(1)
+}
+
+// handleLoadTest 以固定速率對執行中的服務送出主要讀取端點的請求，結束後依端點輸出延遲百分位數
+func handleLoadTest(args []string) {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	target := fs.String("target", "http://localhost:8080", "服務位址（含 BASE_PATH）")
+	rps := fs.Int("rps", 50, "每秒請求數")
+	duration := fs.Duration("duration", 30*time.Second, "測試時間")
+	apiKey := fs.String("api-key", os.Getenv("LOADTEST_API_KEY"), "資料端點需要 API 金鑰時帶入的 X-API-Key")
+	timeout := fs.Duration("timeout", 10*time.Second, "單一請求逾時")
+	fs.Parse(args)
+
+	// Ctrl+C 提前結束時仍輸出已完成請求的結果
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("[INFO] 壓力測試 %s：%d req/s，%v", *target, *rps, *duration)
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		Target:   *target,
+		RPS:      *rps,
+		Duration: *duration,
+		APIKey:   *apiKey,
+		Timeout:  *timeout,
+	})
+	if err != nil {
+		log.Fatalf("[ERROR] 壓力測試失敗: %v", err)
+	}
+
+	log.Println("[INFO] ===== 壓力測試結果 =====")
+	for _, e := range report.Endpoints {
+		log.Printf("[INFO] %s", e)
+	}
+	log.Printf("[INFO] %s", report.Overall)
+	log.Printf("[INFO] 共 %d 個請求（%.1f req/s），錯誤 %d，因同時請求過多略過 %d",
+		report.Requests, report.AchievedRPS(), report.Errors, report.Dropped)
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
 }
 
-// 環境變數取得
-func getEnv(key, def string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+// waitForSync 關閉前等待執行中的同步完成（最多 SHUTDOWN_TIMEOUT_SECONDS），避免交易做到一半被中斷
+func waitForSync(cfg *config.Config) {
+	timeout := time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second
+	log.Println("[INFO] 等待執行中的同步完成...")
+	if !scheduler.WaitIdle(timeout) {
+		log.Printf("[WARN] 同步未在 %v 內完成，強制結束", timeout)
 	}
-	return def
 }
 
 // 使用說明
@@ -275,9 +591,25 @@ func printUsage() {
 	log.Println("  serve            啟動 API 伺服器")
 	log.Println("  schedule         啟動排程器")
 	log.Println("  serve-schedule   啟動 API 伺服器 + 排程器")
+	log.Println("  worker           只執行排程與同步工作佇列（不啟動 API）")
+	log.Println("  verify [--repair] 檢查資料完整性（可選擇修復）")
+	log.Println("  migrate          套用尚未套用的資料表版本")
+	log.Println("  import-coordinates --file fixes.csv  批次匯入人工校正的座標")
+	log.Println("  loadtest --target URL --rps N --duration 60s  對執行中的服務進行壓力測試")
+	log.Println("  index-advisor    檢查循序掃描、缺少的索引與慢查詢")
+	log.Println("  gc [--days 30] [--apply [--delete]]  列出（並停用或刪除）沒有出貨的孤兒店家")
+	log.Println("  demo [--database] 以記憶體中的示範資料啟動地圖（不需要資料庫；--database 時寫入 PostgreSQL）")
 	log.Println("範例:")
 	log.Println("  go run main.go sync")
 	log.Println("  go run main.go serve")
 	log.Println("  go run main.go schedule")
 	log.Println("  go run main.go serve-schedule")
+	log.Println("  go run main.go worker")
+	log.Println("  go run main.go verify --repair")
+	log.Println("  go run main.go migrate")
+	log.Println("  go run main.go import-coordinates --file fixes.csv")
+	log.Println("  go run main.go loadtest --target http://localhost:8080 --rps 200 --duration 60s")
+	log.Println("  go run main.go index-advisor")
+	log.Println("  go run main.go gc --days 60 --apply")
+	log.Println("  go run main.go demo")
 }
@@ -0,0 +1,154 @@
+// Package app 放置各執行檔（完整服務與 cmd/ 底下的精簡版本）共用的啟動流程：
+// 讀取 .env、連接資料庫、檢查資料表版本與檔案儲存設定。這裡不可引用 gin 或 pkg/server，
+// 精簡的同步執行檔才不會帶入 HTTP 相關的相依套件
+package app
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // 容器映像沒有 zoneinfo 時仍可載入 DISPLAY_TIMEZONE
+
+	"PXMarkMapBackEnd/pkg/cdn"
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/fault"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/opendata"
+	"PXMarkMapBackEnd/pkg/storage"
+	"github.com/joho/godotenv"
+)
+
+// LoadEnv 讀取 .env（找不到時使用系統環境變數）
+func LoadEnv() {
+	if err := godotenv.Load(); err != nil {
+		if os.Getenv("GO_ENV") == "production" {
+			log.Println("[INFO] Running in production mode, using platform environment variables")
+		} else {
+			log.Println("[INFO] No .env file found, using system environment variables")
+		}
+	}
+}
+
+// SetTimezone 載入 DISPLAY_TIMEZONE 並設為程序的本地時區，讓 time.Now()、排程時間與 JSON 輸出的 RFC3339 時間一致
+func SetTimezone(cfg *config.Config) {
+	loc, err := time.LoadLocation(cfg.DisplayTimezone)
+	if err != nil {
+		log.Fatalf("❌ 無效的 DISPLAY_TIMEZONE %q: %v", cfg.DisplayTimezone, err)
+	}
+	time.Local = loc
+}
+
+// Configure 將設定傳給不讀取環境變數的套件（需在連接資料庫、同步與讀取工作表之前呼叫）
+func Configure(cfg *config.Config) {
+	google.Configure(google.Settings{
+		SeasonYear:          cfg.SheetSeasonYear,
+		DuplicateDatePolicy: cfg.SheetDuplicateDatePolicy,
+		SheetSizeWarnBytes:  cfg.SheetSizeWarnBytes,
+		SnapshotFallback:    cfg.SheetSnapshotFallback,
+		ProductAliases:      cfg.SheetProductAliases,
+		DataSourcesFile:     cfg.DataSourcesFile,
+		SheetID:             cfg.GoogleSheetID,
+		SheetURL:            cfg.GoogleSheetURL,
+		SheetGIDs:           cfg.GoogleSheetGIDs,
+		SheetNames:          cfg.GoogleSheetNames,
+		PlacesAPIKey:        cfg.PlacesAPIKey,
+		PlacesQPS:           cfg.PlacesQPS,
+		PlacesMode:          cfg.PlacesMode,
+		PlacesFixturesDir:   cfg.PlacesFixtures,
+		DriveAPIKey:         cfg.DriveAPIKey,
+	})
+	storage.Configure(storage.Settings{
+		Kind:      cfg.BlobStore,
+		Dir:       cfg.BlobDir,
+		Endpoint:  cfg.BlobEndpoint,
+		Region:    cfg.BlobRegion,
+		Bucket:    cfg.BlobBucket,
+		Prefix:    cfg.BlobPrefix,
+		AccessKey: cfg.BlobAccessKeyID,
+		SecretKey: cfg.BlobSecretAccessKey,
+	})
+	cdn.Configure(cdn.Settings{
+		PurgeURL:   cfg.CDNPurgeURL,
+		PurgeToken: cfg.CDNPurgeToken,
+	})
+	opendata.Configure(opendata.Settings{
+		HiddenProducts: splitList(cfg.HiddenProducts),
+	})
+	database.ConfigureQuantityRanges(cfg.ShipmentQuantityRanges)
+	fault.Configure(cfg.FaultInject, cfg.Env == "production")
+}
+
+// CheckBlobStore 檢查 BLOB_STORE 設定，設定錯誤時在啟動時就停止，不要等到同步或下載封存時才失敗
+func CheckBlobStore() {
+	blobs, err := storage.Default()
+	if err != nil {
+		log.Fatalf("❌ 無法使用 BLOB_STORE: %v", err)
+	}
+	log.Printf("[INFO] 檔案儲存位置: %s", blobs)
+}
+
+// ConnectDatabase 連接資料庫（maxOpenConns 為連線池上限）
+func ConnectDatabase(cfg *config.Config, maxOpenConns int) *sql.DB {
+	dbConfig := database.DBConfig{
+		Host:         cfg.DBHost,
+		Port:         cfg.DBPort,
+		User:         cfg.DBUser,
+		Password:     cfg.DBPassword,
+		DBName:       cfg.DBName,
+		MaxOpenConns: maxOpenConns,
+		MaxIdleConns: maxOpenConns,
+
+		SlowQueryThreshold: time.Duration(cfg.DBSlowQueryMS) * time.Millisecond,
+		// 需先呼叫 SetTimezone，資料庫連線才會使用 DISPLAY_TIMEZONE
+		Timezone: time.Local,
+	}
+	db, err := database.ConnectDB(dbConfig)
+	if err != nil {
+		log.Fatalf("❌ 無法連接資料庫: %v", err)
+	}
+	return db
+}
+
+// CheckMigrations 有未套用的資料表版本時拒絕啟動（AUTO_MIGRATE=true 時改為自動套用）
+func CheckMigrations(db *sql.DB, cfg *config.Config) {
+	pending, err := database.PendingMigrations(db)
+	if err != nil {
+		log.Fatalf("❌ 無法檢查資料表版本: %v", err)
+	}
+	if len(pending) == 0 {
+		log.Println("[INFO] 資料表結構已是最新版本")
+		return
+	}
+
+	if !cfg.AutoMigrate {
+		for _, m := range pending {
+			log.Printf("[ERROR] 未套用的資料表版本 %d: %s", m.Version, m.Name)
+		}
+		log.Fatalf("❌ 有 %d 個資料表版本尚未套用，請先執行 `migrate` 或設定 AUTO_MIGRATE=true", len(pending))
+	}
+
+	Migrate(db)
+}
+
+// Migrate 套用所有未套用的資料表版本
+func Migrate(db *sql.DB) {
+	applied, err := database.Migrate(db)
+	if err != nil {
+		log.Fatalf("❌ 無法更新資料表結構: %v", err)
+	}
+	log.Printf("[INFO] 已套用 %d 個資料表版本", applied)
+}
+
+// splitList 解析逗號分隔的設定值，忽略空白項目（與 server.ParseList 相同，app 不可引用 pkg/server）
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,109 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MapKey 店家地圖資料共用的 surrogate key，同步後一律清除
+const MapKey = "shopeMap"
+
+// StoreKey 單一店家的 surrogate key
+func StoreKey(storeID int) string {
+	return fmt.Sprintf("store-%d", storeID)
+}
+
+// ProductKey 產品的 surrogate key（產品名稱為中文，先做 URL 編碼以符合 header 格式）
+func ProductKey(product string) string {
+	return "product-" + url.QueryEscape(product)
+}
+
+// SetHeaders 設定 Surrogate-Key（Fastly）與 Cache-Tag（Cloudflare）標頭
+func SetHeaders(h http.Header, keys []string) {
+	keys = unique(keys)
+	if len(keys) == 0 {
+		return
+	}
+	h.Set("Surrogate-Key", strings.Join(keys, " "))
+	h.Set("Cache-Tag", strings.Join(keys, ","))
+}
+
+// PurgeRequest 送到 CDN 清除 webhook 的內容
+type PurgeRequest struct {
+	Keys   []string `json:"keys"`
+	Reason string   `json:"reason"`
+}
+
+// Settings CDN 清除 webhook 設定（由 config.Config 轉換，啟動時以 Configure 設定）
+type Settings struct {
+	PurgeURL   string // CDN_PURGE_URL，空字串 = 不清除
+	PurgeToken string // CDN_PURGE_TOKEN，選填：以 Bearer token 送出
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   Settings
+)
+
+// Configure 設定 Purge 使用的 webhook
+func Configure(s Settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = s
+}
+
+// Purge 呼叫 CDN_PURGE_URL 清除指定的 surrogate keys，未設定時不做任何事
+func Purge(keys []string, reason string) error {
+	settingsMu.RLock()
+	s := settings
+	settingsMu.RUnlock()
+	if s.PurgeURL == "" {
+		return nil
+	}
+	endpoint := s.PurgeURL
+
+	body, _ := json.Marshal(PurgeRequest{Keys: unique(keys), Reason: reason})
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.PurgeToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.PurgeToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("CDN purge error: status %d", resp.StatusCode)
+	}
+
+	log.Printf("[INFO] 已通知 CDN 清除快取: %v", keys)
+	return nil
+}
+
+func unique(keys []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, k := range keys {
+		if k != "" && !seen[k] {
+			seen[k] = true
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShopMapQuery GetShopMap 的查詢條件，零值欄位不送出（伺服器預設近 RECENT_DAYS 天、不分頁）
+type ShopMapQuery struct {
+	Days        int    // 近幾天（不可與 From / To 同時使用，最多 MAX_RECENT_DAYS）
+	From        string // YYYY-MM-DD
+	To          string // YYYY-MM-DD
+	BBox        *BBox  // 只回傳範圍內的店家
+	Limit       int    // 每頁店家數（最多 500）
+	Offset      int
+	Sparklines  bool     // 多帶每個店家各產品的近期走勢
+	Granularity string   // day（預設）或 slot：同一天的多個時段分開回傳
+	Preview     string   // 預覽連結的 token，可看到尚未公開的產品
+	Sort        string   // name（預設）、latestShipment 或 totalQty
+	Desc        bool     // 由大到小排序
+	Fields      []string // 只回傳這些店家欄位（例如 storeId、latitude、longitude），其他欄位為零值
+}
+
+// BBox 地圖可視範圍（經緯度）
+type BBox struct {
+	MinLng, MinLat, MaxLng, MaxLat float64
+}
+
+// ShopMap /api/v1/shopeMap 的回應
+type ShopMap struct {
+	Data []MapStore  `json:"data"`
+	Meta ShopMapMeta `json:"meta"`
+}
+
+// MapStore 地圖上的店家與出貨
+type MapStore struct {
+	StoreID   int           `json:"storeId"`
+	StoreName string        `json:"storeName"`
+	Address   string        `json:"address"`
+	Latitude  float64       `json:"latitude"`
+	Longitude float64       `json:"longitude"`
+	Shipments []MapShipment `json:"shipments"`
+	// TotalQuantityByProduct 期間內各產品數量開頭數字的總和（"3箱" 計為 3）
+	TotalQuantityByProduct map[string]float64   `json:"totalQuantityByProduct"`
+	Sparklines             map[string][]float64 `json:"sparklines,omitempty"` // Sparklines 為 true 時才有
+}
+
+// MapShipment 店家的一筆出貨
+type MapShipment struct {
+	ProductType string `json:"productType"`
+	Date        string `json:"date"`
+	TimeSlot    string `json:"timeSlot,omitempty"` // Granularity 為 slot 時才有
+	Quantity    string `json:"quantity"`
+}
+
+// ShopMapMeta 回應的附加資訊
+type ShopMapMeta struct {
+	Sources       []SourceStatus `json:"sources"`
+	Total         int            `json:"total"`
+	Days          int            `json:"days,omitempty"` // 未指定 From / To 時的天數
+	Generation    int64          `json:"generation"`     // 資料版本，分頁途中改變表示資料已重新同步
+	From          string         `json:"from,omitempty"`
+	To            string         `json:"to,omitempty"`
+	Limit         int            `json:"limit,omitempty"`
+	Offset        int            `json:"offset,omitempty"`
+	SparklineDays int            `json:"sparklineDays,omitempty"`
+}
+
+// SourceStatus 資料來源最近一次同步的狀態
+type SourceStatus struct {
+	SourceID      string     `json:"sourceId"`
+	Name          string     `json:"name"`
+	LastSyncAt    time.Time  `json:"lastSyncAt"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt"`
+	Status        string     `json:"status"`
+	Message       string     `json:"message,omitempty"`
+	// SheetModifiedAt 試算表最後修改時間（伺服器有設定 GOOGLE_DRIVE_API_KEY 才有）
+	SheetModifiedAt *time.Time `json:"sheetModifiedAt,omitempty"`
+}
+
+// GetShopMap 取得地圖資料（GET /api/v1/shopeMap）
+func (c *Client) GetShopMap(ctx context.Context, q ShopMapQuery) (*ShopMap, error) {
+	query := url.Values{}
+	setNonEmpty(query, "from", q.From)
+	setNonEmpty(query, "to", q.To)
+	setNonEmpty(query, "granularity", q.Granularity)
+	setNonEmpty(query, "preview", q.Preview)
+	setNonEmpty(query, "sort", q.Sort)
+	setNonEmpty(query, "fields", strings.Join(q.Fields, ","))
+	if q.Desc {
+		query.Set("order", "desc")
+	}
+	if q.BBox != nil {
+		query.Set("bbox", fmt.Sprintf("%g,%g,%g,%g", q.BBox.MinLng, q.BBox.MinLat, q.BBox.MaxLng, q.BBox.MaxLat))
+	}
+	if q.Days > 0 {
+		query.Set("days", strconv.Itoa(q.Days))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		query.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.Sparklines {
+		query.Set("include", "sparkline")
+	}
+
+	var out ShopMap
+	if err := c.get(ctx, "/api/v1/shopeMap", query, c.apiKeyHeader(), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StoreListQuery ListStores 的查詢條件
+type StoreListQuery struct {
+	Q      string // 店名或地址包含的文字
+	Active *bool
+	Limit  int // 0 = 伺服器預設
+	Offset int
+}
+
+// Store 店家
+type Store struct {
+	ID               int     `json:"id"`
+	StoreName        string  `json:"storeName"`
+	PlaceID          string  `json:"placeId"`
+	FormattedAddress string  `json:"formattedAddress"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	BusinessStatus   string  `json:"businessStatus"`
+	IsActive         bool    `json:"isActive"`
+	Region           string  `json:"region"`
+}
+
+// StoreList ListStores 的結果，Total 為符合條件的店家總數
+type StoreList struct {
+	Data  []Store `json:"data"`
+	Total int     `json:"total"`
+}
+
+// ListStores 查詢店家（GET /api/v1/admin/stores，需要 AdminSecret）
+func (c *Client) ListStores(ctx context.Context, q StoreListQuery) (*StoreList, error) {
+	query := url.Values{}
+	setNonEmpty(query, "q", q.Q)
+	if q.Active != nil {
+		query.Set("active", strconv.FormatBool(*q.Active))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		query.Set("offset", strconv.Itoa(q.Offset))
+	}
+
+	header := http.Header{}
+	header.Set("X-Admin-Secret", c.opts.AdminSecret)
+	var out StoreList
+	if err := c.get(ctx, "/api/v1/admin/stores", query, header, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// 同步類型
+const (
+	SyncDaily   = "daily"
+	SyncMonthly = "monthly"
+)
+
+// SyncTriggered TriggerSync 的結果：Status 為 triggered（在 API 程序內執行）或 queued（排入佇列由 worker 執行）
+type SyncTriggered struct {
+	Status  string `json:"status"`
+	Type    string `json:"type"`
+	JobID   int    `json:"jobId,omitempty"` // 以 SyncJob 查詢結果
+	Message string `json:"message"`
+}
+
+// TriggerSync 觸發同步（POST /api/v1/triggerSync，需要 SyncSecret）；已有同步進行中時回傳 429 的 *APIError，不會重試
+func (c *Client) TriggerSync(ctx context.Context, syncType string) (*SyncTriggered, error) {
+	query := url.Values{}
+	setNonEmpty(query, "type", syncType)
+	header := http.Header{}
+	header.Set("X-Sync-Secret", c.opts.SyncSecret)
+
+	var out SyncTriggered
+	if err := c.do(ctx, http.MethodPost, "/api/v1/triggerSync", query, header, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SyncJob /api/v1/syncJobs/:id 的回應
+type SyncJob struct {
+	ID          int        `json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"` // queued、running、success、failed
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Done 同步工作是否已結束（成功或失敗）
+func (j *SyncJob) Done() bool {
+	return j.Status == "success" || j.Status == "failed"
+}
+
+// SyncJob 查詢 TriggerSync 回傳的同步工作（GET /api/v1/syncJobs/:id，需要 SyncSecret）
+func (c *Client) SyncJob(ctx context.Context, id int) (*SyncJob, error) {
+	header := http.Header{}
+	header.Set("X-Sync-Secret", c.opts.SyncSecret)
+
+	var out SyncJob
+	if err := c.do(ctx, http.MethodGet, "/api/v1/syncJobs/"+strconv.Itoa(id), nil, header, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SyncStatus /api/v1/syncStatus 的回應
+type SyncStatus struct {
+	Running            bool       `json:"running"`
+	LastSync           *SyncRun   `json:"lastSync,omitempty"`
+	LastSuccessfulSync *time.Time `json:"lastSuccessfulSync,omitempty"`
+	SheetLastModified  *time.Time `json:"sheetLastModified,omitempty"` // 早於 LastSuccessfulSync 表示表單沒有新資料
+	NextRuns           struct {
+		Daily   time.Time `json:"daily"`
+		Monthly time.Time `json:"monthly"`
+	} `json:"nextRuns"`
+}
+
+// SyncRun 一次同步的結果
+type SyncRun struct {
+	ID         int        `json:"id"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message"`
+}
+
+// GetSyncStatus 取得目前的同步狀態（GET /api/v1/syncStatus）
+func (c *Client) GetSyncStatus(ctx context.Context) (*SyncStatus, error) {
+	var out SyncStatus
+	if err := c.get(ctx, "/api/v1/syncStatus", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) apiKeyHeader() http.Header {
+	if c.opts.APIKey == "" {
+		return nil
+	}
+	header := http.Header{}
+	header.Set("X-API-Key", c.opts.APIKey)
+	return header
+}
+
+func setNonEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,207 @@
+// Package client 呼叫本服務 HTTP API 的 Go 用戶端（前端建置腳本與內部工具使用），
+// 內建逾時與重試：連線錯誤、429 與 502 / 503 / 504 以指數退避重試（有 Retry-After 時依其等待），只重試 GET
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+// Options 用戶端設定
+type Options struct {
+	BaseURL     string // 服務位址（含 BASE_PATH），例如 https://map.example.com/pxmark
+	APIKey      string // REQUIRE_API_KEY=true 時資料端點需要的 X-API-Key
+	AdminSecret string // 管理端點（ListStores）的 X-Admin-Secret
+	SyncSecret  string // TriggerSync 的 X-Sync-Secret
+	// HTTPClient 未設定時使用逾時 30 秒的 http.Client
+	HTTPClient *http.Client
+	// MaxRetries 最多重試幾次（不含第一次），0 = 預設 3 次，負數 = 不重試
+	MaxRetries int
+	// Backoff 第一次重試前的等待時間，之後每次加倍（最多 30 秒），0 = 預設 500ms
+	Backoff time.Duration
+	// UserAgent 未設定時為 pxmark-client
+	UserAgent string
+}
+
+// Client API 用戶端，可同時在多個 goroutine 使用
+type Client struct {
+	opts    Options
+	baseURL *url.URL
+	http    *http.Client
+}
+
+// APIError 服務回傳的錯誤（非 2xx），Code 與 Message 來自錯誤回應的 JSON
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pxmark: %d %s", e.StatusCode, e.Code)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (requestId " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsNotFound 錯誤是否為 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// New 建立用戶端
+func New(opts Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(opts.BaseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("pxmark: invalid BaseURL %q", opts.BaseURL)
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "pxmark-client"
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{opts: opts, baseURL: u, http: httpClient}, nil
+}
+
+// get 送出 GET 並將回應解碼到 out
+func (c *Client) get(ctx context.Context, path string, query url.Values, header http.Header, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, header, out)
+}
+
+// do 送出請求（GET 失敗時依設定重試）並將 2xx 回應的 JSON 解碼到 out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, out interface{}) error {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	retries := c.opts.MaxRetries
+	if method != http.MethodGet || retries < 0 {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+
+		resp, err := c.http.Do(req)
+		var retryAfter time.Duration
+		canRetry := err != nil && ctx.Err() == nil // 連線錯誤
+		if err == nil {
+			err = decodeResponse(resp, out)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			canRetry = retryableStatus(err)
+		}
+		if err == nil || attempt >= retries || !canRetry {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff 第 attempt 次重試前的等待時間（指數退避加上最多 20% 的隨機延遲，避免多個用戶端同時重試）
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.opts.Backoff << attempt
+	if wait <= 0 || wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// retryableStatus 429 與暫時性的 5xx 才重試
+func retryableStatus(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// decodeResponse 2xx 時解碼 JSON 到 out，否則回傳 *APIError
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var e struct {
+			Code      string `json:"code"`
+			Message   string `json:"message"`
+			RequestID string `json:"requestId"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			apiErr.Code, apiErr.Message, apiErr.RequestID = e.Code, e.Message, e.RequestID
+		} else {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return apiErr
+	}
+	if out == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("pxmark: decode response: %w", err)
+	}
+	return nil
+}
+
+// parseRetryAfter 只支援秒數格式
+func parseRetryAfter(s string) time.Duration {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,386 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config 執行時設定（由環境變數載入）；其他套件不直接讀取環境變數，由 app.Configure 將這裡的值傳入
+type Config struct {
+	// 資料庫
+	DBHost     string `json:"dbHost"`
+	DBPort     int    `json:"dbPort"`
+	DBUser     string `json:"dbUser"`
+	DBPassword string `json:"dbPassword"`
+	DBName     string `json:"dbName"`
+	// 連線池：API 查詢與同步寫入分開，避免同步時搶光 API 的連線
+	DBMaxOpenConns     int `json:"dbMaxOpenConns"`
+	DBSyncMaxOpenConns int `json:"dbSyncMaxOpenConns"`
+	// AutoMigrate 啟動時自動套用未套用的資料表版本，否則發現未套用版本時拒絕啟動
+	AutoMigrate bool `json:"autoMigrate"`
+	// DBSlowQueryMS 大於 0 時記錄超過此毫秒數的查詢（參數值不寫入日誌）
+	DBSlowQueryMS int `json:"dbSlowQueryMs"`
+
+	// API 伺服器
+	APIPort      string `json:"apiPort"`
+	CORSOrigins  string `json:"corsOrigins"`
+	RecentDays   int    `json:"recentDays"`
+	MaxRangeDays int    `json:"maxRangeDays"` // ?from=&to= 允許的最大天數
+	// MaxRecentDays /api/v1/shopeMap?days= 允許的最大天數（小於 RecentDays 時以 RecentDays 為上限）
+	MaxRecentDays int    `json:"maxRecentDays"`
+	EnableSync    bool   `json:"enableSync"`
+	SyncSecret    string `json:"syncSecret"`
+	SyncMode      string `json:"syncMode"` // inline: API 程序內執行；queue: 排入 sync_jobs 由 worker 執行
+	// InstanceID 記錄在 sync_jobs.owner 的程序識別，重新啟動後立即處理自己未完成的工作（預設為主機名稱）；
+	// 其他程序留下的工作依心跳處理
+	InstanceID  string `json:"instanceId"`
+	AdminSecret string `json:"adminSecret"`
+	MapBaseURL  string `json:"mapBaseUrl"` // 短網址轉址的地圖頁面
+	BasePath    string `json:"basePath"`   // 所有路由的前綴（例如 /pxmark），空字串為根路徑
+	// HTTPS：指定憑證檔，或設定 TLSAutocertDomains 由 Let's Encrypt 自動申請（兩者都沒設定時使用 HTTP）
+	TLSCertFile        string `json:"tlsCertFile"`
+	TLSKeyFile         string `json:"tlsKeyFile"`
+	TLSAutocertDomains string `json:"tlsAutocertDomains"` // 逗號分隔
+	TLSAutocertCache   string `json:"tlsAutocertCache"`   // 自動申請的憑證保存目錄
+	TLSHTTPPort        string `json:"tlsHttpPort"`        // 自動申請時回應 HTTP-01 驗證並轉址到 HTTPS 的連接埠
+	// RequireAPIKey 資料端點（地圖、店家、區域、開放資料）需要 X-API-Key，金鑰可來自 APIKeys 或 api_keys 資料表
+	RequireAPIKey bool   `json:"requireApiKey"`
+	APIKeys       string `json:"apiKeys"` // 逗號分隔
+	// APIKeyMonthlyQuota 以管理端點建立金鑰時未指定的每月請求上限（0 表示不限；API_KEYS 的金鑰不受限制）
+	APIKeyMonthlyQuota int `json:"apiKeyMonthlyQuota"`
+	// ShutdownTimeoutSeconds 收到停止訊號後，等待進行中的請求與同步的秒數上限
+	ShutdownTimeoutSeconds int `json:"shutdownTimeoutSeconds"`
+	// MapCacheTTLSeconds /api/v1/shopeMap 回應快取的存活秒數（同步後也會失效），0 = 停用
+	MapCacheTTLSeconds int `json:"mapCacheTtlSeconds"`
+	// StaticDir 不為空時從磁碟讀取前端靜態檔（開發時修改不必重新編譯），否則使用編譯時嵌入的檔案
+	StaticDir string `json:"staticDir"`
+	// DisplayTimezone API 回應與匯出的時間欄位（RFC3339）使用的時區，也是排程時間與資料庫連線的時區
+	DisplayTimezone string `json:"displayTimezone"`
+	// WSPollSeconds 有 /ws 連線時檢查同步是否完成的間隔秒數
+	WSPollSeconds int `json:"wsPollSeconds"`
+	// HiddenProducts 尚未公開的產品（逗號分隔），公開端點與開放資料不顯示
+	HiddenProducts string `json:"hiddenProducts"`
+	// PreviewSigningKey 預覽連結（/api/v1/shopeMap?preview=）的簽章金鑰，可暫時在地圖上看到隱藏的產品
+	PreviewSigningKey string `json:"previewSigningKey"`
+	// GRPCPort 不為空時在此連接埠另外提供 gRPC（明文 HTTP/2，供內部服務使用），空字串 = 停用
+	GRPCPort string `json:"grpcPort"`
+
+	// 檔案儲存：每月封存、同步與工作表快照、開放資料（BLOB_STORE=s3 時金鑰與區域未設定則沿用 AWS_*）
+	BlobStore           string `json:"blobStore"` // local / s3 / gcs
+	BlobDir             string `json:"blobDir"`   // local 的根目錄
+	BlobBucket          string `json:"blobBucket"`
+	BlobPrefix          string `json:"blobPrefix"`
+	BlobEndpoint        string `json:"blobEndpoint"` // S3 相容服務（MinIO、R2）的網址，gcs 預設 https://storage.googleapis.com
+	BlobRegion          string `json:"blobRegion"`
+	BlobAccessKeyID     string `json:"blobAccessKeyId"`
+	BlobSecretAccessKey string `json:"blobSecretAccessKey"`
+
+	// CDN 快取清除
+	CDNPurgeURL   string `json:"cdnPurgeUrl"`
+	CDNPurgeToken string `json:"cdnPurgeToken"`
+
+	// ShipmentQuantityRanges 各產品單日出貨數量的合理範圍（JSON），超出範圍的出貨標記待檢查
+	ShipmentQuantityRanges string `json:"shipmentQuantityRanges"`
+	// FaultInject 非正式環境注入的故障（例如 places_error_rate=0.2,db_latency_ms=200），正式環境一律忽略
+	FaultInject string `json:"faultInject"`
+
+	// 回應的 Cache-Control max-age 秒數（依路由分類），0 = 不設定，瀏覽器與 CDN 每次以 ETag 重新驗證
+	CacheMaxAgeMap     int `json:"cacheMaxAgeMap"`     // /shopeMap、/shopeMap.geojson
+	CacheMaxAgeStores  int `json:"cacheMaxAgeStores"`  // /stores/nearby、/stores/:id/calendar、/stores/:id/timeseries
+	CacheMaxAgeStats   int `json:"cacheMaxAgeStats"`   // /stats/topStores
+	CacheMaxAgeCatalog int `json:"cacheMaxAgeCatalog"` // /products、/regions
+
+	// 排程
+	DailySyncHour     int `json:"dailySyncHour"`
+	DailySyncMinute   int `json:"dailySyncMinute"`
+	MonthlySyncDay    int `json:"monthlySyncDay"`
+	MonthlySyncHour   int `json:"monthlySyncHour"`
+	MonthlySyncMinute int `json:"monthlySyncMinute"`
+	// 孤兒店家清理（沒有出貨、且 StoreGCDays 天內未出現在工作表），每 StoreGCIntervalHours 小時一次，0 = 不排程（預設）；
+	// StoreGCAction 為 dry-run（只記錄，預設）、deactivate（停用）或 delete（刪除），修改資料必須明確設定
+	StoreGCIntervalHours int    `json:"storeGcIntervalHours"`
+	StoreGCDays          int    `json:"storeGcDays"`
+	StoreGCAction        string `json:"storeGcAction"`
+
+	// Google
+	DataSourcesFile  string `json:"dataSourcesFile"` // 多個資料來源的設定檔（JSON），設定時不使用 GoogleSheet*
+	GoogleSheetID    string `json:"googleSheetId"`
+	GoogleSheetURL   string `json:"googleSheetUrl"` // 發布到網路的連結或 gviz/tq 網址，可取代 GoogleSheetID
+	GoogleSheetGIDs  string `json:"googleSheetGids"`
+	GoogleSheetNames string `json:"googleSheetNames"`
+	SheetSeasonYear  int    `json:"sheetSeasonYear"` // 表頭日期沒有年份時的產季年份，0 = 依今天日期推算
+	// SheetDuplicateDatePolicy 同一張工作表重複日期欄位：sum（相加）或 flag（相加並標記）
+	SheetDuplicateDatePolicy string `json:"sheetDuplicateDatePolicy"`
+	SheetSizeWarnBytes       int    `json:"sheetSizeWarnBytes"`    // 單張工作表超過此大小時在同步摘要中警告
+	SheetSnapshotFallback    bool   `json:"sheetSnapshotFallback"` // 工作表下載失敗時改用快照同步
+	SheetProductAliases      string `json:"sheetProductAliases"`   // 工作表名稱對應產品的規則（JSON）
+	PlacesAPIKey             string `json:"placesApiKey"`
+	PlacesQPS                int    `json:"placesQps"`  // 全程序共用的 Places API 每秒查詢上限
+	PlacesMode               string `json:"placesMode"` // live / record / replay
+	PlacesFixtures           string `json:"placesFixtures"`
+	DriveAPIKey              string `json:"driveApiKey"` // 選填：取得試算表最後修改時間
+
+	Env string `json:"env"`
+}
+
+// Load 從環境變數載入設定
+func Load() *Config {
+	c := &Config{
+		DBHost:     GetEnv("DB_HOST", "localhost"),
+		DBPort:     GetEnvInt("DB_PORT", 5432),
+		DBUser:     GetEnv("DB_USER", "postgres"),
+		DBPassword: GetEnv("DB_PASSWORD", ""),
+		DBName:     GetEnv("DB_NAME", "px_mark_map_db"),
+
+		DBMaxOpenConns:     GetEnvInt("DB_MAX_OPEN_CONNS", 10),
+		DBSyncMaxOpenConns: GetEnvInt("DB_SYNC_MAX_OPEN_CONNS", 2),
+		AutoMigrate:        GetEnv("AUTO_MIGRATE", "false") == "true",
+		DBSlowQueryMS:      GetEnvInt("DB_SLOW_QUERY_MS", 0),
+
+		APIPort:       GetEnv("API_PORT", "8080"),
+		CORSOrigins:   GetEnv("CORS_ORIGINS", "*"),
+		RecentDays:    GetEnvInt("RECENT_DAYS", 5), // 若轉換失敗，預設為 5
+		MaxRangeDays:  GetEnvInt("MAX_RANGE_DAYS", 92),
+		MaxRecentDays: GetEnvInt("MAX_RECENT_DAYS", 30),
+		EnableSync:    GetEnv("ENABLE_SYNC_API", "false") == "true",
+		SyncSecret:    GetEnv("SYNC_SECRET", ""),
+		SyncMode:      GetEnv("SYNC_MODE", "inline"),
+		InstanceID:    GetEnv("INSTANCE_ID", hostname()),
+		AdminSecret:   GetEnv("ADMIN_SECRET", ""),
+		MapBaseURL:    GetEnv("MAP_BASE_URL", "/"),
+		BasePath:      normalizeBasePath(GetEnv("BASE_PATH", "")),
+
+		TLSCertFile:        GetEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:         GetEnv("TLS_KEY_FILE", ""),
+		TLSAutocertDomains: GetEnv("TLS_AUTOCERT_DOMAINS", ""),
+		TLSAutocertCache:   GetEnv("TLS_AUTOCERT_CACHE_DIR", "./certs"),
+		TLSHTTPPort:        GetEnv("TLS_HTTP_PORT", "80"),
+
+		RequireAPIKey: GetEnv("REQUIRE_API_KEY", "false") == "true",
+		APIKeys:       GetEnv("API_KEYS", ""),
+
+		APIKeyMonthlyQuota: GetEnvInt("API_KEY_MONTHLY_QUOTA", 0),
+
+		MapCacheTTLSeconds:     GetEnvInt("MAP_CACHE_TTL_SECONDS", 300),
+		ShutdownTimeoutSeconds: GetEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+		StaticDir:              GetEnv("STATIC_DIR", ""),
+		DisplayTimezone:        GetEnv("DISPLAY_TIMEZONE", "Asia/Taipei"),
+		WSPollSeconds:          GetEnvInt("WS_POLL_SECONDS", 10),
+		HiddenProducts:         GetEnv("HIDDEN_PRODUCTS", ""),
+		PreviewSigningKey:      GetEnv("PREVIEW_SIGNING_KEY", ""),
+		GRPCPort:               GetEnv("GRPC_PORT", ""),
+
+		BlobStore:           GetEnv("BLOB_STORE", "local"),
+		BlobDir:             GetEnv("BLOB_DIR", "./data"),
+		BlobBucket:          GetEnv("BLOB_BUCKET", ""),
+		BlobPrefix:          GetEnv("BLOB_PREFIX", ""),
+		BlobEndpoint:        GetEnv("BLOB_ENDPOINT", ""),
+		BlobRegion:          GetEnv("BLOB_REGION", ""),
+		BlobAccessKeyID:     GetEnv("BLOB_ACCESS_KEY_ID", ""),
+		BlobSecretAccessKey: GetEnv("BLOB_SECRET_ACCESS_KEY", ""),
+
+		CDNPurgeURL:   GetEnv("CDN_PURGE_URL", ""),
+		CDNPurgeToken: GetEnv("CDN_PURGE_TOKEN", ""),
+
+		ShipmentQuantityRanges: GetEnv("SHIPMENT_QUANTITY_RANGES", ""),
+		FaultInject:            GetEnv("FAULT_INJECT", ""),
+
+		CacheMaxAgeMap:     GetEnvInt("CACHE_MAX_AGE_MAP", 0),
+		CacheMaxAgeStores:  GetEnvInt("CACHE_MAX_AGE_STORES", 0),
+		CacheMaxAgeStats:   GetEnvInt("CACHE_MAX_AGE_STATS", 0),
+		CacheMaxAgeCatalog: GetEnvInt("CACHE_MAX_AGE_CATALOG", 0),
+
+		DailySyncHour:     GetEnvInt("DAILY_SYNC_HOUR", 0),
+		DailySyncMinute:   GetEnvInt("DAILY_SYNC_MINUTE", 0),
+		MonthlySyncDay:    GetEnvInt("MONTHLY_SYNC_DAY", 1),
+		MonthlySyncHour:   GetEnvInt("MONTHLY_SYNC_HOUR", 3),
+		MonthlySyncMinute: GetEnvInt("MONTHLY_SYNC_MINUTE", 0),
+
+		StoreGCIntervalHours: GetEnvInt("STORE_GC_INTERVAL_HOURS", 0),
+		StoreGCDays:          GetEnvInt("STORE_GC_DAYS", 30),
+		StoreGCAction:        GetEnv("STORE_GC_ACTION", "dry-run"),
+
+		DataSourcesFile:          GetEnv("DATA_SOURCES_FILE", ""),
+		GoogleSheetID:            GetEnv("GOOGLE_SHEET_ID", ""),
+		GoogleSheetURL:           GetEnv("GOOGLE_SHEET_URL", ""),
+		GoogleSheetGIDs:          GetEnv("GOOGLE_SHEET_GIDS", ""),
+		GoogleSheetNames:         GetEnv("GOOGLE_SHEET_NAMES", ""),
+		SheetSeasonYear:          GetEnvInt("SHEET_SEASON_YEAR", 0),
+		SheetDuplicateDatePolicy: GetEnv("SHEET_DUPLICATE_DATE_POLICY", "sum"),
+		SheetSizeWarnBytes:       GetEnvInt("SHEET_SIZE_WARN_BYTES", 10<<20),
+		SheetSnapshotFallback:    strings.EqualFold(GetEnv("SHEET_SNAPSHOT_FALLBACK", "false"), "true"),
+		SheetProductAliases:      GetEnv("SHEET_PRODUCT_ALIASES", ""),
+		PlacesAPIKey:             GetEnv("GOOGLE_PLACES_API_KEY", ""),
+		PlacesQPS:                GetEnvInt("PLACES_QPS", 10),
+		PlacesMode:               GetEnv("PLACES_MODE", "live"),
+		PlacesFixtures:           GetEnv("PLACES_FIXTURES_DIR", "./fixtures/places"),
+		DriveAPIKey:              GetEnv("GOOGLE_DRIVE_API_KEY", ""),
+
+		Env: GetEnv("GO_ENV", "development"),
+	}
+
+	// S3 沿用 AWS SDK 慣用的環境變數
+	if strings.EqualFold(c.BlobStore, "s3") {
+		c.BlobRegion = GetEnv("BLOB_REGION", GetEnv("AWS_REGION", ""))
+		c.BlobAccessKeyID = GetEnv("BLOB_ACCESS_KEY_ID", GetEnv("AWS_ACCESS_KEY_ID", ""))
+		c.BlobSecretAccessKey = GetEnv("BLOB_SECRET_ACCESS_KEY", GetEnv("AWS_SECRET_ACCESS_KEY", ""))
+	}
+	return c
+}
+
+// URLPath 在站內路徑前加上 BASE_PATH；完整網址（http://、https://）原樣回傳
+func (c *Config) URLPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return p
+	}
+	return c.BasePath + p
+}
+
+// normalizeBasePath 整理成 "/xxx" 的形式（去掉結尾的 /），"/" 或空字串表示根路徑
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// Redacted 回傳隱藏密鑰後的設定副本
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.DBPassword = redact(c.DBPassword)
+	r.SyncSecret = redact(c.SyncSecret)
+	r.AdminSecret = redact(c.AdminSecret)
+	r.APIKeys = redact(c.APIKeys)
+	r.PlacesAPIKey = redact(c.PlacesAPIKey)
+	r.DriveAPIKey = redact(c.DriveAPIKey)
+	r.CDNPurgeToken = redact(c.CDNPurgeToken)
+	r.PreviewSigningKey = redact(c.PreviewSigningKey)
+	r.BlobSecretAccessKey = redact(c.BlobSecretAccessKey)
+	return &r
+}
+
+// LogSummary 在啟動時輸出目前生效的設定（密鑰已隱藏）
+func (c *Config) LogSummary() {
+	r := c.Redacted()
+	log.Println("[INFO] ===== 生效設定 =====")
+	log.Printf("[INFO] 環境: %s", r.Env)
+	log.Printf("[INFO] 資料庫: %s@%s:%d/%s (密碼: %s)", r.DBUser, r.DBHost, r.DBPort, r.DBName, r.DBPassword)
+	log.Printf("[INFO] 連線池: API %d 條，同步 %d 條", r.DBMaxOpenConns, r.DBSyncMaxOpenConns)
+	log.Printf("[INFO] 自動套用資料表版本: %v", r.AutoMigrate)
+	if r.DBSlowQueryMS > 0 {
+		log.Printf("[INFO] 慢查詢記錄: 超過 %d ms", r.DBSlowQueryMS)
+	} else {
+		log.Println("[INFO] 慢查詢記錄: 停用")
+	}
+	log.Printf("[INFO] API 連接埠: %s（路徑前綴: %q）", r.APIPort, r.BasePath)
+	switch {
+	case r.TLSCertFile != "":
+		log.Printf("[INFO] HTTPS: 憑證 %s，金鑰 %s", r.TLSCertFile, r.TLSKeyFile)
+	case r.TLSAutocertDomains != "":
+		log.Printf("[INFO] HTTPS: Let's Encrypt 自動申請（%s，保存於 %s，驗證連接埠 %s）", r.TLSAutocertDomains, r.TLSAutocertCache, r.TLSHTTPPort)
+	default:
+		log.Println("[INFO] HTTPS: 停用")
+	}
+	log.Printf("[INFO] CORS 來源: %s", r.CORSOrigins)
+	log.Printf("[INFO] 查詢近 %d 天的出貨資料（?days= 最多 %d 天，指定區間最多 %d 天）", r.RecentDays, r.MaxRecentDays, r.MaxRangeDays)
+	log.Printf("[INFO] 手動同步 API: %v (密鑰: %s，模式: %s，程序識別: %s)", r.EnableSync, r.SyncSecret, r.SyncMode, r.InstanceID)
+	log.Printf("[INFO] 管理端點密鑰: %s", r.AdminSecret)
+	log.Printf("[INFO] 資料端點需要 API 金鑰: %v (環境變數金鑰: %s，新金鑰每月上限: %d)", r.RequireAPIKey, r.APIKeys, r.APIKeyMonthlyQuota)
+	if r.BlobStore == "local" {
+		log.Printf("[INFO] 檔案儲存: 本機目錄 %s", r.BlobDir)
+	} else {
+		log.Printf("[INFO] 檔案儲存: %s bucket %s（前綴: %q，端點: %s，金鑰: %s / %s）",
+			r.BlobStore, r.BlobBucket, r.BlobPrefix, r.BlobEndpoint, r.BlobAccessKeyID, r.BlobSecretAccessKey)
+	}
+	log.Printf("[INFO] 地圖回應快取: %d 秒", r.MapCacheTTLSeconds)
+	log.Printf("[INFO] 顯示時區: %s", r.DisplayTimezone)
+	if r.HiddenProducts != "" {
+		log.Printf("[INFO] 隱藏的產品: %s（預覽連結金鑰: %s）", r.HiddenProducts, r.PreviewSigningKey)
+	}
+	if r.GRPCPort != "" {
+		log.Printf("[INFO] gRPC 連接埠: %s", r.GRPCPort)
+	}
+	if r.StaticDir != "" {
+		log.Printf("[INFO] 靜態檔: 從磁碟讀取 %s", r.StaticDir)
+	} else {
+		log.Println("[INFO] 靜態檔: 使用嵌入的檔案")
+	}
+	log.Printf("[INFO] 關閉時最多等待 %d 秒", r.ShutdownTimeoutSeconds)
+	log.Printf("[INFO] CDN 清除 webhook: %s (token: %s)", r.CDNPurgeURL, r.CDNPurgeToken)
+	if r.ShipmentQuantityRanges != "" {
+		log.Printf("[INFO] 出貨數量合理範圍: %s", r.ShipmentQuantityRanges)
+	}
+	if r.FaultInject != "" {
+		log.Printf("[WARN] 故障注入設定: %s", r.FaultInject)
+	}
+	log.Printf("[INFO] Cache-Control max-age: 地圖 %d 秒，店家 %d 秒，統計 %d 秒，產品與區域 %d 秒",
+		r.CacheMaxAgeMap, r.CacheMaxAgeStores, r.CacheMaxAgeStats, r.CacheMaxAgeCatalog)
+	log.Printf("[INFO] 每日同步: %02d:%02d", r.DailySyncHour, r.DailySyncMinute)
+	log.Printf("[INFO] 每月同步: %d 號 %02d:%02d", r.MonthlySyncDay, r.MonthlySyncHour, r.MonthlySyncMinute)
+	if r.StoreGCIntervalHours > 0 {
+		log.Printf("[INFO] 孤兒店家清理: 每 %d 小時（%d 天未出現在工作表，處理方式: %s）", r.StoreGCIntervalHours, r.StoreGCDays, r.StoreGCAction)
+	} else {
+		log.Println("[INFO] 孤兒店家清理: 不排程")
+	}
+	if r.DataSourcesFile != "" {
+		log.Printf("[INFO] 資料來源設定檔: %s", r.DataSourcesFile)
+	} else {
+		sheet := r.GoogleSheetID
+		if r.GoogleSheetURL != "" {
+			sheet = r.GoogleSheetURL
+		}
+		log.Printf("[INFO] Google Sheet: %s (GIDs: %s, 名稱: %s)", sheet, r.GoogleSheetGIDs, r.GoogleSheetNames)
+	}
+	log.Printf("[INFO] 工作表: 重複日期 %s，超過 %d bytes 警告，下載失敗改用快照: %v", r.SheetDuplicateDatePolicy, r.SheetSizeWarnBytes, r.SheetSnapshotFallback)
+	if r.SheetProductAliases != "" {
+		log.Printf("[INFO] 工作表產品對應規則: %s", r.SheetProductAliases)
+	}
+	if r.SheetSeasonYear != 0 {
+		log.Printf("[INFO] 表頭日期的產季年份: %d", r.SheetSeasonYear)
+	}
+	log.Printf("[INFO] Places API 金鑰: %s（每秒最多 %d 次查詢）", r.PlacesAPIKey, r.PlacesQPS)
+	if c.DriveAPIKey != "" {
+		log.Printf("[INFO] Drive API 金鑰: %s（取得試算表修改時間）", r.DriveAPIKey)
+	}
+	if r.PlacesMode != "live" {
+		log.Printf("[INFO] Places API 模式: %s（fixture 目錄: %s）", r.PlacesMode, r.PlacesFixtures)
+	}
+	log.Println("[INFO] ====================")
+}
+
+// hostname INSTANCE_ID 的預設值（容器重新啟動後主機名稱不變，重新部署的 pod 會換名稱）
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "localhost"
+	}
+	return name
+}
+
+// redact 隱藏密鑰，只保留是否已設定
+func redact(secret string) string {
+	if secret == "" {
+		return "(未設定)"
+	}
+	return "****"
+}
+
+// GetEnv 取得環境變數，未設定時回傳預設值
+func GetEnv(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// GetEnvInt 取得整數環境變數，未設定或格式錯誤時回傳預設值
+func GetEnvInt(key string, def int) int {
+	val, err := strconv.Atoi(strings.TrimSpace(GetEnv(key, "")))
+	if err != nil {
+		return def
+	}
+	return val
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// apiKeyPrefixLen 列表中顯示的金鑰前綴長度（方便辨識，完整金鑰只保存雜湊）
+const apiKeyPrefixLen = 8
+
+// API 金鑰的權限範圍
+const (
+	ScopeReadMap     = "read:map"     // 地圖、店家、產品、區域、匯出、GraphQL 與開放資料
+	ScopeReadStats   = "read:stats"   // 統計（/stats/*）
+	ScopeWriteSync   = "write:sync"   // 觸發同步與查詢同步工作
+	ScopeAdminStores = "admin:stores" // 店家維護（/admin/stores/*）
+)
+
+// APIKeyScopes 所有權限範圍
+var APIKeyScopes = []string{ScopeReadMap, ScopeReadStats, ScopeWriteSync, ScopeAdminStores}
+
+// DefaultAPIKeyScopes 未指定權限範圍時的預設值，也是 API_KEYS 環境變數金鑰的權限（只能讀取資料）
+var DefaultAPIKeyScopes = []string{ScopeReadMap, ScopeReadStats}
+
+// IsValidAPIKeyScope 是否為支援的權限範圍
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey 存取資料端點的 API 金鑰（不含金鑰本身）
+type APIKey struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Prefix       string    `json:"prefix"`
+	Scopes       []string  `json:"scopes"`
+	IsActive     bool      `json:"isActive"`
+	MonthlyQuota int       `json:"monthlyQuota"` // 每月請求上限，0 表示不限
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// HasScope 金鑰是否有指定的權限範圍
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIKey 金鑰的 SHA-256（十六進位），資料庫只保存雜湊
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey 新增 API 金鑰（scopes 需先以 IsValidAPIKeyScope 檢查，monthlyQuota 為 0 表示不限）
+func CreateAPIKey(db *sql.DB, name, key string, scopes []string, monthlyQuota int) (*APIKey, error) {
+	k := APIKey{Name: name, Prefix: key, Scopes: scopes, MonthlyQuota: monthlyQuota}
+	if len(k.Prefix) > apiKeyPrefixLen {
+		k.Prefix = k.Prefix[:apiKeyPrefixLen]
+	}
+	err := db.QueryRow(`
+		INSERT INTO api_keys (name, key_hash, key_prefix, scopes, monthly_quota)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, is_active, created_at
+	`, name, HashAPIKey(key), k.Prefix, pq.Array(scopes), monthlyQuota).Scan(&k.ID, &k.IsActive, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys 列出所有 API 金鑰
+func ListAPIKeys(db *sql.DB) ([]APIKey, error) {
+	rows, err := db.Query(`
+		SELECT id, name, key_prefix, scopes, is_active, monthly_quota, created_at
+		FROM api_keys
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsActive, &k.MonthlyQuota, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// SetAPIKeyActive 啟用或停用 API 金鑰，不存在時回傳 sql.ErrNoRows
+func SetAPIKeyActive(db *sql.DB, id int, active bool) (*APIKey, error) {
+	var k APIKey
+	err := db.QueryRow(`
+		UPDATE api_keys SET is_active = $2
+		WHERE id = $1
+		RETURNING id, name, key_prefix, scopes, is_active, monthly_quota, created_at
+	`, id, active).Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsActive, &k.MonthlyQuota, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// SetAPIKeyScopes 更新 API 金鑰的權限範圍，不存在時回傳 sql.ErrNoRows
+func SetAPIKeyScopes(db *sql.DB, id int, scopes []string) (*APIKey, error) {
+	var k APIKey
+	err := db.QueryRow(`
+		UPDATE api_keys SET scopes = $2
+		WHERE id = $1
+		RETURNING id, name, key_prefix, scopes, is_active, monthly_quota, created_at
+	`, id, pq.Array(scopes)).Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsActive, &k.MonthlyQuota, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// SetAPIKeyQuota 更新 API 金鑰的每月請求上限（0 表示不限），不存在時回傳 sql.ErrNoRows
+func SetAPIKeyQuota(db *sql.DB, id, monthlyQuota int) (*APIKey, error) {
+	var k APIKey
+	err := db.QueryRow(`
+		UPDATE api_keys SET monthly_quota = $2
+		WHERE id = $1
+		RETURNING id, name, key_prefix, scopes, is_active, monthly_quota, created_at
+	`, id, monthlyQuota).Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsActive, &k.MonthlyQuota, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// DeleteAPIKey 刪除 API 金鑰，不存在時回傳 sql.ErrNoRows
+func DeleteAPIKey(db *sql.DB, id int) error {
+	result, err := db.Exec(`DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FindActiveAPIKey 以金鑰查詢啟用中的 API 金鑰，不存在或已停用時回傳 sql.ErrNoRows
+func FindActiveAPIKey(db *sql.DB, key string) (*APIKey, error) {
+	var k APIKey
+	err := db.QueryRow(`
+		SELECT id, name, key_prefix, scopes, is_active, monthly_quota, created_at
+		FROM api_keys
+		WHERE key_hash = $1 AND is_active
+	`, HashAPIKey(key)).Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsActive, &k.MonthlyQuota, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// APIKeyUsage 金鑰一個月的用量
+type APIKeyUsage struct {
+	APIKeyID     int    `json:"apiKeyId"`
+	Name         string `json:"name"`
+	Prefix       string `json:"prefix"`
+	IsActive     bool   `json:"isActive"`
+	MonthlyQuota int    `json:"monthlyQuota"`
+	Requests     int64  `json:"requests"`
+	Rejected     int64  `json:"rejected"` // 超過上限被拒絕的請求
+}
+
+// MonthStart t 所在月份的第一天（t 的時區），用量以這個日期分月
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// RecordAPIKeyUsage 記錄金鑰在 month 月份的一次請求；已達每月上限時不計入用量、改記錄為拒絕並回傳 false。
+// 計數與上限檢查在同一個 UPSERT 完成，多個副本同時處理請求時也不會超過上限
+func RecordAPIKeyUsage(db *sql.DB, k *APIKey, month time.Time) (bool, error) {
+	var requests int64
+	err := db.QueryRow(`
+		INSERT INTO api_key_usage (api_key_id, month, requests)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (api_key_id, month) DO UPDATE SET requests = api_key_usage.requests + 1
+		WHERE $3::int = 0 OR api_key_usage.requests < $3::int
+		RETURNING requests
+	`, k.ID, month.Format("2006-01-02"), k.MonthlyQuota).Scan(&requests)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+	_, err = db.Exec(`
+		UPDATE api_key_usage SET rejected = rejected + 1
+		WHERE api_key_id = $1 AND month = $2
+	`, k.ID, month.Format("2006-01-02"))
+	return false, err
+}
+
+// GetAPIKeyUsage 所有金鑰在 month 月份的用量（沒有請求的金鑰用量為 0），依金鑰 ID 排序
+func GetAPIKeyUsage(db *sql.DB, month time.Time) ([]APIKeyUsage, error) {
+	rows, err := db.Query(`
+		SELECT k.id, k.name, k.key_prefix, k.is_active, k.monthly_quota,
+		       COALESCE(u.requests, 0), COALESCE(u.rejected, 0)
+		FROM api_keys k
+		LEFT JOIN api_key_usage u ON u.api_key_id = k.id AND u.month = $1
+		ORDER BY k.id
+	`, month.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []APIKeyUsage{}
+	for rows.Next() {
+		var u APIKeyUsage
+		if err := rows.Scan(&u.APIKeyID, &u.Name, &u.Prefix, &u.IsActive, &u.MonthlyQuota, &u.Requests, &u.Rejected); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
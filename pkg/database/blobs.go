@@ -0,0 +1,19 @@
+package database
+
+import (
+	"database/sql"
+
+	"PXMarkMapBackEnd/pkg/storage"
+)
+
+// readBlobColumn 讀取 blob_key 指向的內容；升級前寫入的資料沒有 blob_key，改用 data 欄位
+func readBlobColumn(blobs storage.BlobStore, blobKey, data sql.NullString) ([]byte, error) {
+	if blobKey.Valid && blobKey.String != "" {
+		obj, err := blobs.Get(blobKey.String)
+		if err != nil {
+			return nil, err
+		}
+		return obj.Data, nil
+	}
+	return []byte(data.String), nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// 批次店家操作
+const (
+	BulkActionDeactivate = "deactivate"
+	BulkActionReactivate = "reactivate"
+	BulkActionRegeocode  = "re-geocode"
+)
+
+// StoreFilter 批次操作選取店家的條件（多個條件同時成立）
+type StoreFilter struct {
+	Region          string `json:"region,omitempty"`
+	NoShipmentSince string `json:"noShipmentSince,omitempty"` // YYYY-MM-DD，這天（含）之後沒有任何出貨
+	SourceID        string `json:"sourceId,omitempty"`
+}
+
+// IsEmpty 沒有任何條件（批次操作不允許一次套用到所有店家）
+func (f StoreFilter) IsEmpty() bool {
+	return f.Region == "" && f.NoShipmentSince == "" && f.SourceID == ""
+}
+
+// BulkStoreResult 批次操作中單一店家的結果
+type BulkStoreResult struct {
+	StoreID   int    `json:"storeId"`
+	StoreName string `json:"storeName"`
+	Status    string `json:"status"` // 'updated', 'unchanged', 'failed'
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkStoreJob 背景執行的批次店家操作與結果報告
+type BulkStoreJob struct {
+	ID          int               `json:"id"`
+	Action      string            `json:"action"`
+	Filter      StoreFilter       `json:"filter"`
+	Status      string            `json:"status"` // 'running', 'success', 'failed'
+	Total       int               `json:"total"`
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	Results     []BulkStoreResult `json:"results"`
+	Error       string            `json:"error,omitempty"`
+	RequestedAt time.Time         `json:"requestedAt"`
+	FinishedAt  *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// FindStoresByFilter 依條件選取店家（依 ID 排序）
+func FindStoresByFilter(db *sql.DB, f StoreFilter) ([]StoreRecord, error) {
+	rows, err := db.Query(`
+		SELECT `+storeColumns+`
+		FROM stores s
+		WHERE ($1 = '' OR s.region = $1)
+		  AND ($2 = '' OR s.source_id = $2)
+		  AND ($3 = '' OR NOT EXISTS (
+			SELECT 1 FROM shipments sh
+			WHERE sh.store_id = s.id
+			  AND sh.shipment_date >= NULLIF($3, '')::date
+			  AND sh.quantity IS NOT NULL
+			  AND sh.quantity != ''
+			  AND sh.quantity != '0'
+		  ))
+		ORDER BY s.id
+	`, f.Region, f.SourceID, f.NoShipmentSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []StoreRecord{}
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	return stores, rows.Err()
+}
+
+// CreateBulkStoreJob 建立執行中的批次操作紀錄
+func CreateBulkStoreJob(db *sql.DB, action string, filter StoreFilter) (int, error) {
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO bulk_store_jobs (action, filter, status)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, action, string(filterJSON), JobStatusRunning).Scan(&id)
+	return id, err
+}
+
+// FinishBulkStoreJob 記錄批次操作的結果報告（jobErr 不為 nil 表示選取店家等步驟失敗，整批未執行）
+func FinishBulkStoreJob(db *sql.DB, id int, results []BulkStoreResult, jobErr error) error {
+	status, message := JobStatusSuccess, ""
+	if jobErr != nil {
+		status, message = JobStatusFailed, jobErr.Error()
+	}
+	succeeded, failed := 0, 0
+	for _, r := range results {
+		if r.Status == "failed" {
+			failed++
+		} else {
+			succeeded++
+		}
+	}
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE bulk_store_jobs
+		SET status = $1, total = $2, succeeded = $3, failed = $4, results = $5,
+			error = NULLIF($6, ''), finished_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+	`, status, len(results), succeeded, failed, string(resultsJSON), message, id)
+	return err
+}
+
+// GetBulkStoreJob 取得批次操作與結果報告，不存在時回傳 sql.ErrNoRows
+func GetBulkStoreJob(db *sql.DB, id int) (*BulkStoreJob, error) {
+	job := &BulkStoreJob{Results: []BulkStoreResult{}}
+	var filterJSON string
+	var results, message sql.NullString
+	var finishedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, action, filter, status, total, succeeded, failed, results, error, requested_at, finished_at
+		FROM bulk_store_jobs
+		WHERE id = $1
+	`, id).Scan(&job.ID, &job.Action, &filterJSON, &job.Status, &job.Total, &job.Succeeded, &job.Failed,
+		&results, &message, &job.RequestedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(filterJSON), &job.Filter); err != nil {
+		return nil, err
+	}
+	if results.Valid {
+		if err := json.Unmarshal([]byte(results.String), &job.Results); err != nil {
+			return nil, err
+		}
+	}
+	job.Error = message.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StoreCalendar 店家某個月份每天的出貨（Products 為 產品 → 每日數量，與 Days 對應，沒有出貨的日子為空字串）
+type StoreCalendar struct {
+	StoreID   int                 `json:"storeId"`
+	StoreName string              `json:"storeName"`
+	Month     string              `json:"month"`
+	Days      []string            `json:"days"`
+	Products  map[string][]string `json:"products"`
+	// ProductNames 產品 → 顯示名稱（由 API 層依 Accept-Language 填入）
+	ProductNames map[string]string `json:"productNames,omitempty"`
+}
+
+// GetStoreCalendar 取得店家在 month 所屬月份的每日出貨矩陣，店家不存在時回傳 sql.ErrNoRows
+func GetStoreCalendar(db *sql.DB, storeID int, month time.Time) (*StoreCalendar, error) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+
+	cal := &StoreCalendar{
+		StoreID:  storeID,
+		Month:    first.Format("2006-01"),
+		Products: make(map[string][]string),
+	}
+	if err := db.QueryRow(`SELECT store_name FROM stores WHERE id = $1`, storeID).Scan(&cal.StoreName); err != nil {
+		return nil, err
+	}
+
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		cal.Days = append(cal.Days, d.Format("2006-01-02"))
+	}
+
+	rows, err := db.Query(`
+		WITH products AS (
+			SELECT DISTINCT product_type
+			FROM shipments
+			WHERE store_id = $1 AND shipment_date BETWEEN $2::date AND $3::date
+		)
+		SELECT p.product_type, ARRAY_AGG(COALESCE(sh.quantity, '') ORDER BY d.day)
+		FROM products p
+		CROSS JOIN generate_series($2::date, $3::date, INTERVAL '1 day') AS d(day)
+		LEFT JOIN (
+			-- 同一天多個時段合併為一格
+			SELECT sh.product_type, sh.shipment_date, `+dailyQuantity+` AS quantity
+			FROM shipments sh
+			WHERE sh.store_id = $1
+			  AND sh.shipment_date BETWEEN $2::date AND $3::date
+			  AND sh.quality_flag IS NULL
+			GROUP BY sh.product_type, sh.shipment_date
+		) sh
+		       ON sh.product_type = p.product_type
+		      AND sh.shipment_date = d.day::date
+		GROUP BY p.product_type
+	`, storeID, first.Format("2006-01-02"), last.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product string
+		var quantities pq.StringArray
+		if err := rows.Scan(&product, &quantities); err != nil {
+			return nil, err
+		}
+		cal.Products[product] = quantities
+	}
+	return cal, rows.Err()
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 店家狀態檢查旗標
+const (
+	FlagClosedButShipping   = "closed_but_shipping"  // Places 標示永久停業但仍有出貨（可能配對錯地點）
+	FlagNoRecentShipments   = "no_recent_shipments"  // 有座標但長期沒有出貨（可考慮停用）
+	FlagCoordinateCollision = "coordinate_collision" // 與其他店家座標完全相同（同一棟建築或配對錯地點）
+)
+
+// 店家狀態檢查門檻
+const (
+	ClosedShippingDays = 14 // 永久停業店家近幾天仍有出貨即標記
+	InactiveDays       = 90 // 超過幾天沒有出貨即標記
+)
+
+// StoreFlag 店家檢查結果
+type StoreFlag struct {
+	StoreID   int       `json:"storeId"`
+	StoreName string    `json:"storeName"`
+	Flag      string    `json:"flag"`
+	Detail    string    `json:"detail"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// StoreOverview 管理總覽統計
+type StoreOverview struct {
+	TotalStores        int         `json:"totalStores"`
+	StoresWithLocation int         `json:"storesWithLocation"`
+	TotalShipments     int         `json:"totalShipments"`
+	LatestShipmentDate string      `json:"latestShipmentDate"`
+	Flags              []StoreFlag `json:"flags"`
+}
+
+// RunStoreStatusCheck 交叉比對店家營業狀態與出貨紀錄，重新產生檢查旗標
+func RunStoreStatusCheck(db *sql.DB) (map[string]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM store_flags WHERE flag IN ($1, $2, $3)`,
+		FlagClosedButShipping, FlagNoRecentShipments, FlagCoordinateCollision); err != nil {
+		return nil, err
+	}
+
+	checks := []struct {
+		flag  string
+		query string
+	}{
+		{
+			flag: FlagClosedButShipping,
+			query: fmt.Sprintf(`
+				INSERT INTO store_flags (store_id, flag, detail, checked_at)
+				SELECT s.id, $1, 'Places 標示永久停業，但近 %d 天仍有出貨', CURRENT_TIMESTAMP
+				FROM stores s
+				WHERE s.business_status = 'CLOSED_PERMANENTLY'
+				  AND EXISTS (
+					SELECT 1 FROM shipments sh
+					WHERE sh.store_id = s.id
+					  AND sh.shipment_date >= CURRENT_DATE - INTERVAL '%d days'
+					  AND sh.quantity IS NOT NULL
+					  AND sh.quantity != ''
+					  AND sh.quantity != '0'
+				  )
+			`, ClosedShippingDays, ClosedShippingDays),
+		},
+		{
+			flag: FlagNoRecentShipments,
+			query: fmt.Sprintf(`
+				INSERT INTO store_flags (store_id, flag, detail, checked_at)
+				SELECT s.id, $1, '已有座標，但超過 %d 天沒有出貨', CURRENT_TIMESTAMP
+				FROM stores s
+				WHERE s.latitude IS NOT NULL
+				  AND s.longitude IS NOT NULL
+				  AND NOT EXISTS (
+					SELECT 1 FROM shipments sh
+					WHERE sh.store_id = s.id
+					  AND sh.shipment_date >= CURRENT_DATE - INTERVAL '%d days'
+					  AND sh.quantity IS NOT NULL
+					  AND sh.quantity != ''
+					  AND sh.quantity != '0'
+				  )
+			`, InactiveDays, InactiveDays),
+		},
+		{
+			flag: FlagCoordinateCollision,
+			query: `
+				INSERT INTO store_flags (store_id, flag, detail, checked_at)
+				SELECT s.id, $1, '與 ' || STRING_AGG(o.store_name, '、' ORDER BY o.store_name) || ' 座標相同', CURRENT_TIMESTAMP
+				FROM stores s
+				JOIN stores o
+				  ON o.id != s.id
+				 AND o.is_active
+				 AND o.latitude = s.latitude
+				 AND o.longitude = s.longitude
+				WHERE s.is_active
+				GROUP BY s.id
+			`,
+		},
+	}
+
+	counts := make(map[string]int)
+	for _, check := range checks {
+		result, err := tx.Exec(check.query, check.flag)
+		if err != nil {
+			return nil, fmt.Errorf("店家檢查 %s 失敗: %v", check.flag, err)
+		}
+		n, _ := result.RowsAffected()
+		counts[check.flag] = int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 店家狀態檢查完成: 停業仍出貨 %d 家，長期無出貨 %d 家，座標重疊 %d 家",
+		counts[FlagClosedButShipping], counts[FlagNoRecentShipments], counts[FlagCoordinateCollision])
+	return counts, nil
+}
+
+// GetStoreFlags 取得店家檢查旗標
+func GetStoreFlags(db *sql.DB) ([]StoreFlag, error) {
+	rows, err := db.Query(`
+		SELECT f.store_id, s.store_name, f.flag, COALESCE(f.detail, ''), f.checked_at
+		FROM store_flags f
+		JOIN stores s ON s.id = f.store_id
+		ORDER BY f.flag, s.store_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []StoreFlag{}
+	for rows.Next() {
+		var f StoreFlag
+		if err := rows.Scan(&f.StoreID, &f.StoreName, &f.Flag, &f.Detail, &f.CheckedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+
+	return flags, rows.Err()
+}
+
+// GetStoreOverview 取得管理總覽統計與檢查旗標
+func GetStoreOverview(db *sql.DB) (*StoreOverview, error) {
+	var overview StoreOverview
+	var latest sql.NullTime
+
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM stores),
+			(SELECT COUNT(*) FROM stores WHERE latitude IS NOT NULL AND longitude IS NOT NULL),
+			(SELECT COUNT(*) FROM shipments),
+			(SELECT MAX(shipment_date) FROM shipments)
+	`).Scan(&overview.TotalStores, &overview.StoresWithLocation, &overview.TotalShipments, &latest)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Valid {
+		overview.LatestShipmentDate = latest.Time.Format("2006-01-02")
+	}
+
+	overview.Flags, err = GetStoreFlags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &overview, nil
+}
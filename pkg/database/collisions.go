@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CollisionOffsetMeters 座標相同的店家在地圖上分散排列的半徑（公尺）
+const CollisionOffsetMeters = 10
+
+// collisionOffsetDegrees 約 CollisionOffsetMeters 公尺的緯度差
+const collisionOffsetDegrees = CollisionOffsetMeters / 111320.0
+
+// collisionGroups 啟用店家依座標分組：idx 為店家在同座標組內的順序（依 ID），n 為組內店家數
+const collisionGroups = `(
+			SELECT id,
+			       ROW_NUMBER() OVER (PARTITION BY latitude, longitude ORDER BY id) - 1 AS idx,
+			       COUNT(*) OVER (PARTITION BY latitude, longitude) AS n
+			FROM stores
+			WHERE is_active
+		)`
+
+// displayLatitude / displayLongitude 地圖顯示用的座標：與其他店家座標相同時，依組內順序平均分布在
+// 半徑 CollisionOffsetMeters 公尺的圓上（結果固定，不影響資料庫中的座標），c 為 collisionGroups
+var (
+	displayLatitude = fmt.Sprintf(`s.latitude + CASE WHEN c.n > 1
+			THEN %g * SIN(2 * PI() * c.idx / c.n) ELSE 0 END`, collisionOffsetDegrees)
+	displayLongitude = fmt.Sprintf(`s.longitude + CASE WHEN c.n > 1
+			THEN %g * COS(2 * PI() * c.idx / c.n) / COS(RADIANS(s.latitude)) ELSE 0 END`, collisionOffsetDegrees)
+)
+
+// CollidingStore 座標重疊組中的店家
+type CollidingStore struct {
+	StoreID              int    `json:"storeId"`
+	StoreName            string `json:"storeName"`
+	Address              string `json:"address"`
+	PlaceID              string `json:"placeId"`
+	CoordinateProvenance string `json:"coordinateProvenance"`
+}
+
+// CoordinateCollision 座標完全相同的一組啟用店家
+type CoordinateCollision struct {
+	Latitude  float64          `json:"latitude"`
+	Longitude float64          `json:"longitude"`
+	Stores    []CollidingStore `json:"stores"`
+}
+
+// GetCoordinateCollisions 列出座標完全相同的啟用店家（依座標分組，組內依店家 ID 排序）
+func GetCoordinateCollisions(db *sql.DB) ([]CoordinateCollision, error) {
+	rows, err := db.Query(`
+		SELECT s.latitude, s.longitude, s.id, s.store_name, COALESCE(s.formatted_address, ''),
+		       COALESCE(s.place_id, ''), COALESCE(s.coordinate_provenance, '')
+		FROM stores s
+		JOIN ` + collisionGroups + ` c ON c.id = s.id
+		WHERE c.n > 1
+		  AND s.latitude IS NOT NULL
+		  AND s.longitude IS NOT NULL
+		ORDER BY s.latitude, s.longitude, s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collisions := []CoordinateCollision{}
+	for rows.Next() {
+		var lat, lng float64
+		var s CollidingStore
+		if err := rows.Scan(&lat, &lng, &s.StoreID, &s.StoreName, &s.Address, &s.PlaceID, &s.CoordinateProvenance); err != nil {
+			return nil, err
+		}
+		if n := len(collisions); n == 0 || collisions[n-1].Latitude != lat || collisions[n-1].Longitude != lng {
+			collisions = append(collisions, CoordinateCollision{Latitude: lat, Longitude: lng})
+		}
+		last := &collisions[len(collisions)-1]
+		last.Stores = append(last.Stores, s)
+	}
+	return collisions, rows.Err()
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// 座標來源（stores.coordinate_provenance）
+const (
+	ProvenancePlaces       = "places"        // Places API 查詢結果
+	ProvenanceManualImport = "manual_import" // import-coordinates 匯入的人工修正，同步時不會被覆蓋
+)
+
+// CoordinateFix 一筆座標修正，以店名或 place_id 找出店家（兩者都有時以店名為準）
+type CoordinateFix struct {
+	Line      int // 來源檔案的行號，用於錯誤訊息
+	StoreName string
+	PlaceID   string
+	Latitude  float64
+	Longitude float64
+}
+
+// CoordinateImportResult 座標匯入結果
+type CoordinateImportResult struct {
+	Updated   int
+	Unchanged int
+	NotFound  []string // 找不到店家的修正（行號與識別）
+}
+
+// ImportCoordinates 在同一個交易內套用所有座標修正，標記為 manual_import 並寫入稽核紀錄
+func ImportCoordinates(db *sql.DB, fixes []CoordinateFix, changedBy string) (*CoordinateImportResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &CoordinateImportResult{}
+	for _, fix := range fixes {
+		var id int
+		var oldLat, oldLng sql.NullFloat64
+		var oldProvenance sql.NullString
+		if fix.StoreName != "" {
+			err = tx.QueryRow(`SELECT id, latitude, longitude, coordinate_provenance FROM stores WHERE store_name = $1 FOR UPDATE`,
+				fix.StoreName).Scan(&id, &oldLat, &oldLng, &oldProvenance)
+		} else {
+			err = tx.QueryRow(`SELECT id, latitude, longitude, coordinate_provenance FROM stores WHERE place_id = $1 ORDER BY id LIMIT 1 FOR UPDATE`,
+				fix.PlaceID).Scan(&id, &oldLat, &oldLng, &oldProvenance)
+		}
+		if err == sql.ErrNoRows {
+			result.NotFound = append(result.NotFound, fmt.Sprintf("第 %d 行: %s%s", fix.Line, fix.StoreName, fix.PlaceID))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行查詢店家失敗: %v", fix.Line, err)
+		}
+
+		if oldLat.Valid && oldLng.Valid && oldLat.Float64 == fix.Latitude && oldLng.Float64 == fix.Longitude &&
+			oldProvenance.String == ProvenanceManualImport {
+			result.Unchanged++
+			continue
+		}
+
+		_, err = tx.Exec(`
+			UPDATE stores
+			SET latitude = $1, longitude = $2, coordinate_provenance = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $4
+		`, fix.Latitude, fix.Longitude, ProvenanceManualImport, id)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行更新店家 #%d 失敗: %v", fix.Line, id, err)
+		}
+
+		changes := []FieldChange{
+			{Field: "latitude", OldValue: formatNullFloat(oldLat), NewValue: strconv.FormatFloat(fix.Latitude, 'f', -1, 64)},
+			{Field: "longitude", OldValue: formatNullFloat(oldLng), NewValue: strconv.FormatFloat(fix.Longitude, 'f', -1, 64)},
+			{Field: "coordinate_provenance", OldValue: oldProvenance.String, NewValue: ProvenanceManualImport},
+		}
+		for _, change := range changes {
+			if change.OldValue == change.NewValue {
+				continue
+			}
+			_, err = tx.Exec(`
+				INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+				VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+			`, id, change.Field, change.OldValue, change.NewValue, changedBy)
+			if err != nil {
+				return nil, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+			}
+		}
+		result.Updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 座標匯入完成: 更新 %d 筆，未變更 %d 筆，找不到 %d 筆", result.Updated, result.Unchanged, len(result.NotFound))
+	return result, nil
+}
+
+func formatNullFloat(v sql.NullFloat64) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(v.Float64, 'f', -1, 64)
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/storage"
+)
+
+// Export 一個月份的出貨封存（CSV 內容保存在 BLOB_STORE 的 exports/ 底下，資料表只記錄 key）
+type Export struct {
+	ID        int       `json:"id"`
+	Month     string    `json:"month"` // YYYY-MM
+	FileName  string    `json:"fileName"`
+	RowCount  int       `json:"rowCount"`
+	SizeBytes int       `json:"sizeBytes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// exportHeader 封存 CSV 的欄位
+var exportHeader = []string{"store_id", "store_name", "region", "source_id", "product_type", "shipment_date", "time_slot", "quantity"}
+
+// ExportMonthlyShipments 將 month 所屬月份的出貨匯出成 CSV 保存到 blobs，並記錄到 exports（同一個月份重新匯出時覆蓋）
+func ExportMonthlyShipments(db *sql.DB, blobs storage.BlobStore, month time.Time) (*Export, error) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	next := first.AddDate(0, 1, 0)
+
+	rows, err := db.Query(`
+		SELECT s.id, s.store_name, COALESCE(s.region, ''), COALESCE(sh.source_id, ''),
+		       sh.product_type, TO_CHAR(sh.shipment_date, 'YYYY-MM-DD'), sh.time_slot, COALESCE(sh.quantity, '')
+		FROM shipments sh
+		JOIN stores s ON s.id = sh.store_id
+		WHERE sh.shipment_date >= $1 AND sh.shipment_date < $2
+		ORDER BY sh.shipment_date, s.store_name, sh.product_type, sh.time_slot
+	`, first.Format("2006-01-02"), next.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(exportHeader)
+
+	count := 0
+	for rows.Next() {
+		var storeID int
+		var storeName, region, sourceID, product, date, slot, quantity string
+		if err := rows.Scan(&storeID, &storeName, &region, &sourceID, &product, &date, &slot, &quantity); err != nil {
+			return nil, err
+		}
+		w.Write([]string{strconv.Itoa(storeID), storeName, region, sourceID, product, date, slot, quantity})
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		Month:     first.Format("2006-01"),
+		FileName:  fmt.Sprintf("shipments-%s.csv", first.Format("2006-01")),
+		RowCount:  count,
+		SizeBytes: buf.Len(),
+	}
+	blobKey := "exports/" + export.FileName
+	if err := blobs.Put(blobKey, buf.Bytes(), "text/csv; charset=utf-8"); err != nil {
+		return nil, err
+	}
+	err = db.QueryRow(`
+		INSERT INTO exports (month, file_name, row_count, size_bytes, blob_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (month) DO UPDATE SET
+			file_name = EXCLUDED.file_name,
+			row_count = EXCLUDED.row_count,
+			size_bytes = EXCLUDED.size_bytes,
+			data = NULL,
+			blob_key = EXCLUDED.blob_key,
+			created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at
+	`, export.Month, export.FileName, export.RowCount, export.SizeBytes, blobKey).Scan(&export.ID, &export.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ListExports 列出所有封存（新到舊）
+func ListExports(db *sql.DB) ([]Export, error) {
+	rows, err := db.Query(`
+		SELECT id, month, file_name, row_count, size_bytes, created_at
+		FROM exports
+		ORDER BY month DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []Export{}
+	for rows.Next() {
+		var e Export
+		if err := rows.Scan(&e.ID, &e.Month, &e.FileName, &e.RowCount, &e.SizeBytes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// GetExportData 取得封存的檔名與 CSV 內容，不存在時回傳 sql.ErrNoRows
+func GetExportData(db *sql.DB, blobs storage.BlobStore, id int) (string, []byte, error) {
+	var fileName string
+	var data, blobKey sql.NullString
+	err := db.QueryRow(`SELECT file_name, data, blob_key FROM exports WHERE id = $1`, id).Scan(&fileName, &data, &blobKey)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := readBlobColumn(blobs, blobKey, data)
+	return fileName, content, err
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// settingDataGeneration settings 中資料版本的鍵
+const settingDataGeneration = "data_generation"
+
+// NextDataGeneration 資料版本加一並回傳新版本（每次成功同步呼叫一次，版本只增不減）
+func NextDataGeneration(db *sql.DB) (int64, error) {
+	var value string
+	err := db.QueryRow(`
+		INSERT INTO settings (key, value) VALUES ($1, '1')
+		ON CONFLICT (key) DO UPDATE SET
+			value = (settings.value::bigint + 1)::text,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING value
+	`, settingDataGeneration).Scan(&value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// GetDataGeneration 目前的資料版本（尚未成功同步過時為 0）
+func GetDataGeneration(db *sql.DB) (int64, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = $1`, settingDataGeneration).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 索引建議的門檻
+const (
+	// advisorLargeTableRows 資料列數達到此值才檢查循序掃描
+	advisorLargeTableRows = 10000
+	// advisorSlowStatementMs pg_stat_statements 平均耗時超過此毫秒數才列出
+	advisorSlowStatementMs = 50
+	// advisorMaxStatements 最多列出幾個慢查詢
+	advisorMaxStatements = 10
+)
+
+// queryPattern 本套件已知的查詢模式，需要以 Columns 開頭的索引
+type queryPattern struct {
+	Table   string
+	Columns []string
+	UsedBy  string
+}
+
+// knownQueryPatterns 各端點與同步流程實際使用的條件欄位（新增查詢時請一併更新）
+var knownQueryPatterns = []queryPattern{
+	{"shipments", []string{"shipment_date"}, "/api/v1/shopeMap 近 N 天與日期範圍、開放資料"},
+	{"shipments", []string{"store_id"}, "店家日曆、附近店家、刪除店家"},
+	{"shipments", []string{"store_id", "product_type", "shipment_date"}, "同步寫入出貨（ON CONFLICT）"},
+	{"shipments", []string{"source_id"}, "清除資料來源"},
+	{"stores", []string{"store_name"}, "同步寫入店家（ON CONFLICT）"},
+	{"stores", []string{"place_id"}, "import-coordinates 以 place_id 比對店家"},
+	{"stores", []string{"region"}, "配送區域店家、批次店家操作的區域條件"},
+	{"sync_logs", []string{"status", "start_time"}, "/healthz、/api/v1/syncStatus、/ws 的最後成功同步時間"},
+	{"store_audit_logs", []string{"store_id"}, "店家修改紀錄"},
+	{"shipment_audit_logs", []string{"shipment_id"}, "出貨修正紀錄"},
+	{"webhook_deliveries", []string{"webhook_id"}, "webhook 投遞紀錄"},
+	{"district_daily_totals", []string{"date"}, "開放資料區域彙總"},
+}
+
+// TableScanStat 一個資料表的掃描統計（pg_stat_user_tables）
+type TableScanStat struct {
+	Table          string `json:"table"`
+	LiveRows       int64  `json:"liveRows"`
+	SizeBytes      int64  `json:"sizeBytes"`
+	SeqScans       int64  `json:"seqScans"`
+	SeqRowsRead    int64  `json:"seqRowsRead"`
+	IndexScans     int64  `json:"indexScans"`
+	SeqScanSuspect bool   `json:"seqScanSuspect"` // 大資料表以循序掃描為主
+}
+
+// MissingIndex 已知查詢模式缺少的索引
+type MissingIndex struct {
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	UsedBy     string   `json:"usedBy"`
+	Suggestion string   `json:"suggestion"`
+}
+
+// StatementStat pg_stat_statements 中平均耗時較高的查詢
+type StatementStat struct {
+	Query   string  `json:"query"`
+	Calls   int64   `json:"calls"`
+	MeanMs  float64 `json:"meanMs"`
+	TotalMs float64 `json:"totalMs"`
+	Rows    int64   `json:"rows"`
+}
+
+// IndexReport 索引建議報告
+type IndexReport struct {
+	GeneratedAt         time.Time       `json:"generatedAt"`
+	Tables              []TableScanStat `json:"tables"`
+	MissingIndexes      []MissingIndex  `json:"missingIndexes"`
+	StatementsAvailable bool            `json:"statementsAvailable"`
+	SlowStatements      []StatementStat `json:"slowStatements"`
+	StatsSince          *time.Time      `json:"statsSince,omitempty"` // 統計重設的時間，太近時數字參考價值有限
+	Notes               []string        `json:"notes"`
+}
+
+// AdviseIndexes 依資料表掃描統計、已知查詢模式與 pg_stat_statements（有安裝時）產生索引建議
+func AdviseIndexes(db *sql.DB) (*IndexReport, error) {
+	report := &IndexReport{
+		GeneratedAt:    time.Now(),
+		Tables:         []TableScanStat{},
+		MissingIndexes: []MissingIndex{},
+		SlowStatements: []StatementStat{},
+		Notes:          []string{},
+	}
+
+	tables, err := tableScanStats(db)
+	if err != nil {
+		return nil, fmt.Errorf("讀取 pg_stat_user_tables 失敗: %v", err)
+	}
+	report.Tables = tables
+
+	var statsReset sql.NullTime
+	if err := db.QueryRow(`SELECT stats_reset FROM pg_stat_database WHERE datname = current_database()`).Scan(&statsReset); err == nil && statsReset.Valid {
+		report.StatsSince = &statsReset.Time
+	}
+
+	indexes, err := indexColumns(db)
+	if err != nil {
+		return nil, fmt.Errorf("讀取索引定義失敗: %v", err)
+	}
+	existing := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		existing[t.Table] = true
+	}
+	for _, p := range knownQueryPatterns {
+		if !existing[p.Table] || hasLeadingIndex(indexes[p.Table], p.Columns) {
+			continue
+		}
+		report.MissingIndexes = append(report.MissingIndexes, MissingIndex{
+			Table:   p.Table,
+			Columns: p.Columns,
+			UsedBy:  p.UsedBy,
+			Suggestion: fmt.Sprintf("CREATE INDEX CONCURRENTLY idx_%s_%s ON %s(%s);",
+				p.Table, strings.Join(p.Columns, "_"), p.Table, strings.Join(p.Columns, ", ")),
+		})
+	}
+
+	statements, err := slowStatements(db)
+	switch {
+	case err == errStatementsUnavailable:
+		report.Notes = append(report.Notes, "未安裝 pg_stat_statements（CREATE EXTENSION pg_stat_statements 並加入 shared_preload_libraries 後可列出慢查詢）")
+	case err != nil:
+		report.Notes = append(report.Notes, fmt.Sprintf("無法讀取 pg_stat_statements: %v", err))
+	default:
+		report.StatementsAvailable = true
+		report.SlowStatements = statements
+	}
+
+	return report, nil
+}
+
+// tableScanStats 目前 schema 中各資料表的掃描統計，依循序讀取的列數排序
+func tableScanStats(db *sql.DB) ([]TableScanStat, error) {
+	rows, err := db.Query(`
+		SELECT relname, n_live_tup, pg_total_relation_size(relid),
+		       COALESCE(seq_scan, 0), COALESCE(seq_tup_read, 0), COALESCE(idx_scan, 0)
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema()
+		ORDER BY seq_tup_read DESC, relname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []TableScanStat{}
+	for rows.Next() {
+		var t TableScanStat
+		if err := rows.Scan(&t.Table, &t.LiveRows, &t.SizeBytes, &t.SeqScans, &t.SeqRowsRead, &t.IndexScans); err != nil {
+			return nil, err
+		}
+		// 大資料表的循序掃描比索引掃描多，且每次平均讀取超過一半的資料列
+		t.SeqScanSuspect = t.LiveRows >= advisorLargeTableRows &&
+			t.SeqScans > t.IndexScans &&
+			t.SeqRowsRead/t.SeqScans >= t.LiveRows/2
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// indexColumns 各資料表的索引欄位（依索引中的順序；運算式索引的欄位會被略過）
+func indexColumns(db *sql.DB) (map[string][][]string, error) {
+	rows, err := db.Query(`
+		SELECT t.relname, array_agg(a.attname ORDER BY k.n)
+		FROM pg_index x
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace ns ON ns.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE ns.nspname = current_schema()
+		GROUP BY t.relname, x.indexrelid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][][]string)
+	for rows.Next() {
+		var table string
+		var columns []string
+		if err := rows.Scan(&table, pq.Array(&columns)); err != nil {
+			return nil, err
+		}
+		indexes[table] = append(indexes[table], columns)
+	}
+	return indexes, rows.Err()
+}
+
+// hasLeadingIndex 是否有索引以 columns 開頭（順序相同）
+func hasLeadingIndex(indexes [][]string, columns []string) bool {
+	for _, idx := range indexes {
+		if len(idx) < len(columns) {
+			continue
+		}
+		match := true
+		for i, col := range columns {
+			if idx[i] != col {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+var errStatementsUnavailable = errors.New("pg_stat_statements not installed")
+
+// slowStatements 目前資料庫中平均耗時最高的查詢（PostgreSQL 13 起欄位改名為 *_exec_time）
+func slowStatements(db *sql.DB) ([]StatementStat, error) {
+	var installed bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')`).Scan(&installed); err != nil {
+		return nil, err
+	}
+	if !installed {
+		return nil, errStatementsUnavailable
+	}
+
+	query := `
+		SELECT query, calls, %[1]s, %[2]s, rows
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		  AND calls >= 10 AND %[1]s >= $1
+		ORDER BY %[1]s DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(fmt.Sprintf(query, "mean_exec_time", "total_exec_time"), advisorSlowStatementMs, advisorMaxStatements)
+	if err != nil {
+		rows, err = db.Query(fmt.Sprintf(query, "mean_time", "total_time"), advisorSlowStatementMs, advisorMaxStatements)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := []StatementStat{}
+	for rows.Next() {
+		var s StatementStat
+		if err := rows.Scan(&s.Query, &s.Calls, &s.MeanMs, &s.TotalMs, &s.Rows); err != nil {
+			return nil, err
+		}
+		s.Query = compactSQL(s.Query)
+		statements = append(statements, s)
+	}
+	return statements, rows.Err()
+}
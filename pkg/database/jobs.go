@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 同步工作狀態
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// 同步工作的執行方式
+const (
+	JobOriginQueue  = "queue"  // 排入佇列，由 worker 執行
+	JobOriginInline = "inline" // 直接在 API 程序內執行
+)
+
+// SyncJob 排入佇列、等待 worker 執行的同步工作
+type SyncJob struct {
+	ID          int        `json:"id"`
+	Type        string     `json:"type"` // 'daily', 'monthly'
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	CallbackURL string     `json:"-"` // 觸發時指定的進度回呼網址
+	Origin      string     `json:"origin,omitempty"`
+	Owner       string     `json:"owner,omitempty"` // 執行中或已執行的程序（INSTANCE_ID）
+}
+
+// EnqueueSyncJob 排入同步工作（callbackURL 可為空）；已有排隊中或執行中的工作時不重複排入，回傳 ok = false
+func EnqueueSyncJob(db *sql.DB, jobType, callbackURL string) (id int, ok bool, err error) {
+	err = db.QueryRow(`
+		INSERT INTO sync_jobs (type, status, callback_url, origin)
+		SELECT $1, $2, NULLIF($4, ''), $5
+		WHERE NOT EXISTS (SELECT 1 FROM sync_jobs WHERE status IN ($2, $3))
+		RETURNING id
+	`, jobType, JobStatusQueued, JobStatusRunning, callbackURL, JobOriginQueue).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// StartSyncJob 記錄一個直接在 API 程序（owner）內執行的同步工作（狀態為執行中），回傳工作 ID
+func StartSyncJob(db *sql.DB, jobType, callbackURL, owner string) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO sync_jobs (type, status, started_at, heartbeat_at, callback_url, origin, owner)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, NULLIF($3, ''), $4, $5)
+		RETURNING id
+	`, jobType, JobStatusRunning, callbackURL, JobOriginInline, owner).Scan(&id)
+	return id, err
+}
+
+// GetSyncJob 取得同步工作，不存在時回傳 sql.ErrNoRows
+func GetSyncJob(db *sql.DB, id int) (*SyncJob, error) {
+	job := &SyncJob{}
+	var errMsg, origin, owner sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, type, status, error, requested_at, started_at, finished_at, origin, owner
+		FROM sync_jobs
+		WHERE id = $1
+	`, id).Scan(&job.ID, &job.Type, &job.Status, &errMsg, &job.RequestedAt, &startedAt, &finishedAt, &origin, &owner)
+	if err != nil {
+		return nil, err
+	}
+	job.Error = errMsg.String
+	job.Origin = origin.String
+	job.Owner = owner.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
+
+// ClaimSyncJob 取出最早排隊的工作並標記為由 owner 執行中，沒有工作時回傳 sql.ErrNoRows
+func ClaimSyncJob(db *sql.DB, owner string) (*SyncJob, error) {
+	job := &SyncJob{Status: JobStatusRunning, Origin: JobOriginQueue, Owner: owner}
+	err := db.QueryRow(`
+		UPDATE sync_jobs
+		SET status = $1, started_at = CURRENT_TIMESTAMP, heartbeat_at = CURRENT_TIMESTAMP, owner = $3
+		WHERE id = (
+			SELECT id FROM sync_jobs
+			WHERE status = $2
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, type, requested_at, COALESCE(callback_url, '')
+	`, JobStatusRunning, JobStatusQueued, owner).Scan(&job.ID, &job.Type, &job.RequestedAt, &job.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// FinishSyncJob 記錄工作結束
+func FinishSyncJob(db *sql.DB, id int, jobErr error) error {
+	status, message := JobStatusSuccess, ""
+	if jobErr != nil {
+		status, message = JobStatusFailed, jobErr.Error()
+	}
+	_, err := db.Exec(`
+		UPDATE sync_jobs
+		SET status = $1, error = NULLIF($2, ''), finished_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, status, message, id)
+	return err
+}
+
+// TouchSyncJob 更新執行中工作的心跳時間，執行期間需定期呼叫，否則會被 ReclaimStaleSyncJobs 視為已中斷
+func TouchSyncJob(db *sql.DB, id int) error {
+	_, err := db.Exec(`
+		UPDATE sync_jobs
+		SET heartbeat_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2
+	`, id, JobStatusRunning)
+	return err
+}
+
+// ResetRunningSyncJobs 將從佇列取出、仍在執行中的工作全部改回排隊（包含未記錄 origin 的舊工作）；
+// 只有 worker 主控權持有者處理佇列，取得主控權時這些工作必定是之前的 worker 未完成的，不論 owner 為何
+func ResetRunningSyncJobs(db *sql.DB) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE sync_jobs
+		SET status = $1, started_at = NULL, heartbeat_at = NULL, owner = NULL
+		WHERE status = $2 AND (origin = $3 OR origin IS NULL)
+	`, JobStatusQueued, JobStatusRunning, JobOriginQueue)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReclaimStaleSyncJobs 處理超過 staleAfter 沒有心跳的執行中工作（不論 owner）：
+// API 程序內執行的工作標記為失敗，佇列中的工作（包含未記錄 origin 的舊工作）改回排隊
+func ReclaimStaleSyncJobs(db *sql.DB, staleAfter time.Duration, message string) (int64, error) {
+	seconds := int(staleAfter.Seconds())
+	failed, err := db.Exec(`
+		UPDATE sync_jobs
+		SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND origin = $4
+		  AND COALESCE(heartbeat_at, started_at, requested_at) < CURRENT_TIMESTAMP - $5::int * INTERVAL '1 second'
+	`, JobStatusFailed, message, JobStatusRunning, JobOriginInline, seconds)
+	if err != nil {
+		return 0, err
+	}
+	requeued, err := db.Exec(`
+		UPDATE sync_jobs
+		SET status = $1, started_at = NULL, heartbeat_at = NULL, owner = NULL
+		WHERE status = $2 AND (origin = $3 OR origin IS NULL)
+		  AND COALESCE(heartbeat_at, started_at, requested_at) < CURRENT_TIMESTAMP - $4::int * INTERVAL '1 second'
+	`, JobStatusQueued, JobStatusRunning, JobOriginQueue, seconds)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := failed.RowsAffected()
+	m, _ := requeued.RowsAffected()
+	return n + m, nil
+}
+
+// AbandonRunningSyncJobs 將 owner 程序內執行、仍在執行中的工作標記為失敗（API 程序內執行的同步在程序重新啟動後不會繼續）；
+// 其他程序的工作由 ReclaimStaleSyncJobs 依心跳處理
+func AbandonRunningSyncJobs(db *sql.DB, owner, message string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE sync_jobs
+		SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND origin = $4 AND owner = $5
+	`, JobStatusFailed, message, JobStatusRunning, JobOriginInline, owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
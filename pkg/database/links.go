@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ShortLink 地圖短網址
+type ShortLink struct {
+	Code      string    `json:"code"`
+	Target    string    `json:"target"`
+	Product   string    `json:"product,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	Date      string    `json:"date,omitempty"`
+	Clicks    int       `json:"clicks"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateShortLink 建立短網址，代碼重複時回傳錯誤
+func CreateShortLink(db *sql.DB, link ShortLink) (*ShortLink, error) {
+	err := db.QueryRow(`
+		INSERT INTO links (code, target, product, region, date)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING clicks, created_at
+	`, link.Code, link.Target, link.Product, link.Region, link.Date).Scan(&link.Clicks, &link.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ResolveShortLink 取得短網址目標並累計點擊次數，不存在時回傳 sql.ErrNoRows
+func ResolveShortLink(db *sql.DB, code string) (string, error) {
+	var target string
+	err := db.QueryRow(`
+		UPDATE links SET clicks = clicks + 1
+		WHERE code = $1
+		RETURNING target
+	`, code).Scan(&target)
+	return target, err
+}
+
+// ListShortLinks 列出所有短網址（最新的在前）
+func ListShortLinks(db *sql.DB) ([]ShortLink, error) {
+	rows, err := db.Query(`
+		SELECT code, target, COALESCE(product, ''), COALESCE(region, ''), COALESCE(date, ''), clicks, created_at
+		FROM links
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []ShortLink{}
+	for rows.Next() {
+		var l ShortLink
+		if err := rows.Scan(&l.Code, &l.Target, &l.Product, &l.Region, &l.Date, &l.Clicks, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
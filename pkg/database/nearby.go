@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NearbyStore 附近有近期出貨的店家
+type NearbyStore struct {
+	StoreID        int      `json:"storeId"`
+	StoreName      string   `json:"storeName"`
+	Address        string   `json:"address"`
+	Latitude       float64  `json:"latitude"`
+	Longitude      float64  `json:"longitude"`
+	DistanceKm     float64  `json:"distanceKm"`
+	Products       []string `json:"products"`
+	LatestShipment string   `json:"latestShipment"`
+	// ProductNames 產品 → 顯示名稱（由 API 層依 Accept-Language 填入）
+	ProductNames map[string]string `json:"productNames,omitempty"`
+}
+
+// GetNearbyStores 查詢半徑 radiusKm 公里內、近 N 天有出貨的店家（預設依距離排序），
+// product 不為空時只找有該產品出貨的店家，exclude 中的產品（尚未公開）不列入
+func GetNearbyStores(db *sql.DB, lat, lng, radiusKm float64, days int, product string, exclude []string, sort StoreSort) ([]NearbyStore, error) {
+	order := `l.distance ` + sort.direction()
+	switch sort.Field {
+	case SortName:
+		order = `l.store_name ` + sort.direction()
+	case SortLatestShipment:
+		order = `MAX(sh.shipment_date) ` + sort.direction() + `, l.distance`
+	case SortTotalQuantity:
+		order = `SUM(` + normalizedQuantity + `) ` + sort.direction() + `, l.distance`
+	}
+
+	rows, err := db.Query(`
+		WITH located AS (
+			SELECT s.id, s.store_name, COALESCE(s.formatted_address, '') AS address, s.latitude, s.longitude,
+				6371 * 2 * ASIN(LEAST(1, SQRT(
+					POWER(SIN(RADIANS(s.latitude - $1) / 2), 2) +
+					COS(RADIANS($1)) * COS(RADIANS(s.latitude)) * POWER(SIN(RADIANS(s.longitude - $2) / 2), 2)
+				))) AS distance
+			FROM stores s
+			WHERE s.is_active
+			  AND s.latitude IS NOT NULL
+			  AND s.longitude IS NOT NULL
+		)
+		SELECT l.id, l.store_name, l.address, l.latitude, l.longitude, l.distance,
+		       ARRAY_AGG(DISTINCT sh.product_type ORDER BY sh.product_type), MAX(sh.shipment_date)
+		FROM located l
+		JOIN shipments sh ON sh.store_id = l.id
+		WHERE l.distance <= $3
+		  AND sh.shipment_date >= CURRENT_DATE - $4::int
+		  AND sh.quantity IS NOT NULL
+		  AND sh.quantity != ''
+		  AND sh.quantity != '0'
+		  AND sh.quality_flag IS NULL
+		  AND ($5 = '' OR sh.product_type = $5)
+		  AND ($6::text[] IS NULL OR NOT (sh.product_type = ANY($6)))
+		GROUP BY l.id, l.store_name, l.address, l.latitude, l.longitude, l.distance
+		ORDER BY `+order+`
+	`, lat, lng, radiusKm, days, product, pq.Array(exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []NearbyStore{}
+	for rows.Next() {
+		var s NearbyStore
+		var latest time.Time
+		if err := rows.Scan(&s.StoreID, &s.StoreName, &s.Address, &s.Latitude, &s.Longitude, &s.DistanceKm,
+			pq.Array(&s.Products), &latest); err != nil {
+			return nil, err
+		}
+		s.LatestShipment = latest.Format("2006-01-02")
+		stores = append(stores, s)
+	}
+	return stores, rows.Err()
+}
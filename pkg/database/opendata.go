@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DistrictAggregate 單一區域、產品、日期的出貨統計（不含店家資訊）
+type DistrictAggregate struct {
+	District      string  `json:"district"`
+	ProductType   string  `json:"productType"`
+	Date          string  `json:"date"`
+	Stores        int     `json:"stores"`
+	TotalQuantity float64 `json:"totalQuantity"`
+}
+
+// insertDistrictDailyTotals 依區域、產品、日期彙總出貨寫入 district_daily_totals；
+// 只計入啟用中店家、未標記異常的出貨（migration 初次填入也使用）
+const insertDistrictDailyTotals = `
+	INSERT INTO district_daily_totals (district, product_type, date, store_count, total_quantity)
+	SELECT
+		COALESCE(NULLIF(s.region, ''), '未分區'),
+		sh.product_type,
+		sh.shipment_date,
+		COUNT(DISTINCT s.id),
+		SUM(CASE WHEN sh.quantity ~ '^[0-9]+(\.[0-9]+)?$' THEN sh.quantity::numeric ELSE 0 END)
+	FROM shipments sh
+	JOIN stores s ON s.id = sh.store_id
+	WHERE s.is_active
+	  AND sh.quantity IS NOT NULL
+	  AND sh.quantity != ''
+	  AND sh.quantity != '0'
+	  AND sh.quality_flag IS NULL
+	GROUP BY 1, 2, 3
+`
+
+// RefreshDistrictDailyTotals 重新計算 district_daily_totals（同步結束後呼叫），回傳寫入的筆數；
+// 整張表在同一個交易中替換，查詢端不會讀到計算到一半的結果
+func RefreshDistrictDailyTotals(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM district_daily_totals`); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(insertDistrictDailyTotals)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// GetDistrictAggregates 依區域彙總近 N 天的出貨（讀取 district_daily_totals），
+// 店家數少於 minStores 的組合不列出以免辨識出個別店家，回傳被略去的組合數
+func GetDistrictAggregates(db *sql.DB, days, minStores int) ([]DistrictAggregate, int, error) {
+	rows, err := db.Query(`
+		SELECT district, product_type, date, store_count, total_quantity
+		FROM district_daily_totals
+		WHERE date >= CURRENT_DATE - $1::int
+		ORDER BY date, district, product_type
+	`, days)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	aggregates := []DistrictAggregate{}
+	suppressed := 0
+	for rows.Next() {
+		var a DistrictAggregate
+		var date time.Time
+		if err := rows.Scan(&a.District, &a.ProductType, &date, &a.Stores, &a.TotalQuantity); err != nil {
+			return nil, 0, err
+		}
+		if a.Stores < minStores {
+			suppressed++
+			continue
+		}
+		a.Date = date.Format("2006-01-02")
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, suppressed, rows.Err()
+}
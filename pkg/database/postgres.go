@@ -2,11 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
-	_ "github.com/lib/pq"
+	"PXMarkMapBackEnd/pkg/fault"
+	"github.com/lib/pq"
 )
 
 // DBConfig 資料庫連線設定
@@ -16,6 +19,13 @@ type DBConfig struct {
 	User     string
 	Password string
 	DBName   string
+	// 連線池上限（0 表示不限制）
+	MaxOpenConns int
+	MaxIdleConns int
+	// SlowQueryThreshold 大於 0 時記錄超過此時間的查詢
+	SlowQueryThreshold time.Duration
+	// Timezone 連線的 session 時區，查詢結果的時間欄位也會轉成此時區（nil 時使用 time.Local）
+	Timezone *time.Location
 }
 
 // ConnectDB 連接資料庫
@@ -25,10 +35,35 @@ func ConnectDB(config DBConfig) (*sql.DB, error) {
 		config.Host, config.Port, config.User, config.Password, config.DBName,
 	)
 
-	db, err := sql.Open("postgres", connStr)
+	loc := config.Timezone
+	if loc == nil {
+		loc = time.Local
+	}
+	// CURRENT_TIMESTAMP、CURRENT_DATE 等以此時區計算，與程序的 time.Now() 一致
+	if loc.String() != "Local" {
+		connStr += " timezone=" + loc.String()
+	}
+
+	base, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, err
 	}
+	var connector driver.Connector = &timezoneConnector{base: base, loc: loc}
+	// FAULT_INJECT 的延遲放在慢查詢記錄內層，注入的延遲也會出現在慢查詢日誌與統計中
+	if fault.Current().DBEnabled() {
+		connector = &faultConnector{base: connector}
+	}
+	if config.SlowQueryThreshold > 0 {
+		connector = newLoggingConnector(connector, config.SlowQueryThreshold)
+	}
+	db := sql.OpenDB(connector)
+
+	if config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
 
 	if err := db.Ping(); err != nil {
 		return nil, err
@@ -45,55 +80,146 @@ type StoreInfo struct {
 	FormattedAddress string
 	Latitude         float64
 	Longitude        float64
-	OkraShipments    []ShipmentInfo
-	GourdShipments   []ShipmentInfo
+	BusinessStatus   string
+	SourceID         string
+	Region           string
+	Shipments        map[string][]ShipmentInfo // 產品（工作表對應的 product_type）→ 出貨紀錄
 }
 
 // ShipmentInfo 出貨資訊
 type ShipmentInfo struct {
-	Date string
-	Qty  string
+	Date     string
+	TimeSlot string // am / pm，同一天只有一次出貨時為空字串
+	Qty      string
+	SourceID string
+}
+
+// NewShipment 本次同步新出現的出貨（原本沒有數量，現在有）
+type NewShipment struct {
+	StoreID     int    `json:"storeId"`
+	StoreName   string `json:"storeName"`
+	Region      string `json:"region,omitempty"`
+	ProductType string `json:"productType"`
+	Date        string `json:"date"`
+	TimeSlot    string `json:"timeSlot,omitempty"`
+	Quantity    string `json:"quantity"`
+}
+
+// SaveResult 儲存結果
+type SaveResult struct {
+	NewShipments []NewShipment
+	Shipments    UpsertStats
+}
+
+// UpsertStats 出貨寫入的結果統計
+type UpsertStats struct {
+	Inserted  int `json:"inserted"`  // 原本沒有這筆出貨
+	Updated   int `json:"updated"`   // 數量或資料來源有變動
+	Unchanged int `json:"unchanged"` // 與資料庫中相同（重寫舊資料）
+	Flagged   int `json:"flagged"`   // 數量超出合理範圍，已標記且不顯示在公開地圖上
+}
+
+// Total 寫入的出貨總筆數
+func (s UpsertStats) Total() int {
+	return s.Inserted + s.Updated + s.Unchanged
+}
+
+// String 例如 "新增 12、更新 3、未變更 480（新增 2.4%），數量異常 1"
+func (s UpsertStats) String() string {
+	ratio := 0.0
+	if s.Total() > 0 {
+		ratio = float64(s.Inserted) / float64(s.Total()) * 100
+	}
+	msg := fmt.Sprintf("新增 %d、更新 %d、未變更 %d（新增 %.1f%%）", s.Inserted, s.Updated, s.Unchanged, ratio)
+	if s.Flagged > 0 {
+		msg += fmt.Sprintf("，數量異常 %d", s.Flagged)
+	}
+	return msg
+}
+
+// upsertOutcome 單筆出貨寫入的結果
+type upsertOutcome int
+
+const (
+	upsertInserted upsertOutcome = iota
+	upsertUpdated
+	upsertUnchanged
+)
+
+func (s *UpsertStats) add(outcome upsertOutcome) {
+	switch outcome {
+	case upsertInserted:
+		s.Inserted++
+	case upsertUpdated:
+		s.Updated++
+	default:
+		s.Unchanged++
+	}
 }
 
 // SaveStores 儲存店家資料到資料庫
-func SaveStores(db *sql.DB, stores []StoreInfo) error {
+func SaveStores(db *sql.DB, stores []StoreInfo) (*SaveResult, error) {
 	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
+	// 產品改過名稱時以目前名稱寫入
+	aliases, err := loadProductAliasesTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SaveResult{}
+
 	for _, store := range stores {
 		// 插入或更新店家資料
 		var storeID int
 		err := tx.QueryRow(`
-			INSERT INTO stores (store_name, place_id, formatted_address, latitude, longitude, updated_at)
-			VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+			INSERT INTO stores (store_name, place_id, formatted_address, latitude, longitude, business_status, source_id, region, updated_at, last_seen_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 			ON CONFLICT (store_name) 
 			DO UPDATE SET 
-				place_id = EXCLUDED.place_id,
-				formatted_address = EXCLUDED.formatted_address,
-				latitude = EXCLUDED.latitude,
-				longitude = EXCLUDED.longitude,
-				updated_at = CURRENT_TIMESTAMP
+				-- 人工修正（manual_import）的地點不被同步覆蓋
+				place_id = CASE WHEN stores.coordinate_provenance = 'manual_import' THEN stores.place_id ELSE EXCLUDED.place_id END,
+				formatted_address = CASE WHEN stores.coordinate_provenance = 'manual_import' THEN stores.formatted_address ELSE EXCLUDED.formatted_address END,
+				latitude = CASE WHEN stores.coordinate_provenance = 'manual_import' THEN stores.latitude ELSE EXCLUDED.latitude END,
+				longitude = CASE WHEN stores.coordinate_provenance = 'manual_import' THEN stores.longitude ELSE EXCLUDED.longitude END,
+				business_status = EXCLUDED.business_status,
+				source_id = COALESCE(stores.source_id, EXCLUDED.source_id),
+				region = COALESCE(EXCLUDED.region, stores.region),
+				updated_at = CURRENT_TIMESTAMP,
+				last_seen_at = CURRENT_TIMESTAMP
 			RETURNING id
-		`, store.StoreName, store.PlaceID, store.FormattedAddress, store.Latitude, store.Longitude).Scan(&storeID)
+		`, store.StoreName, store.PlaceID, store.FormattedAddress, store.Latitude, store.Longitude, store.BusinessStatus, store.SourceID, store.Region).Scan(&storeID)
 
 		if err != nil {
-			return fmt.Errorf("儲存店家 %s 失敗: %v", store.StoreName, err)
+			return nil, fmt.Errorf("儲存店家 %s 失敗: %v", store.StoreName, err)
 		}
 
-		// 儲存秋葵出貨紀錄
-		for _, shipment := range store.OkraShipments {
-			if err := saveShipment(tx, storeID, "秋葵", shipment); err != nil {
-				log.Printf("儲存秋葵出貨紀錄失敗: %v", err)
-			}
+		// 儲存各產品的出貨紀錄（依產品名稱排序，寫入順序固定）
+		products := make([]string, 0, len(store.Shipments))
+		for product := range store.Shipments {
+			products = append(products, product)
 		}
-
-		// 儲存絲瓜出貨紀錄
-		for _, shipment := range store.GourdShipments {
-			if err := saveShipment(tx, storeID, "產銷絲瓜", shipment); err != nil {
-				log.Printf("儲存絲瓜出貨紀錄失敗: %v", err)
+		sort.Strings(products)
+		for _, product := range products {
+			name := productName(aliases, product)
+			for _, shipment := range store.Shipments[product] {
+				flag := ShipmentQualityFlag(product, shipment.Qty)
+				oldQty, outcome, err := saveShipment(tx, storeID, name, shipment, flag)
+				if err != nil {
+					log.Printf("儲存%s出貨紀錄失敗: %v", name, err)
+					continue
+				}
+				if flag != "" {
+					log.Printf("[WARN] %s %s %s 數量異常: %s（%s）", store.StoreName, name, shipment.Date, shipment.Qty, flag)
+					result.Shipments.Flagged++
+				} else {
+					result.addIfNew(storeID, store, name, shipment, oldQty)
+				}
+				result.Shipments.add(outcome)
 			}
 		}
 
@@ -101,29 +227,72 @@ func SaveStores(db *sql.DB, stores []StoreInfo) error {
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Println("[INFO] 所有資料已成功儲存到資料庫")
-	return nil
+	log.Printf("[INFO] 出貨寫入: %s", result.Shipments)
+	return result, nil
+}
+
+// addIfNew 原本沒有數量、這次有數量的出貨視為新出貨
+func (r *SaveResult) addIfNew(storeID int, store StoreInfo, productType string, shipment ShipmentInfo, oldQty sql.NullString) {
+	if hasQuantity(oldQty.String) || !hasQuantity(shipment.Qty) {
+		return
+	}
+
+	date, _ := parseShipmentDate(shipment.Date)
+	r.NewShipments = append(r.NewShipments, NewShipment{
+		StoreID:     storeID,
+		StoreName:   store.StoreName,
+		Region:      store.Region,
+		ProductType: productType,
+		Date:        date.Format("2006-01-02"),
+		TimeSlot:    shipment.TimeSlot,
+		Quantity:    shipment.Qty,
+	})
+}
+
+// hasQuantity 數量不為空且不為 0
+func hasQuantity(qty string) bool {
+	return qty != "" && qty != "0"
 }
 
-// saveShipment 儲存單筆出貨紀錄
-func saveShipment(tx *sql.Tx, storeID int, productType string, shipment ShipmentInfo) error {
+// saveShipment 儲存單筆出貨紀錄，回傳更新前的數量（原本不存在時為 NULL）與寫入結果
+func saveShipment(tx *sql.Tx, storeID int, productType string, shipment ShipmentInfo, qualityFlag string) (sql.NullString, upsertOutcome, error) {
+	var oldQty, oldSource sql.NullString
+	var existed bool
+
 	date, err := parseShipmentDate(shipment.Date)
 	if err != nil {
 		log.Printf("跳過無效日期 %s: %v", shipment.Date, err)
-		return err
+		return oldQty, upsertUnchanged, err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO shipments (store_id, product_type, shipment_date, quantity)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (store_id, product_type, shipment_date) 
-		DO UPDATE SET quantity = EXCLUDED.quantity
-	`, storeID, productType, date, shipment.Qty)
+	// CTE 讀到的是更新前的資料
+	err = tx.QueryRow(`
+		WITH old AS (
+			SELECT quantity, source_id FROM shipments
+			WHERE store_id = $1 AND product_type = $2 AND shipment_date = $3 AND time_slot = $7
+		)
+		INSERT INTO shipments (store_id, product_type, shipment_date, quantity, source_id, quality_flag, time_slot)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		ON CONFLICT (store_id, product_type, shipment_date, time_slot) 
+		DO UPDATE SET quantity = EXCLUDED.quantity, source_id = EXCLUDED.source_id, quality_flag = EXCLUDED.quality_flag
+		RETURNING (SELECT quantity FROM old), (SELECT source_id FROM old), EXISTS (SELECT 1 FROM old)
+	`, storeID, productType, date, shipment.Qty, shipment.SourceID, qualityFlag, shipment.TimeSlot).Scan(&oldQty, &oldSource, &existed)
+	if err != nil {
+		return oldQty, upsertUnchanged, err
+	}
 
-	return err
+	switch {
+	case !existed:
+		return oldQty, upsertInserted, nil
+	case oldQty.String != shipment.Qty || oldSource.String != shipment.SourceID:
+		return oldQty, upsertUpdated, nil
+	default:
+		return oldQty, upsertUnchanged, nil
+	}
 }
 
 // parseShipmentDate 解析多種日期格式
@@ -145,27 +314,84 @@ func parseShipmentDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("無法解析日期: %s", dateStr)
 }
 
-// GetRecentShipments 查詢近 N 天有出貨的店家
-func GetRecentShipments(db *sql.DB, days int) ([]map[string]interface{}, error) {
+// BBox 地圖可視範圍（經緯度）
+type BBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// GetRecentShipments 查詢近 N 天有出貨的店家，bbox 不為 nil 時只回傳範圍內的店家；
+// bySlot 為 false 時同一天的多個時段合併為一筆（數量加總），店家依 sort 排序
+func GetRecentShipments(db *sql.DB, days int, bbox *BBox, bySlot bool, sort StoreSort) ([]map[string]interface{}, error) {
+	return queryShipments(db, fmt.Sprintf(`sh.shipment_date >= CURRENT_DATE - INTERVAL '%d days'`, days), nil, bbox, bySlot, sort)
+}
+
+// GetShipmentsBetween 查詢指定日期區間（含頭尾）有出貨的店家
+func GetShipmentsBetween(db *sql.DB, from, to time.Time, bbox *BBox, bySlot bool, sort StoreSort) ([]map[string]interface{}, error) {
+	return queryShipments(db, `sh.shipment_date BETWEEN $1 AND $2`, []interface{}{from, to}, bbox, bySlot, sort)
+}
+
+// dailyQuantity 同一天只有一筆時保留原始數量字串，多個時段時加總可解析為數字的數量
+const dailyQuantity = `CASE WHEN COUNT(*) = 1 THEN MAX(sh.quantity)
+			ELSE SUM(CASE WHEN sh.quantity ~ '^[0-9]+(\.[0-9]+)?$' THEN sh.quantity::numeric ELSE 0 END)::text END`
+
+// normalizedQuantity 出貨數量換算成數字：取開頭的數字（"3"、"3箱"、"2.5 箱" → 3、3、2.5），沒有數字時為 0
+const normalizedQuantity = `COALESCE(SUBSTRING(sh.quantity FROM '^\s*([0-9]+(\.[0-9]+)?)')::numeric, 0)`
+
+// queryShipments 查詢符合日期條件（與可視範圍）的出貨紀錄；與其他店家座標相同的店家回傳分散後的顯示座標。
+// 同一店家的出貨相鄰，店家之間依 sort 排序；店家最新出貨日與各產品的數量總和以視窗函數計算（product_total）
+func queryShipments(db *sql.DB, dateFilter string, args []interface{}, bbox *BBox, bySlot bool, sort StoreSort) ([]map[string]interface{}, error) {
+	if bbox != nil {
+		n := len(args)
+		dateFilter += fmt.Sprintf(`
+		  AND s.longitude BETWEEN $%d AND $%d
+		  AND s.latitude BETWEEN $%d AND $%d`, n+1, n+2, n+3, n+4)
+		args = append(args, bbox.MinLng, bbox.MaxLng, bbox.MinLat, bbox.MaxLat)
+	}
+
+	// 合併時段時每列已是 GROUP BY 後的一天，視窗函數加總的是每天的數量和
+	slot, quantity, groupBy, sum := `''`, dailyQuantity, `
+		GROUP BY s.id, s.store_name, s.formatted_address, s.latitude, s.longitude, c.idx, c.n, sh.product_type, sh.shipment_date`,
+		`SUM(SUM(`+normalizedQuantity+`))`
+	if bySlot {
+		slot, quantity, groupBy, sum = `sh.time_slot`, `sh.quantity`, ``, `SUM(`+normalizedQuantity+`)`
+	}
+
+	storeOrder := `s.store_name ` + sort.direction()
+	switch sort.Field {
+	case SortLatestShipment:
+		storeOrder = `MAX(sh.shipment_date) OVER (PARTITION BY s.id) ` + sort.direction() + `, s.store_name`
+	case SortTotalQuantity:
+		storeOrder = sum + ` OVER (PARTITION BY s.id) ` + sort.direction() + `, s.store_name`
+	}
+
 	query := `
 		SELECT 
+			s.id,
 			s.store_name,
 			s.formatted_address,
-			s.latitude,
-			s.longitude,
+			` + displayLatitude + `,
+			` + displayLongitude + `,
 			sh.product_type,
 			sh.shipment_date,
-			sh.quantity
+			` + slot + `,
+			` + quantity + `,
+			(` + sum + ` OVER (PARTITION BY s.id, sh.product_type))::float8
 		FROM stores s
+		JOIN ` + collisionGroups + ` c ON c.id = s.id
 		JOIN shipments sh ON s.id = sh.store_id
-		WHERE sh.shipment_date >= CURRENT_DATE - INTERVAL '%d days'
+		WHERE ` + dateFilter + `
+		  AND s.is_active
 		  AND sh.quantity IS NOT NULL 
 		  AND sh.quantity != ''
 		  AND sh.quantity != '0'
-		ORDER BY s.store_name, sh.product_type, sh.shipment_date DESC
+		  AND sh.quality_flag IS NULL` + groupBy + `
+		ORDER BY ` + storeOrder + `, sh.product_type, sh.shipment_date DESC, 8
 	`
 
-	rows, err := db.Query(fmt.Sprintf(query, days))
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -173,11 +399,13 @@ func GetRecentShipments(db *sql.DB, days int) ([]map[string]interface{}, error)
 
 	var results []map[string]interface{}
 	for rows.Next() {
-		var storeName, address, productType, quantity string
+		var storeID int
+		var storeName, address, productType, timeSlot, quantity string
 		var lat, lng sql.NullFloat64
 		var shipmentDate time.Time
+		var productTotal float64
 
-		err := rows.Scan(&storeName, &address, &lat, &lng, &productType, &shipmentDate, &quantity)
+		err := rows.Scan(&storeID, &storeName, &address, &lat, &lng, &productType, &shipmentDate, &timeSlot, &quantity, &productTotal)
 		if err != nil {
 			return nil, err
 		}
@@ -198,32 +426,45 @@ func GetRecentShipments(db *sql.DB, days int) ([]map[string]interface{}, error)
 		}
 
 		results = append(results, map[string]interface{}{
+			"store_id":      storeID,
 			"store_name":    storeName,
 			"address":       address,
 			"latitude":      latitude,
 			"longitude":     longitude,
 			"product_type":  productType,
 			"shipment_date": shipmentDate.Format("2006-01-02"),
+			"time_slot":     timeSlot,
 			"quantity":      quantity,
+			"product_total": productTotal,
 		})
 	}
 
 	return results, nil
 }
+// ExistingStoreInfo 現有店家資訊
 type ExistingStoreInfo struct {
 	PlaceID          string
 	FormattedAddress string
 	Latitude         float64
 	Longitude        float64
+	BusinessStatus   string
 }
-// ExistingStoreInfo 現有店家資訊
+
 // GetExistingStoresWithLocation 取得已有地點資訊的店家
 func GetExistingStoresWithLocation(db *sql.DB) (map[string]ExistingStoreInfo, error) {
+	return queryExistingStores(db, `((place_id IS NOT NULL AND place_id != '') OR coordinate_provenance = 'manual_import')`)
+}
+
+// GetManualImportStores 取得座標為人工修正（manual_import）的店家，完整同步時沿用而不以 Places API 重新查詢
+func GetManualImportStores(db *sql.DB) (map[string]ExistingStoreInfo, error) {
+	return queryExistingStores(db, `coordinate_provenance = 'manual_import'`)
+}
+
+func queryExistingStores(db *sql.DB, where string) (map[string]ExistingStoreInfo, error) {
 	query := `
-		SELECT store_name, place_id, formatted_address, latitude, longitude
+		SELECT store_name, COALESCE(place_id, ''), COALESCE(formatted_address, ''), latitude, longitude, COALESCE(business_status, '')
 		FROM stores
-		WHERE place_id IS NOT NULL 
-		  AND place_id != ''
+		WHERE ` + where + `
 		  AND latitude IS NOT NULL
 		  AND longitude IS NOT NULL
 	`
@@ -237,10 +478,10 @@ func GetExistingStoresWithLocation(db *sql.DB) (map[string]ExistingStoreInfo, er
 	result := make(map[string]ExistingStoreInfo)
 
 	for rows.Next() {
-		var storeName, placeID, address string
+		var storeName, placeID, address, businessStatus string
 		var lat, lng float64
 
-		if err := rows.Scan(&storeName, &placeID, &address, &lat, &lng); err != nil {
+		if err := rows.Scan(&storeName, &placeID, &address, &lat, &lng, &businessStatus); err != nil {
 			continue
 		}
 
@@ -249,6 +490,7 @@ func GetExistingStoresWithLocation(db *sql.DB) (map[string]ExistingStoreInfo, er
 			FormattedAddress: address,
 			Latitude:         lat,
 			Longitude:        lng,
+			BusinessStatus:   businessStatus,
 		}
 	}
 
@@ -0,0 +1,274 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// productLockKey 產品改名與同步寫入互斥的 advisory lock 鍵值：
+// 同步以共享鎖讀取別名並寫入出貨，改名以獨佔鎖更新，避免改名途中寫入舊名稱造成資料分裂
+const productLockKey = 0x50584d50 // "PXMP"
+
+// ProductAlias 產品的舊名稱 → 目前名稱（同步時以目前名稱寫入，工作表用舊名稱也能辨識）
+type ProductAlias struct {
+	Alias     string    `json:"alias"`
+	Product   string    `json:"product"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Product 同步的產品與顯示名稱（API 依 Accept-Language 選擇；沒有英文名稱時使用中文名稱）
+type Product struct {
+	ProductType string `json:"productType"`
+	NameZhTW    string `json:"nameZhTw"`
+	NameEn      string `json:"nameEn"`
+	// SheetPatterns 工作表名稱的比對規則（正規表示式），名稱與 ProductType 相同的工作表不需要規則
+	SheetPatterns []string  `json:"sheetPatterns"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// ProductType 資料庫中有出貨的產品（前端的產品篩選選項）
+type ProductType struct {
+	ProductType    string `json:"productType"` // 查詢參數使用的識別名稱
+	Name           string `json:"name"`        // 顯示名稱（由 API 層依 Accept-Language 填入）
+	StoreCount     int    `json:"storeCount"`
+	LatestShipment string `json:"latestShipment"`
+}
+
+// ProductRenameResult 產品改名 / 合併的結果
+type ProductRenameResult struct {
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Renamed         int    `json:"renamed"`         // 改為新名稱的出貨筆數
+	Merged          int    `json:"merged"`          // 新名稱已有同店同日出貨而刪除的舊名稱出貨筆數
+	WebhooksUpdated int    `json:"webhooksUpdated"` // 訂閱條件中改為新名稱的 webhook 數
+}
+
+// RenameProduct 將產品 from 改名為 to（to 已存在時合併，同店同日以 to 的出貨為準），
+// 同時更新 webhook 訂閱條件並記錄別名；全部在同一個交易中完成
+func RenameProduct(db *sql.DB, from, to string) (*ProductRenameResult, error) {
+	if from == "" || to == "" || from == to {
+		return nil, fmt.Errorf("from 與 to 必須是不同的產品名稱")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, productLockKey); err != nil {
+		return nil, err
+	}
+
+	result := &ProductRenameResult{From: from, To: to}
+
+	res, err := tx.Exec(`
+		DELETE FROM shipments old
+		USING shipments cur
+		WHERE old.product_type = $1
+		  AND cur.product_type = $2
+		  AND cur.store_id = old.store_id
+		  AND cur.shipment_date = old.shipment_date
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	merged, _ := res.RowsAffected()
+	result.Merged = int(merged)
+
+	res, err = tx.Exec(`UPDATE shipments SET product_type = $2 WHERE product_type = $1`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	renamed, _ := res.RowsAffected()
+	result.Renamed = int(renamed)
+
+	res, err = tx.Exec(`
+		UPDATE webhooks SET products = array_replace(products, $1, $2)
+		WHERE $1 = ANY(products)
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	hooks, _ := res.RowsAffected()
+	result.WebhooksUpdated = int(hooks)
+
+	// 指向舊名稱的別名一併改指向新名稱，別名永遠直接對應目前名稱；改回舊名稱時移除該別名
+	if _, err := tx.Exec(`UPDATE product_aliases SET product = $2 WHERE product = $1`, from, to); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM product_aliases WHERE alias = $1`, to); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO product_aliases (alias, product) VALUES ($1, $2)
+		ON CONFLICT (alias) DO UPDATE SET product = EXCLUDED.product, created_at = CURRENT_TIMESTAMP
+	`, from, to); err != nil {
+		return nil, err
+	}
+
+	// 顯示名稱跟著改名；新名稱已有顯示名稱時保留新名稱的設定
+	if _, err := tx.Exec(`
+		UPDATE products SET product_type = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE product_type = $1 AND NOT EXISTS (SELECT 1 FROM products WHERE product_type = $2)
+	`, from, to); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM products WHERE product_type = $1`, from); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListProductAliases 列出所有產品別名
+func ListProductAliases(db *sql.DB) ([]ProductAlias, error) {
+	rows, err := db.Query(`SELECT alias, product, created_at FROM product_aliases ORDER BY product, alias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := []ProductAlias{}
+	for rows.Next() {
+		var a ProductAlias
+		if err := rows.Scan(&a.Alias, &a.Product, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// GetProductAliases 舊名稱 → 目前名稱
+func GetProductAliases(db *sql.DB) (map[string]string, error) {
+	list, err := ListProductAliases(db)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(list))
+	for _, a := range list {
+		aliases[a.Alias] = a.Product
+	}
+	return aliases, nil
+}
+
+// loadProductAliasesTx 在同步交易中取得共享鎖並讀取別名（改名進行中時等待完成）
+func loadProductAliasesTx(tx *sql.Tx) (map[string]string, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock_shared($1)`, productLockKey); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT alias, product FROM product_aliases`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]string)
+	for rows.Next() {
+		var alias, product string
+		if err := rows.Scan(&alias, &product); err != nil {
+			return nil, err
+		}
+		aliases[alias] = product
+	}
+	return aliases, rows.Err()
+}
+
+// productName 產品目前的名稱（有別名時使用改名後的名稱）
+func productName(aliases map[string]string, product string) string {
+	if name, ok := aliases[product]; ok {
+		return name
+	}
+	return product
+}
+
+// ListProducts 列出所有產品（同步只處理這些產品的工作表）
+func ListProducts(db *sql.DB) ([]Product, error) {
+	rows, err := db.Query(`SELECT product_type, name_zh_tw, name_en, sheet_patterns, updated_at FROM products ORDER BY product_type`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		var p Product
+		if err := rows.Scan(&p.ProductType, &p.NameZhTW, &p.NameEn, pq.Array(&p.SheetPatterns), &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// GetProducts 產品名稱 → 顯示名稱
+func GetProducts(db *sql.DB) (map[string]Product, error) {
+	list, err := ListProducts(db)
+	if err != nil {
+		return nil, err
+	}
+	products := make(map[string]Product, len(list))
+	for _, p := range list {
+		products[p.ProductType] = p
+	}
+	return products, nil
+}
+
+// SaveProduct 新增或更新產品的顯示名稱；SheetPatterns 為 nil 時保留原本的比對規則
+func SaveProduct(db *sql.DB, p Product) (*Product, error) {
+	err := db.QueryRow(`
+		INSERT INTO products (product_type, name_zh_tw, name_en, sheet_patterns)
+		VALUES ($1, $2, $3, COALESCE($4, '{}'::text[]))
+		ON CONFLICT (product_type) DO UPDATE SET
+			name_zh_tw = EXCLUDED.name_zh_tw,
+			name_en = EXCLUDED.name_en,
+			sheet_patterns = COALESCE($4, products.sheet_patterns),
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING sheet_patterns, updated_at
+	`, p.ProductType, p.NameZhTW, p.NameEn, pq.Array(p.SheetPatterns)).Scan(pq.Array(&p.SheetPatterns), &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProductTypes 列出有出貨的產品（啟用店家、數量正常的出貨），exclude 中的產品（尚未公開）不列入
+func ListProductTypes(db *sql.DB, exclude []string) ([]ProductType, error) {
+	rows, err := db.Query(`
+		SELECT sh.product_type, COUNT(DISTINCT sh.store_id), MAX(sh.shipment_date)
+		FROM shipments sh
+		JOIN stores s ON s.id = sh.store_id
+		WHERE s.is_active
+		  AND sh.quantity IS NOT NULL
+		  AND sh.quantity != ''
+		  AND sh.quantity != '0'
+		  AND sh.quality_flag IS NULL
+		  AND ($1::text[] IS NULL OR NOT (sh.product_type = ANY($1)))
+		GROUP BY sh.product_type
+		ORDER BY sh.product_type
+	`, pq.Array(exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []ProductType{}
+	for rows.Next() {
+		var p ProductType
+		var latest time.Time
+		if err := rows.Scan(&p.ProductType, &p.StoreCount, &latest); err != nil {
+			return nil, err
+		}
+		p.LatestShipment = latest.Format("2006-01-02")
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 出貨數量的品質標記（shipments.quality_flag），NULL 表示正常
+const (
+	QualityNegative = "negative"  // 數量為負數
+	QualityTooSmall = "too_small" // 低於該產品的合理下限
+	QualityTooLarge = "too_large" // 超過該產品的合理上限
+)
+
+// QuantityRange 產品單日出貨數量的合理範圍（含頭尾）
+type QuantityRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// defaultQuantityRanges 預設的合理範圍，可用 SHIPMENT_QUANTITY_RANGES 覆蓋
+var defaultQuantityRanges = map[string]QuantityRange{
+	"秋葵":   {Min: 0, Max: 500},
+	"產銷絲瓜": {Min: 0, Max: 500},
+}
+
+var (
+	quantityRangesMu sync.RWMutex
+	quantityRanges   = defaultQuantityRanges
+)
+
+// ConfigureQuantityRanges 套用 SHIPMENT_QUANTITY_RANGES（JSON，例如 {"秋葵":{"min":0,"max":300}}），
+// 未設定的產品使用預設範圍；格式錯誤時記錄警告並使用預設範圍
+func ConfigureQuantityRanges(spec string) {
+	ranges := make(map[string]QuantityRange)
+	for product, r := range defaultQuantityRanges {
+		ranges[product] = r
+	}
+
+	if spec != "" {
+		var custom map[string]QuantityRange
+		if err := json.Unmarshal([]byte(spec), &custom); err != nil {
+			log.Printf("[WARN] SHIPMENT_QUANTITY_RANGES 格式錯誤，使用預設範圍: %v", err)
+			custom = nil
+		}
+		for product, r := range custom {
+			ranges[product] = r
+		}
+	}
+
+	quantityRangesMu.Lock()
+	defer quantityRangesMu.Unlock()
+	quantityRanges = ranges
+}
+
+// ShipmentQualityFlag 檢查出貨數量是否在產品的合理範圍內，正常時回傳空字串；
+// 非數字的數量（例如備註文字）不在檢查範圍內
+func ShipmentQualityFlag(productType, qty string) string {
+	n, err := strconv.ParseFloat(strings.TrimSpace(qty), 64)
+	if err != nil {
+		return ""
+	}
+	if n < 0 {
+		return QualityNegative
+	}
+	quantityRangesMu.RLock()
+	r, ok := quantityRanges[productType]
+	quantityRangesMu.RUnlock()
+	switch {
+	case !ok:
+		return ""
+	case n < r.Min:
+		return QualityTooSmall
+	case n > r.Max:
+		return QualityTooLarge
+	}
+	return ""
+}
+
+// FlaggedShipment 數量異常、未顯示在公開地圖上的出貨
+type FlaggedShipment struct {
+	ID           int       `json:"id"`
+	StoreID      int       `json:"storeId"`
+	StoreName    string    `json:"storeName"`
+	ProductType  string    `json:"productType"`
+	ShipmentDate string    `json:"shipmentDate"`
+	Quantity     string    `json:"quantity"`
+	QualityFlag  string    `json:"qualityFlag"`
+	SourceID     string    `json:"sourceId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// GetFlaggedShipments 列出有品質標記的出貨（新到舊）
+func GetFlaggedShipments(db *sql.DB, limit int) ([]FlaggedShipment, error) {
+	rows, err := db.Query(`
+		SELECT sh.id, s.id, s.store_name, sh.product_type, TO_CHAR(sh.shipment_date, 'YYYY-MM-DD'),
+		       COALESCE(sh.quantity, ''), sh.quality_flag, COALESCE(sh.source_id, ''), sh.created_at
+		FROM shipments sh
+		JOIN stores s ON s.id = sh.store_id
+		WHERE sh.quality_flag IS NOT NULL
+		ORDER BY sh.shipment_date DESC, s.store_name
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []FlaggedShipment{}
+	for rows.Next() {
+		var f FlaggedShipment
+		if err := rows.Scan(&f.ID, &f.StoreID, &f.StoreName, &f.ProductType, &f.ShipmentDate,
+			&f.Quantity, &f.QualityFlag, &f.SourceID, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, f)
+	}
+	return shipments, rows.Err()
+}
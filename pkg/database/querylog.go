@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// QueryStats 查詢次數與耗時統計（啟用慢查詢記錄時才會累計）
+type QueryStats struct {
+	Queries         int64   `json:"queries"`
+	SlowQueries     int64   `json:"slowQueries"`
+	TotalDurationMs float64 `json:"totalDurationMs"`
+	SlowThresholdMs int64   `json:"slowThresholdMs"`
+}
+
+// maxLoggedSQLSize 慢查詢日誌中 SQL 的最大長度
+const maxLoggedSQLSize = 500
+
+var (
+	queryCount      atomic.Int64
+	slowQueryCount  atomic.Int64
+	queryDurationNs atomic.Int64
+	slowThresholdNs atomic.Int64
+)
+
+// GetQueryStats 目前累計的查詢統計
+func GetQueryStats() QueryStats {
+	return QueryStats{
+		Queries:         queryCount.Load(),
+		SlowQueries:     slowQueryCount.Load(),
+		TotalDurationMs: float64(queryDurationNs.Load()) / float64(time.Millisecond),
+		SlowThresholdMs: time.Duration(slowThresholdNs.Load()).Milliseconds(),
+	}
+}
+
+// newLoggingConnector 包裝資料庫 connector，記錄超過 threshold 的查詢（不記錄參數值）
+func newLoggingConnector(base driver.Connector, threshold time.Duration) driver.Connector {
+	slowThresholdNs.Store(int64(threshold))
+	return &loggingConnector{base: base, threshold: threshold}
+}
+
+type loggingConnector struct {
+	base      driver.Connector
+	threshold time.Duration
+}
+
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+func (c *loggingConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// loggingConn 轉呼叫 pq 的連線，並在 Query / Exec 前後計時
+type loggingConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+func (c *loggingConn) observe(query string, args int, start time.Time) {
+	elapsed := time.Since(start)
+	queryCount.Add(1)
+	queryDurationNs.Add(int64(elapsed))
+	if elapsed < c.threshold {
+		return
+	}
+	slowQueryCount.Add(1)
+	log.Printf("[WARN] 慢查詢 %v（%d 個參數已省略）: %s", elapsed.Round(time.Millisecond), args, compactSQL(query))
+}
+
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.observe(query, len(args), start)
+	return rows, err
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.observe(query, len(args), start)
+	return result, err
+}
+
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *loggingConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// compactSQL 將 SQL 壓成一行並截斷，方便在日誌中閱讀
+func compactSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedSQLSize {
+		query = query[:maxLoggedSQLSize] + "..."
+	}
+	return query
+}
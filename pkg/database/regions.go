@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// DeliveryRegion 配送區域（GeoJSON 多邊形）
+type DeliveryRegion struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	GeoJSON   json.RawMessage `json:"geojson"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// CreateDeliveryRegion 新增配送區域
+func CreateDeliveryRegion(db *sql.DB, name string, geojson []byte) (*DeliveryRegion, error) {
+	region := &DeliveryRegion{Name: name, GeoJSON: geojson}
+	err := db.QueryRow(`
+		INSERT INTO regions (name, geojson)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, name, string(geojson)).Scan(&region.ID, &region.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return region, nil
+}
+
+// GetDeliveryRegion 取得單一配送區域，不存在時回傳 sql.ErrNoRows
+func GetDeliveryRegion(db *sql.DB, id int) (*DeliveryRegion, error) {
+	var region DeliveryRegion
+	var geojson string
+	err := db.QueryRow(`SELECT id, name, geojson, created_at FROM regions WHERE id = $1`, id).
+		Scan(&region.ID, &region.Name, &geojson, &region.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	region.GeoJSON = json.RawMessage(geojson)
+	return &region, nil
+}
+
+// ListDeliveryRegions 列出所有配送區域
+func ListDeliveryRegions(db *sql.DB) ([]DeliveryRegion, error) {
+	rows, err := db.Query(`SELECT id, name, geojson, created_at FROM regions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := []DeliveryRegion{}
+	for rows.Next() {
+		var r DeliveryRegion
+		var geojson string
+		if err := rows.Scan(&r.ID, &r.Name, &geojson, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.GeoJSON = json.RawMessage(geojson)
+		regions = append(regions, r)
+	}
+	return regions, rows.Err()
+}
+
+// DeleteDeliveryRegion 刪除配送區域，不存在時回傳 sql.ErrNoRows
+func DeleteDeliveryRegion(db *sql.DB, id int) error {
+	result, err := db.Exec(`DELETE FROM regions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetLocatedStores 取得啟用中且有座標的店家
+func GetLocatedStores(db *sql.DB) ([]StoreRecord, error) {
+	rows, err := db.Query(`
+		SELECT ` + storeColumns + `
+		FROM stores
+		WHERE is_active
+		  AND latitude IS NOT NULL
+		  AND longitude IS NOT NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []StoreRecord{}
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	return stores, rows.Err()
+}
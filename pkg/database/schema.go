@@ -0,0 +1,417 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// Migration 一個版本的資料表變更，Statements 在同一個交易內執行
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// migrations 依版本排序的資料表變更；新增變更請在最後加上新版本，不要修改已發布的版本
+var migrations = []Migration{
+	// 原本只寫在 README 的基本資料表；既有資料庫已手動建立時 IF NOT EXISTS 不做任何修改
+	{Version: 0, Name: "stores_shipments", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS stores (
+			id SERIAL PRIMARY KEY,
+			store_name VARCHAR(255) NOT NULL UNIQUE,
+			place_id VARCHAR(255),
+			formatted_address TEXT,
+			latitude DECIMAL(10, 8),
+			longitude DECIMAL(11, 8),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS shipments (
+			id SERIAL PRIMARY KEY,
+			store_id INTEGER REFERENCES stores(id) ON DELETE CASCADE,
+			product_type VARCHAR(50) NOT NULL,
+			shipment_date DATE NOT NULL,
+			quantity VARCHAR(50),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(store_id, product_type, shipment_date)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_stores_store_name ON stores(store_name)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_store_id ON shipments(store_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_date ON shipments(shipment_date)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_product_type ON shipments(product_type)`,
+	}},
+	{Version: 1, Name: "stores.business_status", Statements: []string{
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS business_status VARCHAR(50)`,
+	}},
+	{Version: 2, Name: "store_flags", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS store_flags (
+			store_id INTEGER REFERENCES stores(id) ON DELETE CASCADE,
+			flag VARCHAR(50) NOT NULL,
+			detail TEXT,
+			checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (store_id, flag)
+		)`,
+	}},
+	{Version: 3, Name: "stores.is_active", Statements: []string{
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE`,
+	}},
+	{Version: 4, Name: "store_audit_logs", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS store_audit_logs (
+			id SERIAL PRIMARY KEY,
+			store_id INTEGER NOT NULL,
+			field VARCHAR(50) NOT NULL,
+			old_value TEXT,
+			new_value TEXT,
+			changed_by VARCHAR(100),
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_store_audit_logs_store_id ON store_audit_logs(store_id)`,
+	}},
+	{Version: 5, Name: "source_id", Statements: []string{
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS source_id VARCHAR(50)`,
+		`ALTER TABLE shipments ADD COLUMN IF NOT EXISTS source_id VARCHAR(50)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_source_id ON shipments(source_id)`,
+	}},
+	{Version: 6, Name: "stores.region", Statements: []string{
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS region VARCHAR(100)`,
+	}},
+	{Version: 7, Name: "links", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS links (
+			code VARCHAR(16) PRIMARY KEY,
+			target TEXT NOT NULL,
+			product VARCHAR(50),
+			region VARCHAR(100),
+			date VARCHAR(30),
+			clicks INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 8, Name: "sync_logs", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS sync_logs (
+			id SERIAL PRIMARY KEY,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP,
+			status VARCHAR(20) NOT NULL,
+			message TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`ALTER TABLE sync_logs ADD COLUMN IF NOT EXISTS output TEXT`,
+	}},
+	{Version: 9, Name: "sync_snapshots", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS sync_snapshots (
+			sync_id INTEGER PRIMARY KEY REFERENCES sync_logs(id) ON DELETE CASCADE,
+			data TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 10, Name: "regions", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS regions (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			geojson TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 11, Name: "webhooks", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			url TEXT NOT NULL,
+			secret VARCHAR(100) NOT NULL,
+			products TEXT[] NOT NULL DEFAULT '{}',
+			regions TEXT[] NOT NULL DEFAULT '{}',
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id SERIAL PRIMARY KEY,
+			webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event VARCHAR(50) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER,
+			error TEXT,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC)`,
+	}},
+	{Version: 12, Name: "sync_jobs", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS sync_jobs (
+			id SERIAL PRIMARY KEY,
+			type VARCHAR(20) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			error TEXT,
+			requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_jobs_status ON sync_jobs(status)`,
+	}},
+	{Version: 13, Name: "source_sync_status", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS source_sync_status (
+			source_id VARCHAR(50) PRIMARY KEY,
+			name VARCHAR(255),
+			last_sync_at TIMESTAMP NOT NULL,
+			last_success_at TIMESTAMP,
+			status VARCHAR(20) NOT NULL,
+			message TEXT
+		)`,
+	}},
+	{Version: 14, Name: "stores.coordinate_provenance", Statements: []string{
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS coordinate_provenance VARCHAR(30)`,
+	}},
+	{Version: 15, Name: "exports", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS exports (
+			id SERIAL PRIMARY KEY,
+			month VARCHAR(7) NOT NULL UNIQUE,
+			file_name VARCHAR(100) NOT NULL,
+			row_count INTEGER NOT NULL,
+			size_bytes INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 16, Name: "api_keys", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			key_hash VARCHAR(64) NOT NULL UNIQUE,
+			key_prefix VARCHAR(12) NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 17, Name: "shipments.quality_flag", Statements: []string{
+		`ALTER TABLE shipments ADD COLUMN IF NOT EXISTS quality_flag VARCHAR(20)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_quality_flag ON shipments(quality_flag) WHERE quality_flag IS NOT NULL`,
+	}},
+	{Version: 18, Name: "product_aliases", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS product_aliases (
+			alias VARCHAR(50) PRIMARY KEY,
+			product VARCHAR(50) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{Version: 19, Name: "sheet_snapshots", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS sheet_snapshots (
+			source_id VARCHAR(50) NOT NULL,
+			gid VARCHAR(50) NOT NULL,
+			sheet_name VARCHAR(255) NOT NULL,
+			data TEXT NOT NULL,
+			fetched_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (source_id, gid)
+		)`,
+	}},
+	{Version: 20, Name: "district_daily_totals", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS district_daily_totals (
+			district VARCHAR(100) NOT NULL,
+			product_type VARCHAR(50) NOT NULL,
+			date DATE NOT NULL,
+			store_count INTEGER NOT NULL,
+			total_quantity NUMERIC NOT NULL,
+			PRIMARY KEY (district, product_type, date)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_district_daily_totals_date ON district_daily_totals(date)`,
+		// 先以現有出貨填入，之後每次同步結束時由 RefreshDistrictDailyTotals 重新計算
+		insertDistrictDailyTotals,
+	}},
+	{Version: 21, Name: "bulk_store_jobs", Statements: []string{
+		`CREATE TABLE IF NOT EXISTS bulk_store_jobs (
+			id SERIAL PRIMARY KEY,
+			action VARCHAR(20) NOT NULL,
+			filter TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			total INTEGER NOT NULL DEFAULT 0,
+			succeeded INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			results TEXT,
+			error TEXT,
+			requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		)`,
+	}},
+	{Version: 22, Name: "shipment_audit_logs", Statements: []string{
+		// 沒有外鍵，出貨刪除後仍保留紀錄；old_value / new_value 為修改前後整筆出貨的 JSON
+		`CREATE TABLE IF NOT EXISTS shipment_audit_logs (
+			id SERIAL PRIMARY KEY,
+			shipment_id INTEGER NOT NULL,
+			store_id INTEGER NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			old_value TEXT,
+			new_value TEXT,
+			note TEXT NOT NULL,
+			changed_by VARCHAR(100),
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipment_audit_logs_shipment_id ON shipment_audit_logs(shipment_id)`,
+	}},
+	{Version: 23, Name: "shipments.time_slot", Statements: []string{
+		// 同一天有上午、下午兩次配送的店家各存一筆；沒有標示時段的出貨為空字串，維持原本一天一筆
+		`ALTER TABLE shipments ADD COLUMN IF NOT EXISTS time_slot VARCHAR(10) NOT NULL DEFAULT ''`,
+		`ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_store_id_product_type_shipment_date_key`,
+		`ALTER TABLE shipments ADD CONSTRAINT shipments_store_id_product_type_shipment_date_time_slot_key
+			UNIQUE (store_id, product_type, shipment_date, time_slot)`,
+	}},
+	{Version: 24, Name: "blob_keys", Statements: []string{
+		// 封存與快照的內容改存放在 BLOB_STORE，資料表只保留 key；升級前寫入的資料仍從 data 讀取
+		`ALTER TABLE exports ALTER COLUMN data DROP NOT NULL`,
+		`ALTER TABLE exports ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255)`,
+		`ALTER TABLE sheet_snapshots ALTER COLUMN data DROP NOT NULL`,
+		`ALTER TABLE sheet_snapshots ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255)`,
+		`ALTER TABLE sync_snapshots ALTER COLUMN data DROP NOT NULL`,
+		`ALTER TABLE sync_snapshots ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255)`,
+	}},
+	{Version: 25, Name: "products", Statements: []string{
+		// 產品的顯示名稱（依 Accept-Language 選擇），product_type 仍為出貨與查詢參數使用的識別名稱
+		`CREATE TABLE IF NOT EXISTS products (
+			product_type VARCHAR(50) PRIMARY KEY,
+			name_zh_tw VARCHAR(100) NOT NULL,
+			name_en VARCHAR(100) NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO products (product_type, name_zh_tw, name_en) VALUES
+			('秋葵', '秋葵', 'Okra'),
+			('產銷絲瓜', '產銷絲瓜', 'Sponge gourd')
+		ON CONFLICT (product_type) DO NOTHING`,
+	}},
+	{Version: 26, Name: "sync_jobs.callback_url", Statements: []string{
+		// 觸發同步時指定的進度回呼網址（worker 執行時送出各階段進度）
+		`ALTER TABLE sync_jobs ADD COLUMN IF NOT EXISTS callback_url TEXT`,
+	}},
+	{Version: 27, Name: "sheet_last_modified", Statements: []string{
+		// 試算表的最後修改時間（Drive API），區分「同步正常但表單沒有更新」與「同步故障」
+		`ALTER TABLE sync_logs ADD COLUMN IF NOT EXISTS sheet_last_modified TIMESTAMP`,
+		`ALTER TABLE source_sync_status ADD COLUMN IF NOT EXISTS sheet_modified_at TIMESTAMP`,
+	}},
+	{Version: 28, Name: "stores.last_seen_at", Statements: []string{
+		// 店家最後一次出現在工作表的時間（清理改名後留下、沒有出貨的舊店家）；既有店家以 updated_at 為準
+		`ALTER TABLE stores ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP`,
+		`UPDATE stores SET last_seen_at = COALESCE(updated_at, created_at) WHERE last_seen_at IS NULL`,
+	}},
+	{Version: 29, Name: "settings", Statements: []string{
+		// 伺服器層級的設定值；data_generation 為資料版本，每次成功同步加一（回應的 meta.generation）
+		`CREATE TABLE IF NOT EXISTS settings (
+			key VARCHAR(100) PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO settings (key, value)
+			SELECT 'data_generation', COUNT(*)::text FROM sync_logs WHERE status = 'success'
+		ON CONFLICT (key) DO NOTHING`,
+		// 每次同步產生的資料版本，對照使用者截圖中的 generation 與同步紀錄
+		`ALTER TABLE sync_logs ADD COLUMN IF NOT EXISTS data_generation BIGINT`,
+	}},
+	{Version: 30, Name: "api_keys.scopes", Statements: []string{
+		// API 金鑰的權限範圍；既有金鑰維持原本只能讀取資料的權限
+		`ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT ARRAY['read:map', 'read:stats']::text[]`,
+	}},
+	{Version: 31, Name: "sync_jobs.owner", Statements: []string{
+		// 工作的執行方式（queue：worker 從佇列取出；inline：API 程序內執行）與執行的程序（INSTANCE_ID），
+		// 程序重新啟動時只重設自己未完成的工作，不影響其他 worker 或 API 副本
+		`ALTER TABLE sync_jobs ADD COLUMN IF NOT EXISTS origin VARCHAR(20)`,
+		`ALTER TABLE sync_jobs ADD COLUMN IF NOT EXISTS owner VARCHAR(255)`,
+	}},
+	{Version: 32, Name: "api_key_usage", Statements: []string{
+		// 每個金鑰每月的請求上限（0 表示不限）與每月用量，超過上限的請求回應 429 並記錄在 rejected
+		`ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS monthly_quota INTEGER NOT NULL DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS api_key_usage (
+			api_key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+			month DATE NOT NULL,
+			requests BIGINT NOT NULL DEFAULT 0,
+			rejected BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (api_key_id, month)
+		)`,
+	}},
+	{Version: 33, Name: "products.sheet_patterns", Statements: []string{
+		// 同步的產品改由 products 資料表決定：工作表名稱與 product_type 相同，或符合 sheet_patterns（正規表示式）時
+		// 視為該產品的出貨表；預設產品沿用原本的比對規則
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS sheet_patterns TEXT[] NOT NULL DEFAULT '{}'`,
+		`UPDATE products SET sheet_patterns = ARRAY['秋葵'] WHERE product_type = '秋葵' AND sheet_patterns = '{}'`,
+		`UPDATE products SET sheet_patterns = ARRAY['絲瓜'] WHERE product_type = '產銷絲瓜' AND sheet_patterns = '{}'`,
+	}},
+	{Version: 34, Name: "sync_jobs.heartbeat_at", Statements: []string{
+		// 執行中的工作定期更新 heartbeat_at；程序換了主機名稱或未設定 owner 時，
+		// 仍可依最後一次心跳判斷工作已中斷，不必等同一個 INSTANCE_ID 重新啟動
+		`ALTER TABLE sync_jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP`,
+	}},
+}
+
+// ensureMigrationTable 建立記錄已套用版本的資料表
+func ensureMigrationTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// PendingMigrations 回傳尚未套用的版本（依版本排序）
+func PendingMigrations(db *sql.DB) ([]Migration, error) {
+	if err := ensureMigrationTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate 依序套用所有尚未套用的版本，回傳本次套用的數量
+func Migrate(db *sql.DB) (int, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return i, fmt.Errorf("套用版本 %d (%s) 失敗: %v", m.Version, m.Name, err)
+		}
+		log.Printf("[INFO] 已套用資料表版本 %d: %s", m.Version, m.Name)
+	}
+	return len(pending), nil
+}
+
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
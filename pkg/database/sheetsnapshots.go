@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/storage"
+)
+
+// sheetSnapshotKey 工作表快照在 BLOB_STORE 中的 key
+func sheetSnapshotKey(sourceID, gid string) string {
+	return "sheet-snapshots/" + url.PathEscape(sourceID) + "/" + url.PathEscape(gid) + ".json"
+}
+
+// SaveSheetSnapshot 保存工作表最近一次成功下載的內容到 blobs（每張工作表只保留一份）
+func SaveSheetSnapshot(db *sql.DB, blobs storage.BlobStore, sourceID, gid, sheetName string, records [][]string) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	key := sheetSnapshotKey(sourceID, gid)
+	if err := blobs.Put(key, data, "application/json"); err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO sheet_snapshots (source_id, gid, sheet_name, blob_key, fetched_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (source_id, gid)
+		DO UPDATE SET sheet_name = EXCLUDED.sheet_name, data = NULL, blob_key = EXCLUDED.blob_key, fetched_at = EXCLUDED.fetched_at
+	`, sourceID, gid, sheetName, key)
+	return err
+}
+
+// GetSheetSnapshot 取得工作表的快照與下載時間，不存在時回傳 sql.ErrNoRows
+func GetSheetSnapshot(db *sql.DB, blobs storage.BlobStore, sourceID, gid string) ([][]string, time.Time, error) {
+	var data, blobKey sql.NullString
+	var fetchedAt time.Time
+	err := db.QueryRow(`
+		SELECT data, blob_key, fetched_at FROM sheet_snapshots WHERE source_id = $1 AND gid = $2
+	`, sourceID, gid).Scan(&data, &blobKey, &fetchedAt)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	content, err := readBlobColumn(blobs, blobKey, data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var records [][]string
+	if err := json.Unmarshal(content, &records); err != nil {
+		return nil, time.Time{}, err
+	}
+	return records, fetchedAt, nil
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 出貨人工修正的動作（shipment_audit_logs.action）
+const (
+	ShipmentActionUpdate = "update"
+	ShipmentActionDelete = "delete"
+)
+
+// ShipmentRecord 資料庫中的一筆出貨
+type ShipmentRecord struct {
+	ID           int       `json:"id"`
+	StoreID      int       `json:"storeId"`
+	StoreName    string    `json:"storeName"`
+	ProductType  string    `json:"productType"`
+	ShipmentDate string    `json:"shipmentDate"`
+	TimeSlot     string    `json:"timeSlot,omitempty"`
+	Quantity     string    `json:"quantity"`
+	QualityFlag  string    `json:"qualityFlag,omitempty"`
+	SourceID     string    `json:"sourceId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ShipmentUpdate 要修改的欄位，nil 表示不修改
+type ShipmentUpdate struct {
+	ProductType  *string
+	ShipmentDate *time.Time
+	Quantity     *string
+}
+
+// ShipmentAuditLog 出貨修正紀錄，OldValue / NewValue 為修改前後的整筆出貨（刪除時 NewValue 為 nil）
+type ShipmentAuditLog struct {
+	ID        int             `json:"id"`
+	Action    string          `json:"action"`
+	OldValue  *ShipmentRecord `json:"oldValue"`
+	NewValue  *ShipmentRecord `json:"newValue"`
+	Note      string          `json:"note"`
+	ChangedBy string          `json:"changedBy"`
+	ChangedAt time.Time       `json:"changedAt"`
+}
+
+const shipmentRecordQuery = `
+	SELECT sh.id, sh.store_id, s.store_name, sh.product_type, sh.shipment_date::text, sh.time_slot,
+	       COALESCE(sh.quantity, ''), COALESCE(sh.quality_flag, ''), COALESCE(sh.source_id, ''), sh.created_at
+	FROM shipments sh
+	JOIN stores s ON s.id = sh.store_id
+	WHERE sh.id = $1
+`
+
+// getShipment 讀取一筆出貨，lock 為 true 時鎖定該筆資料直到交易結束
+func getShipment(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, id int, lock bool) (*ShipmentRecord, error) {
+	query := shipmentRecordQuery
+	if lock {
+		query += ` FOR UPDATE OF sh`
+	}
+	var r ShipmentRecord
+	err := q.QueryRow(query, id).Scan(&r.ID, &r.StoreID, &r.StoreName, &r.ProductType, &r.ShipmentDate,
+		&r.TimeSlot, &r.Quantity, &r.QualityFlag, &r.SourceID, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetShipmentByID 依 ID 取得單筆出貨，不存在時回傳 sql.ErrNoRows
+func GetShipmentByID(db *sql.DB, id int) (*ShipmentRecord, error) {
+	return getShipment(db, id, false)
+}
+
+// UpdateShipment 修改出貨（重新計算品質標記）並寫入附註的修正紀錄；
+// 不存在時回傳 sql.ErrNoRows，與同店同產品同日的出貨重複時回傳 unique_violation（23505）的 *pq.Error
+func UpdateShipment(db *sql.DB, id int, update ShipmentUpdate, note, changedBy string) (*ShipmentRecord, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old, err := getShipment(tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	productType, quantity, date := old.ProductType, old.Quantity, old.ShipmentDate
+	if update.ProductType != nil {
+		productType = *update.ProductType
+	}
+	if update.Quantity != nil {
+		quantity = *update.Quantity
+	}
+	if update.ShipmentDate != nil {
+		date = update.ShipmentDate.Format("2006-01-02")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE shipments
+		SET product_type = $1, shipment_date = $2::date, quantity = $3, quality_flag = NULLIF($4, '')
+		WHERE id = $5
+	`, productType, date, quantity, ShipmentQualityFlag(productType, quantity), id)
+	if err != nil {
+		return nil, fmt.Errorf("更新出貨 #%d 失敗: %w", id, err)
+	}
+
+	updated, err := getShipment(tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertShipmentAudit(tx, ShipmentActionUpdate, old, updated, note, changedBy); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 已修正出貨 #%d（%s %s %s）: %s", id, updated.StoreName, updated.ProductType, updated.ShipmentDate, note)
+	return updated, nil
+}
+
+// DeleteShipment 刪除出貨並寫入附註的修正紀錄，回傳被刪除的出貨；不存在時回傳 sql.ErrNoRows
+func DeleteShipment(db *sql.DB, id int, note, changedBy string) (*ShipmentRecord, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old, err := getShipment(tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM shipments WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	if err := insertShipmentAudit(tx, ShipmentActionDelete, old, nil, note, changedBy); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 已刪除出貨 #%d（%s %s %s）: %s", id, old.StoreName, old.ProductType, old.ShipmentDate, note)
+	return old, nil
+}
+
+func insertShipmentAudit(tx *sql.Tx, action string, old, updated *ShipmentRecord, note, changedBy string) error {
+	oldJSON, err := json.Marshal(old)
+	if err != nil {
+		return err
+	}
+	var newJSON sql.NullString
+	if updated != nil {
+		b, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		newJSON = sql.NullString{String: string(b), Valid: true}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO shipment_audit_logs (shipment_id, store_id, action, old_value, new_value, note, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, old.ID, old.StoreID, action, string(oldJSON), newJSON, note, changedBy)
+	if err != nil {
+		return fmt.Errorf("寫入出貨修正紀錄失敗: %v", err)
+	}
+	return nil
+}
+
+// GetShipmentAuditLogs 出貨的修正紀錄（新到舊）
+func GetShipmentAuditLogs(db *sql.DB, shipmentID int) ([]ShipmentAuditLog, error) {
+	rows, err := db.Query(`
+		SELECT id, action, old_value, new_value, note, COALESCE(changed_by, ''), changed_at
+		FROM shipment_audit_logs
+		WHERE shipment_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []ShipmentAuditLog{}
+	for rows.Next() {
+		var l ShipmentAuditLog
+		var oldValue, newValue sql.NullString
+		if err := rows.Scan(&l.ID, &l.Action, &oldValue, &newValue, &l.Note, &l.ChangedBy, &l.ChangedAt); err != nil {
+			return nil, err
+		}
+		if oldValue.Valid {
+			l.OldValue = &ShipmentRecord{}
+			if err := json.Unmarshal([]byte(oldValue.String), l.OldValue); err != nil {
+				return nil, err
+			}
+		}
+		if newValue.Valid {
+			l.NewValue = &ShipmentRecord{}
+			if err := json.Unmarshal([]byte(newValue.String), l.NewValue); err != nil {
+				return nil, err
+			}
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// ShipmentQuery 出貨查詢條件，零值表示不限制
+type ShipmentQuery struct {
+	StoreIDs      []int
+	From          string // YYYY-MM-DD（含）
+	To            string // YYYY-MM-DD（含）
+	Product       string
+	Exclude       []string // 不列入的產品（尚未公開）
+	PerStoreLimit int      // 每家店最多幾筆（最新的優先）
+	Limit         int
+}
+
+// QueryShipments 依條件查詢出貨（新到舊）
+func QueryShipments(db *sql.DB, f ShipmentQuery) ([]ShipmentRecord, error) {
+	var storeIDs interface{}
+	if f.StoreIDs != nil {
+		storeIDs = pq.Array(f.StoreIDs)
+	}
+	var perStore, limit interface{}
+	if f.PerStoreLimit > 0 {
+		perStore = f.PerStoreLimit
+	}
+	if f.Limit > 0 {
+		limit = f.Limit
+	}
+
+	rows, err := db.Query(`
+		SELECT id, store_id, store_name, product_type, shipment_date::text, time_slot,
+		       quantity, quality_flag, source_id, created_at
+		FROM (
+			SELECT sh.id, sh.store_id, s.store_name, sh.product_type, sh.shipment_date, sh.time_slot,
+			       COALESCE(sh.quantity, '') AS quantity, COALESCE(sh.quality_flag, '') AS quality_flag,
+			       COALESCE(sh.source_id, '') AS source_id, sh.created_at,
+			       ROW_NUMBER() OVER (PARTITION BY sh.store_id ORDER BY sh.shipment_date DESC, sh.time_slot DESC, sh.id DESC) AS n
+			FROM shipments sh
+			JOIN stores s ON s.id = sh.store_id
+			WHERE ($1::int[] IS NULL OR sh.store_id = ANY($1))
+			  AND ($2 = '' OR sh.shipment_date >= $2::date)
+			  AND ($3 = '' OR sh.shipment_date <= $3::date)
+			  AND ($4 = '' OR sh.product_type = $4)
+			  AND ($7::text[] IS NULL OR NOT (sh.product_type = ANY($7)))
+		) t
+		WHERE ($5::int IS NULL OR n <= $5)
+		ORDER BY shipment_date DESC, time_slot DESC, id DESC
+		LIMIT $6
+	`, storeIDs, f.From, f.To, f.Product, perStore, limit, pq.Array(f.Exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []ShipmentRecord{}
+	for rows.Next() {
+		var r ShipmentRecord
+		if err := rows.Scan(&r.ID, &r.StoreID, &r.StoreName, &r.ProductType, &r.ShipmentDate,
+			&r.TimeSlot, &r.Quantity, &r.QualityFlag, &r.SourceID, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, r)
+	}
+	return shipments, rows.Err()
+}
+
+// LatestShipmentDates 各店家最近一次出貨的日期（YYYY-MM-DD），product 為空時不限產品，exclude 中的產品不列入；
+// 沒有出貨的店家不在結果中
+func LatestShipmentDates(db *sql.DB, storeIDs []int, product string, exclude []string) (map[int]string, error) {
+	rows, err := db.Query(`
+		SELECT store_id, MAX(shipment_date)::text
+		FROM shipments
+		WHERE store_id = ANY($1) AND ($2 = '' OR product_type = $2) AND ($3::text[] IS NULL OR NOT (product_type = ANY($3)))
+		GROUP BY store_id
+	`, pq.Array(storeIDs), product, pq.Array(exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dates := make(map[int]string, len(storeIDs))
+	for rows.Next() {
+		var id int
+		var date string
+		if err := rows.Scan(&id, &date); err != nil {
+			return nil, err
+		}
+		dates[id] = date
+	}
+	return dates, rows.Err()
+}
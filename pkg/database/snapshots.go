@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/storage"
+)
+
+// StoreSnapshot 快照中的店家狀態
+type StoreSnapshot struct {
+	Address        string  `json:"address"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	BusinessStatus string  `json:"businessStatus"`
+	IsActive       bool    `json:"isActive"`
+}
+
+// Snapshot 某次同步後的店家與出貨狀態（出貨以 "店家|產品|日期" 為鍵，值為數量）
+type Snapshot struct {
+	Stores    map[string]StoreSnapshot `json:"stores"`
+	Shipments map[string]string        `json:"shipments"`
+}
+
+// SnapshotChange 單一項目的變化
+type SnapshotChange struct {
+	Key    string      `json:"key"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// ChangeSet 新增、移除、修改的項目
+type ChangeSet struct {
+	Added   []SnapshotChange `json:"added"`
+	Removed []SnapshotChange `json:"removed"`
+	Changed []SnapshotChange `json:"changed"`
+}
+
+// SnapshotDiff 兩次同步之間的差異
+type SnapshotDiff struct {
+	From      int       `json:"from"`
+	To        int       `json:"to"`
+	Stores    ChangeSet `json:"stores"`
+	Shipments ChangeSet `json:"shipments"`
+}
+
+// CaptureSnapshot 讀取目前的店家與出貨狀態
+func CaptureSnapshot(db *sql.DB) (*Snapshot, error) {
+	snap := &Snapshot{
+		Stores:    make(map[string]StoreSnapshot),
+		Shipments: make(map[string]string),
+	}
+
+	rows, err := db.Query(`
+		SELECT store_name, COALESCE(formatted_address, ''), COALESCE(latitude, 0), COALESCE(longitude, 0),
+		       COALESCE(business_status, ''), is_active
+		FROM stores
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var s StoreSnapshot
+		if err := rows.Scan(&name, &s.Address, &s.Latitude, &s.Longitude, &s.BusinessStatus, &s.IsActive); err != nil {
+			return nil, err
+		}
+		snap.Stores[name] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	shipmentRows, err := db.Query(`
+		SELECT s.store_name, sh.product_type, sh.shipment_date, sh.time_slot, COALESCE(sh.quantity, '')
+		FROM shipments sh
+		JOIN stores s ON s.id = sh.store_id
+		WHERE sh.quantity IS NOT NULL AND sh.quantity != '' AND sh.quantity != '0'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer shipmentRows.Close()
+	for shipmentRows.Next() {
+		var name, product, slot, qty string
+		var date time.Time
+		if err := shipmentRows.Scan(&name, &product, &date, &slot, &qty); err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s|%s|%s", name, product, date.Format("2006-01-02"))
+		if slot != "" {
+			// 沒有時段的出貨維持舊格式，與之前的快照可以直接比較
+			key += "|" + slot
+		}
+		snap.Shipments[key] = qty
+	}
+	return snap, shipmentRows.Err()
+}
+
+// SaveSyncSnapshot 保存某次同步後的快照到 blobs（sync-snapshots/<同步編號>.json）
+func SaveSyncSnapshot(db *sql.DB, blobs storage.BlobStore, syncID int, snap *Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("sync-snapshots/%d.json", syncID)
+	if err := blobs.Put(key, data, "application/json"); err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO sync_snapshots (sync_id, blob_key)
+		VALUES ($1, $2)
+		ON CONFLICT (sync_id) DO UPDATE SET data = NULL, blob_key = EXCLUDED.blob_key
+	`, syncID, key)
+	return err
+}
+
+// GetSyncSnapshot 取得某次同步的快照，不存在時回傳 sql.ErrNoRows
+func GetSyncSnapshot(db *sql.DB, blobs storage.BlobStore, syncID int) (*Snapshot, error) {
+	var data, blobKey sql.NullString
+	if err := db.QueryRow(`SELECT data, blob_key FROM sync_snapshots WHERE sync_id = $1`, syncID).Scan(&data, &blobKey); err != nil {
+		return nil, err
+	}
+	content, err := readBlobColumn(blobs, blobKey, data)
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// DiffSnapshots 比較兩份快照（from → to）
+func DiffSnapshots(fromID, toID int, from, to *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{From: fromID, To: toID}
+
+	for _, key := range sortedKeys(from.Stores, to.Stores) {
+		before, inFrom := from.Stores[key]
+		after, inTo := to.Stores[key]
+		diff.Stores.add(key, before, after, inFrom, inTo, before == after)
+	}
+	for _, key := range sortedKeys(from.Shipments, to.Shipments) {
+		before, inFrom := from.Shipments[key]
+		after, inTo := to.Shipments[key]
+		diff.Shipments.add(key, before, after, inFrom, inTo, before == after)
+	}
+	return diff
+}
+
+func (cs *ChangeSet) add(key string, before, after interface{}, inFrom, inTo, equal bool) {
+	switch {
+	case !inFrom:
+		cs.Added = append(cs.Added, SnapshotChange{Key: key, After: after})
+	case !inTo:
+		cs.Removed = append(cs.Removed, SnapshotChange{Key: key, Before: before})
+	case !equal:
+		cs.Changed = append(cs.Changed, SnapshotChange{Key: key, Before: before, After: after})
+	}
+}
+
+// sortedKeys 合併兩個 map 的鍵並排序
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
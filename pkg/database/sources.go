@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SourceFreshness 資料來源最近一次同步的狀態
+type SourceFreshness struct {
+	SourceID      string     `json:"sourceId"`
+	Name          string     `json:"name"`
+	LastSyncAt    time.Time  `json:"lastSyncAt"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt"`
+	Status        string     `json:"status"` // 'success', 'partial', 'failed'
+	Message       string     `json:"message,omitempty"`
+	// SheetModifiedAt 試算表最後修改時間（Drive API，未設定 GOOGLE_DRIVE_API_KEY 時沒有）
+	SheetModifiedAt *time.Time `json:"sheetModifiedAt,omitempty"`
+}
+
+// RecordSourceSync 記錄資料來源的同步結果；sheetModified 為 nil 時保留上次取得的試算表修改時間
+func RecordSourceSync(db *sql.DB, sourceID, name, status, message string, sheetModified *time.Time) error {
+	var modified sql.NullTime
+	if sheetModified != nil {
+		modified = sql.NullTime{Time: sheetModified.Local(), Valid: true}
+	}
+	_, err := db.Exec(`
+		INSERT INTO source_sync_status (source_id, name, last_sync_at, last_success_at, status, message, sheet_modified_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, CASE WHEN $3 = 'success' THEN CURRENT_TIMESTAMP END, $3, $4, $5)
+		ON CONFLICT (source_id)
+		DO UPDATE SET
+			name = EXCLUDED.name,
+			last_sync_at = EXCLUDED.last_sync_at,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, source_sync_status.last_success_at),
+			status = EXCLUDED.status,
+			message = EXCLUDED.message,
+			sheet_modified_at = COALESCE(EXCLUDED.sheet_modified_at, source_sync_status.sheet_modified_at)
+	`, sourceID, name, status, message, modified)
+	return err
+}
+
+// GetSourceFreshness 取得所有資料來源的同步狀態
+func GetSourceFreshness(db *sql.DB) ([]SourceFreshness, error) {
+	rows, err := db.Query(`
+		SELECT source_id, name, last_sync_at, last_success_at, status, COALESCE(message, ''), sheet_modified_at
+		FROM source_sync_status
+		ORDER BY source_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []SourceFreshness{}
+	for rows.Next() {
+		var f SourceFreshness
+		var lastSuccess, sheetModified sql.NullTime
+		if err := rows.Scan(&f.SourceID, &f.Name, &f.LastSyncAt, &lastSuccess, &f.Status, &f.Message, &sheetModified); err != nil {
+			return nil, err
+		}
+		if lastSuccess.Valid {
+			f.LastSuccessAt = &lastSuccess.Time
+		}
+		if sheetModified.Valid {
+			f.SheetModifiedAt = &sheetModified.Time
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// GetDataLastModified 地圖資料最後變動的時間：資料來源同步、店家更新、同步結束、出貨人工修正、
+// 店家刪除或停用（store_audit_logs，刪除的店家已不在 stores 中）、產品顯示名稱修改的最晚時間，
+// 至少為今天 0 點（近 N 天的查詢範圍每天都會改變）
+func GetDataLastModified(db *sql.DB) (time.Time, error) {
+	var lastModified time.Time
+	err := db.QueryRow(`
+		SELECT GREATEST(
+			(SELECT MAX(last_sync_at) FROM source_sync_status),
+			(SELECT MAX(updated_at) FROM stores),
+			(SELECT MAX(end_time) FROM sync_logs),
+			(SELECT MAX(created_at) FROM product_aliases),
+			(SELECT MAX(changed_at) FROM shipment_audit_logs),
+			(SELECT MAX(changed_at) FROM store_audit_logs),
+			(SELECT MAX(updated_at) FROM products),
+			CURRENT_DATE::timestamp
+		) AT TIME ZONE current_setting('TimeZone')
+	`).Scan(&lastModified)
+	return lastModified, err
+}
+
+// GetLatestShipmentDate 地圖上會顯示的最新出貨日期（YYYY-MM-DD，啟用店家、數量正常的出貨），
+// exclude 中的產品（尚未公開）不列入，沒有任何出貨時回傳空字串
+func GetLatestShipmentDate(db *sql.DB, exclude []string) (string, error) {
+	var latest sql.NullTime
+	err := db.QueryRow(`
+		SELECT MAX(sh.shipment_date)
+		FROM shipments sh
+		JOIN stores s ON s.id = sh.store_id
+		WHERE s.is_active
+		  AND sh.quantity IS NOT NULL
+		  AND sh.quantity != ''
+		  AND sh.quantity != '0'
+		  AND sh.quality_flag IS NULL
+		  AND ($1::text[] IS NULL OR NOT (sh.product_type = ANY($1)))
+	`, pq.Array(exclude)).Scan(&latest)
+	if err != nil || !latest.Valid {
+		return "", err
+	}
+	return latest.Time.Format("2006-01-02"), nil
+}
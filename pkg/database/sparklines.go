@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// SparklineDays 走勢圖涵蓋的天數（含今天）
+const SparklineDays = 14
+
+// GetSparklines 取得近 SparklineDays 天每個店家、產品的每日出貨量（由舊到新，沒有出貨的日子為 0），
+// 回傳 店名 → 產品 → 每日數量
+func GetSparklines(db *sql.DB) (map[string]map[string][]float64, error) {
+	rows, err := db.Query(`
+		WITH pairs AS (
+			SELECT DISTINCT store_id, product_type
+			FROM shipments
+			WHERE shipment_date > CURRENT_DATE - $1::int
+		),
+		totals AS (
+			SELECT store_id, product_type, shipment_date,
+			       SUM(CASE WHEN quantity ~ '^[0-9]+(\.[0-9]+)?$' THEN quantity::numeric ELSE 0 END) AS total
+			FROM shipments
+			WHERE shipment_date > CURRENT_DATE - $1::int
+			  AND quality_flag IS NULL
+			GROUP BY store_id, product_type, shipment_date
+		)
+		SELECT s.store_name, p.product_type, ARRAY_AGG(COALESCE(t.total, 0)::float8 ORDER BY d.day)
+		FROM pairs p
+		JOIN stores s ON s.id = p.store_id
+		CROSS JOIN generate_series(CURRENT_DATE - ($1::int - 1), CURRENT_DATE, INTERVAL '1 day') AS d(day)
+		LEFT JOIN totals t
+		       ON t.store_id = p.store_id
+		      AND t.product_type = p.product_type
+		      AND t.shipment_date = d.day::date
+		GROUP BY s.store_name, p.product_type
+	`, SparklineDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]map[string][]float64)
+	for rows.Next() {
+		var storeName, product string
+		var values pq.Float64Array
+		if err := rows.Scan(&storeName, &product, &values); err != nil {
+			return nil, err
+		}
+		if result[storeName] == nil {
+			result[storeName] = make(map[string][]float64)
+		}
+		result[storeName][product] = values
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 孤兒店家的處理方式
+const (
+	StoreGCDryRun     = "dry-run"    // 只列出，不修改
+	StoreGCDeactivate = "deactivate" // 停用（保留資料列，可再啟用）
+	StoreGCDelete     = "delete"     // 刪除
+)
+
+// DefaultStoreGCDays 超過幾天沒有出現在工作表才視為孤兒店家
+const DefaultStoreGCDays = 30
+
+// OrphanedStore 沒有任何出貨、且近期沒有出現在工作表的店家（例如改名後留下的舊名稱）
+type OrphanedStore struct {
+	ID         int        `json:"id"`
+	StoreName  string     `json:"storeName"`
+	SourceID   string     `json:"sourceId,omitempty"`
+	IsActive   bool       `json:"isActive"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"` // 最後一次出現在工作表的時間
+}
+
+// StoreGCReport 孤兒店家清理結果
+type StoreGCReport struct {
+	Action      string          `json:"action"`
+	Days        int             `json:"days"`
+	Stores      []OrphanedStore `json:"stores"`
+	Deactivated int             `json:"deactivated"`
+	Deleted     int             `json:"deleted"`
+}
+
+// IsValidStoreGCAction 是否為支援的處理方式
+func IsValidStoreGCAction(action string) bool {
+	return action == StoreGCDryRun || action == StoreGCDeactivate || action == StoreGCDelete
+}
+
+// CollectOrphanedStores 找出沒有任何出貨、且超過 days 天沒有出現在工作表的店家，
+// 依 action 只列出、停用或刪除（單一交易，每個修改的店家寫入稽核紀錄）
+func CollectOrphanedStores(db *sql.DB, days int, action, changedBy string) (*StoreGCReport, error) {
+	if !IsValidStoreGCAction(action) {
+		return nil, fmt.Errorf("不支援的處理方式: %s", action)
+	}
+	if days <= 0 {
+		return nil, fmt.Errorf("days 必須大於 0")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 鎖定候選店家，避免同步在清理途中寫入新的出貨
+	cutoff := time.Now().AddDate(0, 0, -days)
+	rows, err := tx.Query(`
+		SELECT s.id, s.store_name, COALESCE(s.source_id, ''), s.is_active, s.last_seen_at
+		FROM stores s
+		WHERE NOT EXISTS (SELECT 1 FROM shipments sh WHERE sh.store_id = s.id)
+		  AND COALESCE(s.last_seen_at, s.created_at, s.updated_at) < $1
+		ORDER BY s.id
+		FOR UPDATE
+	`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StoreGCReport{Action: action, Days: days, Stores: []OrphanedStore{}}
+	for rows.Next() {
+		var s OrphanedStore
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&s.ID, &s.StoreName, &s.SourceID, &s.IsActive, &lastSeen); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if lastSeen.Valid {
+			s.LastSeenAt = &lastSeen.Time
+		}
+		report.Stores = append(report.Stores, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range report.Stores {
+		switch action {
+		case StoreGCDeactivate:
+			if !s.IsActive {
+				continue
+			}
+			if _, err := tx.Exec(`UPDATE stores SET is_active = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, s.ID); err != nil {
+				return nil, fmt.Errorf("停用店家 %s 失敗: %v", s.StoreName, err)
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+				VALUES ($1, 'is_active', 'true', 'false', $2)
+			`, s.ID, changedBy); err != nil {
+				return nil, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+			}
+			report.Deactivated++
+		case StoreGCDelete:
+			if _, err := tx.Exec(`DELETE FROM stores WHERE id = $1`, s.ID); err != nil {
+				return nil, fmt.Errorf("刪除店家 %s 失敗: %v", s.StoreName, err)
+			}
+			// store_audit_logs 沒有外鍵，店家刪除後仍保留紀錄
+			if _, err := tx.Exec(`
+				INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+				VALUES ($1, 'store_name', $2, NULL, $3)
+			`, s.ID, s.StoreName, changedBy); err != nil {
+				return nil, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+			}
+			report.Deleted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if action != StoreGCDryRun {
+		log.Printf("[INFO] 孤兒店家清理: %d 家符合條件，停用 %d 家，刪除 %d 家",
+			len(report.Stores), report.Deactivated, report.Deleted)
+	}
+	return report, nil
+}
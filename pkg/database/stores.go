@@ -0,0 +1,414 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// StoreRecord 資料庫中的店家紀錄
+type StoreRecord struct {
+	ID               int     `json:"id"`
+	StoreName        string  `json:"storeName"`
+	PlaceID          string  `json:"placeId"`
+	FormattedAddress string  `json:"formattedAddress"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	BusinessStatus   string  `json:"businessStatus"`
+	IsActive         bool    `json:"isActive"`
+	Region           string  `json:"region"`
+}
+
+// FieldChange 單一欄位的修改紀錄
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// StorePatchColumns 允許透過管理 API 修改的欄位
+var StorePatchColumns = map[string]bool{
+	"store_name":        true,
+	"place_id":          true,
+	"formatted_address": true,
+	"latitude":          true,
+	"longitude":         true,
+	"is_active":         true,
+}
+
+const storeColumns = `id, store_name, place_id, formatted_address, latitude, longitude, business_status, is_active, region`
+
+// scanStore 讀取一筆店家資料（處理可能為 NULL 的欄位）
+func scanStore(scanner interface{ Scan(...interface{}) error }) (StoreRecord, error) {
+	var store StoreRecord
+	var placeID, address, businessStatus, region sql.NullString
+	var lat, lng sql.NullFloat64
+
+	err := scanner.Scan(&store.ID, &store.StoreName, &placeID, &address, &lat, &lng, &businessStatus, &store.IsActive, &region)
+	if err != nil {
+		return store, err
+	}
+
+	store.PlaceID = placeID.String
+	store.FormattedAddress = address.String
+	store.Latitude = lat.Float64
+	store.Longitude = lng.Float64
+	store.BusinessStatus = businessStatus.String
+	store.Region = region.String
+	return store, nil
+}
+
+// GetStoreByID 依 ID 取得單一店家，不存在時回傳 sql.ErrNoRows
+func GetStoreByID(db *sql.DB, id int) (*StoreRecord, error) {
+	row := db.QueryRow(`SELECT `+storeColumns+` FROM stores WHERE id = $1`, id)
+	store, err := scanStore(row)
+	if err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// GetStoresByIDs 依 ID 取得店家資料
+func GetStoresByIDs(db *sql.DB, ids []int) ([]StoreRecord, error) {
+	query := `
+		SELECT ` + storeColumns + `
+		FROM stores
+		WHERE id = ANY($1)
+		ORDER BY id
+	`
+
+	rows, err := db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stores []StoreRecord
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+
+	return stores, rows.Err()
+}
+
+// UpdateStoreLocation 更新店家地點資訊
+func UpdateStoreLocation(db *sql.DB, id int, placeID, address string, lat, lng float64, businessStatus string) error {
+	_, err := db.Exec(`
+		UPDATE stores
+		SET place_id = $1,
+			formatted_address = $2,
+			latitude = $3,
+			longitude = $4,
+			business_status = $5,
+			coordinate_provenance = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+	`, placeID, address, lat, lng, businessStatus, ProvenancePlaces, id)
+	return err
+}
+
+// PatchStore 只更新有提供的欄位（值為 nil 表示設為 NULL），並為每個變更的欄位寫入稽核紀錄
+func PatchStore(db *sql.DB, id int, changes map[string]interface{}, changedBy string) ([]FieldChange, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 鎖定該筆資料，確認存在
+	var exists int
+	if err := tx.QueryRow(`SELECT id FROM stores WHERE id = $1 FOR UPDATE`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	var applied []FieldChange
+	for column, value := range changes {
+		if !StorePatchColumns[column] {
+			return nil, fmt.Errorf("不允許修改欄位: %s", column)
+		}
+
+		var oldValue, newValue sql.NullString
+		if err := tx.QueryRow(fmt.Sprintf(`SELECT %s::text FROM stores WHERE id = $1`, column), id).Scan(&oldValue); err != nil {
+			return nil, err
+		}
+
+		err := tx.QueryRow(fmt.Sprintf(`
+			UPDATE stores SET %s = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+			RETURNING %s::text
+		`, column, column), value, id).Scan(&newValue)
+		if err != nil {
+			return nil, fmt.Errorf("更新欄位 %s 失敗: %w", column, err)
+		}
+
+		if oldValue == newValue {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+			VALUES ($1, $2, $3, $4, $5)
+		`, id, column, oldValue, newValue, changedBy)
+		if err != nil {
+			return nil, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+		}
+
+		applied = append(applied, FieldChange{Field: column, OldValue: oldValue.String, NewValue: newValue.String})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 已更新店家 #%d 的 %d 個欄位", id, len(applied))
+	return applied, nil
+}
+
+// NewStore 透過管理 API 建立的店家；有座標時標記為 manual_import，同步時不會再以 Places API 查詢
+type NewStore struct {
+	StoreName        string
+	PlaceID          string
+	FormattedAddress string
+	Latitude         *float64
+	Longitude        *float64
+	Region           string
+}
+
+// CreateStore 新增店家並寫入稽核紀錄；店名重複時回傳 unique_violation（23505）的 *pq.Error
+func CreateStore(db *sql.DB, store NewStore, changedBy string) (*StoreRecord, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var provenance sql.NullString
+	if store.Latitude != nil && store.Longitude != nil {
+		provenance = sql.NullString{String: ProvenanceManualImport, Valid: true}
+	}
+
+	row := tx.QueryRow(`
+		INSERT INTO stores (store_name, place_id, formatted_address, latitude, longitude, region, coordinate_provenance)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7)
+		RETURNING `+storeColumns,
+		store.StoreName, store.PlaceID, store.FormattedAddress, store.Latitude, store.Longitude, store.Region, provenance)
+	created, err := scanStore(row)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+		VALUES ($1, 'store_name', NULL, $2, $3)
+	`, created.ID, created.StoreName, changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] 已建立店家 #%d (%s)", created.ID, created.StoreName)
+	return &created, nil
+}
+
+// ListStores 依店名或地址關鍵字（q）與是否營業中（active 為 nil 時不限）列出店家，回傳該頁資料與符合的總數
+func ListStores(db *sql.DB, q string, active *bool, limit, offset int) ([]StoreRecord, int, error) {
+	where := `
+		WHERE ($1 = '' OR store_name ILIKE '%' || $1 || '%' OR formatted_address ILIKE '%' || $1 || '%')
+		  AND ($2::boolean IS NULL OR is_active = $2)
+	`
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM stores`+where, q, active).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT `+storeColumns+`
+		FROM stores`+where+`
+		ORDER BY store_name
+		LIMIT $3 OFFSET $4
+	`, q, active, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stores := []StoreRecord{}
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		stores = append(stores, store)
+	}
+	return stores, total, rows.Err()
+}
+
+// CountStoreShipments 店家的出貨紀錄筆數
+func CountStoreShipments(db *sql.DB, id int) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM shipments WHERE store_id = $1`, id).Scan(&n)
+	return n, err
+}
+
+// DeleteStore 刪除店家（出貨紀錄一併刪除），並在稽核紀錄留下被刪除的店名；不存在時回傳 sql.ErrNoRows
+func DeleteStore(db *sql.DB, id int, changedBy string) (shipments int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	if err := tx.QueryRow(`SELECT store_name FROM stores WHERE id = $1 FOR UPDATE`, id).Scan(&name); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM shipments WHERE store_id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	shipments = int(n)
+
+	if _, err := tx.Exec(`DELETE FROM stores WHERE id = $1`, id); err != nil {
+		return 0, err
+	}
+
+	// store_audit_logs 沒有外鍵，店家刪除後仍保留紀錄
+	_, err = tx.Exec(`
+		INSERT INTO store_audit_logs (store_id, field, old_value, new_value, changed_by)
+		VALUES ($1, 'store_name', $2, NULL, $3)
+	`, id, name, changedBy)
+	if err != nil {
+		return 0, fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("[INFO] 已刪除店家 #%d (%s) 與 %d 筆出貨紀錄", id, name, shipments)
+	return shipments, nil
+}
+
+// SourceStats 單一資料來源的資料量
+type SourceStats struct {
+	SourceID  string `json:"sourceId"`
+	Stores    int    `json:"stores"`
+	Shipments int    `json:"shipments"`
+}
+
+// GetSourceStats 依資料來源統計店家與出貨筆數
+func GetSourceStats(db *sql.DB) (map[string]SourceStats, error) {
+	rows, err := db.Query(`
+		SELECT source_id, SUM(stores), SUM(shipments)
+		FROM (
+			SELECT COALESCE(source_id, '') AS source_id, COUNT(*) AS stores, 0 AS shipments
+			FROM stores GROUP BY 1
+			UNION ALL
+			SELECT COALESCE(source_id, ''), 0, COUNT(*)
+			FROM shipments GROUP BY 1
+		) t
+		GROUP BY source_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]SourceStats)
+	for rows.Next() {
+		var s SourceStats
+		if err := rows.Scan(&s.SourceID, &s.Stores, &s.Shipments); err != nil {
+			return nil, err
+		}
+		stats[s.SourceID] = s
+	}
+	return stats, rows.Err()
+}
+
+// PurgeSourceData 刪除某資料來源的出貨紀錄，以及該來源建立且已沒有出貨紀錄的店家
+func PurgeSourceData(db *sql.DB, sourceID string) (shipments, stores int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM shipments WHERE source_id = $1`, sourceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, _ := result.RowsAffected()
+	shipments = int(n)
+
+	result, err = tx.Exec(`
+		DELETE FROM stores s
+		WHERE s.source_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM shipments sh WHERE sh.store_id = s.id)
+	`, sourceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, _ = result.RowsAffected()
+	stores = int(n)
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	log.Printf("[INFO] 已清除資料來源 %s: %d 筆出貨紀錄，%d 個店家", sourceID, shipments, stores)
+	return shipments, stores, nil
+}
+
+// StoreQuery 店家查詢條件，零值表示不限制
+type StoreQuery struct {
+	IDs    []int
+	Query  string // 店名或地址包含的文字
+	Region string
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// QueryStores 依條件查詢店家（依店名排序）
+func QueryStores(db *sql.DB, f StoreQuery) ([]StoreRecord, error) {
+	var ids interface{}
+	if f.IDs != nil {
+		ids = pq.Array(f.IDs)
+	}
+	rows, err := db.Query(`
+		SELECT `+storeColumns+`
+		FROM stores
+		WHERE ($1::int[] IS NULL OR id = ANY($1))
+		  AND ($2 = '' OR store_name ILIKE '%' || $2 || '%' OR formatted_address ILIKE '%' || $2 || '%')
+		  AND ($3 = '' OR region = $3)
+		  AND ($4::boolean IS NULL OR is_active = $4)
+		ORDER BY store_name
+		LIMIT $5 OFFSET $6
+	`, ids, f.Query, f.Region, f.Active, f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []StoreRecord{}
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	return stores, rows.Err()
+}
@@ -0,0 +1,23 @@
+package database
+
+// 店家列表的排序欄位（?sort=）
+const (
+	SortName           = "name"
+	SortLatestShipment = "latestShipment"
+	SortTotalQuantity  = "totalQty"
+	SortDistance       = "distance" // 只用於附近店家
+)
+
+// StoreSort 店家列表的排序方式；Field 為空時使用各查詢的預設排序（地圖依店名、附近店家依距離）
+type StoreSort struct {
+	Field string
+	Desc  bool
+}
+
+// direction SQL 的排序方向
+func (s StoreSort) direction() string {
+	if s.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
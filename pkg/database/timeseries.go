@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 時間序列的區間單位（週以星期一開始）
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// StoreTimeseries 店家依區間加總的出貨量（Products 為 產品 → 每個區間的數量，與 Buckets 對應，沒有出貨的區間為 0）
+type StoreTimeseries struct {
+	StoreID     int                  `json:"storeId"`
+	StoreName   string               `json:"storeName"`
+	Granularity string               `json:"granularity"`
+	Buckets     []string             `json:"buckets"` // 每個區間的第一天
+	Products    map[string][]float64 `json:"products"`
+	// ProductNames 產品 → 顯示名稱（由 API 層依 Accept-Language 填入）
+	ProductNames map[string]string `json:"productNames,omitempty"`
+}
+
+// bucketStart t 所屬區間的第一天
+func bucketStart(t time.Time, granularity string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case BucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// addBuckets 往後（n 為負數時往前）移動 n 個區間
+func addBuckets(t time.Time, granularity string, n int) time.Time {
+	switch granularity {
+	case BucketWeek:
+		return t.AddDate(0, 0, 7*n)
+	case BucketMonth:
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// GetStoreTimeseries 取得店家最近 periods 個區間（含目前區間）的出貨量，只計算可解析為數字的數量、不含數量異常的出貨；
+// product 不為空時只回傳該產品，exclude 中的產品（尚未公開）不列入，店家不存在時回傳 sql.ErrNoRows
+func GetStoreTimeseries(db *sql.DB, storeID int, product, granularity string, periods int, exclude []string) (*StoreTimeseries, error) {
+	last := bucketStart(time.Now(), granularity)
+	first := addBuckets(last, granularity, -(periods - 1))
+
+	ts := &StoreTimeseries{
+		StoreID:     storeID,
+		Granularity: granularity,
+		Products:    make(map[string][]float64),
+	}
+	if err := db.QueryRow(`SELECT store_name FROM stores WHERE id = $1`, storeID).Scan(&ts.StoreName); err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, periods)
+	for i, b := 0, first; i < periods; i, b = i+1, addBuckets(b, granularity, 1) {
+		key := b.Format("2006-01-02")
+		index[key] = i
+		ts.Buckets = append(ts.Buckets, key)
+	}
+
+	rows, err := db.Query(`
+		SELECT sh.product_type, date_trunc($2, sh.shipment_date)::date,
+		       SUM(CASE WHEN sh.quantity ~ '^[0-9]+(\.[0-9]+)?$' THEN sh.quantity::numeric ELSE 0 END)::float8
+		FROM shipments sh
+		WHERE sh.store_id = $1
+		  AND sh.shipment_date >= $3::date
+		  AND sh.quality_flag IS NULL
+		  AND ($4 = '' OR sh.product_type = $4)
+		  AND ($5::text[] IS NULL OR NOT (sh.product_type = ANY($5)))
+		GROUP BY 1, 2
+	`, storeID, granularity, first.Format("2006-01-02"), product, pq.Array(exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p string
+		var bucket time.Time
+		var total float64
+		if err := rows.Scan(&p, &bucket, &total); err != nil {
+			return nil, err
+		}
+		i, ok := index[bucket.Format("2006-01-02")]
+		if !ok {
+			// 未來日期的出貨不在區間內
+			continue
+		}
+		if ts.Products[p] == nil {
+			ts.Products[p] = make([]float64, periods)
+		}
+		ts.Products[p][i] += total
+	}
+	return ts, rows.Err()
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"time"
+)
+
+// timezoneConnector 將查詢結果中的時間欄位統一為 loc 時區：
+// TIMESTAMP（無時區）由 lib/pq 標成 UTC，實際是連線時區（DSN 的 timezone）的牆上時間，改標為 loc；
+// TIMESTAMPTZ 轉成 loc；DATE 維持 UTC 午夜，避免日期在時區轉換後跑到前一天
+type timezoneConnector struct {
+	base driver.Connector
+	loc  *time.Location
+}
+
+func (c *timezoneConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timezoneConn{Conn: conn, loc: c.loc}, nil
+}
+
+func (c *timezoneConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// timezoneConn 轉呼叫 pq 的連線，並包裝 Query 與 Prepare 回傳的結果
+type timezoneConn struct {
+	driver.Conn
+	loc *time.Location
+}
+
+func (c *timezoneConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &timezoneRows{Rows: rows, loc: c.loc}, nil
+}
+
+func (c *timezoneConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *timezoneConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timezoneStmt{Stmt: stmt, loc: c.loc}, nil
+}
+
+func (c *timezoneConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timezoneConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timezoneConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timezoneConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// timezoneStmt 包裝 prepared statement 的查詢結果
+type timezoneStmt struct {
+	driver.Stmt
+	loc *time.Location
+}
+
+func (s *timezoneStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := queryer.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &timezoneRows{Rows: rows, loc: s.loc}, nil
+}
+
+func (s *timezoneStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, args)
+}
+
+// timezoneRows 在 Next 時依欄位型別調整時間值，其餘欄位資訊轉呼叫 pq
+type timezoneRows struct {
+	driver.Rows
+	loc *time.Location
+}
+
+func (r *timezoneRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		t, ok := v.(time.Time)
+		if !ok {
+			continue
+		}
+		switch r.ColumnTypeDatabaseTypeName(i) {
+		case "TIMESTAMP":
+			dest[i] = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), r.loc)
+		case "TIMESTAMPTZ":
+			dest[i] = t.In(r.loc)
+		}
+	}
+	return nil
+}
+
+func (r *timezoneRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typed.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *timezoneRows) ColumnTypeScanType(index int) reflect.Type {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typed.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *timezoneRows) ColumnTypeLength(index int) (int64, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return typed.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *timezoneRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return typed.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func (r *timezoneRows) HasNextResultSet() bool {
+	if multi, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return multi.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *timezoneRows) NextResultSet() error {
+	if multi, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return multi.NextResultSet()
+	}
+	return io.EOF
+}
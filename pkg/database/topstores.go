@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TopStore 出貨量排行榜中的店家
+type TopStore struct {
+	Rank           int     `json:"rank"` // 總量相同時名次相同
+	StoreID        int     `json:"storeId"`
+	StoreName      string  `json:"storeName"`
+	Region         string  `json:"region"`
+	TotalQuantity  float64 `json:"totalQuantity"`
+	ShipmentDays   int     `json:"shipmentDays"` // 有出貨的天數
+	LatestShipment string  `json:"latestShipment"`
+}
+
+// GetTopStores 依近 N 天的出貨總量排列店家（只計算可解析為數字的數量，不含停用的店家與數量異常的出貨），
+// product 不為空時只計算該產品，exclude 中的產品（尚未公開）不列入
+func GetTopStores(db *sql.DB, product string, days, limit int, exclude []string) ([]TopStore, error) {
+	rows, err := db.Query(`
+		WITH totals AS (
+			SELECT s.id, s.store_name, COALESCE(s.region, '') AS region,
+			       SUM(CASE WHEN sh.quantity ~ '^[0-9]+(\.[0-9]+)?$' THEN sh.quantity::numeric ELSE 0 END) AS total,
+			       COUNT(DISTINCT sh.shipment_date) AS shipment_days,
+			       MAX(sh.shipment_date) AS latest
+			FROM shipments sh
+			JOIN stores s ON s.id = sh.store_id
+			WHERE s.is_active
+			  AND sh.shipment_date >= CURRENT_DATE - $1::int
+			  AND sh.quantity IS NOT NULL
+			  AND sh.quantity != ''
+			  AND sh.quantity != '0'
+			  AND sh.quality_flag IS NULL
+			  AND ($2 = '' OR sh.product_type = $2)
+			  AND ($3::text[] IS NULL OR NOT (sh.product_type = ANY($3)))
+			GROUP BY s.id, s.store_name, s.region
+		)
+		SELECT RANK() OVER (ORDER BY total DESC), id, store_name, region, total::float8, shipment_days, latest
+		FROM totals
+		WHERE total > 0
+		ORDER BY total DESC, store_name
+		LIMIT $4
+	`, days, product, pq.Array(exclude), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []TopStore{}
+	for rows.Next() {
+		var s TopStore
+		var latest time.Time
+		if err := rows.Scan(&s.Rank, &s.StoreID, &s.StoreName, &s.Region, &s.TotalQuantity, &s.ShipmentDays, &latest); err != nil {
+			return nil, err
+		}
+		s.LatestShipment = latest.Format("2006-01-02")
+		stores = append(stores, s)
+	}
+	return stores, rows.Err()
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// FlagMissingCoordinates 有近期出貨但缺少座標的店家（地圖上會顯示在 0,0）
+const FlagMissingCoordinates = "missing_coordinates"
+
+// IntegrityIssue 單一類型的資料問題
+type IntegrityIssue struct {
+	Check    string `json:"check"`
+	Count    int    `json:"count"`
+	Repaired int    `json:"repaired"`
+	Detail   string `json:"detail"`
+}
+
+// IntegrityReport 資料完整性檢查結果
+type IntegrityReport struct {
+	Issues []IntegrityIssue `json:"issues"`
+}
+
+// HasIssues 是否有任何問題
+func (r *IntegrityReport) HasIssues() bool {
+	for _, issue := range r.Issues {
+		if issue.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// integrityCheck 一項檢查：count 計算問題筆數，repair 修復並回傳修復筆數
+type integrityCheck struct {
+	name   string
+	detail string
+	count  string
+	repair func(tx *sql.Tx) (int64, error)
+}
+
+var integrityChecks = []integrityCheck{
+	{
+		name:   "orphaned_shipments",
+		detail: "出貨紀錄的 store_id 為空或對應的店家不存在（修復：刪除）",
+		count: `
+			SELECT COUNT(*) FROM shipments sh
+			WHERE sh.store_id IS NULL
+			   OR NOT EXISTS (SELECT 1 FROM stores s WHERE s.id = sh.store_id)
+		`,
+		repair: func(tx *sql.Tx) (int64, error) {
+			return execRows(tx, `
+				DELETE FROM shipments sh
+				WHERE sh.store_id IS NULL
+				   OR NOT EXISTS (SELECT 1 FROM stores s WHERE s.id = sh.store_id)
+			`)
+		},
+	},
+	{
+		name:   "duplicate_shipments",
+		detail: "相同 (store_id, product_type, shipment_date, time_slot) 的重複出貨紀錄（修復：保留最新一筆）",
+		count: `
+			SELECT COALESCE(SUM(cnt - 1), 0) FROM (
+				SELECT COUNT(*) AS cnt FROM shipments
+				GROUP BY store_id, product_type, shipment_date, time_slot
+				HAVING COUNT(*) > 1
+			) d
+		`,
+		repair: func(tx *sql.Tx) (int64, error) {
+			return execRows(tx, `
+				DELETE FROM shipments sh
+				USING shipments newer
+				WHERE sh.store_id = newer.store_id
+				  AND sh.product_type = newer.product_type
+				  AND sh.shipment_date = newer.shipment_date
+				  AND sh.time_slot = newer.time_slot
+				  AND sh.id < newer.id
+			`)
+		},
+	},
+	{
+		name:   "served_without_coordinates",
+		detail: "啟用中且有出貨紀錄，但缺少座標的店家（修復：標記為 missing_coordinates，可於管理端點批次查詢地點）",
+		count: `
+			SELECT COUNT(*) FROM stores s
+			WHERE s.is_active
+			  AND (s.latitude IS NULL OR s.longitude IS NULL)
+			  AND EXISTS (SELECT 1 FROM shipments sh WHERE sh.store_id = s.id)
+		`,
+		repair: func(tx *sql.Tx) (int64, error) {
+			return execRows(tx, `
+				INSERT INTO store_flags (store_id, flag, detail, checked_at)
+				SELECT s.id, $1, '有出貨紀錄但缺少座標', CURRENT_TIMESTAMP
+				FROM stores s
+				WHERE s.is_active
+				  AND (s.latitude IS NULL OR s.longitude IS NULL)
+				  AND EXISTS (SELECT 1 FROM shipments sh WHERE sh.store_id = s.id)
+				ON CONFLICT (store_id, flag) DO UPDATE SET checked_at = EXCLUDED.checked_at
+			`, FlagMissingCoordinates)
+		},
+	},
+}
+
+// VerifyIntegrity 檢查資料完整性，repair 為 true 時同時修復（單一交易）
+func VerifyIntegrity(db *sql.DB, repair bool) (*IntegrityReport, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &IntegrityReport{}
+	for _, check := range integrityChecks {
+		issue := IntegrityIssue{Check: check.name, Detail: check.detail}
+		if err := tx.QueryRow(check.count).Scan(&issue.Count); err != nil {
+			return nil, err
+		}
+
+		if repair && issue.Count > 0 {
+			n, err := check.repair(tx)
+			if err != nil {
+				return nil, err
+			}
+			issue.Repaired = int(n)
+			log.Printf("[INFO] 已修復 %s: %d 筆", check.name, n)
+		}
+
+		report.Issues = append(report.Issues, issue)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func execRows(tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook 第三方訂閱的 webhook（Products / Regions 為空表示不篩選）
+type Webhook struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Products  []string  `json:"products"`
+	Regions   []string  `json:"regions"`
+	IsActive  bool      `json:"isActive"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookDelivery 單次 webhook 投遞紀錄
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	WebhookID      int        `json:"webhookId"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"` // 'pending', 'success', 'failed'
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Payload        string     `json:"payload"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// CreateWebhook 新增 webhook
+func CreateWebhook(db *sql.DB, hook Webhook) (*Webhook, error) {
+	err := db.QueryRow(`
+		INSERT INTO webhooks (name, url, secret, products, regions)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, is_active, created_at
+	`, hook.Name, hook.URL, hook.Secret, pq.Array(hook.Products), pq.Array(hook.Regions)).Scan(&hook.ID, &hook.IsActive, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// ListWebhooks 列出 webhook，activeOnly 為 true 時只列出啟用中的
+func ListWebhooks(db *sql.DB, activeOnly bool) ([]Webhook, error) {
+	rows, err := db.Query(`
+		SELECT id, name, url, secret, products, regions, is_active, created_at
+		FROM webhooks
+		WHERE is_active OR NOT $1
+		ORDER BY id
+	`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var h Webhook
+		if err := rows.Scan(&h.ID, &h.Name, &h.URL, &h.Secret, pq.Array(&h.Products), pq.Array(&h.Regions), &h.IsActive, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook 刪除 webhook（投遞紀錄一併刪除），不存在時回傳 sql.ErrNoRows
+func DeleteWebhook(db *sql.DB, id int) error {
+	result, err := db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateWebhookDelivery 建立一筆待投遞紀錄
+func CreateWebhookDelivery(db *sql.DB, webhookID int, event, payload string) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO webhook_deliveries (webhook_id, event, status, payload)
+		VALUES ($1, $2, 'pending', $3)
+		RETURNING id
+	`, webhookID, event, payload).Scan(&id)
+	return id, err
+}
+
+// UpdateWebhookDelivery 更新投遞結果
+func UpdateWebhookDelivery(db *sql.DB, id int, status string, attempts, responseStatus int, errMsg string) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $1,
+			attempts = $2,
+			response_status = NULLIF($3, 0),
+			error = NULLIF($4, ''),
+			delivered_at = CASE WHEN $1 = 'success' THEN CURRENT_TIMESTAMP ELSE delivered_at END
+		WHERE id = $5
+	`, status, attempts, responseStatus, errMsg, id)
+	return err
+}
+
+// GetWebhookDeliveries 取得 webhook 最近的投遞紀錄
+func GetWebhookDeliveries(db *sql.DB, webhookID, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.Query(`
+		SELECT id, webhook_id, event, status, attempts, COALESCE(response_status, 0),
+		       COALESCE(error, ''), payload, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.Error, &d.Payload, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,108 @@
+// Package demo 示範模式使用的假資料：台南市十多家虛構店家與近兩週的秋葵、絲瓜出貨，
+// 讓還沒有工作表與 Google 金鑰的產銷班也能在本機看到完整的地圖與 API
+package demo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+// SourceID 示範資料的資料來源代號（meta.sources 中顯示）
+const SourceID = "demo"
+
+// Days 產生近幾天的出貨
+const Days = 14
+
+// 示範出貨的產品（資料表版本 25 建立的預設產品）
+const (
+	productOkra  = "秋葵"
+	productGourd = "產銷絲瓜"
+)
+
+// 虛構店家（座標在台南市區附近，名稱與地址皆非真實店家）
+var stores = []struct {
+	name, region string
+	lat, lng     float64
+}{
+	{"示範 安平店", "台南市安平區", 22.9997, 120.1668},
+	{"示範 安南店", "台南市安南區", 23.0468, 120.1853},
+	{"示範 北區店", "台南市北區", 23.0098, 120.2105},
+	{"示範 中西區店", "台南市中西區", 22.9925, 120.1985},
+	{"示範 東區店", "台南市東區", 22.9841, 120.2246},
+	{"示範 南區店", "台南市南區", 22.9606, 120.1907},
+	{"示範 永康店", "台南市永康區", 23.0262, 120.2536},
+	{"示範 仁德店", "台南市仁德區", 22.9721, 120.2512},
+	{"示範 歸仁店", "台南市歸仁區", 22.9670, 120.2935},
+	{"示範 新市店", "台南市新市區", 23.0789, 120.2951},
+	{"示範 善化店", "台南市善化區", 23.1325, 120.2967},
+	{"示範 佳里店", "台南市佳里區", 23.1650, 120.1771},
+}
+
+// Stores 產生示範店家與 today 往前 Days 天的出貨（固定亂數種子，每次產生相同的資料）
+func Stores(today time.Time) []database.StoreInfo {
+	rng := rand.New(rand.NewSource(1))
+	result := make([]database.StoreInfo, 0, len(stores))
+	for i, s := range stores {
+		store := database.StoreInfo{
+			StoreName:        s.name,
+			PlaceID:          fmt.Sprintf("demo-%02d", i+1),
+			FormattedAddress: s.region,
+			Latitude:         s.lat,
+			Longitude:        s.lng,
+			BusinessStatus:   "OPERATIONAL",
+			SourceID:         SourceID,
+			Region:           s.region,
+			Shipments:        map[string][]database.ShipmentInfo{},
+		}
+		for d := Days - 1; d >= 0; d-- {
+			date := today.AddDate(0, 0, -d).Format("2006/01/02")
+			// 約六成的日子有秋葵、三成有絲瓜，部分店家一天出貨兩次
+			if rng.Intn(10) < 6 {
+				if rng.Intn(5) == 0 {
+					store.Shipments[productOkra] = append(store.Shipments[productOkra],
+						database.ShipmentInfo{Date: date, TimeSlot: "am", Qty: fmt.Sprint(1 + rng.Intn(4)), SourceID: SourceID},
+						database.ShipmentInfo{Date: date, TimeSlot: "pm", Qty: fmt.Sprint(1 + rng.Intn(3)), SourceID: SourceID})
+				} else {
+					store.Shipments[productOkra] = append(store.Shipments[productOkra],
+						database.ShipmentInfo{Date: date, Qty: fmt.Sprint(1 + rng.Intn(6)), SourceID: SourceID})
+				}
+			}
+			if rng.Intn(10) < 3 {
+				store.Shipments[productGourd] = append(store.Shipments[productGourd],
+					database.ShipmentInfo{Date: date, Qty: fmt.Sprint(1 + rng.Intn(4)), SourceID: SourceID})
+			}
+		}
+		result = append(result, store)
+	}
+	return result
+}
+
+// Seed 資料庫沒有任何店家時寫入示範資料並回傳 true；已有店家時不做任何修改
+// （避免把假資料混進正式資料庫），回傳 false
+func Seed(db *sql.DB) (bool, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM stores`).Scan(&count); err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	result, err := database.SaveStores(db, Stores(time.Now()))
+	if err != nil {
+		return false, fmt.Errorf("寫入示範資料失敗: %v", err)
+	}
+	if err := database.RecordSourceSync(db, SourceID, "示範資料", "success", result.Shipments.String(), nil); err != nil {
+		log.Printf("[WARN] 無法記錄示範資料來源: %v", err)
+	}
+	// 與成功同步相同，資料版本加一
+	if _, err := database.NextDataGeneration(db); err != nil {
+		log.Printf("[WARN] 無法更新資料版本: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,226 @@
+package demo
+
+import (
+	"cmp"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+// Memory 示範資料的記憶體版本，不需要資料庫即可提供店家地圖端點（server.MapStore）；
+// 查詢結果的欄位、合併時段與排序方式與 database.GetRecentShipments 相同
+type Memory struct {
+	stores    []database.StoreInfo
+	today     time.Time
+	createdAt time.Time
+}
+
+// NewMemory 產生 now 當天往前 Days 天的示範資料
+func NewMemory(now time.Time) *Memory {
+	return &Memory{
+		stores:    Stores(now),
+		today:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		createdAt: now.Truncate(time.Second),
+	}
+}
+
+// memoryShipment 一筆出貨（合併時段時為一天的加總）
+type memoryShipment struct {
+	storeID  int
+	store    *database.StoreInfo
+	product  string
+	date     time.Time
+	slot     string
+	quantity string
+	total    float64 // 原始各筆數量開頭數字的總和
+}
+
+// leadingNumber 與 SQL 的 normalizedQuantity 相同，取數量開頭的數字（"3箱" → 3），沒有數字時為 0
+var leadingNumber = regexp.MustCompile(`^\s*([0-9]+(\.[0-9]+)?)`)
+
+func normalizedQuantity(q string) float64 {
+	m := leadingNumber.FindStringSubmatch(q)
+	if m == nil {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(m[1], 64)
+	return v
+}
+
+// numericOnly 與 SQL 的 dailyQuantity 相同，只加總整個數量都是數字的出貨
+var numericOnly = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+func numericQuantity(q string) float64 {
+	if !numericOnly.MatchString(q) {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(q, 64)
+	return v
+}
+
+// LastModified 資料產生的時間，至少為今天 0 點（與 GetDataLastModified 相同）
+func (m *Memory) LastModified() (time.Time, error) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if midnight.After(m.createdAt) {
+		return midnight, nil
+	}
+	return m.createdAt, nil
+}
+
+// RecentShipments 近 days 天的出貨
+func (m *Memory) RecentShipments(days int, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error) {
+	return m.query(m.today.AddDate(0, 0, -days), m.today, bbox, bySlot, sort), nil
+}
+
+// ShipmentsBetween 指定日期區間（含頭尾）的出貨
+func (m *Memory) ShipmentsBetween(from, to time.Time, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error) {
+	return m.query(from, to, bbox, bySlot, sort), nil
+}
+
+// SourceFreshness 示範資料來源（產生時即為同步成功）
+func (m *Memory) SourceFreshness() ([]database.SourceFreshness, error) {
+	return []database.SourceFreshness{{
+		SourceID:      SourceID,
+		Name:          "示範資料",
+		LastSyncAt:    m.createdAt,
+		LastSuccessAt: &m.createdAt,
+		Status:        "success",
+	}}, nil
+}
+
+// Products 示範出貨的產品與資料表版本 25 的預設顯示名稱
+func (m *Memory) Products() (map[string]database.Product, error) {
+	return map[string]database.Product{
+		productOkra:  {ProductType: productOkra, NameZhTW: productOkra, NameEn: "Okra"},
+		productGourd: {ProductType: productGourd, NameZhTW: productGourd, NameEn: "Sponge gourd"},
+	}, nil
+}
+
+// Sparklines 近 database.SparklineDays 天每個店家、產品的每日出貨量（與 GetSparklines 相同）
+func (m *Memory) Sparklines() (map[string]map[string][]float64, error) {
+	first := m.today.AddDate(0, 0, -(database.SparklineDays - 1))
+	result := make(map[string]map[string][]float64)
+	for i := range m.stores {
+		store := &m.stores[i]
+		for product, list := range store.Shipments {
+			for _, s := range list {
+				date, err := time.Parse("2006/01/02", s.Date)
+				if err != nil || date.Before(first) || date.After(m.today) {
+					continue
+				}
+				if result[store.StoreName] == nil {
+					result[store.StoreName] = make(map[string][]float64)
+				}
+				values := result[store.StoreName][product]
+				if values == nil {
+					values = make([]float64, database.SparklineDays)
+					result[store.StoreName][product] = values
+				}
+				values[int(date.Sub(first).Hours()/24)] += numericQuantity(s.Qty)
+			}
+		}
+	}
+	return result, nil
+}
+
+// query 回傳與 queryShipments 相同欄位的出貨列：同一店家相鄰，店家依 sort 排序，店家內依產品、日期新到舊、時段排序
+func (m *Memory) query(from, to time.Time, bbox *database.BBox, bySlot bool, storeSort database.StoreSort) []map[string]interface{} {
+	var shipments []memoryShipment
+	for i := range m.stores {
+		store := &m.stores[i]
+		if bbox != nil && (store.Longitude < bbox.MinLng || store.Longitude > bbox.MaxLng ||
+			store.Latitude < bbox.MinLat || store.Latitude > bbox.MaxLat) {
+			continue
+		}
+		for product, list := range store.Shipments {
+			daily := map[string]*memoryShipment{}
+			for _, s := range list {
+				date, err := time.Parse("2006/01/02", s.Date)
+				if err != nil || date.Before(from) || date.After(to) || s.Qty == "" || s.Qty == "0" {
+					continue
+				}
+				if bySlot {
+					shipments = append(shipments, memoryShipment{i + 1, store, product, date, s.TimeSlot, s.Qty, normalizedQuantity(s.Qty)})
+					continue
+				}
+				// 同一天多個時段時加總數量（只有一筆時保留原始數量字串）
+				if d, ok := daily[s.Date]; ok {
+					d.quantity = strconv.FormatFloat(numericQuantity(d.quantity)+numericQuantity(s.Qty), 'f', -1, 64)
+					d.total += normalizedQuantity(s.Qty)
+					continue
+				}
+				daily[s.Date] = &memoryShipment{i + 1, store, product, date, "", s.Qty, normalizedQuantity(s.Qty)}
+			}
+			for _, d := range daily {
+				shipments = append(shipments, *d)
+			}
+		}
+	}
+
+	// 每個店家、產品的數量總和與店家的排序值
+	productTotals := map[int]map[string]float64{}
+	latest := map[int]time.Time{}
+	storeTotals := map[int]float64{}
+	for _, s := range shipments {
+		if productTotals[s.storeID] == nil {
+			productTotals[s.storeID] = map[string]float64{}
+		}
+		productTotals[s.storeID][s.product] += s.total
+		storeTotals[s.storeID] += s.total
+		if s.date.After(latest[s.storeID]) {
+			latest[s.storeID] = s.date
+		}
+	}
+
+	sort.Slice(shipments, func(i, j int) bool {
+		a, b := shipments[i], shipments[j]
+		if a.storeID != b.storeID {
+			var order int
+			switch storeSort.Field {
+			case database.SortLatestShipment:
+				order = latest[a.storeID].Compare(latest[b.storeID])
+			case database.SortTotalQuantity:
+				order = cmp.Compare(storeTotals[a.storeID], storeTotals[b.storeID])
+			default:
+				order = strings.Compare(a.store.StoreName, b.store.StoreName)
+			}
+			if storeSort.Desc {
+				order = -order
+			}
+			if order == 0 {
+				// 排序值相同時依店名
+				order = strings.Compare(a.store.StoreName, b.store.StoreName)
+			}
+			return order < 0
+		}
+		if a.product != b.product {
+			return a.product < b.product
+		}
+		if !a.date.Equal(b.date) {
+			return a.date.After(b.date)
+		}
+		return a.slot < b.slot
+	})
+
+	results := []map[string]interface{}{}
+	for _, s := range shipments {
+		results = append(results, map[string]interface{}{
+			"store_id":      s.storeID,
+			"store_name":    s.store.StoreName,
+			"address":       s.store.FormattedAddress,
+			"latitude":      s.store.Latitude,
+			"longitude":     s.store.Longitude,
+			"product_type":  s.product,
+			"shipment_date": s.date.Format("2006-01-02"),
+			"time_slot":     s.slot,
+			"quantity":      s.quantity,
+			"product_total": productTotals[s.storeID][s.product],
+		})
+	}
+	return results
+}
@@ -0,0 +1,122 @@
+package demo
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+var testNow = time.Date(2025, 6, 14, 9, 0, 0, 0, time.Local)
+
+// storeOrder 查詢結果中店家出現的順序（同一店家的出貨必須相鄰）
+func storeOrder(t *testing.T, rows []map[string]interface{}) []string {
+	t.Helper()
+	var order []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		name := row["store_name"].(string)
+		if len(order) > 0 && order[len(order)-1] == name {
+			continue
+		}
+		if seen[name] {
+			t.Fatalf("shipments of %s are not adjacent", name)
+		}
+		seen[name] = true
+		order = append(order, name)
+	}
+	return order
+}
+
+func TestMemoryRecentShipments(t *testing.T) {
+	m := NewMemory(testNow)
+	rows, err := m.RecentShipments(Days, nil, false, database.StoreSort{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(storeOrder(t, rows)); got != len(stores) {
+		t.Errorf("stores = %d, want %d", got, len(stores))
+	}
+
+	// 合併時段時每個店家、產品、日期只有一列，數量為各時段的和
+	slots, _ := m.RecentShipments(Days, nil, true, database.StoreSort{})
+	sums := map[string]float64{}
+	for _, row := range slots {
+		q, _ := strconv.ParseFloat(row["quantity"].(string), 64)
+		sums[row["store_name"].(string)+row["product_type"].(string)+row["shipment_date"].(string)] += q
+	}
+	if len(slots) <= len(rows) {
+		t.Errorf("granularity=slot returned %d rows, want more than the %d merged rows", len(slots), len(rows))
+	}
+	for _, row := range rows {
+		key := row["store_name"].(string) + row["product_type"].(string) + row["shipment_date"].(string)
+		q, _ := strconv.ParseFloat(row["quantity"].(string), 64)
+		if q != sums[key] {
+			t.Errorf("%s quantity = %v, want the slot sum %v", key, q, sums[key])
+		}
+		if row["time_slot"] != "" {
+			t.Errorf("%s time_slot = %q, want empty when merged", key, row["time_slot"])
+		}
+	}
+
+	// days=2 與 CURRENT_DATE - INTERVAL '2 days' 相同，包含 2 天前到今天
+	recent, _ := m.RecentShipments(2, nil, false, database.StoreSort{})
+	for _, row := range recent {
+		if date := row["shipment_date"].(string); date < "2025-06-12" {
+			t.Errorf("days=2 returned %s", date)
+		}
+	}
+}
+
+func TestMemoryFilterAndSort(t *testing.T) {
+	m := NewMemory(testNow)
+
+	// 只包含安平店（120.1668, 22.9997）
+	bbox := &database.BBox{MinLng: 120.16, MinLat: 22.99, MaxLng: 120.17, MaxLat: 23.0}
+	rows, _ := m.RecentShipments(Days, bbox, false, database.StoreSort{})
+	if got := storeOrder(t, rows); len(got) != 1 || got[0] != "示範 安平店" {
+		t.Errorf("bbox stores = %v, want [示範 安平店]", got)
+	}
+
+	rows, _ = m.RecentShipments(Days, nil, false, database.StoreSort{Field: database.SortTotalQuantity, Desc: true})
+	totals := map[string]float64{}
+	for _, row := range rows {
+		totals[row["store_name"].(string)+row["product_type"].(string)] = row["product_total"].(float64)
+	}
+	storeTotal := func(name string) float64 {
+		return totals[name+productOkra] + totals[name+productGourd]
+	}
+	order := storeOrder(t, rows)
+	for i := 1; i < len(order); i++ {
+		if storeTotal(order[i-1]) < storeTotal(order[i]) {
+			t.Errorf("sort=totalQty&order=desc: %s (%v) before %s (%v)", order[i-1], storeTotal(order[i-1]), order[i], storeTotal(order[i]))
+		}
+	}
+
+	from := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)
+	rows, _ = m.ShipmentsBetween(from, to, nil, false, database.StoreSort{})
+	for _, row := range rows {
+		if date := row["shipment_date"].(string); date != "2025-06-10" && date != "2025-06-11" {
+			t.Errorf("from=2025-06-10&to=2025-06-11 returned %s", date)
+		}
+	}
+}
+
+func TestMemorySparklines(t *testing.T) {
+	m := NewMemory(testNow)
+	sparklines, _ := m.Sparklines()
+	rows, _ := m.RecentShipments(Days, nil, false, database.StoreSort{})
+	for _, row := range rows {
+		values := sparklines[row["store_name"].(string)][row["product_type"].(string)]
+		if len(values) != database.SparklineDays {
+			t.Fatalf("sparkline for %s has %d values, want %d", row["store_name"], len(values), database.SparklineDays)
+		}
+		date, _ := time.Parse("2006-01-02", row["shipment_date"].(string))
+		q, _ := strconv.ParseFloat(row["quantity"].(string), 64)
+		if got := values[database.SparklineDays-1-int(m.today.Sub(date).Hours()/24)]; got != q {
+			t.Errorf("sparkline %s %s = %v, want %v", row["store_name"], row["shipment_date"], got, q)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package fault 依 FAULT_INJECT 在非正式環境注入錯誤與延遲，用來在產季前確認重試、部分失敗與降級流程確實有效：
+//
+//	FAULT_INJECT=places_error_rate=0.2,db_latency_ms=200
+//
+// GO_ENV=production 時一律忽略
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/metrics"
+)
+
+// ErrInjected 所有注入的錯誤都包裝此錯誤，可用 errors.Is 判斷
+var ErrInjected = errors.New("fault injected")
+
+// Settings 注入的故障；比率為 0 ~ 1 的機率
+type Settings struct {
+	PlacesErrorRate  float64       // Places API 查詢失敗
+	SheetsErrorRate  float64       // 工作表下載失敗（可驗證快照備援）
+	WebhookErrorRate float64       // webhook 投遞失敗（可驗證重試）
+	DBErrorRate      float64       // 資料庫查詢 / 執行失敗
+	DBLatency        time.Duration // 每個資料庫查詢 / 執行前的延遲
+}
+
+// Enabled 是否有任何故障會被注入
+func (s Settings) Enabled() bool {
+	return s != Settings{}
+}
+
+// DBEnabled 是否需要包裝資料庫連線
+func (s Settings) DBEnabled() bool {
+	return s.DBErrorRate > 0 || s.DBLatency > 0
+}
+
+func (s Settings) String() string {
+	var parts []string
+	add := func(key string, rate float64) {
+		if rate > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%g", key, rate))
+		}
+	}
+	add("places_error_rate", s.PlacesErrorRate)
+	add("sheets_error_rate", s.SheetsErrorRate)
+	add("webhook_error_rate", s.WebhookErrorRate)
+	add("db_error_rate", s.DBErrorRate)
+	if s.DBLatency > 0 {
+		parts = append(parts, fmt.Sprintf("db_latency_ms=%d", s.DBLatency.Milliseconds()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse 解析 key=value 以逗號分隔的設定
+func Parse(spec string) (Settings, error) {
+	var s Settings
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Settings{}, fmt.Errorf("%q 需為 key=value", item)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if key == "db_latency_ms" {
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms < 0 {
+				return Settings{}, fmt.Errorf("db_latency_ms 需為非負整數: %q", value)
+			}
+			s.DBLatency = time.Duration(ms) * time.Millisecond
+			continue
+		}
+
+		var rate *float64
+		switch key {
+		case "places_error_rate":
+			rate = &s.PlacesErrorRate
+		case "sheets_error_rate":
+			rate = &s.SheetsErrorRate
+		case "webhook_error_rate":
+			rate = &s.WebhookErrorRate
+		case "db_error_rate":
+			rate = &s.DBErrorRate
+		default:
+			return Settings{}, fmt.Errorf("不支援的故障類型: %s", key)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 || v > 1 {
+			return Settings{}, fmt.Errorf("%s 需為 0 ~ 1 之間的數字: %q", key, value)
+		}
+		*rate = v
+	}
+	return s, nil
+}
+
+var (
+	currentMu sync.RWMutex
+	current   Settings
+)
+
+// Configure 套用 FAULT_INJECT 的設定（需在連接資料庫之前呼叫）；正式環境（production）或格式錯誤時不注入故障
+func Configure(spec string, production bool) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = Settings{}
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return
+	}
+	if production {
+		log.Println("[WARN] 正式環境不支援 FAULT_INJECT，已忽略")
+		return
+	}
+	s, err := Parse(spec)
+	if err != nil {
+		log.Printf("[WARN] FAULT_INJECT 設定錯誤，不注入故障: %v", err)
+		return
+	}
+	current = s
+	if s.Enabled() {
+		log.Printf("[WARN] 故障注入已啟用: %s", s)
+	}
+}
+
+// Current 目前的設定（Configure 之前為不注入任何故障）
+func Current() Settings {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// inject 依機率 rate 回傳注入的錯誤
+func inject(kind string, rate float64) error {
+	if rate <= 0 || rand.Float64() >= rate {
+		return nil
+	}
+	metrics.AddCounter("pxmark_faults_injected_total", "Faults injected by FAULT_INJECT", metrics.Labels{"kind": kind}, 1)
+	return fmt.Errorf("%w: %s", ErrInjected, kind)
+}
+
+// PlacesError Places API 查詢前呼叫，命中時回傳錯誤
+func PlacesError() error {
+	return inject("places", Current().PlacesErrorRate)
+}
+
+// SheetsError 下載工作表前呼叫，命中時回傳錯誤
+func SheetsError() error {
+	return inject("sheets", Current().SheetsErrorRate)
+}
+
+// WebhookError 投遞 webhook 前呼叫，命中時回傳錯誤
+func WebhookError() error {
+	return inject("webhook", Current().WebhookErrorRate)
+}
+
+// DB 資料庫查詢 / 執行前呼叫：等待 db_latency_ms（ctx 取消時提前結束），再依 db_error_rate 回傳錯誤
+func DB(ctx context.Context) error {
+	s := Current()
+	if s.DBLatency > 0 {
+		timer := time.NewTimer(s.DBLatency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return inject("db", s.DBErrorRate)
+}
@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Polygon 多邊形：第一個環為外框，其餘為內部的洞；座標為 [經度, 緯度]
+type Polygon [][][2]float64
+
+// Area 配送區域（可由多個多邊形組成）
+type Area []Polygon
+
+// geometry GeoJSON 幾何物件（也接受 Feature 包裝）
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geometry       `json:"geometry"`
+}
+
+// ParseGeoJSON 解析 GeoJSON Polygon / MultiPolygon（或包含它們的 Feature）
+func ParseGeoJSON(data []byte) (Area, error) {
+	var g geometry
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %v", err)
+	}
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+		g = *g.Geometry
+	}
+
+	var area Area
+	switch g.Type {
+	case "Polygon":
+		var p Polygon
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %v", err)
+		}
+		area = Area{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &area); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type: %q", g.Type)
+	}
+
+	for _, p := range area {
+		if len(p) == 0 {
+			return nil, fmt.Errorf("polygon has no rings")
+		}
+		for _, ring := range p {
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("polygon ring must have at least 4 positions")
+			}
+		}
+	}
+	return area, nil
+}
+
+// Contains 點（經度、緯度）是否落在區域內
+func (a Area) Contains(lng, lat float64) bool {
+	for _, p := range a {
+		if p.Contains(lng, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 點是否在外框內且不在任何洞內
+func (p Polygon) Contains(lng, lat float64) bool {
+	if !ringContains(p[0], lng, lat) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, lng, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains 射線法判斷點是否在環內
+func ringContains(ring [][2]float64, lng, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
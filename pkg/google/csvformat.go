@@ -0,0 +1,150 @@
+package google
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 匯出的 CSV 偶爾不是標準的 UTF-8 逗號分隔（例如另存成 Big5、Excel「Unicode 文字」存成 UTF-16、
+// Excel 依地區設定改用分號），
+// 解析前先統一成 UTF-8 並判斷分隔符號
+
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingBig5    = "big5"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// delimiterCandidates 依優先順序排列，平手時使用較前面的
+var delimiterCandidates = []rune{',', ';', '\t'}
+
+// sniffLines 判斷分隔符號時讀取的列數
+const sniffLines = 10
+
+// csvFormat 解析前偵測到的格式
+type csvFormat struct {
+	Encoding  string
+	BOM       bool
+	Delimiter rune
+}
+
+// normalizeCSV 去掉 UTF-8 BOM；有 UTF-16 BOM 時依 BOM 轉換，
+// 內容不是有效的 UTF-8 時視為 Big5 轉換，轉換後仍無效則維持原樣
+func normalizeCSV(body []byte) ([]byte, csvFormat) {
+	format := csvFormat{Encoding: EncodingUTF8, Delimiter: ','}
+	for _, bom := range []struct {
+		prefix   []byte
+		name     string
+		encoding encoding.Encoding
+	}{
+		{utf16LEBOM, EncodingUTF16LE, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)},
+		{utf16BEBOM, EncodingUTF16BE, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+	} {
+		if !bytes.HasPrefix(body, bom.prefix) {
+			continue
+		}
+		if decoded, err := bom.encoding.NewDecoder().Bytes(body); err == nil {
+			body = decoded
+			format.Encoding = bom.name
+			format.BOM = true
+		}
+		break
+	}
+	if bytes.HasPrefix(body, utf8BOM) {
+		body = body[len(utf8BOM):]
+		format.BOM = true
+	}
+	if !utf8.Valid(body) {
+		// 解碼器把無法對應的位元組換成 U+FFFD 而不回傳錯誤，出現 U+FFFD 即表示不是 Big5
+		if decoded, err := traditionalchinese.Big5.NewDecoder().Bytes(body); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+			body = decoded
+			format.Encoding = EncodingBig5
+		}
+	}
+	format.Delimiter = sniffDelimiter(body)
+	return body, format
+}
+
+// sniffDelimiter 以前幾列（引號內的字元不算）判斷分隔符號：
+// 選擇在表頭出現、且各列出現次數最一致的候選字元，都沒有出現時使用逗號。
+// 只出現在資料列中的分號（例如備註欄）不會改變判斷
+func sniffDelimiter(body []byte) rune {
+	lines := splitCSVLines(body, sniffLines)
+	if len(lines) == 0 {
+		return ','
+	}
+
+	best, bestScore := ',', 0
+	for _, d := range delimiterCandidates {
+		header := countUnquoted(lines[0], d)
+		if header == 0 {
+			continue
+		}
+		// 分數：與表頭相同欄位數的列數，次要比較表頭的欄位數
+		score := 0
+		for _, line := range lines {
+			if countUnquoted(line, d) == header {
+				score++
+			}
+		}
+		score = score*1000 + header
+		if score > bestScore {
+			best, bestScore = d, score
+		}
+	}
+	return best
+}
+
+// splitCSVLines 取出前 n 個非空白的列（引號內的換行不分列）
+func splitCSVLines(body []byte, n int) []string {
+	var lines []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(body) && len(lines) < n; i++ {
+		switch body[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '\n':
+			if inQuotes {
+				continue
+			}
+			if line := strings.TrimRight(string(body[start:i]), "\r"); strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			start = i + 1
+		}
+	}
+	if len(lines) < n && start < len(body) {
+		if line := strings.TrimRight(string(body[start:]), "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// countUnquoted 計算 d 在引號外出現的次數
+func countUnquoted(line string, d rune) int {
+	count := 0
+	inQuotes := false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == d && !inQuotes:
+			count++
+		}
+	}
+	return count
+}
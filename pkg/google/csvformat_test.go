@@ -0,0 +1,104 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const sampleCSV = "店名,區域,6/1,6/2\r\n安南店,台南市安南區,3,5\r\n永康店,台南市永康區,,2\r\n"
+
+var sampleRecords = [][]string{
+	{"店名", "區域", "6/1", "6/2"},
+	{"安南店", "台南市安南區", "3", "5"},
+	{"永康店", "台南市永康區", "", "2"},
+}
+
+func encode(t *testing.T, e encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := e.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b
+}
+
+func TestParseCSVEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		bom      bool
+	}{
+		{"utf-8", []byte(sampleCSV), EncodingUTF8, false},
+		{"utf-8 with BOM", append(append([]byte{}, utf8BOM...), sampleCSV...), EncodingUTF8, true},
+		{"big5", encode(t, traditionalchinese.Big5, sampleCSV), EncodingBig5, false},
+		{"utf-16le with BOM", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), sampleCSV), EncodingUTF16LE, true},
+		{"utf-16be with BOM", encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), sampleCSV), EncodingUTF16BE, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, format, err := parseCSV(tt.body)
+			if err != nil {
+				t.Fatalf("parseCSV: %v", err)
+			}
+			if format.Encoding != tt.encoding || format.BOM != tt.bom || format.Delimiter != ',' {
+				t.Errorf("format = %+v, want encoding %s, BOM %v, delimiter ','", format, tt.encoding, tt.bom)
+			}
+			// BOM 不能留在第一個表頭儲存格
+			if !reflect.DeepEqual(records, sampleRecords) {
+				t.Errorf("records = %q, want %q", records, sampleRecords)
+			}
+		})
+	}
+}
+
+func TestParseCSVInvalidBytesKeptAsIs(t *testing.T) {
+	// 不是 UTF-8 也不是 Big5 的內容維持原樣，不會被轉成錯誤的文字
+	body := []byte{'a', ',', 0xFF, 0xFF, '\n'}
+	got, format := normalizeCSV(body)
+	if format.Encoding != EncodingUTF8 || string(got) != string(body) {
+		t.Errorf("normalizeCSV = %q, %+v, want the body unchanged", got, format)
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want rune
+	}{
+		{"comma", "店名,6/1,6/2\n安南店,3,5\n", ','},
+		{"semicolon", "店名;6/1;6/2\n安南店;3;5\n永康店;;2\n", ';'},
+		{"tab", "店名\t6/1\t6/2\n安南店\t3\t5\n", '\t'},
+		{"single column defaults to comma", "店名\n安南店\n", ','},
+		{"empty body", "", ','},
+		{"semicolons only in data rows", "店名,備註,6/1\n安南店,早上;下午,3\n永康店,,2\n", ','},
+		{"quoted commas in a semicolon file", "店名;備註;6/1\n安南店;\"早上,下午\";3\n永康店;\"a,b,c\";2\n", ';'},
+		{"quoted newlines are not rows", "店名;6/1;6/2\n\"安南\n店\";3;5\n", ';'},
+		{"most consistent candidate wins", "a,b;c;d\n1,2;3;4\n5;6;7;8\n9;10;11;12\n", ';'},
+		{"blank lines are skipped", "\n\n店名\t6/1\n\n安南店\t3\n", '\t'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffDelimiter([]byte(tt.body)); got != tt.want {
+				t.Errorf("sniffDelimiter(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSheetCSVSemicolonBig5(t *testing.T) {
+	body := encode(t, traditionalchinese.Big5, "店名; 6/1 ;6/2\r\n安南店; 3 ;5\r\n")
+	records, err := ParseSheetCSV(body)
+	if err != nil {
+		t.Fatalf("ParseSheetCSV: %v", err)
+	}
+	want := [][]string{{"店名", "6/1", "6/2"}, {"安南店", "3", "5"}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
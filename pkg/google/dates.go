@@ -0,0 +1,187 @@
+package google
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 日期表頭格式
+var (
+	fullDatePattern  = regexp.MustCompile(`^(\d{4})[/\-.](\d{1,2})[/\-.](\d{1,2})$`)
+	monthDayPattern  = regexp.MustCompile(`^(\d{1,2})[/\-.](\d{1,2})$`)
+	chineseMDPattern = regexp.MustCompile(`^(\d{1,2})月(\d{1,2})日?$`)
+	// rocDatePattern 民國年，例如 "114/6/1"、"114.06.01"、"114年6月1日"
+	rocDatePattern = regexp.MustCompile(`^(\d{2,3})(?:[/\-.](\d{1,2})[/\-.]|年(\d{1,2})月)(\d{1,2})日?$`)
+
+	// timeSlotPattern 日期後的時段，例如 "6/1上午"、"6/1 PM"、"6/1(下午)"
+	timeSlotPattern = regexp.MustCompile(`(?i)^(.+?)\s*[(（]?(上午|早上|下午|晚上|am|pm)[)）]?$`)
+)
+
+// 一天內的出貨時段（同一天有多次配送的店家）；沒有時段的出貨為空字串
+const (
+	TimeSlotAM = "am"
+	TimeSlotPM = "pm"
+)
+
+var timeSlotNames = map[string]string{
+	"上午": TimeSlotAM,
+	"早上": TimeSlotAM,
+	"am": TimeSlotAM,
+	"下午": TimeSlotPM,
+	"晚上": TimeSlotPM,
+	"pm": TimeSlotPM,
+}
+
+// rocYearOffset 民國年 + 1911 = 西元年
+const rocYearOffset = 1911
+
+// futureTolerance 推算年份時允許日期超過今天的範圍（表頭可能預先排好下週的日期）
+const futureTolerance = 31 * 24 * time.Hour
+
+// headerDate 解析後的表頭日期
+type headerDate struct {
+	year, month, day int
+	hasYear          bool
+	ok               bool
+	slot             string
+}
+
+// parseHeaderDate 解析單一表頭儲存格（可帶時段，例如 "6/1上午"）
+func parseHeaderDate(cell string) headerDate {
+	if m := timeSlotPattern.FindStringSubmatch(cell); m != nil {
+		d := parseHeaderDay(m[1])
+		d.slot = timeSlotNames[strings.ToLower(m[2])]
+		return d
+	}
+	return parseHeaderDay(cell)
+}
+
+// parseHeaderDay 解析不含時段的日期
+func parseHeaderDay(cell string) headerDate {
+	if m := fullDatePattern.FindStringSubmatch(cell); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		return headerDate{year: y, month: mo, day: d, hasYear: true, ok: validMonthDay(mo, d)}
+	}
+	if m := rocDatePattern.FindStringSubmatch(cell); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2] + m[3]) // 兩種格式只會有一個有值
+		d, _ := strconv.Atoi(m[4])
+		return headerDate{year: y + rocYearOffset, month: mo, day: d, hasYear: true, ok: validMonthDay(mo, d)}
+	}
+
+	m := monthDayPattern.FindStringSubmatch(cell)
+	if m == nil {
+		m = chineseMDPattern.FindStringSubmatch(cell)
+	}
+	if m != nil {
+		mo, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		return headerDate{month: mo, day: d, ok: validMonthDay(mo, d)}
+	}
+
+	return headerDate{}
+}
+
+func validMonthDay(month, day int) bool {
+	return month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+// NormalizeHeaderDates 將表頭日期統一為 2006-01-02 格式，並推算沒有年份的日期（例如 "1/2"）；
+// 帶時段的表頭輸出為 "2006-01-02 am" / "2006-01-02 pm"，可用 SplitHeaderSlot 拆開
+//
+// 推算規則：
+//  1. 有年份（西元或民國年）的儲存格直接使用，並作為後續欄位的基準年
+//  2. 月份比前一欄小（例如 12 月 → 1 月）視為跨年，年份 +1
+//  3. 基準年優先使用 seasonYear（表格設定的產季年份），未設定時以 now 推算，
+//     使最後一欄落在今天附近而不是未來
+//
+// 無法辨識為日期的儲存格維持原樣。
+func NormalizeHeaderDates(header []string, seasonYear int, now time.Time) []string {
+	parsed := make([]headerDate, len(header))
+	rollovers := 0
+	prevMonth := 0
+	for i, cell := range header {
+		parsed[i] = parseHeaderDate(cell)
+		if !parsed[i].ok {
+			continue
+		}
+		if parsed[i].hasYear {
+			prevMonth = parsed[i].month
+			continue
+		}
+		if prevMonth != 0 && parsed[i].month < prevMonth {
+			rollovers++
+		}
+		prevMonth = parsed[i].month
+	}
+
+	baseYear := seasonYear
+	if baseYear == 0 {
+		baseYear = inferBaseYear(parsed, rollovers, now)
+	}
+
+	result := make([]string, len(header))
+	year := baseYear
+	prevMonth = 0
+	for i, cell := range header {
+		d := parsed[i]
+		if !d.ok {
+			result[i] = cell
+			continue
+		}
+
+		if d.hasYear {
+			year = d.year
+		} else if prevMonth != 0 && d.month < prevMonth {
+			year++
+		}
+		prevMonth = d.month
+
+		t := time.Date(year, time.Month(d.month), d.day, 0, 0, 0, 0, time.UTC)
+		if t.Month() != time.Month(d.month) {
+			// 不存在的日期（例如 2/30）維持原樣，交由後續解析略過
+			result[i] = cell
+			continue
+		}
+		result[i] = t.Format("2006-01-02")
+		if d.slot != "" {
+			result[i] += " " + d.slot
+		}
+	}
+
+	return result
+}
+
+// SplitHeaderSlot 將 NormalizeHeaderDates 的結果拆成日期與時段（沒有時段時為空字串）
+func SplitHeaderSlot(header string) (date, slot string) {
+	date, slot, _ = strings.Cut(header, " ")
+	return date, slot
+}
+
+// inferBaseYear 在沒有設定產季年份時，推算第一欄的年份
+func inferBaseYear(parsed []headerDate, rollovers int, now time.Time) int {
+	// 最後一個沒有年份的日期
+	var last *headerDate
+	for i := len(parsed) - 1; i >= 0; i-- {
+		if parsed[i].ok && !parsed[i].hasYear {
+			last = &parsed[i]
+			break
+		}
+	}
+
+	if last == nil {
+		return now.Year() - rollovers
+	}
+
+	// 最後一欄取不超過 now + futureTolerance 的最晚年份：
+	// 12 月下旬預先排好的 "1/2" 屬於明年，1 月初讀到只有 12 月的表格屬於去年
+	lastYear := now.Year() + 1
+	for time.Date(lastYear, time.Month(last.month), last.day, 0, 0, 0, 0, now.Location()).Sub(now) > futureTolerance {
+		lastYear--
+	}
+	return lastYear - rollovers
+}
@@ -0,0 +1,111 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeHeaderDates(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		header     []string
+		seasonYear int
+		now        time.Time
+		want       []string
+	}{
+		{
+			name:   "dates within the current year",
+			header: []string{"店名", "6/1", "6/2", "6/3"},
+			now:    day(2025, time.June, 10),
+			want:   []string{"店名", "2025-06-01", "2025-06-02", "2025-06-03"},
+		},
+		{
+			name:       "December to January with a season year",
+			header:     []string{"店名", "12/30", "12/31", "1/1", "1/2"},
+			seasonYear: 2024,
+			now:        day(2025, time.June, 10),
+			want:       []string{"店名", "2024-12-30", "2024-12-31", "2025-01-01", "2025-01-02"},
+		},
+		{
+			name:   "December to January read in early January",
+			header: []string{"店名", "12/30", "12/31", "1/1", "1/2"},
+			now:    day(2025, time.January, 3),
+			want:   []string{"店名", "2024-12-30", "2024-12-31", "2025-01-01", "2025-01-02"},
+		},
+		{
+			name:   "December to January read in late December with upcoming dates",
+			header: []string{"店名", "12/30", "12/31", "1/1", "1/2"},
+			now:    day(2024, time.December, 20),
+			want:   []string{"店名", "2024-12-30", "2024-12-31", "2025-01-01", "2025-01-02"},
+		},
+		{
+			name:   "December-only sheet read in January belongs to last year",
+			header: []string{"店名", "12/30", "12/31"},
+			now:    day(2025, time.January, 5),
+			want:   []string{"店名", "2024-12-30", "2024-12-31"},
+		},
+		{
+			name:   "next year's dates far in the future stay in the past season",
+			header: []string{"店名", "11/30", "12/1"},
+			now:    day(2025, time.October, 1),
+			want:   []string{"店名", "2024-11-30", "2024-12-01"},
+		},
+		{
+			name:       "two rollovers in one sheet",
+			header:     []string{"店名", "12/31", "1/1", "12/31", "1/1"},
+			seasonYear: 2023,
+			want:       []string{"店名", "2023-12-31", "2024-01-01", "2024-12-31", "2025-01-01"},
+		},
+		{
+			name:   "explicit year is used as the base for following columns",
+			header: []string{"店名", "2024/12/31", "1/1", "1月2日"},
+			now:    day(2025, time.January, 10),
+			want:   []string{"店名", "2024-12-31", "2025-01-01", "2025-01-02"},
+		},
+		{
+			name:   "ROC year rolls over into January",
+			header: []string{"店名", "113/12/31", "114年1月1日", "1/2"},
+			now:    day(2025, time.January, 10),
+			want:   []string{"店名", "2024-12-31", "2025-01-01", "2025-01-02"},
+		},
+		{
+			name:       "time slots across the new year",
+			header:     []string{"店名", "12/31上午", "12/31 下午", "1/1(AM)", "1/1 pm"},
+			seasonYear: 2024,
+			want:       []string{"店名", "2024-12-31 am", "2024-12-31 pm", "2025-01-01 am", "2025-01-01 pm"},
+		},
+		{
+			name:       "cells that are not dates are kept",
+			header:     []string{"店名", "區域", "2/30", "13/1", "3/1"},
+			seasonYear: 2025,
+			want:       []string{"店名", "區域", "2/30", "13/1", "2025-03-01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeHeaderDates(tt.header, tt.seasonYear, tt.now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeHeaderDates(%q) =\n  %q\nwant\n  %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitHeaderSlot(t *testing.T) {
+	tests := []struct {
+		header, date, slot string
+	}{
+		{"2025-01-01 am", "2025-01-01", TimeSlotAM},
+		{"2025-01-01 pm", "2025-01-01", TimeSlotPM},
+		{"2025-01-01", "2025-01-01", ""},
+	}
+	for _, tt := range tests {
+		date, slot := SplitHeaderSlot(tt.header)
+		if date != tt.date || slot != tt.slot {
+			t.Errorf("SplitHeaderSlot(%q) = %q, %q, want %q, %q", tt.header, date, slot, tt.date, tt.slot)
+		}
+	}
+}
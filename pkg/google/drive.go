@@ -0,0 +1,64 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// driveFilesEndpoint Drive API v3 的檔案資訊端點
+const driveFilesEndpoint = "https://www.googleapis.com/drive/v3/files/"
+
+const driveRequestTimeout = 10 * time.Second
+
+// spreadsheetIDPattern 試算表網址中的檔案 ID（/spreadsheets/d/{id}/，「發布到網路」的 /d/e/ 網址不是檔案 ID）
+var spreadsheetIDPattern = regexp.MustCompile(`/spreadsheets/d/([A-Za-z0-9_-]{20,})`)
+
+// driveFileID 資料來源的試算表檔案 ID，無法得知時回傳空字串
+func driveFileID(source DataSource) string {
+	if source.SheetID != "" {
+		return source.SheetID
+	}
+	if m := spreadsheetIDPattern.FindStringSubmatch(source.URL); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// FetchSheetModifiedTime 以 Drive API 取得資料來源試算表的最後修改時間（表單需分享為「知道連結的任何人」）；
+// 未設定 GOOGLE_DRIVE_API_KEY 或無法得知檔案 ID 時回傳 ok = false
+func FetchSheetModifiedTime(source DataSource) (modified time.Time, ok bool, err error) {
+	apiKey := currentSettings().DriveAPIKey
+	fileID := driveFileID(source)
+	if apiKey == "" || fileID == "" {
+		return time.Time{}, false, nil
+	}
+
+	query := url.Values{"fields": {"modifiedTime"}, "supportsAllDrives": {"true"}, "key": {apiKey}}
+	client := &http.Client{Timeout: driveRequestTimeout}
+	resp, err := client.Get(driveFilesEndpoint + url.PathEscape(fileID) + "?" + query.Encode())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, fmt.Errorf("Drive API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var file struct {
+		ModifiedTime time.Time `json:"modifiedTime"`
+	}
+	if err := json.Unmarshal(body, &file); err != nil {
+		return time.Time{}, false, fmt.Errorf("解析 Drive API 回應失敗: %v", err)
+	}
+	if file.ModifiedTime.IsZero() {
+		return time.Time{}, false, fmt.Errorf("Drive API 回應沒有 modifiedTime")
+	}
+	return file.ModifiedTime, true, nil
+}
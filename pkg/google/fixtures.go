@@ -0,0 +1,74 @@
+package google
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Places API 查詢模式（PLACES_MODE）
+const (
+	PlacesModeLive   = "live"   // 直接呼叫 API（預設）
+	PlacesModeRecord = "record" // 呼叫 API 並將回應保存成 fixture
+	PlacesModeReplay = "replay" // 只讀取 fixture，不呼叫 API（staging / CI 不產生費用）
+)
+
+// placeFixture 保存的一次查詢（request 方便人工檢視，response 為 API 原始回應）
+type placeFixture struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// placesMode 目前的查詢模式，未設定或無法辨識時為 live
+func placesMode() string {
+	switch mode := strings.ToLower(currentSettings().PlacesMode); mode {
+	case PlacesModeRecord, PlacesModeReplay:
+		return mode
+	default:
+		return PlacesModeLive
+	}
+}
+
+// placeFixturePath 以請求內容的雜湊作為檔名，同一個查詢（店名 + 區域偏好）對應同一個 fixture
+func placeFixturePath(bodyJSON []byte) string {
+	dir := currentSettings().PlacesFixturesDir
+	if dir == "" {
+		dir = "./fixtures/places"
+	}
+	sum := sha256.Sum256(bodyJSON)
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// recordPlaceFixture 保存查詢的回應
+func recordPlaceFixture(bodyJSON, respBody []byte) error {
+	path := placeFixturePath(bodyJSON)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(placeFixture{Request: bodyJSON, Response: respBody}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// replayPlaceFixture 讀取保存的回應，沒有 fixture 時回傳錯誤（視同查詢失敗）
+func replayPlaceFixture(bodyJSON []byte) ([]byte, error) {
+	data, err := os.ReadFile(placeFixturePath(bodyJSON))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no Places fixture for request %s", bodyJSON)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture placeFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid Places fixture %s: %v", placeFixturePath(bodyJSON), err)
+	}
+	return fixture.Response, nil
+}
@@ -0,0 +1,230 @@
+package google
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/fault"
+)
+
+// 工作表讀取方式（依資料來源設定的 URL 自動判斷）
+const (
+	SheetFormatExport = "export" // /export?format=csv（預設，只需 sheetId）
+	SheetFormatPubCSV = "pub"    // 發布到網路的 /pub?output=csv（含 pubhtml 連結）
+	SheetFormatGviz   = "gviz"   // /gviz/tq 的 JSON
+)
+
+// SheetURL 依資料來源與 gid 組出工作表網址與讀取方式
+func SheetURL(source DataSource, gid string) (string, string, error) {
+	if source.URL == "" {
+		return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/export?format=csv&gid=%s", source.SheetID, gid), SheetFormatExport, nil
+	}
+
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return "", "", fmt.Errorf("資料來源 %s 的 url 無效: %v", source.ID, err)
+	}
+	q := u.Query()
+	q.Set("gid", gid)
+
+	var format string
+	switch {
+	case strings.Contains(u.Path, "/gviz/tq"):
+		q.Set("tqx", "out:json")
+		q.Set("headers", "1") // 第一列當作表頭
+		format = SheetFormatGviz
+	case strings.HasSuffix(u.Path, "/pubhtml"), strings.HasSuffix(u.Path, "/pub"):
+		u.Path = strings.TrimSuffix(u.Path, "html")
+		q.Set("output", "csv")
+		q.Set("single", "true")
+		format = SheetFormatPubCSV
+	case strings.HasSuffix(u.Path, "/export"):
+		q.Set("format", "csv")
+		format = SheetFormatExport
+	default:
+		return "", "", fmt.Errorf("資料來源 %s 的 url 格式不支援: %s", source.ID, source.URL)
+	}
+
+	u.RawQuery = q.Encode()
+	return u.String(), format, nil
+}
+
+// SheetStats 單張工作表的下載與解析統計
+type SheetStats struct {
+	Bytes    int           // 下載的位元組數
+	Rows     int           // 解析出的列數（含表頭）
+	Download time.Duration // 下載時間
+	Parse    time.Duration // 解析時間
+	// CSV 的原始格式（已自動轉換），gviz JSON 時為空
+	Encoding  string // utf-8 / big5
+	Delimiter rune
+}
+
+// LoadSheet 讀取資料來源中的一張工作表（CSV 或 gviz JSON），回傳去除空白後的儲存格
+func LoadSheet(source DataSource, gid string) ([][]string, error) {
+	records, _, err := LoadSheetWithStats(source, gid)
+	return records, err
+}
+
+// LoadSheetWithStats 同 LoadSheet，另外回傳下載大小與解析時間
+func LoadSheetWithStats(source DataSource, gid string) ([][]string, SheetStats, error) {
+	var stats SheetStats
+	sheetURL, format, err := SheetURL(source, gid)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	if err := fault.SheetsError(); err != nil {
+		return nil, stats, err
+	}
+
+	start := time.Now()
+	resp, err := http.Get(sheetURL)
+	if err != nil {
+		return nil, stats, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, stats, fmt.Errorf("sheet error: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, stats, err
+	}
+	stats.Bytes = len(body)
+	stats.Download = time.Since(start)
+
+	start = time.Now()
+	var records [][]string
+	if format == SheetFormatGviz {
+		records, err = parseGviz(bytes.NewReader(body))
+	} else {
+		var csvFmt csvFormat
+		records, csvFmt, err = parseCSV(body)
+		stats.Encoding, stats.Delimiter = csvFmt.Encoding, csvFmt.Delimiter
+		if csvFmt.Encoding != EncodingUTF8 || csvFmt.Delimiter != ',' {
+			log.Printf("[WARN] 工作表 %s/%s 不是 UTF-8 逗號分隔（編碼: %s，分隔符號: %q），已自動轉換", source.ID, gid, csvFmt.Encoding, csvFmt.Delimiter)
+		}
+	}
+	if err != nil {
+		return nil, stats, err
+	}
+
+	trimCells(records)
+	stats.Rows = len(records)
+	stats.Parse = time.Since(start)
+	return records, stats, nil
+}
+
+// ParseSheetCSV 解析下載的工作表 CSV（與 LoadSheet 相同：自動轉換編碼與分隔符號、去除儲存格前後空白）
+func ParseSheetCSV(body []byte) ([][]string, error) {
+	records, _, err := parseCSV(body)
+	if err != nil {
+		return nil, err
+	}
+	trimCells(records)
+	return records, nil
+}
+
+// trimCells 去掉儲存格前後的空白
+func trimCells(records [][]string) {
+	for i := range records {
+		for j := range records[i] {
+			records[i][j] = strings.TrimSpace(records[i][j])
+		}
+	}
+}
+
+// parseCSV 去掉 BOM、轉換 Big5 並依偵測到的分隔符號解析 CSV
+func parseCSV(body []byte) ([][]string, csvFormat, error) {
+	body, format := normalizeCSV(body)
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.Comma = format.Delimiter
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	return records, format, err
+}
+
+// gvizResponse gviz/tq 回應中的資料表
+type gvizResponse struct {
+	Status string `json:"status"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Table struct {
+		Cols []struct {
+			Label string `json:"label"`
+		} `json:"cols"`
+		Rows []struct {
+			C []*struct {
+				V interface{} `json:"v"`
+				F string      `json:"f"`
+			} `json:"c"`
+		} `json:"rows"`
+	} `json:"table"`
+}
+
+// parseGviz 解析 gviz JSON（外層包著 google.visualization.Query.setResponse(...)），
+// 欄位標籤當作第一列，儲存格優先使用格式化後的文字
+func parseGviz(r io.Reader) ([][]string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(string(body), "("), strings.LastIndex(string(body), ")")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("無法解析 gviz 回應")
+	}
+
+	var res gvizResponse
+	if err := json.Unmarshal(body[start+1:end], &res); err != nil {
+		return nil, fmt.Errorf("無法解析 gviz 回應: %v", err)
+	}
+	if res.Status == "error" {
+		if len(res.Errors) > 0 {
+			return nil, fmt.Errorf("gviz error: %s", res.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("gviz error")
+	}
+
+	header := make([]string, len(res.Table.Cols))
+	for i, col := range res.Table.Cols {
+		header[i] = col.Label
+	}
+	records := [][]string{header}
+
+	for _, row := range res.Table.Rows {
+		record := make([]string, len(res.Table.Cols))
+		for i, cell := range row.C {
+			if cell == nil || i >= len(record) {
+				continue
+			}
+			if cell.F != "" {
+				record[i] = cell.F
+				continue
+			}
+			switch v := cell.V.(type) {
+			case string:
+				record[i] = v
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				record[i] = strconv.FormatBool(v)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
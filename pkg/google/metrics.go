@@ -0,0 +1,67 @@
+package google
+
+import (
+	"fmt"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/metrics"
+)
+
+// defaultSheetSizeWarnBytes 單張工作表超過此大小時在同步摘要中警告（CSV 匯出過大時 Google 會直接失敗）
+const defaultSheetSizeWarnBytes = 10 << 20
+
+// sheetSizeWarnBytes SHEET_SIZE_WARN_BYTES，未設定時使用預設值
+func sheetSizeWarnBytes() int {
+	if n := currentSettings().SheetSizeWarnBytes; n > 0 {
+		return n
+	}
+	return defaultSheetSizeWarnBytes
+}
+
+// setStats 將下載與解析統計寫入報告
+func (r *SheetReport) setStats(stats SheetStats) {
+	r.Bytes = stats.Bytes
+	r.DownloadMs = durationMs(stats.Download)
+	r.ParseMs = durationMs(stats.Parse)
+	if stats.Encoding != "" && stats.Encoding != EncodingUTF8 {
+		r.Encoding = stats.Encoding
+	}
+	if stats.Delimiter != 0 && stats.Delimiter != ',' {
+		r.Delimiter = string(stats.Delimiter)
+	}
+}
+
+// recordSheetMetrics 記錄單張工作表的大小、列數與耗時（依資料來源與工作表名稱區分）
+func recordSheetMetrics(sourceID, sheetName string, stats SheetStats, err error) {
+	labels := metrics.Labels{"source": sourceID, "sheet": sheetName}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.AddCounter("pxmark_sheet_loads_total", "Google Sheets 工作表讀取次數",
+		metrics.Labels{"source": sourceID, "sheet": sheetName, "result": result}, 1)
+	if err != nil {
+		return
+	}
+
+	metrics.SetGauge("pxmark_sheet_bytes", "最近一次下載的工作表大小（位元組）", labels, float64(stats.Bytes))
+	metrics.SetGauge("pxmark_sheet_rows", "最近一次解析出的列數（含表頭）", labels, float64(stats.Rows))
+	metrics.SetGauge("pxmark_sheet_download_seconds", "最近一次下載工作表的耗時（秒）", labels, stats.Download.Seconds())
+	metrics.SetGauge("pxmark_sheet_parse_seconds", "最近一次解析工作表的耗時（秒）", labels, stats.Parse.Seconds())
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// FormatBytes 以 KB / MB 顯示位元組數
+func FormatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
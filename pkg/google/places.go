@@ -7,9 +7,10 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"sync"
-	"time"
+
+	"PXMarkMapBackEnd/pkg/fault"
 )
 
 // PlaceSearchResponse 回傳結構
@@ -17,6 +18,7 @@ type PlaceSearchResponse struct {
 	Places []struct {
 		ID               string `json:"id"`
 		FormattedAddress string `json:"formattedAddress"`
+		BusinessStatus   string `json:"businessStatus"` // OPERATIONAL, CLOSED_TEMPORARILY, CLOSED_PERMANENTLY
 		DisplayName      struct {
 			Text string `json:"text"`
 		} `json:"displayName"`
@@ -27,17 +29,79 @@ type PlaceSearchResponse struct {
 	} `json:"places"`
 }
 
+// regionBiasRadius 以區域中心為圓心的搜尋偏好半徑（公尺）
+const regionBiasRadius = 10000.0
+
+// LocationBias 搜尋時偏好的範圍（圓形）
+type LocationBias struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64 // 公尺
+}
+
 // SearchPlaceByName 查詢店名
 func SearchPlaceByName(storeName string) (*PlaceSearchResponse, error) {
-	apiKey := os.Getenv("GOOGLE_PLACES_API_KEY")
+	return SearchPlace(storeName, nil, PriorityScheduled)
+}
+
+// SearchPlace 查詢地點，bias 不為 nil 時優先回傳該範圍內的結果；
+// 所有查詢都經過共用佇列排隊，依 priority 決定先後（PLACES_MODE=replay 時改讀 fixture，不呼叫 API）
+func SearchPlace(textQuery string, bias *LocationBias, priority Priority) (*PlaceSearchResponse, error) {
+	bodyMap := map[string]interface{}{"textQuery": textQuery}
+	if bias != nil {
+		bodyMap["locationBias"] = map[string]interface{}{
+			"circle": map[string]interface{}{
+				"center": map[string]float64{"latitude": bias.Latitude, "longitude": bias.Longitude},
+				"radius": bias.Radius,
+			},
+		}
+	}
+	bodyJSON, _ := json.Marshal(bodyMap)
+
+	if err := fault.PlacesError(); err != nil {
+		return nil, err
+	}
+
+	var respBody []byte
+	var err error
+	switch placesMode() {
+	case PlacesModeReplay:
+		respBody, err = replayPlaceFixture(bodyJSON)
+	case PlacesModeRecord:
+		if respBody, err = fetchPlaces(bodyJSON, priority); err == nil {
+			if err := recordPlaceFixture(bodyJSON, respBody); err != nil {
+				log.Printf("[WARN] 無法保存 Places fixture: %v", err)
+			}
+		}
+	default:
+		respBody, err = fetchPlaces(bodyJSON, priority)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result PlaceSearchResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Places) == 0 {
+		return nil, fmt.Errorf("no places found for %s", textQuery)
+	}
+
+	return &result, nil
+}
+
+// fetchPlaces 呼叫 Places API Text Search，回傳原始回應內容
+func fetchPlaces(bodyJSON []byte, priority Priority) ([]byte, error) {
+	apiKey := currentSettings().PlacesAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("GOOGLE_PLACES_API_KEY not set")
 	}
 
-	endpoint := "https://places.googleapis.com/v1/places:searchText"
+	waitForPlaceSlot(priority)
 
-	bodyMap := map[string]string{"textQuery": storeName}
-	bodyJSON, _ := json.Marshal(bodyMap)
+	endpoint := "https://places.googleapis.com/v1/places:searchText"
 
 	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(bodyJSON))
 	if err != nil {
@@ -46,7 +110,7 @@ func SearchPlaceByName(storeName string) (*PlaceSearchResponse, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Goog-Api-Key", apiKey)
-	req.Header.Set("X-Goog-FieldMask", "places.displayName,places.id,places.formattedAddress,places.location")
+	req.Header.Set("X-Goog-FieldMask", "places.displayName,places.id,places.formattedAddress,places.location,places.businessStatus")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -59,17 +123,106 @@ func SearchPlaceByName(storeName string) (*PlaceSearchResponse, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Google API error: status %d, body: %s", resp.StatusCode, string(respBody))
 	}
+	return respBody, nil
+}
 
-	var result PlaceSearchResponse
+// BusinessStatusUnspecified Places 沒有回傳營業狀態時記錄的值（與 API 的列舉相同），避免每次同步重複查詢
+const BusinessStatusUnspecified = "BUSINESS_STATUS_UNSPECIFIED"
+
+// FetchBusinessStatus 以 Place Details 取得地點的營業狀態（只要求 businessStatus 欄位，不變更座標與地址）；
+// 與 SearchPlace 相同經過共用佇列，PLACES_MODE=replay 時改讀 fixture
+func FetchBusinessStatus(placeID string, priority Priority) (string, error) {
+	// fixture 以查詢內容的雜湊為檔名，Place Details 沒有 request body，以地點 ID 與欄位組成
+	key, _ := json.Marshal(map[string]string{"placeId": placeID, "fieldMask": "businessStatus"})
+
+	if err := fault.PlacesError(); err != nil {
+		return "", err
+	}
+
+	var respBody []byte
+	var err error
+	switch placesMode() {
+	case PlacesModeReplay:
+		respBody, err = replayPlaceFixture(key)
+	case PlacesModeRecord:
+		if respBody, err = fetchPlaceDetails(placeID, priority); err == nil {
+			if err := recordPlaceFixture(key, respBody); err != nil {
+				log.Printf("[WARN] 無法保存 Places fixture: %v", err)
+			}
+		}
+	default:
+		respBody, err = fetchPlaceDetails(placeID, priority)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		BusinessStatus string `json:"businessStatus"`
+	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", err
+	}
+	if result.BusinessStatus == "" {
+		return BusinessStatusUnspecified, nil
+	}
+	return result.BusinessStatus, nil
+}
+
+// fetchPlaceDetails 呼叫 Places API Place Details，回傳原始回應內容
+func fetchPlaceDetails(placeID string, priority Priority) ([]byte, error) {
+	apiKey := currentSettings().PlacesAPIKey
+	if apiKey == "" {
+		return nil, fmt.Errorf("GOOGLE_PLACES_API_KEY not set")
+	}
+
+	waitForPlaceSlot(priority)
+
+	req, err := http.NewRequest("GET", "https://places.googleapis.com/v1/places/"+url.PathEscape(placeID), nil)
+	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("X-Goog-Api-Key", apiKey)
+	req.Header.Set("X-Goog-FieldMask", "businessStatus")
 
-	if len(result.Places) == 0 {
-		return nil, fmt.Errorf("no places found for %s", storeName)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return &result, nil
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Google API error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// RefreshBusinessStatus 為已有地點但沒有營業狀態的店家補上營業狀態（business_status 欄位加入前
+// 就已查詢過地點的店家，同步時沿用資料庫的地點，不會再經過 Text Search）
+func RefreshBusinessStatus(storeMap map[string]*StoreData, priority Priority) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10) // 同時最多 10 個查詢
+
+	for storeName, storeData := range storeMap {
+		wg.Add(1)
+		go func(name string, data *StoreData) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			status, err := FetchBusinessStatus(data.PlaceID, priority)
+			if err != nil {
+				log.Printf("⚠ 無法取得 %s 的營業狀態: %v", name, err)
+				return
+			}
+			data.BusinessStatus = status
+			log.Printf("✓ %s 的營業狀態: %s", name, status)
+		}(storeName, storeData)
+	}
+
+	wg.Wait()
 }
 
 // EnrichStoresWithPlaceData 為所有店家加上地點資訊
@@ -103,7 +256,7 @@ func SearchPlaceByName(storeName string) (*PlaceSearchResponse, error) {
 
 // 	return nil
 // }
-func EnrichStoresWithPlaceData(storeMap map[string]*StoreData) error {
+func EnrichStoresWithPlaceData(storeMap map[string]*StoreData, priority Priority) error {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, 10) // 同時最多 10 個查詢
 
@@ -114,10 +267,10 @@ func EnrichStoresWithPlaceData(storeMap map[string]*StoreData) error {
 			sem <- struct{}{} // 進入工作池
 			defer func() { <-sem }()
 
-			searchQuery := "全聯 " + name
+			searchQuery := StoreSearchQuery(name, data.Region)
 			log.Printf("搜尋店家: %s", searchQuery)
 
-			placeRes, err := SearchPlaceByName(searchQuery)
+			placeRes, err := SearchPlace(searchQuery, RegionBias(data.Region, priority), priority)
 			if err != nil {
 				log.Printf("⚠ 無法找到 %s 的地點資訊: %v", searchQuery, err)
 				return
@@ -129,14 +282,12 @@ func EnrichStoresWithPlaceData(storeMap map[string]*StoreData) error {
 				data.FormattedAddress = place.FormattedAddress
 				data.Latitude = place.Location.Latitude
 				data.Longitude = place.Location.Longitude
+				data.BusinessStatus = place.BusinessStatus
 
 				log.Printf("✓ 找到 %s: %s (%.6f, %.6f)",
 					name, place.FormattedAddress,
 					place.Location.Latitude, place.Location.Longitude)
 			}
-
-			// 為避免 API 配額過快消耗，可加一點點間隔
-			time.Sleep(150 * time.Millisecond)
 		}(storeName, storeData)
 	}
 
@@ -0,0 +1,67 @@
+package google
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// withReplayFixtures 以 PLACES_MODE=replay 與暫存的 fixture 目錄執行測試，結束後還原
+func withReplayFixtures(t *testing.T) {
+	t.Helper()
+	prev := currentSettings()
+	s := prev
+	s.PlacesMode = PlacesModeReplay
+	s.PlacesFixturesDir = t.TempDir()
+	Configure(s)
+	t.Cleanup(func() { Configure(prev) })
+}
+
+func recordDetails(t *testing.T, placeID, response string) {
+	t.Helper()
+	key, _ := json.Marshal(map[string]string{"placeId": placeID, "fieldMask": "businessStatus"})
+	if err := recordPlaceFixture(key, []byte(response)); err != nil {
+		t.Fatalf("recordPlaceFixture: %v", err)
+	}
+}
+
+func TestFetchBusinessStatus(t *testing.T) {
+	withReplayFixtures(t)
+	recordDetails(t, "ChIJclosed", `{"businessStatus":"CLOSED_PERMANENTLY"}`)
+	recordDetails(t, "ChIJnostatus", `{}`)
+
+	tests := []struct {
+		placeID, want string
+	}{
+		{"ChIJclosed", "CLOSED_PERMANENTLY"},
+		// 沒有營業狀態的地點記錄為 UNSPECIFIED，下次同步不再查詢
+		{"ChIJnostatus", BusinessStatusUnspecified},
+	}
+	for _, tt := range tests {
+		got, err := FetchBusinessStatus(tt.placeID, PriorityScheduled)
+		if err != nil || got != tt.want {
+			t.Errorf("FetchBusinessStatus(%s) = %q, %v, want %q", tt.placeID, got, err, tt.want)
+		}
+	}
+	if _, err := FetchBusinessStatus("ChIJmissing", PriorityScheduled); err == nil {
+		t.Errorf("FetchBusinessStatus without a fixture returned no error")
+	}
+}
+
+func TestRefreshBusinessStatus(t *testing.T) {
+	withReplayFixtures(t)
+	recordDetails(t, "ChIJopen", `{"businessStatus":"OPERATIONAL"}`)
+
+	stores := map[string]*StoreData{
+		"安南店": {StoreName: "安南店", PlaceID: "ChIJopen", Latitude: 23.04, Longitude: 120.18},
+		"永康店": {StoreName: "永康店", PlaceID: "ChIJmissing"},
+	}
+	RefreshBusinessStatus(stores, PriorityScheduled)
+
+	if got := stores["安南店"]; got.BusinessStatus != "OPERATIONAL" || got.Latitude != 23.04 {
+		t.Errorf("安南店 = %+v, want OPERATIONAL with the coordinates unchanged", got)
+	}
+	// 查詢失敗時維持空白，下次同步再試
+	if got := stores["永康店"].BusinessStatus; got != "" {
+		t.Errorf("永康店 status = %q, want empty after a failed lookup", got)
+	}
+}
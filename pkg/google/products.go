@@ -0,0 +1,129 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ProductRule 同步的產品（由 products 資料表載入）：工作表名稱與 ProductType 相同或符合 SheetPatterns 時視為該產品
+type ProductRule struct {
+	ProductType   string   // 寫入資料庫的 product_type
+	SheetPatterns []string // 工作表名稱的比對規則（正規表示式），例如「絲瓜」對應產銷絲瓜
+}
+
+var (
+	productsMu sync.RWMutex
+	products   []ProductRule
+)
+
+// SetProducts 設定同步的產品（同步前由資料庫載入），不在清單中的工作表不會同步
+func SetProducts(rules []ProductRule) {
+	productsMu.Lock()
+	defer productsMu.Unlock()
+	products = rules
+}
+
+func currentProducts() []ProductRule {
+	productsMu.RLock()
+	defer productsMu.RUnlock()
+	return products
+}
+
+var (
+	productAliasesMu sync.RWMutex
+	productAliases   map[string]string // 舊名稱 → 目前名稱（資料庫 product_aliases）
+)
+
+// SetProductAliases 設定產品改名後的別名（同步前由資料庫載入），工作表使用新名稱或舊名稱都能辨識
+func SetProductAliases(aliases map[string]string) {
+	productAliasesMu.Lock()
+	defer productAliasesMu.Unlock()
+	productAliases = aliases
+}
+
+// ProductDisplayName 產品目前的名稱（改名後寫入資料庫與顯示用的名稱）
+func ProductDisplayName(product string) string {
+	productAliasesMu.RLock()
+	defer productAliasesMu.RUnlock()
+	if name, ok := productAliases[product]; ok {
+		return name
+	}
+	return product
+}
+
+// isProductName 工作表名稱是否為產品的目前名稱或改名前的名稱
+func isProductName(sheetName, product string) bool {
+	if sheetName == product {
+		return true
+	}
+	display := ProductDisplayName(product)
+	return sheetName == display || ProductDisplayName(sheetName) == display
+}
+
+// productMatcher 工作表名稱 → 產品（依 SetProducts 的順序比對）
+type productMatcher []productPatterns
+
+type productPatterns struct {
+	product  string
+	patterns []*regexp.Regexp
+}
+
+// loadProductMatcher 以 SetProducts 設定的產品建立比對規則；SHEET_PRODUCT_ALIASES（JSON，例如 {"秋葵":["^秋葵","okra"]}）
+// 中列出的產品改用設定檔的規則
+func loadProductMatcher() (productMatcher, error) {
+	rules := currentProducts()
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("沒有要同步的產品（products 資料表沒有資料）")
+	}
+
+	var custom map[string][]string
+	if spec := currentSettings().ProductAliases; spec != "" {
+		if err := json.Unmarshal([]byte(spec), &custom); err != nil {
+			return nil, fmt.Errorf("SHEET_PRODUCT_ALIASES 格式錯誤: %v", err)
+		}
+	}
+
+	m := make(productMatcher, 0, len(rules))
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.ProductType] = true
+		patterns := rule.SheetPatterns
+		if p, ok := custom[rule.ProductType]; ok {
+			patterns = p
+		}
+		entry := productPatterns{product: rule.ProductType}
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("產品 %s 的比對規則 %q 無效: %v", rule.ProductType, p, err)
+			}
+			entry.patterns = append(entry.patterns, re)
+		}
+		m = append(m, entry)
+	}
+	for product := range custom {
+		if !known[product] {
+			return nil, fmt.Errorf("SHEET_PRODUCT_ALIASES 有不支援的產品: %s", product)
+		}
+	}
+	return m, nil
+}
+
+// match 依工作表名稱找出產品，名稱完全相同（含改名前後的名稱）優先，其次依產品順序比對規則
+func (m productMatcher) match(sheetName string) (string, bool) {
+	for _, p := range m {
+		if isProductName(sheetName, p.product) {
+			return p.product, true
+		}
+	}
+	for _, p := range m {
+		for _, re := range p.patterns {
+			if re.MatchString(sheetName) {
+				return p.product, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,111 @@
+package google
+
+import (
+	"strings"
+	"testing"
+)
+
+// withProducts 以指定的產品與 SHEET_PRODUCT_ALIASES 執行測試，結束後還原
+func withProducts(t *testing.T, rules []ProductRule, aliases string) {
+	t.Helper()
+	prevProducts, prevSettings := currentProducts(), currentSettings()
+	SetProducts(rules)
+	s := prevSettings
+	s.ProductAliases = aliases
+	Configure(s)
+	t.Cleanup(func() {
+		SetProducts(prevProducts)
+		Configure(prevSettings)
+	})
+}
+
+var tableProducts = []ProductRule{
+	{ProductType: "產銷絲瓜", SheetPatterns: []string{"絲瓜"}},
+	{ProductType: "秋葵", SheetPatterns: []string{"秋葵"}},
+	{ProductType: "苦瓜"}, // 新增的產品沒有規則，只讀取名稱相同的工作表
+}
+
+func TestProductMatcher(t *testing.T) {
+	withProducts(t, tableProducts, "")
+	m, err := loadProductMatcher()
+	if err != nil {
+		t.Fatalf("loadProductMatcher: %v", err)
+	}
+
+	tests := []struct {
+		sheet, product string
+		ok             bool
+	}{
+		{"秋葵", "秋葵", true},
+		{"秋葵出貨表", "秋葵", true},
+		{"絲瓜", "產銷絲瓜", true},
+		{"苦瓜", "苦瓜", true},
+		{"苦瓜出貨表", "", false},
+		{"南瓜", "", false},
+	}
+	for _, tt := range tests {
+		product, ok := m.match(tt.sheet)
+		if product != tt.product || ok != tt.ok {
+			t.Errorf("match(%q) = %q, %v, want %q, %v", tt.sheet, product, ok, tt.product, tt.ok)
+		}
+	}
+}
+
+func TestProductMatcherAliasesOverrideTable(t *testing.T) {
+	withProducts(t, tableProducts, `{"秋葵":["(?i)^okra"]}`)
+	m, err := loadProductMatcher()
+	if err != nil {
+		t.Fatalf("loadProductMatcher: %v", err)
+	}
+	if product, ok := m.match("Okra 2025"); !ok || product != "秋葵" {
+		t.Errorf("match(Okra 2025) = %q, %v, want 秋葵", product, ok)
+	}
+	// 設定檔的規則取代資料表的規則，名稱完全相同仍然可以對應
+	if _, ok := m.match("秋葵出貨表"); ok {
+		t.Errorf("match(秋葵出貨表) used the table pattern overridden by SHEET_PRODUCT_ALIASES")
+	}
+	if product, _ := m.match("秋葵"); product != "秋葵" {
+		t.Errorf("match(秋葵) = %q, want 秋葵", product)
+	}
+}
+
+func TestProductMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []ProductRule
+		aliases string
+		want    string
+	}{
+		{"no products", nil, "", "products 資料表沒有資料"},
+		{"alias for an unknown product", tableProducts, `{"南瓜":["南瓜"]}`, "不支援的產品: 南瓜"},
+		{"invalid table pattern", []ProductRule{{ProductType: "秋葵", SheetPatterns: []string{"("}}}, "", "比對規則"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withProducts(t, tt.rules, tt.aliases)
+			if _, err := loadProductMatcher(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadProductMatcher() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSheetShipmentsByProduct(t *testing.T) {
+	storeMap := make(map[string]*StoreData)
+	records := [][]string{{"店名", "6/1"}, {"安南店", "3"}}
+	opts := ParseOptions{DuplicatePolicy: DuplicatePolicySum, SeasonYear: 2025}
+	for _, product := range []string{"秋葵", "苦瓜"} {
+		opts.Product = product
+		organizeSheet(storeMap, records, "default", opts)
+	}
+
+	store := storeMap["安南店"]
+	if store == nil || len(store.Shipments) != 2 {
+		t.Fatalf("store = %+v, want shipments for 秋葵 and 苦瓜", store)
+	}
+	for _, product := range []string{"秋葵", "苦瓜"} {
+		if got := store.Shipments[product]; len(got) != 1 || got[0].Date != "2025-06-01" || got[0].Qty != "3" {
+			t.Errorf("Shipments[%s] = %+v", product, got)
+		}
+	}
+}
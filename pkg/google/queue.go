@@ -0,0 +1,68 @@
+package google
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Priority Places API 查詢的優先順序
+type Priority int
+
+const (
+	PriorityScheduled Priority = iota // 排程同步
+	PriorityManual                    // 手動觸發（手動同步、管理端點批次查詢）
+)
+
+// placeQueue 全程序共用的 Places API 查詢佇列：依 PLACES_QPS 控制整體查詢速率，
+// 有手動查詢在等待時優先放行，避免每日同步與手動同步各自計算間隔而超出配額
+type placeQueue struct {
+	once      sync.Once
+	manual    chan chan struct{}
+	scheduled chan chan struct{}
+}
+
+var places = &placeQueue{
+	manual:    make(chan chan struct{}),
+	scheduled: make(chan chan struct{}),
+}
+
+// waitForPlaceSlot 等待輪到這次查詢
+func waitForPlaceSlot(priority Priority) {
+	places.once.Do(func() { go places.run(placeQueryInterval()) })
+
+	ready := make(chan struct{})
+	if priority == PriorityManual {
+		places.manual <- ready
+	} else {
+		places.scheduled <- ready
+	}
+	<-ready
+}
+
+// run 每隔 interval 放行一個查詢，手動查詢優先
+func (q *placeQueue) run(interval time.Duration) {
+	log.Printf("[INFO] Places API 查詢間隔: %v", interval)
+	for {
+		var ready chan struct{}
+		select {
+		case ready = <-q.manual:
+		default:
+			select {
+			case ready = <-q.manual:
+			case ready = <-q.scheduled:
+			}
+		}
+		close(ready)
+		time.Sleep(interval)
+	}
+}
+
+// placeQueryInterval 由 PLACES_QPS（每秒查詢數，預設 10）換算查詢間隔
+func placeQueryInterval() time.Duration {
+	qps := currentSettings().PlacesQPS
+	if qps <= 0 {
+		qps = 10
+	}
+	return time.Second / time.Duration(qps)
+}
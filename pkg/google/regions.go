@@ -0,0 +1,62 @@
+package google
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// RegionHeaders 表頭中代表區域欄位的名稱
+var RegionHeaders = []string{"區域", "地區", "行政區"}
+
+// regionCenters 區域中心座標快取（nil 表示查詢失敗，不再重試）
+var (
+	regionCenters   = make(map[string]*LocationBias)
+	regionCentersMu sync.Mutex
+)
+
+// isRegionHeader 判斷表頭儲存格是否為區域欄位
+func isRegionHeader(cell string) bool {
+	for _, h := range RegionHeaders {
+		if cell == h {
+			return true
+		}
+	}
+	return false
+}
+
+// StoreSearchQuery 組合搜尋關鍵字：全聯 + 店名（+ 區域），例如 "全聯 安南店 台南市安南區"
+func StoreSearchQuery(storeName, region string) string {
+	query := "全聯 " + storeName
+	if region = strings.TrimSpace(region); region != "" {
+		query += " " + region
+	}
+	return query
+}
+
+// RegionBias 取得區域中心作為搜尋偏好範圍，同一區域只查詢一次 Places API
+func RegionBias(region string, priority Priority) *LocationBias {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		return nil
+	}
+
+	regionCentersMu.Lock()
+	defer regionCentersMu.Unlock()
+
+	if bias, ok := regionCenters[region]; ok {
+		return bias
+	}
+
+	var bias *LocationBias
+	res, err := SearchPlace(region, nil, priority)
+	if err != nil {
+		log.Printf("⚠ 無法找到區域 %s 的中心座標: %v", region, err)
+	} else {
+		loc := res.Places[0].Location
+		bias = &LocationBias{Latitude: loc.Latitude, Longitude: loc.Longitude, Radius: regionBiasRadius}
+	}
+
+	regionCenters[region] = bias
+	return bias
+}
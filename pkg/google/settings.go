@@ -0,0 +1,48 @@
+package google
+
+import "sync"
+
+// Settings 讀取工作表與查詢地點時使用的設定（由 config.Config 轉換，啟動時以 Configure 設定，不直接讀取環境變數）
+type Settings struct {
+	// SeasonYear 表頭日期沒有年份時的產季年份（SHEET_SEASON_YEAR），0 = 依今天日期推算
+	SeasonYear int
+	// DuplicateDatePolicy 同一張工作表重複日期欄位的處理方式（SHEET_DUPLICATE_DATE_POLICY），sum 或 flag
+	DuplicateDatePolicy string
+	// SheetSizeWarnBytes 單張工作表超過此大小時在同步摘要中警告（SHEET_SIZE_WARN_BYTES），0 = 預設 10MB
+	SheetSizeWarnBytes int
+	// SnapshotFallback 下載失敗的工作表改用快照同步（SHEET_SNAPSHOT_FALLBACK）
+	SnapshotFallback bool
+	// ProductAliases 工作表名稱對應產品的規則（SHEET_PRODUCT_ALIASES，JSON），空字串 = 只用 products 資料表的規則
+	ProductAliases string
+
+	// DataSourcesFile 資料來源設定檔（DATA_SOURCES_FILE），空字串時以 Sheet* 組成單一來源
+	DataSourcesFile string
+	SheetID         string // GOOGLE_SHEET_ID
+	SheetURL        string // GOOGLE_SHEET_URL
+	SheetGIDs       string // GOOGLE_SHEET_GIDS，逗號分隔
+	SheetNames      string // GOOGLE_SHEET_NAMES，與 SheetGIDs 對應，逗號分隔
+
+	PlacesAPIKey      string // GOOGLE_PLACES_API_KEY
+	PlacesQPS         int    // PLACES_QPS，全程序共用的每秒查詢上限，0 = 預設 10
+	PlacesMode        string // PLACES_MODE：live / record / replay
+	PlacesFixturesDir string // PLACES_FIXTURES_DIR，空字串 = ./fixtures/places
+	DriveAPIKey       string // GOOGLE_DRIVE_API_KEY，選填：取得試算表最後修改時間
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   Settings
+)
+
+// Configure 設定 pkg/google 的執行設定（在同步或讀取工作表之前呼叫）
+func Configure(s Settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = s
+}
+
+func currentSettings() Settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
+}
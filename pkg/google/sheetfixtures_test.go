@@ -0,0 +1,135 @@
+package google
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/sheetlayouts 收錄產銷班實際使用過的工作表樣式（<名稱>.csv）與預期的解析結果（<名稱>.golden.json），
+// 新增樣式時放入 .csv 後執行 go test ./pkg/google -run TestSheetFixtures -update 產生 golden 並檢查內容
+var update = flag.Bool("update", false, "以目前的解析結果覆寫 testdata/sheetlayouts 的 golden")
+
+const sheetLayoutsDir = "testdata/sheetlayouts"
+
+// 固定的解析條件，讓沒有年份的表頭日期每次都推算成相同的年份
+var (
+	fixtureSeasonYear = 2025
+	fixtureNow        = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// fixtureResult golden 的內容，店家依名稱排序
+type fixtureResult struct {
+	Rows            int             `json:"rows"`
+	DuplicateDates  []DuplicateDate `json:"duplicateDates,omitempty"`
+	DuplicateStores []string        `json:"duplicateStores,omitempty"`
+	Conflicts       int             `json:"conflicts"`
+	Stores          []fixtureStore  `json:"stores"`
+}
+
+type fixtureStore struct {
+	StoreName string            `json:"storeName"`
+	Region    string            `json:"region,omitempty"`
+	Shipments []fixtureShipment `json:"shipments"`
+}
+
+type fixtureShipment struct {
+	Date     string `json:"date"`
+	TimeSlot string `json:"timeSlot,omitempty"`
+	Qty      string `json:"qty"`
+}
+
+// parseFixture 以同步相同的規則解析樣式（產品為秋葵，重複欄位相加），回傳 golden 格式的 JSON
+func parseFixture(t *testing.T, body []byte) []byte {
+	t.Helper()
+	records, err := ParseSheetCSV(body)
+	if err != nil {
+		t.Fatalf("ParseSheetCSV: %v", err)
+	}
+	storeMap, report := ParseSheet(records, ParseOptions{
+		Product:         "秋葵",
+		DuplicatePolicy: DuplicatePolicySum,
+		SeasonYear:      fixtureSeasonYear,
+		Now:             fixtureNow,
+	})
+
+	result := fixtureResult{
+		Rows:            report.Rows,
+		DuplicateDates:  report.DuplicateDates,
+		DuplicateStores: report.DuplicateStores,
+		Conflicts:       report.Conflicts,
+		Stores:          []fixtureStore{},
+	}
+	for _, s := range storeMap {
+		store := fixtureStore{StoreName: s.StoreName, Region: s.Region, Shipments: []fixtureShipment{}}
+		for _, sh := range s.Shipments["秋葵"] {
+			store.Shipments = append(store.Shipments, fixtureShipment{Date: sh.Date, TimeSlot: sh.TimeSlot, Qty: sh.Qty})
+		}
+		result.Stores = append(result.Stores, store)
+	}
+	sort.Slice(result.Stores, func(i, j int) bool { return result.Stores[i].StoreName < result.Stores[j].StoreName })
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return append(out, '\n')
+}
+
+// firstDiff 第一個不同的行（行號從 1 開始）
+func firstDiff(want, got string) (line int, w, g string) {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		w, g = "", ""
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return i + 1, w, g
+		}
+	}
+	return 0, "", ""
+}
+
+func TestSheetFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(sheetLayoutsDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no layouts in %s", sheetLayoutsDir)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".csv")
+		t.Run(name, func(t *testing.T) {
+			body, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := parseFixture(t, body)
+			goldenPath := filepath.Join(sheetLayoutsDir, name+".golden.json")
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if line, w, g := firstDiff(string(want), string(got)); line > 0 {
+				t.Errorf("%s differs at line %d:\n  want %s\n  got  %s\n(run with -update if the new result is correct)", goldenPath, line, w, g)
+			}
+		})
+	}
+}
@@ -1,112 +1,465 @@
 package google
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
-	"net/http"
-	"os"
-	"strings"
+	"strconv"
+	"time"
 )
 
+// 重複日期欄位的處理方式（SHEET_DUPLICATE_DATE_POLICY）
+const (
+	DuplicatePolicySum  = "sum"  // 數量相加（預設）
+	DuplicatePolicyFlag = "flag" // 保留第一欄，只回報衝突
+)
+
+// DuplicateDate 同一張表中重複出現的日期欄位
+type DuplicateDate struct {
+	Date    string `json:"date"`
+	Columns []int  `json:"columns"` // 從 1 開始的欄位編號
+}
+
+// SheetReport 單一工作表的讀取結果
+type SheetReport struct {
+	Source          string          `json:"source"`
+	Sheet           string          `json:"sheet"`
+	Product         string          `json:"product,omitempty"` // 對應到的產品，空白表示沒有對應
+	Rows            int             `json:"rows"`
+	DuplicateDates  []DuplicateDate `json:"duplicateDates,omitempty"`
+	Conflicts       int             `json:"conflicts"`                 // 重複欄位中無法合併的儲存格數
+	DuplicateStores []string        `json:"duplicateStores,omitempty"` // 出現在多列的店家（數量已合併）
+	Bytes           int             `json:"bytes"`                     // 下載的位元組數
+	DownloadMs      float64         `json:"downloadMs"`
+	ParseMs         float64         `json:"parseMs"`
+	StaleSince      *time.Time      `json:"staleSince,omitempty"` // 下載失敗改用快照時，快照的下載時間
+	Encoding        string          `json:"encoding,omitempty"`   // CSV 不是 UTF-8 時的原始編碼（已自動轉換）
+	Delimiter       string          `json:"delimiter,omitempty"`  // CSV 不是逗號分隔時偵測到的分隔符號
+	Error           string          `json:"error,omitempty"`
+}
+
+// LoadReport 讀取所有工作表的結果摘要
+type LoadReport struct {
+	Sheets            []SheetReport `json:"sheets"`
+	UnmatchedProducts []string      `json:"unmatchedProducts,omitempty"` // 沒有任何工作表對應到的產品
+	// SheetModified 資料來源 → 試算表最後修改時間（Drive API，有設定 GOOGLE_DRIVE_API_KEY 才有）
+	SheetModified map[string]time.Time `json:"sheetModified,omitempty"`
+}
+
+// Warnings 整理需要注意的問題
+func (r *LoadReport) Warnings() []string {
+	var warnings []string
+	for _, s := range r.Sheets {
+		if s.Error != "" {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 讀取失敗: %s", s.Source, s.Sheet, s.Error))
+		} else if s.StaleSince != nil {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 下載失敗，改用 %s 的快照同步，資料可能不是最新",
+				s.Source, s.Sheet, s.StaleSince.Format("2006-01-02 15:04")))
+		} else if s.Product == "" {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 沒有對應到任何產品，已略過", s.Source, s.Sheet))
+		}
+		for _, d := range s.DuplicateDates {
+			warnings = append(warnings, fmt.Sprintf("%s 的日期 %s 重複出現於第 %v 欄", s.Sheet, d.Date, d.Columns))
+		}
+		if len(s.DuplicateStores) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s 的店家 %v 出現在多列，數量已合併", s.Sheet, s.DuplicateStores))
+		}
+		if s.Encoding != "" {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 的 CSV 編碼為 %s，已自動轉換為 UTF-8", s.Source, s.Sheet, s.Encoding))
+		}
+		if s.Delimiter != "" {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 的 CSV 以 %q 分隔，已自動判斷", s.Source, s.Sheet, s.Delimiter))
+		}
+		if s.Conflicts > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s 有 %d 個重複日期的數量無法合併，已保留第一欄", s.Sheet, s.Conflicts))
+		}
+		if limit := sheetSizeWarnBytes(); s.Bytes > limit {
+			warnings = append(warnings, fmt.Sprintf("%s/%s 大小 %s 已超過 %s，接近匯出上限", s.Source, s.Sheet, FormatBytes(s.Bytes), FormatBytes(limit)))
+		}
+	}
+	for _, p := range r.UnmatchedProducts {
+		warnings = append(warnings, fmt.Sprintf("產品 %s 沒有對應的工作表", p))
+	}
+	return warnings
+}
+
+// TotalBytes 所有工作表下載的位元組數
+func (r *LoadReport) TotalBytes() int {
+	total := 0
+	for _, s := range r.Sheets {
+		total += s.Bytes
+	}
+	return total
+}
+
+// StaleSources 有工作表改用快照同步的資料來源
+func (r *LoadReport) StaleSources() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, s := range r.Sheets {
+		if s.StaleSince != nil && !seen[s.Source] {
+			seen[s.Source] = true
+			ids = append(ids, s.Source)
+		}
+	}
+	return ids
+}
+
+// LastModified 所有資料來源中最晚的試算表修改時間，沒有任何修改時間時回傳 nil
+func (r *LoadReport) LastModified() *time.Time {
+	var latest *time.Time
+	for _, t := range r.SheetModified {
+		if latest == nil || t.After(*latest) {
+			t := t
+			latest = &t
+		}
+	}
+	return latest
+}
+
+// 資料來源的讀取狀態
+const (
+	SourceStatusSuccess = "success"
+	SourceStatusPartial = "partial" // 部分工作表讀取失敗
+	SourceStatusFailed  = "failed"
+	SourceStatusStale   = "stale_source" // 部分工作表下載失敗，改用快照
+)
+
+// SourceStatus 依工作表讀取結果判斷資料來源的狀態
+func (r *LoadReport) SourceStatus(sourceID string) string {
+	total, failed, stale := 0, 0, 0
+	for _, s := range r.Sheets {
+		if s.Source != sourceID {
+			continue
+		}
+		total++
+		if s.Error != "" {
+			failed++
+		} else if s.StaleSince != nil {
+			stale++
+		}
+	}
+
+	switch {
+	case total == 0 || failed == total:
+		return SourceStatusFailed
+	case failed > 0:
+		return SourceStatusPartial
+	case stale > 0:
+		return SourceStatusStale
+	default:
+		return SourceStatusSuccess
+	}
+}
+
 // 出貨紀錄
 type Shipment struct {
-	Date string
-	Qty  string
+	Date     string
+	TimeSlot string // 時段（am / pm），表頭沒有標示時段時為空字串
+	Qty      string
+	SourceID string // 資料來源
 }
 
 // 每個店名的資料
 type StoreData struct {
-	StoreName            string
-	OkraShipments        []Shipment
-	SpongeGourdShipments []Shipment
+	StoreName string
+	SourceID  string                // 第一個列出此店家的資料來源
+	Region    string                // 表格中的區域欄位（例如 "台南市安南區"），用於地點查詢
+	Shipments map[string][]Shipment // 產品 → 出貨紀錄
 	// 地點資訊
 	PlaceID          string
 	FormattedAddress string
 	Latitude         float64
 	Longitude        float64
+	BusinessStatus   string // Places 回傳的營業狀態
 }
 
 // 抓單個 CSV
 func LoadSheetByGID(sheetID, gid string) ([][]string, error) {
-	csvURL := fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/export?format=csv&gid=%s", sheetID, gid)
-	resp, err := http.Get(csvURL)
+	return LoadSheet(DataSource{SheetID: sheetID}, gid)
+}
+
+// 抓所有 sheet 並整理
+func LoadAndOrganizeSheets() (map[string]*StoreData, *LoadReport, error) {
+	sources, err := LoadDataSources()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
+	return LoadAndOrganizeSources(sources)
+}
 
-	reader := csv.NewReader(resp.Body)
-	reader.LazyQuotes = true
-	reader.FieldsPerRecord = -1
+// LoadAndOrganizeSources 抓指定資料來源的所有 sheet 並整理
+func LoadAndOrganizeSources(sources []DataSource) (map[string]*StoreData, *LoadReport, error) {
+	policy := currentSettings().DuplicateDatePolicy
+	if policy != DuplicatePolicyFlag {
+		policy = DuplicatePolicySum
+	}
 
-	records, err := reader.ReadAll()
+	matcher, err := loadProductMatcher()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// 去掉空格
-	for i := range records {
-		for j := range records[i] {
-			records[i][j] = strings.TrimSpace(records[i][j])
+	storeMap := make(map[string]*StoreData)
+	report := &LoadReport{}
+	matched := make(map[string]bool)
+
+	for _, source := range sources {
+		if modified, ok, err := FetchSheetModifiedTime(source); err != nil {
+			log.Printf("[WARN] 無法取得資料來源 %s 的試算表修改時間: %v", source.ID, err)
+		} else if ok {
+			if report.SheetModified == nil {
+				report.SheetModified = make(map[string]time.Time)
+			}
+			report.SheetModified[source.ID] = modified
+		}
+
+		for i, gid := range source.GIDs {
+			product, ok := matcher.match(source.Names[i])
+			if !ok {
+				log.Printf("[WARN] 工作表 %s/%s 沒有對應到任何產品，已略過", source.ID, source.Names[i])
+				report.Sheets = append(report.Sheets, SheetReport{Source: source.ID, Sheet: source.Names[i]})
+				continue
+			}
+			matched[product] = true
+
+			sheetReport, err := loadSheetInto(storeMap, source, gid, source.Names[i], product, policy)
+			if err != nil {
+				log.Printf("failed to load sheet %s/%s: %v\n", source.ID, source.Names[i], err)
+				report.Sheets = append(report.Sheets, SheetReport{Source: source.ID, Sheet: source.Names[i], Product: product, Error: err.Error()})
+				continue
+			}
+			if sheetReport != nil {
+				report.Sheets = append(report.Sheets, *sheetReport)
+			}
 		}
 	}
-	return records, nil
-}
 
-// 抓所有 sheet 並整理
-func LoadAndOrganizeSheets() (map[string]*StoreData, error) {
-	sheetID := os.Getenv("GOOGLE_SHEET_ID")
-	gidsEnv := os.Getenv("GOOGLE_SHEET_GIDS")   // 例如 "0,123456789"
-	namesEnv := os.Getenv("GOOGLE_SHEET_NAMES") // 對應名稱 "秋葵,產銷絲瓜"
+	for _, p := range matcher {
+		if !matched[p.product] {
+			log.Printf("[WARN] 產品 %s 沒有對應的工作表", p.product)
+			report.UnmatchedProducts = append(report.UnmatchedProducts, p.product)
+		}
+	}
+
+	return storeMap, report, nil
+}
 
-	if sheetID == "" || gidsEnv == "" || namesEnv == "" {
-		return nil, fmt.Errorf("GOOGLE_SHEET_ID or GOOGLE_SHEET_GIDS or GOOGLE_SHEET_NAMES not set")
+// loadSheetInto 讀取單張工作表（對應到 product）並合併到 storeMap
+func loadSheetInto(storeMap map[string]*StoreData, source DataSource, gid, sheetName, product, policy string) (*SheetReport, error) {
+	records, stats, err := LoadSheetWithStats(source, gid)
+	recordSheetMetrics(source.ID, sheetName, stats, err)
+	var staleSince *time.Time
+	if err != nil {
+		snapshot, fetchedAt, ok := loadSheetSnapshot(source, gid, sheetName)
+		if !ok {
+			return nil, err
+		}
+		log.Printf("[WARN] 工作表 %s/%s 下載失敗（%v），改用 %s 的快照", source.ID, sheetName, err, fetchedAt.Format("2006-01-02 15:04"))
+		records, staleSince = snapshot, &fetchedAt
+	} else {
+		saveSheetSnapshot(source, gid, sheetName, records)
 	}
 
-	gids := strings.Split(gidsEnv, ",")
-	names := strings.Split(namesEnv, ",")
-	if len(gids) != len(names) {
-		return nil, fmt.Errorf("GIDs count and Names count do not match")
+	sheetReport := organizeSheet(storeMap, records, source.ID, ParseOptions{
+		Product:         product,
+		DuplicatePolicy: policy,
+		SeasonYear:      currentSettings().SeasonYear,
+		Now:             time.Now(),
+	})
+	sheetReport.Sheet = sheetName
+	sheetReport.StaleSince = staleSince
+	sheetReport.setStats(stats)
+	for _, d := range sheetReport.DuplicateDates {
+		log.Printf("[WARN] %s 的日期 %s 重複出現於第 %v 欄（處理方式: %s）", sheetName, d.Date, d.Columns, policy)
+	}
+	if len(sheetReport.DuplicateStores) > 0 {
+		log.Printf("[WARN] %s 中的店家 %v 出現在多列（處理方式: %s）", sheetName, sheetReport.DuplicateStores, policy)
 	}
+	return &sheetReport, nil
+}
 
+// ParseOptions 解析工作表內容的設定
+type ParseOptions struct {
+	Product         string    // 工作表對應的產品
+	DuplicatePolicy string    // 重複日期欄位或重複店家列的處理方式，預設 DuplicatePolicySum
+	SeasonYear      int       // 表頭日期沒有年份時的產季年份，0 = 依 Now 推算
+	Now             time.Time // 推算年份的基準時間
+}
+
+// ParseSheet 解析已下載的工作表內容（不下載、不記錄日誌），回傳店家與出貨；
+// 與同步使用相同的規則，供 TestSheetFixtures 以真實表格樣式驗證解析結果
+func ParseSheet(records [][]string, opts ParseOptions) (map[string]*StoreData, SheetReport) {
+	if opts.DuplicatePolicy != DuplicatePolicyFlag {
+		opts.DuplicatePolicy = DuplicatePolicySum
+	}
 	storeMap := make(map[string]*StoreData)
+	report := organizeSheet(storeMap, records, "", opts)
+	return storeMap, report
+}
 
-	for i, gid := range gids {
-		sheetName := strings.TrimSpace(names[i])
-		records, err := LoadSheetByGID(sheetID, strings.TrimSpace(gid))
-		if err != nil {
-			log.Printf("failed to load sheet %s: %v\n", sheetName, err)
-			continue
+// maxTitleRows 表頭前最多允許幾列標題（例如「114年度秋葵出貨表」）
+const maxTitleRows = 5
+
+// organizeSheet 將交叉表（第一欄店名、表頭為日期）整理成出貨並合併到 storeMap：
+//   - 表頭前的標題列略過（第一個含有日期的列視為表頭）
+//   - 區域欄位為合併儲存格時只有第一列有值，之後的空白沿用上一列
+//   - 店名空白的列（空白列、合併儲存格）略過
+//   - 同一店家出現在多列、或同一日期出現在多欄時，數量依 DuplicatePolicy 合併
+func organizeSheet(storeMap map[string]*StoreData, records [][]string, sourceID string, opts ParseOptions) SheetReport {
+	headerRow := findHeaderRow(records)
+	report := SheetReport{Source: sourceID, Product: opts.Product, Rows: len(records) - headerRow - 1}
+	if report.Rows < 1 {
+		report.Rows = len(records)
+		return report
+	}
+
+	// 交叉表: 表頭是日期（沒有年份的日期會推算年份）
+	header := NormalizeHeaderDates(records[headerRow], opts.SeasonYear, opts.Now)
+
+	// 區域欄位（若有）不算日期欄
+	regionCol := -1
+	for k := 1; k < len(header); k++ {
+		if isRegionHeader(header[k]) {
+			regionCol = k
+			header[k] = ""
+			break
 		}
+	}
+	dates, columns := groupDateColumns(header)
+
+	for _, date := range dates {
+		if len(columns[date]) > 1 {
+			cols := make([]int, len(columns[date]))
+			for i, c := range columns[date] {
+				cols[i] = c + 1
+			}
+			report.DuplicateDates = append(report.DuplicateDates, DuplicateDate{Date: date, Columns: cols})
+		}
+	}
 
-		if len(records) < 2 {
+	// 依店家、日期收集儲存格（重複的欄與列一起合併）
+	var storeOrder []string
+	values := make(map[string]map[string][]string)
+	rowCount := make(map[string]int)
+	region := ""
+	for _, row := range records[headerRow+1:] {
+		if regionCol > 0 && regionCol < len(row) && row[regionCol] != "" {
+			region = row[regionCol]
+		}
+		storeName := row[0]
+		if storeName == "" {
 			continue
 		}
+		if _, ok := storeMap[storeName]; !ok {
+			storeMap[storeName] = &StoreData{StoreName: storeName, SourceID: sourceID, Shipments: make(map[string][]Shipment)}
+		}
+		if regionCol > 0 && region != "" && storeMap[storeName].Region == "" {
+			storeMap[storeName].Region = region
+		}
+
+		if values[storeName] == nil {
+			values[storeName] = make(map[string][]string)
+			storeOrder = append(storeOrder, storeName)
+		}
+		rowCount[storeName]++
+		for _, date := range dates {
+			for _, k := range columns[date] {
+				if k < len(row) {
+					values[storeName][date] = append(values[storeName][date], row[k])
+				}
+			}
+		}
+	}
 
-		// 交叉表: 第一列是日期
-		header := records[0]
+	for _, storeName := range storeOrder {
+		if rowCount[storeName] > 1 {
+			report.DuplicateStores = append(report.DuplicateStores, storeName)
+		}
+		for _, date := range dates {
+			cells, ok := values[storeName][date]
+			if !ok {
+				continue
+			}
 
-		for j := 1; j < len(records); j++ {
-			row := records[j]
-			storeName := row[0]
-			if _, ok := storeMap[storeName]; !ok {
-				storeMap[storeName] = &StoreData{StoreName: storeName}
+			qty, conflict := mergeQuantities(cells, opts.DuplicatePolicy)
+			if conflict {
+				report.Conflicts++
 			}
 
-			for k := 1; k < len(row) && k < len(header); k++ {
-				date := header[k]
-				qty := row[k]
+			day, slot := SplitHeaderSlot(date)
+			shipment := Shipment{Date: day, TimeSlot: slot, Qty: qty, SourceID: sourceID}
+			storeMap[storeName].Shipments[opts.Product] = append(storeMap[storeName].Shipments[opts.Product], shipment)
+		}
+	}
 
-				shipment := Shipment{Date: date, Qty: qty}
-				if sheetName == "秋葵" {
-					storeMap[storeName].OkraShipments = append(storeMap[storeName].OkraShipments, shipment)
-				} else if sheetName == "產銷絲瓜" {
-					storeMap[storeName].SpongeGourdShipments = append(storeMap[storeName].SpongeGourdShipments, shipment)
-				}
+	return report
+}
+
+// findHeaderRow 表頭所在的列：前幾列中第一個（店名欄以外）含有日期的列，都沒有時為第一列
+func findHeaderRow(records [][]string) int {
+	for i := 0; i < len(records) && i < maxTitleRows+1; i++ {
+		for _, cell := range records[i][min(1, len(records[i])):] {
+			if parseHeaderDate(cell).ok {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// groupDateColumns 依日期整理欄位編號（保留第一次出現的順序）
+func groupDateColumns(header []string) ([]string, map[string][]int) {
+	var dates []string
+	columns := make(map[string][]int)
+	for k := 1; k < len(header); k++ {
+		date := header[k]
+		if date == "" {
+			continue
+		}
+		if _, ok := columns[date]; !ok {
+			dates = append(dates, date)
+		}
+		columns[date] = append(columns[date], k)
+	}
+	return dates, columns
+}
+
+// mergeQuantities 合併重複日期欄位的數量，回傳合併結果與是否有無法合併的衝突
+func mergeQuantities(values []string, policy string) (string, bool) {
+	var nonEmpty []string
+	for _, v := range values {
+		if v != "" {
+			nonEmpty = append(nonEmpty, v)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return "", false
+	}
+	if len(nonEmpty) == 1 {
+		return nonEmpty[0], false
+	}
+
+	if policy == DuplicatePolicySum {
+		total := 0.0
+		numeric := true
+		for _, v := range nonEmpty {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				numeric = false
+				break
 			}
+			total += n
+		}
+		if numeric {
+			return strconv.FormatFloat(total, 'f', -1, 64), false
 		}
 	}
 
-	return storeMap, nil
+	// 保留第一欄，數值不同時回報衝突
+	for _, v := range nonEmpty[1:] {
+		if v != nonEmpty[0] {
+			return nonEmpty[0], true
+		}
+	}
+	return nonEmpty[0], false
 }
\ No newline at end of file
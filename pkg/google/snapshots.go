@@ -0,0 +1,61 @@
+package google
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// SheetSnapshotStore 保存與讀取工作表最近一次成功下載的內容（由同步流程以 BLOB_STORE 與資料庫實作）
+type SheetSnapshotStore interface {
+	SaveSheetSnapshot(sourceID, gid, sheetName string, records [][]string) error
+	LoadSheetSnapshot(sourceID, gid string) ([][]string, time.Time, error)
+}
+
+var (
+	snapshotStoreMu sync.RWMutex
+	snapshotStore   SheetSnapshotStore
+)
+
+// SetSheetSnapshotStore 設定工作表快照的保存位置（nil 表示不保存）
+func SetSheetSnapshotStore(store SheetSnapshotStore) {
+	snapshotStoreMu.Lock()
+	defer snapshotStoreMu.Unlock()
+	snapshotStore = store
+}
+
+func getSnapshotStore() SheetSnapshotStore {
+	snapshotStoreMu.RLock()
+	defer snapshotStoreMu.RUnlock()
+	return snapshotStore
+}
+
+// snapshotFallbackEnabled SHEET_SNAPSHOT_FALLBACK=true 時，下載失敗的工作表改用快照同步
+func snapshotFallbackEnabled() bool {
+	return currentSettings().SnapshotFallback
+}
+
+// saveSheetSnapshot 下載成功後更新快照（失敗只記錄警告，不影響同步）
+func saveSheetSnapshot(source DataSource, gid, sheetName string, records [][]string) {
+	store := getSnapshotStore()
+	if store == nil {
+		return
+	}
+	if err := store.SaveSheetSnapshot(source.ID, gid, sheetName, records); err != nil {
+		log.Printf("[WARN] 無法保存工作表 %s/%s 的快照: %v", source.ID, sheetName, err)
+	}
+}
+
+// loadSheetSnapshot 下載失敗時讀取快照；未啟用或沒有快照時 ok 為 false
+func loadSheetSnapshot(source DataSource, gid, sheetName string) (records [][]string, fetchedAt time.Time, ok bool) {
+	store := getSnapshotStore()
+	if store == nil || !snapshotFallbackEnabled() {
+		return nil, time.Time{}, false
+	}
+	records, fetchedAt, err := store.LoadSheetSnapshot(source.ID, gid)
+	if err != nil {
+		log.Printf("[WARN] 工作表 %s/%s 沒有可用的快照: %v", source.ID, sheetName, err)
+		return nil, time.Time{}, false
+	}
+	return records, fetchedAt, true
+}
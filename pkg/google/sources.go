@@ -0,0 +1,111 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultSourceID 只使用 GOOGLE_SHEET_* 環境變數時的資料來源 ID
+const DefaultSourceID = "default"
+
+// DataSource 一個資料來源（一份 Google Sheet，可包含多張工作表）
+type DataSource struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	SheetID string `json:"sheetId"`
+	// URL 選填：發布到網路的連結（pubhtml / pub?output=csv）或 gviz/tq 網址，
+	// 設定後依網址自動判斷讀取方式，不需要 sheetId
+	URL   string   `json:"url,omitempty"`
+	GIDs  []string `json:"gids"`
+	Names []string `json:"names"` // 與 GIDs 對應的產品名稱
+	// Secret 該來源專屬的密鑰，持有者只能同步或清除自己來源的資料
+	Secret string `json:"secret,omitempty"`
+}
+
+// LoadDataSources 載入資料來源設定
+// 有設定 DATA_SOURCES_FILE（JSON 陣列）時使用檔案，否則以 GOOGLE_SHEET_* 組成單一來源
+func LoadDataSources() ([]DataSource, error) {
+	s := currentSettings()
+	if s.DataSourcesFile != "" {
+		return loadDataSourcesFile(s.DataSourcesFile)
+	}
+
+	sheetID := s.SheetID
+	sheetURL := s.SheetURL
+	gids := s.SheetGIDs   // 例如 "0,123456789"
+	names := s.SheetNames // 對應名稱 "秋葵,產銷絲瓜"
+
+	if (sheetID == "" && sheetURL == "") || gids == "" || names == "" {
+		return nil, fmt.Errorf("GOOGLE_SHEET_ID or GOOGLE_SHEET_GIDS or GOOGLE_SHEET_NAMES not set")
+	}
+
+	source := DataSource{
+		ID:      DefaultSourceID,
+		Name:    "Google Sheet",
+		SheetID: sheetID,
+		URL:     sheetURL,
+		GIDs:    splitTrim(gids),
+		Names:   splitTrim(names),
+	}
+	if err := source.validate(); err != nil {
+		return nil, err
+	}
+	return []DataSource{source}, nil
+}
+
+// FindDataSource 依 ID 取得資料來源
+func FindDataSource(sources []DataSource, id string) (*DataSource, bool) {
+	for i := range sources {
+		if sources[i].ID == id {
+			return &sources[i], true
+		}
+	}
+	return nil, false
+}
+
+func loadDataSourcesFile(path string) ([]DataSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取資料來源設定失敗: %v", err)
+	}
+
+	var sources []DataSource
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("解析資料來源設定失敗: %v", err)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("資料來源設定為空: %s", path)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range sources {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		if seen[s.ID] {
+			return nil, fmt.Errorf("資料來源 ID 重複: %s", s.ID)
+		}
+		seen[s.ID] = true
+	}
+	return sources, nil
+}
+
+func (s DataSource) validate() error {
+	if s.ID == "" || (s.SheetID == "" && s.URL == "") {
+		return fmt.Errorf("資料來源缺少 id 或 sheetId / url")
+	}
+	if len(s.GIDs) == 0 || len(s.GIDs) != len(s.Names) {
+		return fmt.Errorf("資料來源 %s 的 GIDs count and Names count do not match", s.ID)
+	}
+	return nil
+}
+
+func splitTrim(s string) []string {
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
@@ -0,0 +1,580 @@
+// Package graphql 實作 GraphQL 查詢的最小子集（query、參數、變數、別名、fragment、巢狀選取與 __typename），
+// 讓前端一次取得剛好需要的欄位；不支援 mutation、subscription、directive 與 introspection
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// 內建的純量型別
+const (
+	TypeString  = "String"
+	TypeInt     = "Int"
+	TypeFloat   = "Float"
+	TypeBoolean = "Boolean"
+	TypeID      = "ID"
+)
+
+// Schema 查詢的型別定義
+type Schema struct {
+	Query    string             // 根型別名稱
+	Types    map[string]*Object // 物件型別
+	MaxDepth int                // 巢狀物件的最大層數（0 表示不限制）
+}
+
+// Object 物件型別
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+// FieldDef 物件上的欄位
+type FieldDef struct {
+	// Type 回傳型別，例如 String、Store、[Shipment]（輸出型別的 ! 只作為說明，不檢查）
+	Type string
+	// Args 參數名稱 → 型別，例如 {"limit": "Int", "ids": "[Int]", "id": "Int!"}
+	Args map[string]string
+	// Resolve 取值函式；nil 時依欄位名稱讀取來源 struct 的 json 標籤（或 map 的鍵）
+	Resolve ResolveFunc
+}
+
+// ResolveFunc 欄位取值函式
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// ResolveParams 取值時的參數
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{} // 上層物件的值（根型別為 nil）
+	Args    Args        // 已依型別轉換的參數（未提供的參數不在 map 中）
+}
+
+// Result 查詢結果（GraphQL 回應格式）
+type Result struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error 查詢錯誤；Path 為發生錯誤的欄位路徑（解析與驗證錯誤沒有路徑）
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Request 查詢請求（POST /graphql 的 JSON 內容）
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Execute 解析、驗證並執行查詢；解析或驗證失敗時 Data 為空，欄位取值失敗時該欄位為 null 並記錄錯誤
+func Execute(ctx context.Context, schema *Schema, req Request) *Result {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	variables, errs := coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Result{Errors: errs}
+	}
+
+	e := &executor{ctx: ctx, schema: schema, doc: doc, variables: variables}
+	e.validate(schema.Query, op.Selections, nil, 1, map[string]bool{})
+	if len(e.errors) > 0 {
+		return &Result{Errors: e.errors}
+	}
+
+	data := e.executeObject(schema.Query, nil, op.Selections, nil)
+	return &Result{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the query contains multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func coerceVariables(op *Operation, values map[string]interface{}) (map[string]interface{}, []*Error) {
+	coerced := make(map[string]interface{}, len(op.Variables))
+	var errs []*Error
+	for _, def := range op.Variables {
+		v, ok := values[def.Name]
+		if !ok {
+			if def.Default != nil {
+				v, ok = def.Default, true
+			} else if def.NonNull {
+				errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s of type %s is required", def.Name, def.Type)})
+				continue
+			}
+		}
+		if !ok {
+			continue
+		}
+		c, err := coerce(def.Type, v)
+		if err != nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s: %v", def.Name, err)})
+			continue
+		}
+		coerced[def.Name] = c
+	}
+	return coerced, errs
+}
+
+type executor struct {
+	ctx       context.Context
+	schema    *Schema
+	doc       *Document
+	variables map[string]interface{}
+	errors    []*Error
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+// collectedField 同一個回應鍵的欄位（fragment 展開後可能出現多次，子選取合併）
+type collectedField struct {
+	key        string
+	name       string
+	first      *Field
+	selections []Selection
+}
+
+// collectFields 展開 fragment，依出現順序合併相同回應鍵的欄位
+func (e *executor) collectFields(typeName string, selections []Selection, fields []*collectedField, index map[string]int, visited map[string]bool) []*collectedField {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *Field:
+			key := s.ResponseKey()
+			if i, ok := index[key]; ok {
+				fields[i].selections = append(fields[i].selections, s.Selections...)
+				continue
+			}
+			index[key] = len(fields)
+			fields = append(fields, &collectedField{key: key, name: s.Name, first: s, selections: append([]Selection(nil), s.Selections...)})
+		case *InlineFragment:
+			if s.TypeCondition == "" || s.TypeCondition == typeName {
+				fields = e.collectFields(typeName, s.Selections, fields, index, visited)
+			}
+		case *FragmentSpread:
+			f := e.doc.Fragments[s.Name]
+			if f == nil || visited[s.Name] || f.TypeCondition != typeName {
+				continue
+			}
+			visited[s.Name] = true
+			fields = e.collectFields(typeName, f.Selections, fields, index, visited)
+			delete(visited, s.Name)
+		}
+	}
+	return fields
+}
+
+// validate 檢查欄位、參數、fragment 與巢狀層數，錯誤記錄在 e.errors
+func (e *executor) validate(typeName string, selections []Selection, path []interface{}, depth int, spreading map[string]bool) {
+	if e.schema.MaxDepth > 0 && depth > e.schema.MaxDepth {
+		e.fail(path, "query is nested too deeply (max depth %d)", e.schema.MaxDepth)
+		return
+	}
+	obj := e.schema.Types[typeName]
+
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *InlineFragment:
+			if s.TypeCondition != "" && e.schema.Types[s.TypeCondition] == nil {
+				e.fail(path, "unknown type %q", s.TypeCondition)
+				continue
+			}
+			e.validate(typeName, s.Selections, path, depth, spreading)
+		case *FragmentSpread:
+			f := e.doc.Fragments[s.Name]
+			switch {
+			case f == nil:
+				e.fail(path, "unknown fragment %q", s.Name)
+			case spreading[s.Name]:
+				e.fail(path, "fragment %q spreads itself", s.Name)
+			case e.schema.Types[f.TypeCondition] == nil:
+				e.fail(path, "unknown type %q", f.TypeCondition)
+			case f.TypeCondition != typeName:
+				e.fail(path, "fragment %q on %s cannot be spread on %s", s.Name, f.TypeCondition, typeName)
+			default:
+				spreading[s.Name] = true
+				e.validate(typeName, f.Selections, path, depth, spreading)
+				delete(spreading, s.Name)
+			}
+		case *Field:
+			fieldPath := appendPath(path, s.ResponseKey())
+			if s.Name == "__typename" {
+				if len(s.Selections) > 0 {
+					e.fail(fieldPath, "field __typename must not have a selection")
+				}
+				continue
+			}
+			def := obj.Fields[s.Name]
+			if def == nil {
+				e.fail(fieldPath, "unknown field %q on type %s", s.Name, typeName)
+				continue
+			}
+			if _, err := e.coerceArgs(def, s.Arguments); err != nil {
+				e.fail(fieldPath, "%v", err)
+			}
+			named, _ := unwrapType(def.Type)
+			if e.schema.Types[named] == nil {
+				if len(s.Selections) > 0 {
+					e.fail(fieldPath, "field %q of type %s must not have a selection", s.Name, def.Type)
+				}
+				continue
+			}
+			if len(s.Selections) == 0 {
+				e.fail(fieldPath, "field %q of type %s must have a selection of subfields", s.Name, def.Type)
+				continue
+			}
+			e.validate(named, s.Selections, fieldPath, depth+1, spreading)
+		}
+	}
+}
+
+// executeObject 依選取執行物件的各欄位，回傳保留欄位順序的結果
+func (e *executor) executeObject(typeName string, source interface{}, selections []Selection, path []interface{}) *orderedObject {
+	obj := e.schema.Types[typeName]
+	out := &orderedObject{}
+	for _, f := range e.collectFields(typeName, selections, nil, map[string]int{}, map[string]bool{}) {
+		fieldPath := appendPath(path, f.key)
+		if f.name == "__typename" {
+			out.set(f.key, typeName)
+			continue
+		}
+		def := obj.Fields[f.name]
+		args, err := e.coerceArgs(def, f.first.Arguments)
+		if err != nil {
+			e.fail(fieldPath, "%v", err)
+			out.set(f.key, nil)
+			continue
+		}
+
+		var value interface{}
+		if def.Resolve != nil {
+			value, err = def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+		} else {
+			value, err = defaultResolve(source, f.name)
+		}
+		if err != nil {
+			e.fail(fieldPath, "%v", err)
+			out.set(f.key, nil)
+			continue
+		}
+		out.set(f.key, e.complete(def.Type, value, f.selections, fieldPath))
+	}
+	return out
+}
+
+// complete 依欄位型別處理取得的值：物件執行子選取、list 逐項處理、純量直接輸出
+func (e *executor) complete(typ string, value interface{}, selections []Selection, path []interface{}) interface{} {
+	if isNil(value) {
+		if strings.HasPrefix(strings.TrimSuffix(typ, "!"), "[") && value != nil {
+			return []interface{}{}
+		}
+		return nil
+	}
+	named, list := unwrapType(typ)
+	if list {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(path, "expected a list for type %s", typ)
+			return nil
+		}
+		inner := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(typ, "!"), "["), "]")
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = e.complete(inner, rv.Index(i).Interface(), selections, appendPath(path, i))
+		}
+		return items
+	}
+	if e.schema.Types[named] != nil {
+		return e.executeObject(named, value, selections, path)
+	}
+	return value
+}
+
+// defaultResolve 依 json 標籤讀取 struct 欄位（或 map 的鍵）
+func defaultResolve(source interface{}, name string) (interface{}, error) {
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[name], nil
+	}
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot resolve field %q", name)
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return rv.Field(i).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot resolve field %q", name)
+}
+
+// coerceArgs 將欄位參數（含變數）轉換為宣告的型別
+func (e *executor) coerceArgs(def *FieldDef, arguments []Argument) (Args, error) {
+	args := Args{}
+	for _, arg := range arguments {
+		typ, ok := def.Args[arg.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument %q", arg.Name)
+		}
+		if _, dup := args[arg.Name]; dup {
+			return nil, fmt.Errorf("duplicate argument %q", arg.Name)
+		}
+		value, provided, err := e.literal(arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.Name, err)
+		}
+		if !provided {
+			continue
+		}
+		c, err := coerce(typ, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.Name, err)
+		}
+		args[arg.Name] = c
+	}
+	for name, typ := range def.Args {
+		if _, ok := args[name]; !ok && strings.HasSuffix(typ, "!") {
+			return nil, fmt.Errorf("argument %q of type %s is required", name, typ)
+		}
+	}
+	return args, nil
+}
+
+// literal 將解析出的值換成 Go 值（代入變數）；未提供的變數回傳 provided=false
+func (e *executor) literal(v Value) (value interface{}, provided bool, err error) {
+	switch x := v.(type) {
+	case Variable:
+		value, ok := e.variables[string(x)]
+		return value, ok, nil
+	case []Value:
+		list := make([]interface{}, 0, len(x))
+		for _, item := range x {
+			value, provided, err := e.literal(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if !provided {
+				value = nil
+			}
+			list = append(list, value)
+		}
+		return list, true, nil
+	case []Argument:
+		return nil, false, fmt.Errorf("input objects are not supported")
+	case EnumValue:
+		return nil, false, fmt.Errorf("unexpected enum value %s", x)
+	}
+	return v, true, nil
+}
+
+// coerce 將輸入值（查詢中的常數或 JSON 變數）轉換為型別 typ；單一值會包成 list
+func coerce(typ string, v interface{}) (interface{}, error) {
+	nonNull := strings.HasSuffix(typ, "!")
+	typ = strings.TrimSuffix(typ, "!")
+	if v == nil {
+		if nonNull {
+			return nil, fmt.Errorf("expected non-null %s", typ)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(typ, "[") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]")
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			c, err := coerce(inner, item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = c
+		}
+		return list, nil
+	}
+
+	switch typ {
+	case TypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case TypeID:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case int:
+			return fmt.Sprint(x), nil
+		case float64:
+			if x == math.Trunc(x) {
+				return fmt.Sprint(int64(x)), nil
+			}
+		}
+	case TypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case TypeInt:
+		switch x := v.(type) {
+		case int:
+			return x, nil
+		case float64:
+			if x == math.Trunc(x) && math.Abs(x) <= math.MaxInt32 {
+				return int(x), nil
+			}
+		case json.Number:
+			var n int
+			if _, err := fmt.Sscan(string(x), &n); err == nil {
+				return n, nil
+			}
+		}
+	case TypeFloat:
+		switch x := v.(type) {
+		case int:
+			return float64(x), nil
+		case float64:
+			return x, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown input type %s", typ)
+	}
+	return nil, fmt.Errorf("expected %s, got %v", typ, v)
+}
+
+// unwrapType 去掉 list 與 non-null 標記，回傳具名型別與是否為 list
+func unwrapType(typ string) (string, bool) {
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(typ, "[") {
+		named, _ := unwrapType(strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]"))
+		return named, true
+	}
+	return typ, false
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
+}
+
+// orderedObject 依選取順序輸出欄位的 JSON 物件
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *orderedObject) set(key string, value interface{}) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Args 已轉換型別的參數
+type Args map[string]interface{}
+
+// Has 是否提供了參數（值可能為 null）
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String 字串參數，未提供或為 null 時回傳空字串
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int 整數參數，未提供或為 null 時回傳 def
+func (a Args) Int(name string, def int) int {
+	if n, ok := a[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// Bool 布林參數，未提供或為 null 時回傳 nil
+func (a Args) Bool(name string) *bool {
+	if b, ok := a[name].(bool); ok {
+		return &b
+	}
+	return nil
+}
+
+// Ints 整數 list 參數（忽略 null 項目）
+func (a Args) Ints(name string) []int {
+	items, _ := a[name].([]interface{})
+	var ints []int
+	for _, item := range items {
+		if n, ok := item.(int); ok {
+			ints = append(ints, n)
+		}
+	}
+	return ints
+}
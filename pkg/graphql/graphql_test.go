@@ -0,0 +1,260 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testStore struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"storeName"`
+	Region *string `json:"region"`
+}
+
+type testShipment struct {
+	StoreID  int    `json:"storeId"`
+	Quantity string `json:"quantity"`
+}
+
+func testSchema() *Schema {
+	region := "台南市"
+	stores := []testStore{{ID: 1, Name: "安南店", Region: &region}, {ID: 2, Name: "永康店"}}
+	shipments := map[int][]testShipment{
+		1: {{StoreID: 1, Quantity: "3"}, {StoreID: 1, Quantity: "5"}},
+	}
+
+	return &Schema{
+		Query:    "Query",
+		MaxDepth: 4,
+		Types: map[string]*Object{
+			"Query": {Name: "Query", Fields: map[string]*FieldDef{
+				"stores": {
+					Type: "[Store]",
+					Args: map[string]string{"ids": "[Int]", "limit": "Int"},
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						ids := p.Args.Ints("ids")
+						var out []testStore
+						for _, s := range stores {
+							if len(ids) > 0 && !containsInt(ids, s.ID) {
+								continue
+							}
+							out = append(out, s)
+						}
+						if limit := p.Args.Int("limit", len(out)); limit < len(out) {
+							out = out[:limit]
+						}
+						return out, nil
+					},
+				},
+				"store": {
+					Type: "Store",
+					Args: map[string]string{"id": "Int!"},
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						for _, s := range stores {
+							if s.ID == p.Args.Int("id", 0) {
+								return s, nil
+							}
+						}
+						return nil, nil
+					},
+				},
+				"echo": {
+					Type: "String",
+					Args: map[string]string{"s": "String", "n": "Int", "f": "Float", "b": "Boolean", "id": "ID"},
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						b, _ := json.Marshal(p.Args)
+						return string(b), nil
+					},
+				},
+				"broken": {
+					Type: "String",
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						return nil, errors.New("database is down")
+					},
+				},
+			}},
+			"Store": {Name: "Store", Fields: map[string]*FieldDef{
+				"id":        {Type: "Int"},
+				"storeName": {Type: "String"},
+				"region":    {Type: "String"},
+				"shipments": {
+					Type: "[Shipment]",
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						return shipments[p.Source.(testStore).ID], nil
+					},
+				},
+			}},
+			"Shipment": {Name: "Shipment", Fields: map[string]*FieldDef{
+				"quantity": {Type: "String"},
+				"store": {
+					Type: "Store",
+					Resolve: func(p ResolveParams) (interface{}, error) {
+						return stores[p.Source.(testShipment).StoreID-1], nil
+					},
+				},
+			}},
+		},
+	}
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, n := range ids {
+		if n == id {
+			return true
+		}
+	}
+	return false
+}
+
+func execute(t *testing.T, req Request) (string, *Result) {
+	t.Helper()
+	result := Execute(context.Background(), testSchema(), req)
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshal result: %v", err)
+	}
+	return string(b), result
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "field order follows the selection",
+			req:  Request{Query: `{ stores { storeName id } }`},
+			want: `{"data":{"stores":[{"storeName":"安南店","id":1},{"storeName":"永康店","id":2}]}}`,
+		},
+		{
+			name: "aliases, arguments and __typename",
+			req:  Request{Query: `{ a: store(id: 2) { __typename name: storeName region } b: store(id: 9) { id } }`},
+			want: `{"data":{"a":{"__typename":"Store","name":"永康店","region":null},"b":null}}`,
+		},
+		{
+			name: "variables with defaults and a single value coerced to a list",
+			req: Request{
+				Query:     `query Q($ids: [Int], $limit: Int = 5) { stores(ids: $ids, limit: $limit) { id } }`,
+				Variables: map[string]interface{}{"ids": float64(2)},
+			},
+			want: `{"data":{"stores":[{"id":2}]}}`,
+		},
+		{
+			name: "fragments merge sub-selections of the same key",
+			req: Request{Query: `
+				{ stores(limit: 1) { ...A ... on Store { shipments { quantity } } shipments { store { id } } } }
+				fragment A on Store { id }`},
+			want: `{"data":{"stores":[{"id":1,"shipments":[{"quantity":"3","store":{"id":1}},{"quantity":"5","store":{"id":1}}]}]}}`,
+		},
+		{
+			name: "nil list becomes an empty list",
+			req:  Request{Query: `{ store(id: 2) { shipments { quantity } } }`},
+			want: `{"data":{"store":{"shipments":[]}}}`,
+		},
+		{
+			name: "scalar coercion",
+			req:  Request{Query: `{ echo(s: "x", n: 3, f: 2, b: true, id: 7) }`},
+			want: `{"data":{"echo":"{\"b\":true,\"f\":2,\"id\":\"7\",\"n\":3,\"s\":\"x\"}"}}`,
+		},
+		{
+			name: "operationName picks one of several operations",
+			req:  Request{Query: `query A { store(id: 1) { id } } query B { store(id: 2) { id } }`, OperationName: "B"},
+			want: `{"data":{"store":{"id":2}}}`,
+		},
+		{
+			name: "resolver errors null the field and keep the rest",
+			req:  Request{Query: `{ broken store(id: 1) { id } }`},
+			want: `{"data":{"broken":null,"store":{"id":1}},"errors":[{"message":"database is down","path":["broken"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := execute(t, tt.req)
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"syntax error", Request{Query: `{ stores { id }`}, "syntax error"},
+		{"multiple operations without a name", Request{Query: `query A { echo } query B { echo }`}, "operationName is required"},
+		{"unknown operation", Request{Query: `query A { echo }`, OperationName: "C"}, `unknown operation "C"`},
+		{"missing required variable", Request{Query: `query($id: Int!) { store(id: $id) { id } }`}, "variable $id of type Int! is required"},
+		{"variable of the wrong type", Request{Query: `query($id: Int!) { store(id: $id) { id } }`, Variables: map[string]interface{}{"id": "x"}}, "variable $id: expected Int"},
+		{"unknown field", Request{Query: `{ stores { phone } }`}, `unknown field "phone" on type Store`},
+		{"unknown argument", Request{Query: `{ stores(region: "x") { id } }`}, `unknown argument "region"`},
+		{"duplicate argument", Request{Query: `{ stores(limit: 1, limit: 2) { id } }`}, `duplicate argument "limit"`},
+		{"missing required argument", Request{Query: `{ store { id } }`}, `argument "id" of type Int! is required`},
+		{"argument of the wrong type", Request{Query: `{ store(id: "1") { id } }`}, `argument "id": expected Int`},
+		{"non-integer Int", Request{Query: `{ echo(n: 1.5) }`}, `argument "n": expected Int`},
+		{"input objects", Request{Query: `{ echo(s: {a: 1}) }`}, "input objects are not supported"},
+		{"enum values", Request{Query: `{ echo(s: OPEN) }`}, "unexpected enum value OPEN"},
+		{"scalar with a selection", Request{Query: `{ echo { id } }`}, "must not have a selection"},
+		{"object without a selection", Request{Query: `{ stores }`}, "must have a selection of subfields"},
+		{"__typename with a selection", Request{Query: `{ __typename { id } }`}, "field __typename must not have a selection"},
+		{"unknown fragment", Request{Query: `{ stores { ...X } }`}, `unknown fragment "X"`},
+		{"fragment on the wrong type", Request{Query: `{ stores { ...S } } fragment S on Shipment { quantity }`}, "cannot be spread on Store"},
+		{"fragment on an unknown type", Request{Query: `{ stores { ...S } } fragment S on Nope { id }`}, `unknown type "Nope"`},
+		{"inline fragment on an unknown type", Request{Query: `{ stores { ... on Nope { id } } }`}, `unknown type "Nope"`},
+		{"self-referencing fragment", Request{Query: `{ stores { ...S } } fragment S on Store { id ...S }`}, `fragment "S" spreads itself`},
+		{"nested too deeply", Request{Query: `{ stores { shipments { store { shipments { quantity } } } } }`}, "nested too deeply (max depth 4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, result := execute(t, tt.req)
+			if result.Data != nil {
+				t.Errorf("data = %v, want nil", result.Data)
+			}
+			if len(result.Errors) == 0 {
+				t.Fatalf("no errors, want %q", tt.want)
+			}
+			if msg := result.Errors[0].Message; !strings.Contains(msg, tt.want) {
+				t.Errorf("error = %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteValidationErrorPath(t *testing.T) {
+	_, result := execute(t, Request{Query: `{ list: stores { id phone } }`})
+	if len(result.Errors) != 1 {
+		t.Fatalf("errors = %v, want 1", result.Errors)
+	}
+	path, _ := json.Marshal(result.Errors[0].Path)
+	if string(path) != `["list","phone"]` {
+		t.Errorf("path = %s, want [\"list\",\"phone\"]", path)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	args := Args{"s": "x", "n": 3, "b": false, "ids": []interface{}{1, nil, 2}, "null": nil}
+	if args.String("s") != "x" || args.String("n") != "" {
+		t.Errorf("String: %q %q", args.String("s"), args.String("n"))
+	}
+	if args.Int("n", 0) != 3 || args.Int("missing", 7) != 7 || args.Int("null", 7) != 7 {
+		t.Errorf("Int: %d %d %d", args.Int("n", 0), args.Int("missing", 7), args.Int("null", 7))
+	}
+	if b := args.Bool("b"); b == nil || *b {
+		t.Errorf("Bool(b) = %v, want false", b)
+	}
+	if args.Bool("missing") != nil {
+		t.Errorf("Bool(missing) should be nil")
+	}
+	if ids := args.Ints("ids"); len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Ints = %v, want [1 2]", ids)
+	}
+	if !args.Has("null") || args.Has("missing") {
+		t.Errorf("Has: null=%v missing=%v", args.Has("null"), args.Has("missing"))
+	}
+}
@@ -0,0 +1,460 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document 解析後的查詢文件
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation 一個 query（只支援 query，mutation / subscription 會在解析時回傳錯誤）
+type Operation struct {
+	Name       string
+	Variables  []VariableDefinition
+	Selections []Selection
+}
+
+// VariableDefinition 變數宣告，例如 ($limit: Int = 20)
+type VariableDefinition struct {
+	Name     string
+	Type     string // 原始型別字串，例如 Int、[Int!]!
+	Default  Value
+	NonNull  bool
+	Position int
+}
+
+// Fragment 具名 fragment
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+// Selection 欄位、fragment spread 或 inline fragment 其中之一
+type Selection interface{ selection() }
+
+// Field 選取的欄位
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  []Argument
+	Selections []Selection
+	Position   int
+}
+
+// FragmentSpread ...Name
+type FragmentSpread struct {
+	Name     string
+	Position int
+}
+
+// InlineFragment ... on Type { ... }
+type InlineFragment struct {
+	TypeCondition string
+	Selections    []Selection
+}
+
+func (*Field) selection()          {}
+func (*FragmentSpread) selection() {}
+func (*InlineFragment) selection() {}
+
+// ResponseKey 回應中使用的鍵（有別名時為別名）
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Argument 欄位參數
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Value 參數值：Variable、[]Value（list）、[]Argument（object）或 Go 的 int / float64 / string / bool / nil；
+// enum 以 EnumValue 表示
+type Value interface{}
+
+// Variable $name
+type Variable string
+
+// EnumValue 不加引號的列舉值
+type EnumValue string
+
+// SyntaxError 查詢語法錯誤
+type SyntaxError struct {
+	Message  string
+	Position int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Position, e.Message)
+}
+
+// 詞彙類型
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// Parse 解析查詢文件
+func Parse(src string) (doc *Document, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, se
+		}
+	}()
+	p.next()
+
+	doc = &Document{Fragments: map[string]*Fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			doc.Operations = append(doc.Operations, &Operation{Selections: p.parseSelectionSet()})
+		case p.peek(tokName, "query"):
+			p.next()
+			op := &Operation{}
+			if p.tok.kind == tokName {
+				op.Name = p.expectName()
+			}
+			if p.peek(tokPunct, "(") {
+				op.Variables = p.parseVariableDefinitions()
+			}
+			op.Selections = p.parseSelectionSet()
+			doc.Operations = append(doc.Operations, op)
+		case p.peek(tokName, "fragment"):
+			p.next()
+			f := &Fragment{Name: p.expectName()}
+			p.expectKeyword("on")
+			f.TypeCondition = p.expectName()
+			f.Selections = p.parseSelectionSet()
+			if _, dup := doc.Fragments[f.Name]; dup {
+				p.fail("duplicate fragment " + f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		case p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			p.fail(p.tok.value + " is not supported")
+		default:
+			p.fail("unexpected " + p.describe())
+		}
+	}
+	if len(doc.Operations) == 0 {
+		p.fail("no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseVariableDefinitions() []VariableDefinition {
+	p.expect("(")
+	var defs []VariableDefinition
+	for !p.peek(tokPunct, ")") {
+		def := VariableDefinition{Position: p.tok.pos}
+		p.expect("$")
+		def.Name = p.expectName()
+		p.expect(":")
+		def.Type, def.NonNull = p.parseType()
+		if p.peek(tokPunct, "=") {
+			p.next()
+			def.Default = p.parseValue(true)
+		}
+		defs = append(defs, def)
+	}
+	p.next()
+	return defs
+}
+
+// parseType 回傳型別字串與最外層是否為 non-null
+func (p *parser) parseType() (string, bool) {
+	var t string
+	if p.peek(tokPunct, "[") {
+		p.next()
+		inner, _ := p.parseType()
+		p.expect("]")
+		t = "[" + inner + "]"
+	} else {
+		t = p.expectName()
+	}
+	if p.peek(tokPunct, "!") {
+		p.next()
+		return t + "!", true
+	}
+	return t, false
+}
+
+func (p *parser) parseSelectionSet() []Selection {
+	p.expect("{")
+	var selections []Selection
+	for !p.peek(tokPunct, "}") {
+		if p.tok.kind == tokEOF {
+			p.fail("unterminated selection set")
+		}
+		selections = append(selections, p.parseSelection())
+	}
+	p.next()
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() Selection {
+	if p.peek(tokPunct, "...") {
+		pos := p.tok.pos
+		p.next()
+		if p.peek(tokName, "on") {
+			p.next()
+			return &InlineFragment{TypeCondition: p.expectName(), Selections: p.parseSelectionSet()}
+		}
+		if p.peek(tokPunct, "{") {
+			return &InlineFragment{Selections: p.parseSelectionSet()}
+		}
+		return &FragmentSpread{Name: p.expectName(), Position: pos}
+	}
+
+	field := &Field{Position: p.tok.pos, Name: p.expectName()}
+	if p.peek(tokPunct, ":") {
+		p.next()
+		field.Alias, field.Name = field.Name, p.expectName()
+	}
+	if p.peek(tokPunct, "(") {
+		p.next()
+		for !p.peek(tokPunct, ")") {
+			name := p.expectName()
+			p.expect(":")
+			field.Arguments = append(field.Arguments, Argument{Name: name, Value: p.parseValue(false)})
+		}
+		p.next()
+	}
+	if p.peek(tokPunct, "@") {
+		p.fail("directives are not supported")
+	}
+	if p.peek(tokPunct, "{") {
+		field.Selections = p.parseSelectionSet()
+	}
+	return field
+}
+
+// parseValue 解析參數值；const 為 true 時（變數預設值）不允許使用變數
+func (p *parser) parseValue(constant bool) Value {
+	t := p.tok
+	switch t.kind {
+	case tokPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				p.fail("variable not allowed in default value")
+			}
+			p.next()
+			return Variable(p.expectName())
+		case "[":
+			p.next()
+			list := []Value{}
+			for !p.peek(tokPunct, "]") {
+				if p.tok.kind == tokEOF {
+					p.fail("unterminated list")
+				}
+				list = append(list, p.parseValue(constant))
+			}
+			p.next()
+			return list
+		case "{":
+			p.next()
+			obj := []Argument{}
+			for !p.peek(tokPunct, "}") {
+				name := p.expectName()
+				p.expect(":")
+				obj = append(obj, Argument{Name: name, Value: p.parseValue(constant)})
+			}
+			p.next()
+			return obj
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			p.failAt(t.pos, "invalid integer "+t.value)
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			p.failAt(t.pos, "invalid number "+t.value)
+		}
+		return f
+	case tokString:
+		p.next()
+		return t.value
+	case tokName:
+		p.next()
+		switch t.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return EnumValue(t.value)
+	}
+	p.fail("unexpected " + p.describe())
+	return nil
+}
+
+func (p *parser) peek(kind int, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) expect(punct string) {
+	if !p.peek(tokPunct, punct) {
+		p.fail(fmt.Sprintf("expected %q, got %s", punct, p.describe()))
+	}
+	p.next()
+}
+
+func (p *parser) expectKeyword(name string) {
+	if !p.peek(tokName, name) {
+		p.fail(fmt.Sprintf("expected %q, got %s", name, p.describe()))
+	}
+	p.next()
+}
+
+func (p *parser) expectName() string {
+	if p.tok.kind != tokName {
+		p.fail("expected name, got " + p.describe())
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) describe() string {
+	switch p.tok.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return "string"
+	}
+	return strconv.Quote(p.tok.value)
+}
+
+func (p *parser) fail(msg string) {
+	p.failAt(p.tok.pos, msg)
+}
+
+func (p *parser) failAt(pos int, msg string) {
+	panic(&SyntaxError{Message: msg, Position: pos})
+}
+
+// next 讀取下一個詞彙（略過空白、逗號與 # 註解）
+func (p *parser) next() {
+	src := p.src
+	for p.pos < len(src) {
+		c := src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if c == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := p.pos
+	if p.pos >= len(src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := src[p.pos]
+	switch {
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokPunct, value: "...", pos: start}
+	case strings.IndexByte("{}():$![]=@", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokPunct, value: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(src) && (src[p.pos] == '_' || isLetter(src[p.pos]) || isDigit(src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, value: src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		p.pos++
+		kind := tokInt
+		for p.pos < len(src) {
+			d := src[p.pos]
+			if isDigit(d) {
+				p.pos++
+			} else if d == '.' || d == 'e' || d == 'E' || ((d == '+' || d == '-') && (src[p.pos-1] == 'e' || src[p.pos-1] == 'E')) {
+				kind = tokFloat
+				p.pos++
+			} else {
+				break
+			}
+		}
+		p.tok = token{kind: kind, value: src[start:p.pos], pos: start}
+	case c == '"':
+		p.tok = token{kind: tokString, value: p.readString(), pos: start}
+	default:
+		p.failAt(start, fmt.Sprintf("unexpected character %q", c))
+	}
+}
+
+// readString 讀取雙引號字串（支援 JSON 的跳脫字元；不支援 """ 區塊字串）
+func (p *parser) readString() string {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			p.failAt(start, "unterminated string")
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(p.src[start:p.pos])
+			if err != nil {
+				p.failAt(start, "invalid string")
+			}
+			return s
+		}
+		p.pos++
+	}
+	p.failAt(start, "unterminated string")
+	return ""
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
@@ -0,0 +1,158 @@
+package graphql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOperation(t *testing.T) {
+	doc, err := Parse(`
+		# 店家與出貨
+		query Stores($limit: Int = 20, $ids: [Int!]!) {
+			first: stores(ids: $ids, limit: $limit, q: "全聯", active: true, ratio: 1.5, none: null, tag: OPEN) {
+				id
+				...storeFields
+				... on Store { region }
+				... { placeId }
+			}
+		}
+		fragment storeFields on Store { storeName }
+	`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(doc.Operations) != 1 {
+		t.Fatalf("operations = %d, want 1", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Name != "Stores" {
+		t.Errorf("operation name = %q, want Stores", op.Name)
+	}
+
+	wantVars := []VariableDefinition{
+		{Name: "limit", Type: "Int", Default: 20},
+		{Name: "ids", Type: "[Int!]!", NonNull: true},
+	}
+	if len(op.Variables) != len(wantVars) {
+		t.Fatalf("variables = %d, want %d", len(op.Variables), len(wantVars))
+	}
+	for i, want := range wantVars {
+		got := op.Variables[i]
+		got.Position = 0
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("variable %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	field := op.Selections[0].(*Field)
+	if field.Alias != "first" || field.Name != "stores" || field.ResponseKey() != "first" {
+		t.Errorf("field alias/name = %q/%q", field.Alias, field.Name)
+	}
+	wantArgs := []Argument{
+		{Name: "ids", Value: Variable("ids")},
+		{Name: "limit", Value: Variable("limit")},
+		{Name: "q", Value: "全聯"},
+		{Name: "active", Value: true},
+		{Name: "ratio", Value: 1.5},
+		{Name: "none", Value: nil},
+		{Name: "tag", Value: EnumValue("OPEN")},
+	}
+	if !reflect.DeepEqual(field.Arguments, wantArgs) {
+		t.Errorf("arguments = %#v, want %#v", field.Arguments, wantArgs)
+	}
+
+	if len(field.Selections) != 4 {
+		t.Fatalf("selections = %d, want 4", len(field.Selections))
+	}
+	if spread, ok := field.Selections[1].(*FragmentSpread); !ok || spread.Name != "storeFields" {
+		t.Errorf("selection 1 = %#v, want spread storeFields", field.Selections[1])
+	}
+	if inline, ok := field.Selections[2].(*InlineFragment); !ok || inline.TypeCondition != "Store" {
+		t.Errorf("selection 2 = %#v, want inline fragment on Store", field.Selections[2])
+	}
+	if inline, ok := field.Selections[3].(*InlineFragment); !ok || inline.TypeCondition != "" {
+		t.Errorf("selection 3 = %#v, want inline fragment without type", field.Selections[3])
+	}
+
+	f := doc.Fragments["storeFields"]
+	if f == nil || f.TypeCondition != "Store" || f.Selections[0].(*Field).Name != "storeName" {
+		t.Errorf("fragment storeFields = %#v", f)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Value
+	}{
+		{`-12`, -12},
+		{`3.25`, 3.25},
+		{`1e3`, 1000.0},
+		{`"a\"b\n"`, "a\"b\n"},
+		{`false`, false},
+		{`[1, 2 3]`, []Value{1, 2, 3}},
+		{`[]`, []Value{}},
+		{`{a: 1, b: "x"}`, []Argument{{Name: "a", Value: 1}, {Name: "b", Value: "x"}}},
+	}
+	for _, tt := range tests {
+		doc, err := Parse("{ f(v: " + tt.src + ") }")
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tt.src, err)
+			continue
+		}
+		got := doc.Operations[0].Selections[0].(*Field).Arguments[0].Value
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%s) value = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{``, "no operation"},
+		{`{ }`, "empty selection set"},
+		{`{ stores`, "unterminated selection set"},
+		{`{ stores(limit: 1 }`, "expected name"},
+		{`mutation { x }`, "mutation is not supported"},
+		{`subscription { x }`, "subscription is not supported"},
+		{`{ stores @include(if: true) }`, "directives are not supported"},
+		{`query($a: Int = $b) { x }`, "variable not allowed in default value"},
+		{`{ f(v: [1, 2) }`, "unexpected"},
+		{`{ f(v: "abc) }`, "unterminated string"},
+		{`{ f(v: 99999999999999999999) }`, "invalid integer"},
+		{`{ a } fragment F on T { a } fragment F on T { b }`, "duplicate fragment F"},
+		{`fragment F T { a }`, `expected "on"`},
+		{`{ a % }`, "unexpected character"},
+		{`{ a } garbage`, "unexpected"},
+	}
+	for _, tt := range tests {
+		doc, err := Parse(tt.src)
+		if err == nil {
+			t.Errorf("Parse(%q) = %#v, want error containing %q", tt.src, doc, tt.want)
+			continue
+		}
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Parse(%q) error %T, want *SyntaxError", tt.src, err)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	_, err := Parse("{ a\n  % }")
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("error = %v, want *SyntaxError", err)
+	}
+	if se.Position != 6 {
+		t.Errorf("position = %d, want 6", se.Position)
+	}
+}
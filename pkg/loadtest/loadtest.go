@@ -0,0 +1,285 @@
+// Package loadtest 以固定速率（open loop）對執行中的服務送出主要讀取端點的請求，
+// 並依端點統計延遲百分位數，用來在流量高峰前驗證快取與連線池設定
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 壓力測試參數
+type Options struct {
+	Target   string        // 服務位址（含 BASE_PATH），例如 http://localhost:8080
+	RPS      int           // 每秒請求數
+	Duration time.Duration // 測試時間
+	APIKey   string        // 設定時帶 X-API-Key
+	Timeout  time.Duration // 單一請求逾時
+	// MaxInFlight 同時進行中的請求上限，超過時略過該次請求並計入 Dropped（服務明顯跟不上時避免無限累積）
+	MaxInFlight int
+}
+
+// EndpointStats 單一端點的統計
+type EndpointStats struct {
+	Name     string
+	Requests int
+	Errors   int // 連線錯誤、逾時或 5xx
+	Status   map[int]int
+	P50      time.Duration
+	P90      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Report 測試結果
+type Report struct {
+	Elapsed   time.Duration
+	Requests  int
+	Errors    int
+	Dropped   int
+	Endpoints []EndpointStats
+	Overall   EndpointStats
+}
+
+// AchievedRPS 實際達到的每秒請求數
+func (r *Report) AchievedRPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+type result struct {
+	name     string
+	status   int
+	err      error
+	duration time.Duration
+}
+
+// Run 依 opts 執行壓力測試，ctx 取消時提前結束並回傳目前的結果
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if opts.RPS <= 0 || opts.RPS > 100000 {
+		return nil, fmt.Errorf("rps must be between 1 and 100000")
+	}
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 1000
+	}
+	target := strings.TrimSuffix(opts.Target, "/")
+
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.MaxInFlight,
+			MaxIdleConnsPerHost: opts.MaxInFlight,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		results  []result
+		dropped  int
+		st       = runState{products: fetchProducts(client, target, opts.APIKey)}
+		inFlight = make(chan struct{}, opts.MaxInFlight)
+		r        = rand.New(rand.NewSource(time.Now().UnixNano()))
+	)
+
+	ticker := time.NewTicker(time.Second / time.Duration(opts.RPS))
+	defer ticker.Stop()
+
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		name, path := pickScenario(r, &st)
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			dropped++
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			res := doRequest(client, target+path, opts.APIKey, name, &st.ids)
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		}()
+	}
+	// 已送出的請求不受測試時間限制，等它們完成或逾時
+	wg.Wait()
+
+	report := buildReport(results, time.Since(start))
+	report.Dropped = dropped
+	return report, nil
+}
+
+// fetchProducts 取得服務中有出貨的產品（附近店家請求的產品篩選），失敗時回傳空清單（不指定產品）
+func fetchProducts(client *http.Client, target, apiKey string) []string {
+	req, err := http.NewRequest(http.MethodGet, target+"/api/v1/products", nil)
+	if err != nil {
+		return nil
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var body []struct {
+		ProductType string `json:"productType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil
+	}
+	products := make([]string, len(body))
+	for i, p := range body {
+		products[i] = p.ProductType
+	}
+	return products
+}
+
+// doRequest 送出一個 GET 請求並讀完回應內容（計入下載時間）；附近店家的回應會收集店家 ID
+func doRequest(client *http.Client, url, apiKey, name string, ids *storeIDs) result {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return result{name: name, err: err}
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{name: name, err: err, duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+
+	if name == "stores/nearby" && resp.StatusCode == http.StatusOK {
+		var body struct {
+			Data []struct {
+				StoreID int `json:"storeId"`
+			} `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		found := make([]int, 0, len(body.Data))
+		for _, s := range body.Data {
+			found = append(found, s.StoreID)
+		}
+		ids.add(found)
+	}
+	if _, copyErr := io.Copy(io.Discard, resp.Body); err == nil {
+		err = copyErr
+	}
+	return result{name: name, status: resp.StatusCode, err: err, duration: time.Since(start)}
+}
+
+// buildReport 依端點彙整結果，端點依請求數排序
+func buildReport(results []result, elapsed time.Duration) *Report {
+	byName := make(map[string][]result)
+	for _, res := range results {
+		byName[res.name] = append(byName[res.name], res)
+	}
+
+	report := &Report{Elapsed: elapsed, Requests: len(results)}
+	for name, rs := range byName {
+		report.Endpoints = append(report.Endpoints, summarize(name, rs))
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		if report.Endpoints[i].Requests != report.Endpoints[j].Requests {
+			return report.Endpoints[i].Requests > report.Endpoints[j].Requests
+		}
+		return report.Endpoints[i].Name < report.Endpoints[j].Name
+	})
+	report.Overall = summarize("all", results)
+	report.Errors = report.Overall.Errors
+	return report
+}
+
+func summarize(name string, results []result) EndpointStats {
+	stats := EndpointStats{Name: name, Requests: len(results), Status: make(map[int]int)}
+	durations := make([]time.Duration, 0, len(results))
+	for _, res := range results {
+		if res.err != nil || res.status >= 500 {
+			stats.Errors++
+		}
+		if res.err == nil {
+			stats.Status[res.status]++
+		}
+		durations = append(durations, res.duration)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.P50 = percentile(durations, 50)
+	stats.P90 = percentile(durations, 90)
+	stats.P95 = percentile(durations, 95)
+	stats.P99 = percentile(durations, 99)
+	if len(durations) > 0 {
+		stats.Max = durations[len(durations)-1]
+	}
+	return stats
+}
+
+// percentile 已排序資料的第 p 百分位數（nearest-rank）
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String 一行的端點摘要，例如 shopeMap n=1200 err=0 p50=12ms p90=30ms p95=41ms p99=95ms max=210ms [200:1180 304:20]
+func (s EndpointStats) String() string {
+	codes := make([]int, 0, len(s.Status))
+	for code := range s.Status {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d:%d", code, s.Status[code])
+	}
+	round := func(d time.Duration) string { return d.Round(100 * time.Microsecond).String() }
+	return fmt.Sprintf("%-20s n=%-6d err=%-4d p50=%-8s p90=%-8s p95=%-8s p99=%-8s max=%-8s [%s]",
+		s.Name, s.Requests, s.Errors, round(s.P50), round(s.P90), round(s.P95), round(s.P99), round(s.Max), strings.Join(parts, " "))
+}
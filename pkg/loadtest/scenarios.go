@@ -0,0 +1,169 @@
+package loadtest
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// scenario 一種請求；build 產生路徑與查詢字串，name 用於依端點彙整延遲
+type scenario struct {
+	name   string
+	weight int
+	build  func(r *rand.Rand, st *runState) string
+}
+
+// city 地圖使用者常見的查詢中心（權重約略依人口）
+type city struct {
+	lat, lng float64
+	weight   int
+}
+
+var cities = []city{
+	{25.0330, 121.5654, 12}, // 台北
+	{25.0120, 121.4657, 16}, // 新北
+	{24.9936, 121.3010, 9},  // 桃園
+	{24.1477, 120.6736, 12}, // 台中
+	{22.9999, 120.2270, 8},  // 台南
+	{22.6273, 120.3014, 12}, // 高雄
+	{24.8138, 120.9675, 4},  // 新竹
+	{23.4801, 120.4491, 3},  // 嘉義
+	{24.7021, 121.7378, 2},  // 宜蘭
+	{23.9872, 121.6016, 2},  // 花蓮
+}
+
+// scenarios 主要的讀取端點；大部分流量是開啟地圖（預設近 N 天）與拖曳地圖（bbox）
+var scenarios = []scenario{
+	{name: "shopeMap", weight: 30, build: func(r *rand.Rand, _ *runState) string {
+		return "/api/v1/shopeMap"
+	}},
+	{name: "shopeMap?bbox", weight: 20, build: func(r *rand.Rand, _ *runState) string {
+		lat, lng := pickCity(r)
+		// 手機畫面大約 0.05 ~ 0.2 度的範圍
+		span := 0.05 + r.Float64()*0.15
+		q := url.Values{}
+		q.Set("bbox", fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", lng-span, lat-span, lng+span, lat+span))
+		return "/api/v1/shopeMap?" + q.Encode()
+	}},
+	{name: "shopeMap?from&to", weight: 10, build: func(r *rand.Rand, _ *runState) string {
+		today := time.Now()
+		from := today.AddDate(0, 0, -r.Intn(14))
+		to := from.AddDate(0, 0, r.Intn(7))
+		if to.After(today) {
+			to = today
+		}
+		q := url.Values{}
+		q.Set("from", from.Format("2006-01-02"))
+		q.Set("to", to.Format("2006-01-02"))
+		return "/api/v1/shopeMap?" + q.Encode()
+	}},
+	{name: "shopeMap.geojson", weight: 10, build: func(r *rand.Rand, _ *runState) string {
+		return "/api/v1/shopeMap.geojson"
+	}},
+	{name: "stores/nearby", weight: 15, build: nearbyPath},
+	{name: "stores/:id/calendar", weight: 5, build: func(r *rand.Rand, st *runState) string {
+		id, ok := st.ids.pick(r)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("/api/v1/stores/%d/calendar", id)
+	}},
+	{name: "regions", weight: 5, build: func(r *rand.Rand, _ *runState) string {
+		return "/api/v1/regions"
+	}},
+	{name: "syncStatus", weight: 5, build: func(r *rand.Rand, _ *runState) string {
+		return "/api/v1/syncStatus"
+	}},
+}
+
+// pickCity 依權重選一個城市，並在約 5 公里內隨機偏移
+func pickCity(r *rand.Rand) (float64, float64) {
+	total := 0
+	for _, c := range cities {
+		total += c.weight
+	}
+	n := r.Intn(total)
+	for _, c := range cities {
+		if n < c.weight {
+			return c.lat + (r.Float64()-0.5)*0.1, c.lng + (r.Float64()-0.5)*0.1
+		}
+		n -= c.weight
+	}
+	return cities[0].lat, cities[0].lng
+}
+
+// pickScenario 依權重選一種請求；店家日曆在還沒取得店家 ID 前改用附近店家
+func pickScenario(r *rand.Rand, st *runState) (string, string) {
+	total := 0
+	for _, s := range scenarios {
+		total += s.weight
+	}
+	n := r.Intn(total)
+	for _, s := range scenarios {
+		if n < s.weight {
+			if path := s.build(r, st); path != "" {
+				return s.name, path
+			}
+			break
+		}
+		n -= s.weight
+	}
+	return "stores/nearby", nearbyPath(r, st)
+}
+
+// nearbyPath 在城市附近查詢店家，三分之一的請求指定產品（服務有產品時）
+func nearbyPath(r *rand.Rand, st *runState) string {
+	lat, lng := pickCity(r)
+	q := url.Values{}
+	q.Set("lat", fmt.Sprintf("%.5f", lat))
+	q.Set("lng", fmt.Sprintf("%.5f", lng))
+	q.Set("radius", fmt.Sprint([]int{3, 5, 5, 10, 20}[r.Intn(5)]))
+	if r.Intn(3) == 0 && len(st.products) > 0 {
+		q.Set("product", st.products[r.Intn(len(st.products))])
+	}
+	return "/api/v1/stores/nearby?" + q.Encode()
+}
+
+// maxStoreIDs 保留的店家 ID 數量上限
+const maxStoreIDs = 1000
+
+// runState 組成請求時使用的資料：開始前取得的產品與執行中收集的店家 ID
+type runState struct {
+	products []string // 服務中有出貨的產品（/api/v1/products）
+	ids      storeIDs
+}
+
+// storeIDs 從附近店家回應收集的店家 ID，供店家日曆請求使用
+type storeIDs struct {
+	mu  sync.Mutex
+	ids []int
+	set map[int]bool
+}
+
+func (s *storeIDs) add(ids []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.set == nil {
+		s.set = make(map[int]bool)
+	}
+	for _, id := range ids {
+		if len(s.ids) >= maxStoreIDs {
+			return
+		}
+		if !s.set[id] {
+			s.set[id] = true
+			s.ids = append(s.ids, id)
+		}
+	}
+}
+
+func (s *storeIDs) pick(r *rand.Rand) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ids) == 0 {
+		return 0, false
+	}
+	return s.ids[r.Intn(len(s.ids))], true
+}
@@ -0,0 +1,96 @@
+// Package metrics 以 Prometheus 文字格式輸出程序內的指標（不依賴 client_golang，只支援 gauge 與 counter）
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Labels 指標標籤
+type Labels map[string]string
+
+type family struct {
+	help   string
+	kind   string // gauge / counter
+	values map[string]float64
+}
+
+var (
+	mu       sync.Mutex
+	families = make(map[string]*family)
+)
+
+// SetGauge 設定 gauge 的值
+func SetGauge(name, help string, labels Labels, value float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	getFamily(name, help, "gauge").values[labelString(labels)] = value
+}
+
+// AddCounter 將 counter 加上 delta
+func AddCounter(name, help string, labels Labels, delta float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	getFamily(name, help, "counter").values[labelString(labels)] += delta
+}
+
+func getFamily(name, help, kind string) *family {
+	f, ok := families[name]
+	if !ok {
+		f = &family{help: help, kind: kind, values: make(map[string]float64)}
+		families[name] = f
+	}
+	return f
+}
+
+// labelString 依標籤名稱排序輸出 {a="1",b="2"}，沒有標籤時為空字串
+func labelString(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		parts[i] = fmt.Sprintf(`%s="%s"`, k, v)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// WriteText 以 Prometheus 文字格式（text/plain; version=0.0.4）輸出所有指標
+func WriteText(w io.Writer) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := families[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind); err != nil {
+			return err
+		}
+		series := make([]string, 0, len(f.values))
+		for labels := range f.values {
+			series = append(series, labels)
+		}
+		sort.Strings(series)
+		for _, labels := range series {
+			if _, err := fmt.Fprintf(w, "%s%s %g\n", name, labels, f.values[labels]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
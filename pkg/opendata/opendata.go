@@ -0,0 +1,119 @@
+package opendata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/storage"
+)
+
+const (
+	// Days 開放資料涵蓋的天數
+	Days = 30
+	// MinStores 每個區域/產品/日期至少要有幾個店家才列出
+	MinStores = 3
+	// LatestFile 最新一份開放資料的檔名
+	LatestFile = "latest.json"
+)
+
+// fileNamePattern 允許對外提供的檔名
+var fileNamePattern = regexp.MustCompile(`^(shipments-\d{4}-\d{2}-\d{2}|latest)\.json$`)
+
+// Dump 開放資料檔案內容
+type Dump struct {
+	GeneratedAt time.Time                    `json:"generatedAt"`
+	From        string                       `json:"from"`
+	To          string                       `json:"to"`
+	MinStores   int                          `json:"minStores"`
+	Suppressed  int                          `json:"suppressed"` // 店家數不足而略去的組合數
+	Data        []database.DistrictAggregate `json:"data"`
+}
+
+// Key 開放資料檔在 BLOB_STORE 中的 key
+func Key(name string) string {
+	return "opendata/" + name
+}
+
+// Settings 開放資料設定（由 config.Config 轉換，啟動時以 Configure 設定）
+type Settings struct {
+	HiddenProducts []string // 尚未公開的產品（HIDDEN_PRODUCTS），不列入開放資料
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   Settings
+)
+
+// Configure 設定 Generate 使用的設定
+func Configure(s Settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = s
+}
+
+// hiddenProducts 尚未公開的產品，不列入開放資料
+func hiddenProducts() map[string]bool {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	hidden := make(map[string]bool)
+	for _, p := range settings.HiddenProducts {
+		hidden[p] = true
+	}
+	return hidden
+}
+
+// FileName 某天產生的開放資料檔名
+func FileName(day time.Time) string {
+	return fmt.Sprintf("shipments-%s.json", day.Format("2006-01-02"))
+}
+
+// IsValidFileName 檢查請求的檔名（避免讀取目錄外的檔案）
+func IsValidFileName(name string) bool {
+	return fileNamePattern.MatchString(name)
+}
+
+// Generate 產生當天的開放資料檔保存到 blobs，並更新 latest.json
+func Generate(db *sql.DB, blobs storage.BlobStore, now time.Time) (string, error) {
+	data, suppressed, err := database.GetDistrictAggregates(db, Days, MinStores)
+	if err != nil {
+		return "", err
+	}
+	if hidden := hiddenProducts(); len(hidden) > 0 {
+		visible := data[:0]
+		for _, d := range data {
+			if !hidden[d.ProductType] {
+				visible = append(visible, d)
+			}
+		}
+		data = visible
+	}
+
+	dump := Dump{
+		GeneratedAt: now,
+		From:        now.AddDate(0, 0, -Days).Format("2006-01-02"),
+		To:          now.Format("2006-01-02"),
+		MinStores:   MinStores,
+		Suppressed:  suppressed,
+		Data:        data,
+	}
+	body, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := FileName(now)
+	for _, file := range []string{name, LatestFile} {
+		if err := blobs.Put(Key(file), body, "application/json"); err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("[INFO] 已產生開放資料 %s（%d 筆，略去 %d 筆）", name, len(data), suppressed)
+	return name, nil
+}
@@ -0,0 +1,260 @@
+package rpc
+
+import (
+	"errors"
+	"math"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 訊息的編碼與解碼，欄位編號對應 proto/pxmark/v1/map.proto；
+// 依 proto3 規則，值為預設值（0、空字串、false）的欄位不寫出
+
+var errMalformed = errors.New("malformed message")
+
+// listStoresRequest ListStoresRequest
+type listStoresRequest struct {
+	IDs    []int
+	Query  string
+	Region string
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// getStoreRequest GetStoreRequest
+type getStoreRequest struct {
+	ID int
+}
+
+// listShipmentsRequest ListShipmentsRequest
+type listShipmentsRequest struct {
+	StoreIDs      []int
+	From          string
+	To            string
+	Product       string
+	Limit         int
+	PerStoreLimit int
+}
+
+func (r *listStoresRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeInt32s(v, typ, &r.IDs)
+		case 2:
+			return consumeString(v, typ, &r.Query)
+		case 3:
+			return consumeString(v, typ, &r.Region)
+		case 4:
+			var active bool
+			n, err := consumeBool(v, typ, &active)
+			r.Active = &active
+			return n, err
+		case 5:
+			return consumeInt32(v, typ, &r.Limit)
+		case 6:
+			return consumeInt32(v, typ, &r.Offset)
+		}
+		return -1, nil
+	})
+}
+
+func (r *getStoreRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		if num == 1 {
+			return consumeInt32(v, typ, &r.ID)
+		}
+		return -1, nil
+	})
+}
+
+func (r *listShipmentsRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeInt32s(v, typ, &r.StoreIDs)
+		case 2:
+			return consumeString(v, typ, &r.From)
+		case 3:
+			return consumeString(v, typ, &r.To)
+		case 4:
+			return consumeString(v, typ, &r.Product)
+		case 5:
+			return consumeInt32(v, typ, &r.Limit)
+		case 6:
+			return consumeInt32(v, typ, &r.PerStoreLimit)
+		}
+		return -1, nil
+	})
+}
+
+// appendStore 編碼 Store
+func appendStore(b []byte, s database.StoreRecord) []byte {
+	b = appendInt32(b, 1, s.ID)
+	b = appendString(b, 2, s.StoreName)
+	b = appendString(b, 3, s.PlaceID)
+	b = appendString(b, 4, s.FormattedAddress)
+	b = appendDouble(b, 5, s.Latitude)
+	b = appendDouble(b, 6, s.Longitude)
+	b = appendString(b, 7, s.BusinessStatus)
+	b = appendBool(b, 8, s.IsActive)
+	b = appendString(b, 9, s.Region)
+	return b
+}
+
+// appendShipment 編碼 Shipment
+func appendShipment(b []byte, s database.ShipmentRecord) []byte {
+	b = appendInt32(b, 1, s.ID)
+	b = appendInt32(b, 2, s.StoreID)
+	b = appendString(b, 3, s.StoreName)
+	b = appendString(b, 4, s.ProductType)
+	b = appendString(b, 5, s.ShipmentDate)
+	b = appendString(b, 6, s.TimeSlot)
+	b = appendString(b, 7, s.Quantity)
+	b = appendString(b, 8, s.QualityFlag)
+	b = appendString(b, 9, s.SourceID)
+	return b
+}
+
+// marshalStores 編碼 ListStoresResponse
+func marshalStores(stores []database.StoreRecord) []byte {
+	var b []byte
+	for _, s := range stores {
+		b = appendMessage(b, 1, appendStore(nil, s))
+	}
+	return b
+}
+
+// marshalShipments 編碼 ListShipmentsResponse
+func marshalShipments(shipments []database.ShipmentRecord) []byte {
+	var b []byte
+	for _, s := range shipments {
+		b = appendMessage(b, 1, appendShipment(nil, s))
+	}
+	return b
+}
+
+func appendInt32(b []byte, num protowire.Number, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(int64(int32(v))))
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// decodeFields 逐一讀取欄位交給 field 處理；field 回傳 -1 表示不認得的欄位（略過，與新版用戶端相容）
+func decodeFields(b []byte, field func(num protowire.Number, typ protowire.Type, v []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errMalformed
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errMalformed
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeInt32(b []byte, typ protowire.Type, out *int) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errMalformed
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, errMalformed
+	}
+	*out = int(int32(v))
+	return n, nil
+}
+
+// consumeInt32s repeated int32，接受 packed（proto3 預設）與逐筆兩種編碼
+func consumeInt32s(b []byte, typ protowire.Type, out *[]int) (int, error) {
+	if typ == protowire.VarintType {
+		var v int
+		n, err := consumeInt32(b, typ, &v)
+		*out = append(*out, v)
+		return n, err
+	}
+	if typ != protowire.BytesType {
+		return 0, errMalformed
+	}
+	packed, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, errMalformed
+	}
+	for len(packed) > 0 {
+		v, m := protowire.ConsumeVarint(packed)
+		if m < 0 {
+			return 0, errMalformed
+		}
+		*out = append(*out, int(int32(v)))
+		packed = packed[m:]
+	}
+	return n, nil
+}
+
+func consumeString(b []byte, typ protowire.Type, out *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errMalformed
+	}
+	v, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, errMalformed
+	}
+	*out = v
+	return n, nil
+}
+
+func consumeBool(b []byte, typ protowire.Type, out *bool) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errMalformed
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, errMalformed
+	}
+	*out = v != 0
+	return n, nil
+}
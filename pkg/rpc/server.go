@@ -0,0 +1,225 @@
+// Package rpc 以 gRPC 提供店家與出貨資料給內部服務（介面定義見 proto/pxmark/v1/map.proto）；
+// 不依賴 grpc-go，直接以 HTTP/2（h2c）處理 gRPC 的訊息框架與 grpc-status 尾端標頭，只支援 unary 呼叫
+package rpc
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+// ServiceName proto 中的完整服務名稱，方法路徑為 /pxmark.v1.MapService/<方法>
+const ServiceName = "pxmark.v1.MapService"
+
+const (
+	maxRequestBytes = 64 << 10
+
+	defaultLimit       = 100
+	maxLimit           = 1000
+	maxPerStoreLimit   = 200
+	readHeaderTimeout  = 10 * time.Second
+	contentTypeGRPC    = "application/grpc"
+	trailerGRPCStatus  = "Grpc-Status"
+	trailerGRPCMessage = "Grpc-Message"
+)
+
+// gRPC 狀態碼（https://grpc.github.io/grpc/core/md_doc_statuscodes.html）
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeNotFound          = 5
+	codePermissionDenied  = 7
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+	codeUnauthenticated   = 16
+)
+
+// statusError 以指定的 gRPC 狀態碼回應的錯誤
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string { return e.message }
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// method 一個 unary 方法：解碼請求、查詢並回傳編碼後的回應
+type method func(req []byte) ([]byte, error)
+
+// Server gRPC 服務
+type Server struct {
+	db      *sql.DB
+	cfg     *config.Config
+	apiKeys []string
+	exclude []string // 尚未公開的產品
+	methods map[string]method
+}
+
+// NewServer 建立 gRPC 服務
+func NewServer(db *sql.DB, cfg *config.Config) *Server {
+	s := &Server{
+		db:      db,
+		cfg:     cfg,
+		apiKeys: parseList(cfg.APIKeys),
+		exclude: parseList(cfg.HiddenProducts),
+	}
+	s.methods = map[string]method{
+		"/" + ServiceName + "/ListStores":    s.listStores,
+		"/" + ServiceName + "/GetStore":      s.getStore,
+		"/" + ServiceName + "/ListShipments": s.listShipments,
+	}
+	return s
+}
+
+// HTTPServer 以明文 HTTP/2（prior knowledge，gRPC 用戶端的預設）監聽 addr 的 http.Server
+func (s *Server) HTTPServer(addr string) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: readHeaderTimeout}
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	return srv
+}
+
+// ServeHTTP 處理單一 gRPC 呼叫
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGRPC) {
+		http.Error(w, "gRPC only", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeGRPC)
+	w.Header().Set("Trailer", trailerGRPCStatus+", "+trailerGRPCMessage)
+	w.WriteHeader(http.StatusOK)
+
+	start := time.Now()
+	resp, err := s.call(r)
+	if err == nil {
+		err = writeFrame(w, resp)
+	}
+
+	code, message := codeOK, ""
+	if err != nil {
+		var se *statusError
+		if errors.As(err, &se) {
+			code, message = se.code, se.message
+		} else {
+			code, message = codeInternal, err.Error()
+		}
+		level := "[WARN]"
+		if code == codeInternal {
+			level = "[ERROR]"
+		}
+		log.Printf("%s gRPC %s 失敗（%d）: %s", level, r.URL.Path, code, message)
+	} else if time.Since(start) > time.Second {
+		log.Printf("[WARN] gRPC %s 耗時 %s", r.URL.Path, time.Since(start).Round(time.Millisecond))
+	}
+	w.Header().Set(trailerGRPCStatus, strconv.Itoa(code))
+	if message != "" {
+		// grpc-message 依規格以 percent-encoding 傳送
+		w.Header().Set(trailerGRPCMessage, url.PathEscape(message))
+	}
+}
+
+// call 驗證金鑰、讀取請求訊息並執行對應的方法
+func (s *Server) call(r *http.Request) ([]byte, error) {
+	m, ok := s.methods[r.URL.Path]
+	if !ok {
+		return nil, errorf(codeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+	if err := s.authenticate(r); err != nil {
+		return nil, err
+	}
+	req, err := readFrame(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return m(req)
+}
+
+// authenticate REQUIRE_API_KEY=true 時，metadata 的 x-api-key 必須是 API_KEYS 或 api_keys 資料表中的有效金鑰，
+// 資料表中的金鑰另須有 read:map 權限，並計入每月用量（超過上限時回傳 RESOURCE_EXHAUSTED）
+func (s *Server) authenticate(r *http.Request) error {
+	if !s.cfg.RequireAPIKey {
+		return nil
+	}
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		return errorf(codeUnauthenticated, "API key required")
+	}
+	for _, k := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			return nil
+		}
+	}
+	apiKey, err := database.FindActiveAPIKey(s.db, key)
+	if err == sql.ErrNoRows {
+		return errorf(codeUnauthenticated, "Invalid API key")
+	}
+	if err != nil {
+		return err
+	}
+	if !apiKey.HasScope(database.ScopeReadMap) {
+		return errorf(codePermissionDenied, "API key lacks scope "+database.ScopeReadMap)
+	}
+	ok, err := database.RecordAPIKeyUsage(s.db, apiKey, database.MonthStart(time.Now()))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorf(codeResourceExhausted, "API key monthly quota exceeded")
+	}
+	return nil
+}
+
+// readFrame 讀取一則 gRPC 訊息：1 byte 壓縮旗標 + 4 bytes 長度（big-endian）+ 內容
+func readFrame(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, errorf(codeInvalidArgument, "missing request message")
+	}
+	if prefix[0] != 0 {
+		return nil, errorf(codeUnimplemented, "compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxRequestBytes {
+		return nil, errorf(codeResourceExhausted, "request message is too large")
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errorf(codeInvalidArgument, "truncated request message")
+	}
+	return msg, nil
+}
+
+func writeFrame(w io.Writer, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
+
+// parseList 逗號分隔的設定值（與 server.ParseList 相同，避免 rpc 依賴 HTTP 路由套件）
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
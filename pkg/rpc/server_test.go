@@ -0,0 +1,309 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/bufbuild/protocompile"
+	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// mapProto 編譯 proto/pxmark/v1/map.proto，測試以 proto 定義產生的訊息（dynamicpb）與 grpc-go 用戶端
+// 檢查手寫的編碼與訊息框架，修改 proto 而忘了修改 messages.go 時測試會失敗
+func mapProto(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"../../proto"}}),
+	}
+	files, err := compiler.Compile(context.Background(), "pxmark/v1/map.proto")
+	if err != nil {
+		t.Fatalf("compile map.proto: %v", err)
+	}
+	return files[0]
+}
+
+func newMessage(fd protoreflect.FileDescriptor, name string) *dynamicpb.Message {
+	return dynamicpb.NewMessage(fd.Messages().ByName(protoreflect.Name(name)))
+}
+
+// set 依欄位名稱設定值（repeated int32 傳 []int32）
+func set(m *dynamicpb.Message, field string, v interface{}) {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(field))
+	if ids, ok := v.([]int32); ok {
+		list := m.Mutable(fd).List()
+		for _, id := range ids {
+			list.Append(protoreflect.ValueOfInt32(id))
+		}
+		return
+	}
+	m.Set(fd, protoreflect.ValueOf(v))
+}
+
+func get(m protoreflect.Message, field string) interface{} {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(field))).Interface()
+}
+
+func TestStoreEncodingMatchesProto(t *testing.T) {
+	fd := mapProto(t)
+	stores := []database.StoreRecord{
+		{ID: 12, StoreName: "全聯安南店", PlaceID: "ChIJ123", FormattedAddress: "台南市安南區", Latitude: 23.04, Longitude: 120.18, BusinessStatus: "OPERATIONAL", IsActive: true, Region: "台南市"},
+		{ID: 13, StoreName: "全聯永康店", Latitude: -1.5},
+	}
+
+	resp := newMessage(fd, "ListStoresResponse")
+	if err := proto.Unmarshal(marshalStores(stores), resp); err != nil {
+		t.Fatalf("unmarshal ListStoresResponse: %v", err)
+	}
+	list := resp.Get(resp.Descriptor().Fields().ByName("stores")).List()
+	if list.Len() != len(stores) {
+		t.Fatalf("stores = %d, want %d", list.Len(), len(stores))
+	}
+	for i, want := range stores {
+		got := list.Get(i).Message()
+		fields := map[string]interface{}{
+			"id": int32(want.ID), "store_name": want.StoreName, "place_id": want.PlaceID,
+			"formatted_address": want.FormattedAddress, "latitude": want.Latitude, "longitude": want.Longitude,
+			"business_status": want.BusinessStatus, "is_active": want.IsActive, "region": want.Region,
+		}
+		for name, v := range fields {
+			if g := get(got, name); g != v {
+				t.Errorf("store %d %s = %v, want %v", i, name, g, v)
+			}
+		}
+	}
+}
+
+func TestShipmentEncodingMatchesProto(t *testing.T) {
+	fd := mapProto(t)
+	shipments := []database.ShipmentRecord{
+		{ID: 1, StoreID: 12, StoreName: "全聯安南店", ProductType: "秋葵", ShipmentDate: "2025-06-01", TimeSlot: "am", Quantity: "3", QualityFlag: "too_large", SourceID: "main"},
+		{ID: 2, StoreID: 12, StoreName: "全聯安南店", ProductType: "產銷絲瓜", ShipmentDate: "2025-06-02", Quantity: "0"},
+	}
+
+	resp := newMessage(fd, "ListShipmentsResponse")
+	if err := proto.Unmarshal(marshalShipments(shipments), resp); err != nil {
+		t.Fatalf("unmarshal ListShipmentsResponse: %v", err)
+	}
+	list := resp.Get(resp.Descriptor().Fields().ByName("shipments")).List()
+	if list.Len() != len(shipments) {
+		t.Fatalf("shipments = %d, want %d", list.Len(), len(shipments))
+	}
+	for i, want := range shipments {
+		got := list.Get(i).Message()
+		fields := map[string]interface{}{
+			"id": int32(want.ID), "store_id": int32(want.StoreID), "store_name": want.StoreName,
+			"product_type": want.ProductType, "shipment_date": want.ShipmentDate, "time_slot": want.TimeSlot,
+			"quantity": want.Quantity, "quality_flag": want.QualityFlag, "source_id": want.SourceID,
+		}
+		for name, v := range fields {
+			if g := get(got, name); g != v {
+				t.Errorf("shipment %d %s = %v, want %v", i, name, g, v)
+			}
+		}
+	}
+}
+
+func TestRequestDecodingMatchesProto(t *testing.T) {
+	fd := mapProto(t)
+
+	// optional bool 設為 false 時仍要帶出欄位（只查停用的店家）
+	stores := newMessage(fd, "ListStoresRequest")
+	set(stores, "ids", []int32{1, 2, 300000})
+	set(stores, "q", "安南")
+	set(stores, "region", "台南市")
+	set(stores, "active", false)
+	set(stores, "limit", int32(50))
+	set(stores, "offset", int32(100))
+	b, err := proto.Marshal(stores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var storesReq listStoresRequest
+	if err := storesReq.unmarshal(b); err != nil {
+		t.Fatalf("listStoresRequest.unmarshal: %v", err)
+	}
+	if fmt.Sprint(storesReq.IDs) != "[1 2 300000]" || storesReq.Query != "安南" || storesReq.Region != "台南市" ||
+		storesReq.Active == nil || *storesReq.Active || storesReq.Limit != 50 || storesReq.Offset != 100 {
+		t.Errorf("listStoresRequest = %+v (active %v)", storesReq, storesReq.Active)
+	}
+
+	// 沒有設定 optional 欄位時為 nil（不篩選）
+	b, _ = proto.Marshal(newMessage(fd, "ListStoresRequest"))
+	var empty listStoresRequest
+	if err := empty.unmarshal(b); err != nil || empty.Active != nil {
+		t.Errorf("empty listStoresRequest = %+v, %v", empty, err)
+	}
+
+	shipments := newMessage(fd, "ListShipmentsRequest")
+	set(shipments, "store_ids", []int32{7, 8})
+	set(shipments, "from", "2025-06-01")
+	set(shipments, "to", "2025-06-30")
+	set(shipments, "product", "秋葵")
+	set(shipments, "limit", int32(10))
+	set(shipments, "per_store_limit", int32(3))
+	b, _ = proto.Marshal(shipments)
+	var shipmentsReq listShipmentsRequest
+	if err := shipmentsReq.unmarshal(b); err != nil {
+		t.Fatalf("listShipmentsRequest.unmarshal: %v", err)
+	}
+	want := listShipmentsRequest{StoreIDs: []int{7, 8}, From: "2025-06-01", To: "2025-06-30", Product: "秋葵", Limit: 10, PerStoreLimit: 3}
+	if fmt.Sprint(shipmentsReq) != fmt.Sprint(want) {
+		t.Errorf("listShipmentsRequest = %+v, want %+v", shipmentsReq, want)
+	}
+
+	// 負數的 int32 以 10 bytes 的 varint 編碼
+	getStore := newMessage(fd, "GetStoreRequest")
+	set(getStore, "id", int32(-5))
+	b, _ = proto.Marshal(getStore)
+	var getReq getStoreRequest
+	if err := getReq.unmarshal(b); err != nil || getReq.ID != -5 {
+		t.Errorf("getStoreRequest = %+v, %v, want id -5", getReq, err)
+	}
+}
+
+func TestDecodeSkipsUnknownFields(t *testing.T) {
+	fd := mapProto(t)
+	req := newMessage(fd, "GetStoreRequest")
+	set(req, "id", int32(9))
+	b, _ := proto.Marshal(req)
+	// 新版用戶端多送的欄位（編號 15，字串）
+	b = append(b, 0x7a, 0x02, 'h', 'i')
+
+	var got getStoreRequest
+	if err := got.unmarshal(b); err != nil || got.ID != 9 {
+		t.Errorf("getStoreRequest = %+v, %v, want id 9", got, err)
+	}
+	if err := got.unmarshal([]byte{0x08}); err == nil {
+		t.Errorf("truncated message decoded without error")
+	}
+}
+
+// startServer 在本機隨機連接埠啟動 gRPC 服務，回傳連上的 grpc-go 用戶端
+func startServer(t *testing.T, db *sql.DB, cfg *config.Config) *grpc.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(db, cfg).HTTPServer(lis.Addr().String())
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func invoke(ctx context.Context, conn *grpc.ClientConn, method string, req, resp proto.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return conn.Invoke(ctx, "/"+ServiceName+"/"+method, req, resp)
+}
+
+func TestGRPCClientErrors(t *testing.T) {
+	fd := mapProto(t)
+	conn := startServer(t, nil, &config.Config{RequireAPIKey: true, APIKeys: "internal-key"})
+	withKey := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "internal-key")
+
+	badDates := newMessage(fd, "ListShipmentsRequest")
+	set(badDates, "from", "6/1")
+	badLimit := newMessage(fd, "ListStoresRequest")
+	set(badLimit, "limit", int32(5000))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		req     proto.Message
+		code    codes.Code
+		message string
+	}{
+		{"missing API key", context.Background(), "ListStores", newMessage(fd, "ListStoresRequest"), codes.Unauthenticated, "API key required"},
+		{"invalid dates", withKey, "ListShipments", badDates, codes.InvalidArgument, "from and to must be YYYY-MM-DD"},
+		{"limit out of range", withKey, "ListStores", badLimit, codes.InvalidArgument, "limit must be between 1 and 1000"},
+		{"unknown method", withKey, "DeleteStore", newMessage(fd, "GetStoreRequest"), codes.Unimplemented, "unknown method /pxmark.v1.MapService/DeleteStore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := invoke(tt.ctx, conn, tt.method, tt.req, newMessage(fd, "ListStoresResponse"))
+			st, ok := status.FromError(err)
+			if !ok || st.Code() != tt.code || st.Message() != tt.message {
+				t.Errorf("error = %v, want %s %q", err, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+// TestGRPCClientRoundTrip 以 grpc-go 用戶端查詢資料庫中的店家（需要 TEST_DATABASE_URL）
+func TestGRPCClientRoundTrip(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		if os.Getenv("REQUIRE_TEST_DATABASE") != "" {
+			t.Fatal("REQUIRE_TEST_DATABASE is set but TEST_DATABASE_URL is not")
+		}
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	lat, lng := 23.04, 120.18
+	name := fmt.Sprintf("grpc-test-%d", time.Now().UnixNano())
+	store, err := database.CreateStore(db, database.NewStore{StoreName: name, Latitude: &lat, Longitude: &lng, Region: "台南市"}, "test")
+	if err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	t.Cleanup(func() { database.DeleteStore(db, store.ID, "test") })
+
+	fd := mapProto(t)
+	conn := startServer(t, db, &config.Config{})
+
+	req := newMessage(fd, "GetStoreRequest")
+	set(req, "id", int32(store.ID))
+	resp := newMessage(fd, "Store")
+	if err := invoke(context.Background(), conn, "GetStore", req, resp); err != nil {
+		t.Fatalf("GetStore: %v", err)
+	}
+	if get(resp, "id") != int32(store.ID) || get(resp, "store_name") != name || get(resp, "latitude") != lat || get(resp, "region") != "台南市" {
+		t.Errorf("GetStore = %v", resp)
+	}
+
+	list := newMessage(fd, "ListStoresRequest")
+	set(list, "ids", []int32{int32(store.ID)})
+	listResp := newMessage(fd, "ListStoresResponse")
+	if err := invoke(context.Background(), conn, "ListStores", list, listResp); err != nil {
+		t.Fatalf("ListStores: %v", err)
+	}
+	if n := listResp.Get(listResp.Descriptor().Fields().ByName("stores")).List().Len(); n != 1 {
+		t.Errorf("ListStores returned %d stores, want 1", n)
+	}
+
+	set(req, "id", int32(-1))
+	err = invoke(context.Background(), conn, "GetStore", req, newMessage(fd, "Store"))
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetStore(-1) = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"database/sql"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+// listStores ListStores：依條件查詢店家（依店名排序）
+func (s *Server) listStores(b []byte) ([]byte, error) {
+	var req listStoresRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, errorf(codeInvalidArgument, "%v", err)
+	}
+	limit, err := checkLimit(req.Limit, defaultLimit, maxLimit)
+	if err != nil {
+		return nil, err
+	}
+	if req.Offset < 0 {
+		return nil, errorf(codeInvalidArgument, "offset must not be negative")
+	}
+
+	stores, err := database.QueryStores(s.db, database.StoreQuery{
+		IDs:    req.IDs,
+		Query:  req.Query,
+		Region: req.Region,
+		Active: req.Active,
+		Limit:  limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshalStores(stores), nil
+}
+
+// getStore GetStore：取得單一店家
+func (s *Server) getStore(b []byte) ([]byte, error) {
+	var req getStoreRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, errorf(codeInvalidArgument, "%v", err)
+	}
+	store, err := database.GetStoreByID(s.db, req.ID)
+	if err == sql.ErrNoRows {
+		return nil, errorf(codeNotFound, "store %d not found", req.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return appendStore(nil, *store), nil
+}
+
+// listShipments ListShipments：依條件查詢出貨（新到舊），不列入隱藏的產品
+func (s *Server) listShipments(b []byte) ([]byte, error) {
+	var req listShipmentsRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, errorf(codeInvalidArgument, "%v", err)
+	}
+	for _, d := range []string{req.From, req.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, errorf(codeInvalidArgument, "from and to must be YYYY-MM-DD")
+		}
+	}
+	limit, err := checkLimit(req.Limit, defaultLimit, maxLimit)
+	if err != nil {
+		return nil, err
+	}
+	if req.PerStoreLimit < 0 || req.PerStoreLimit > maxPerStoreLimit {
+		return nil, errorf(codeInvalidArgument, "per_store_limit must be between 0 and %d", maxPerStoreLimit)
+	}
+
+	shipments, err := database.QueryShipments(s.db, database.ShipmentQuery{
+		StoreIDs:      req.StoreIDs,
+		From:          req.From,
+		To:            req.To,
+		Product:       req.Product,
+		Exclude:       s.exclude,
+		PerStoreLimit: req.PerStoreLimit,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshalShipments(shipments), nil
+}
+
+// checkLimit limit 為 0（未指定）時使用預設值
+func checkLimit(limit, def, max int) (int, error) {
+	if limit == 0 {
+		return def, nil
+	}
+	if limit < 1 || limit > max {
+		return 0, errorf(codeInvalidArgument, "limit must be between 1 and %d", max)
+	}
+	return limit, nil
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/sync"
+	"PXMarkMapBackEnd/pkg/webhook"
+)
+
+// 同步進度回呼的事件
+const (
+	EventSyncStageStarted  = "sync.stage.started"
+	EventSyncStageFinished = "sync.stage.finished"
+	EventSyncFinished      = "sync.finished"
+)
+
+// 同步後的維護階段（同步本身的階段見 sync.Stage*）
+const (
+	StageStatusCheck    = "statusCheck"    // 店家營業狀態檢查
+	StageSnapshot       = "snapshot"       // 同步快照
+	StageDistrictTotals = "districtTotals" // 區域每日彙總
+	StageOpenData       = "openData"       // 開放資料檔
+)
+
+const callbackTimeout = 5 * time.Second
+
+// SyncEvent 送到 callbackUrl 的內容
+type SyncEvent struct {
+	Event      string        `json:"event"`
+	Seq        int           `json:"seq"` // 同一次同步中從 1 開始遞增，接收端可依此排序
+	SentAt     time.Time     `json:"sentAt"`
+	SyncType   string        `json:"syncType"` // 'daily', 'monthly'
+	Stage      string        `json:"stage,omitempty"`
+	Status     string        `json:"status,omitempty"` // 階段：'success', 'failed'；同步：'success', 'stale_source', 'failed'
+	DurationMs int64         `json:"durationMs,omitempty"`
+	Stats      interface{}   `json:"stats,omitempty"`
+	Summary    *sync.Summary `json:"summary,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// SyncCallback 將同步各階段的進度送到觸發同步時指定的 callbackUrl（CI 在日誌中顯示詳細進度），
+// 簽章方式與 webhook 相同（密鑰為 SYNC_SECRET）；只送一次不重試，失敗只記錄警告、不影響同步
+type SyncCallback struct {
+	URL      string
+	Secret   string
+	SyncType string
+
+	seq     int
+	started map[string]time.Time
+	begin   time.Time
+	client  *http.Client
+}
+
+// NewSyncCallback 建立同步進度回呼
+func NewSyncCallback(url, secret, syncType string) *SyncCallback {
+	return &SyncCallback{
+		URL:      url,
+		Secret:   secret,
+		SyncType: syncType,
+		started:  make(map[string]time.Time),
+		begin:    time.Now(),
+		client:   &http.Client{Timeout: callbackTimeout},
+	}
+}
+
+// StageStarted 回報階段開始
+func (cb *SyncCallback) StageStarted(stage string) {
+	cb.started[stage] = time.Now()
+	cb.send(SyncEvent{Event: EventSyncStageStarted, Stage: stage})
+}
+
+// StageFinished 回報階段結束與統計
+func (cb *SyncCallback) StageFinished(stage string, stats interface{}, err error) {
+	event := SyncEvent{Event: EventSyncStageFinished, Stage: stage, Status: "success", Stats: stats}
+	if start, ok := cb.started[stage]; ok {
+		event.DurationMs = time.Since(start).Milliseconds()
+	}
+	if err != nil {
+		event.Status, event.Error = "failed", err.Error()
+	}
+	cb.send(event)
+}
+
+// SyncFinished 回報整個同步結束（含同步後的維護階段）
+func (cb *SyncCallback) SyncFinished(status string, summary *sync.Summary, err error) {
+	event := SyncEvent{Event: EventSyncFinished, Status: status, Summary: summary,
+		DurationMs: time.Since(cb.begin).Milliseconds()}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	cb.send(event)
+}
+
+// send 送出一個事件，非 2xx 視為失敗
+func (cb *SyncCallback) send(event SyncEvent) {
+	cb.seq++
+	event.Seq = cb.seq
+	event.SentAt = time.Now()
+	event.SyncType = cb.SyncType
+
+	if err := cb.post(event); err != nil {
+		log.Printf("[WARN] 同步進度回呼 %s（%s %s）送出失敗: %v", cb.URL, event.Event, event.Stage, err)
+	}
+}
+
+func (cb *SyncCallback) post(event SyncEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", cb.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PXMark-Event", event.Event)
+	req.Header.Set("X-PXMark-Timestamp", timestamp)
+	req.Header.Set("X-PXMark-Signature", "sha256="+webhook.Sign(cb.Secret, timestamp, body))
+
+	resp, err := cb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package scheduler
 
 import (
 	"database/sql"
-	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/opendata"
+	"PXMarkMapBackEnd/pkg/storage"
 	"PXMarkMapBackEnd/pkg/sync"
 )
 
@@ -14,6 +17,11 @@ import (
 type Scheduler struct {
 	DB       *sql.DB
 	Interval time.Duration
+	Priority google.Priority // Places API 查詢優先順序（手動觸發時設為 PriorityManual）
+	Progress sync.Progress   // 同步各階段的進度回報（手動觸發時指定 callbackUrl 才有）
+
+	CallbackSecret string // 工作佇列的進度回呼簽章密鑰（SYNC_SECRET）
+	InstanceID     string // 工作佇列記錄在 sync_jobs.owner 的程序識別（INSTANCE_ID）
 }
 
 // SyncLog 同步執行記錄
@@ -21,8 +29,12 @@ type SyncLog struct {
 	ID        int
 	StartTime time.Time
 	EndTime   sql.NullTime
-	Status    string // 'running', 'success', 'failed'
+	Status    string // 'running', 'success', 'stale_source', 'failed'
 	Message   string
+	// SheetLastModified 同步時取得的試算表最後修改時間（Drive API）
+	SheetLastModified sql.NullTime
+	// DataGeneration 成功同步產生的資料版本（回應的 meta.generation）
+	DataGeneration sql.NullInt64
 }
 
 // NewScheduler 建立新的排程器
@@ -45,6 +57,7 @@ func (s *Scheduler) InitSyncLogTable() error {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
 		CREATE INDEX IF NOT EXISTS idx_sync_logs_start_time ON sync_logs(start_time);
+		ALTER TABLE sync_logs ADD COLUMN IF NOT EXISTS output TEXT;
 	`
 	_, err := s.DB.Exec(query)
 	if err != nil {
@@ -64,7 +77,7 @@ func (s *Scheduler) Start() {
 	}
 
 	// 立即執行一次
-	s.runSync(false)
+	s.RunSync(false)
 
 	// 建立定時器
 	ticker := time.NewTicker(s.Interval)
@@ -73,85 +86,102 @@ func (s *Scheduler) Start() {
 	for {
 		select {
 		case <-ticker.C:
-			s.runSync(false)
+			s.RunSync(false)
 		}
 	}
 }
 
-// StartDaily 每天固定時間執行（每日更新）
-func (s *Scheduler) StartDaily(hour, minute int, isFullSync bool) {
-	syncType := "每日更新"
-	if isFullSync {
-		syncType = "完整同步"
-	}
-
-	log.Printf("[INFO] 排程器啟動,每天 %02d:%02d 執行%s", hour, minute, syncType)
-
-	// 初始化記錄表
-	if err := s.InitSyncLogTable(); err != nil {
-		log.Printf("[WARN] 無法建立記錄表: %v", err)
-	}
-
+// DailySyncJob 每天 hour:minute 執行同步的排程工作（isFullSync 為 false 時為每日更新）
+func (s *Scheduler) DailySyncJob(hour, minute int, isFullSync bool) Job {
 	// 檢查上次執行時間
 	lastRun, err := s.GetLastSyncTime()
 	if err == nil && !lastRun.IsZero() {
 		log.Printf("[INFO] 上次同步時間: %s", lastRun.Format("2006-01-02 15:04:05"))
 	}
 
-	for {
-		now := time.Now()
-		nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
-
-		// 如果今天的執行時間已過,設定為明天
-		if now.After(nextRun) {
-			nextRun = nextRun.Add(24 * time.Hour)
-		}
-
-		waitDuration := time.Until(nextRun)
-		log.Printf("[INFO] 下次執行時間: %s", nextRun.Format("2006-01-02 15:04:05"))
-		log.Printf("[INFO] 等待時間: %v", waitDuration.Round(time.Second))
-
-		// 等待到指定時間
-		time.Sleep(waitDuration)
-
-		// 執行同步
-		s.runSync(isFullSync)
+	return Job{
+		Name:     "daily",
+		Schedule: DailyAt{Hour: hour, Minute: minute},
+		Run:      func() error { return s.RunSync(isFullSync) },
 	}
 }
 
-// StartMonthly 每月固定日期執行（完整同步）
-func (s *Scheduler) StartMonthly(dayOfMonth, hour, minute int) {
-	log.Printf("[INFO] 排程器啟動，每月 %d 號 %02d:%02d 執行完整同步", dayOfMonth, hour, minute)
-
-	// 初始化記錄表
-	if err := s.InitSyncLogTable(); err != nil {
-		log.Printf("[WARN] 無法建立記錄表: %v", err)
+// MonthlySyncJob 每月 dayOfMonth 號 hour:minute 執行完整同步，並封存上個月出貨的排程工作
+func (s *Scheduler) MonthlySyncJob(dayOfMonth, hour, minute int) Job {
+	return Job{
+		Name:     "monthly",
+		Schedule: MonthlyAt{Day: dayOfMonth, Hour: hour, Minute: minute},
+		Run: func() error {
+			err := s.RunSync(true)
+
+			// 封存上個月的出貨（同步失敗時仍封存資料庫中既有的資料）
+			s.RunMonthlyExport(time.Now().AddDate(0, -1, 0))
+			return err
+		},
 	}
+}
 
-	for {
-		now := time.Now()
+// StoreGCJob 每隔 interval 清理孤兒店家（沒有出貨、且 days 天內未出現在工作表）的排程工作，
+// action 為 database.StoreGCDryRun / StoreGCDeactivate / StoreGCDelete
+func (s *Scheduler) StoreGCJob(interval time.Duration, days int, action string) Job {
+	return Job{
+		Name:     "store-gc",
+		Schedule: Every(interval),
+		Run: func() error {
+			report, err := database.CollectOrphanedStores(s.DB, days, action, "scheduler")
+			if err != nil {
+				return err
+			}
+			log.Printf("[INFO] 孤兒店家清理（%s）: 找到 %d 家，停用 %d 家，刪除 %d 家",
+				report.Action, len(report.Stores), report.Deactivated, report.Deleted)
+			return nil
+		},
+	}
+}
 
-		// 計算下次執行時間
-		nextRun := time.Date(now.Year(), now.Month(), dayOfMonth, hour, minute, 0, 0, now.Location())
+// RunMonthlyExport 將 month 所屬月份的出貨匯出成 CSV 封存
+func (s *Scheduler) RunMonthlyExport(month time.Time) {
+	blobs, err := storage.Default()
+	if err != nil {
+		log.Printf("[ERROR] 封存 %s 出貨失敗，無法使用 BLOB_STORE: %v", month.Format("2006-01"), err)
+		return
+	}
+	export, err := database.ExportMonthlyShipments(s.DB, blobs, month)
+	if err != nil {
+		log.Printf("[ERROR] 封存 %s 出貨失敗: %v", month.Format("2006-01"), err)
+		return
+	}
+	log.Printf("[INFO] 已封存 %s 出貨: %d 筆（%d bytes）", export.Month, export.RowCount, export.SizeBytes)
+}
 
-		// 如果本月的執行時間已過，移到下個月
-		if now.After(nextRun) {
-			nextRun = nextRun.AddDate(0, 1, 0)
-		}
+// NextDailyRun 計算 now 之後下一次每日排程的執行時間
+func NextDailyRun(now time.Time, hour, minute int) time.Time {
+	nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
 
-		waitDuration := time.Until(nextRun)
-		log.Printf("[INFO] 下次完整同步時間: %s", nextRun.Format("2006-01-02 15:04:05"))
-		log.Printf("[INFO] 等待時間: %v", waitDuration.Round(time.Hour))
+	// 如果今天的執行時間已過,設定為明天
+	if now.After(nextRun) {
+		nextRun = nextRun.Add(24 * time.Hour)
+	}
+	return nextRun
+}
 
-		time.Sleep(waitDuration)
+// NextMonthlyRun 計算 now 之後下一次每月排程的執行時間
+func NextMonthlyRun(now time.Time, dayOfMonth, hour, minute int) time.Time {
+	nextRun := time.Date(now.Year(), now.Month(), dayOfMonth, hour, minute, 0, 0, now.Location())
 
-		// 執行完整同步
-		s.runSync(true)
+	// 如果本月的執行時間已過，移到下個月
+	if now.After(nextRun) {
+		nextRun = nextRun.AddDate(0, 1, 0)
 	}
+	return nextRun
 }
 
-// runSync 執行同步任務（根據 isFullSync 決定類型）
-func (s *Scheduler) runSync(isFullSync bool) {
+// RunSync 執行同步任務（根據 isFullSync 決定類型），並將執行日誌保存到 sync_logs
+func (s *Scheduler) RunSync(isFullSync bool) error {
+	runLock.Lock()
+	defer runLock.Unlock()
+
+	capture := startLogCapture()
 	startTime := time.Now()
 
 	syncType := "每日"
@@ -170,11 +200,12 @@ func (s *Scheduler) runSync(isFullSync bool) {
 	}
 
 	// 執行同步（根據類型）
+	var summary *sync.Summary
 	var syncErr error
 	if isFullSync {
-		syncErr = sync.SyncData(s.DB) // 完整同步
+		summary, syncErr = sync.SyncData(s.DB, s.Priority, s.Progress) // 完整同步
 	} else {
-		syncErr = sync.SyncDataDaily(s.DB) // 每日同步
+		summary, syncErr = sync.SyncDataDaily(s.DB, s.Priority, s.Progress) // 每日同步
 	}
 
 	endTime := time.Now()
@@ -185,13 +216,115 @@ func (s *Scheduler) runSync(isFullSync bool) {
 		log.Printf("[ERROR] 同步失敗: %v", syncErr)
 		log.Printf("[INFO] 執行時間: %v", duration.Round(time.Second))
 		s.LogSyncEnd(logID, endTime, "failed", syncErr.Error())
+		if s.Progress != nil {
+			s.Progress.SyncFinished("failed", nil, syncErr)
+		}
 	} else {
 		log.Printf("[INFO] %s同步完成", syncType)
 		log.Printf("[INFO] 執行時間: %v", duration.Round(time.Second))
-		s.LogSyncEnd(logID, endTime, "success", fmt.Sprintf("%s同步成功", syncType))
+		for _, w := range summary.Warnings {
+			log.Printf("[WARN] %s", w)
+		}
+		status := "success"
+		if len(summary.StaleSources) > 0 {
+			// 有資料來源改用快照：地點補查與後續維護照常執行，但不算成功同步（不更新上次同步時間）
+			status = "stale_source"
+			log.Printf("[WARN] ⚠ 資料來源 %s 下載失敗，本次同步使用上次的工作表快照，請檢查表單分享設定", strings.Join(summary.StaleSources, ", "))
+		}
+		if status == "success" {
+			// 資料版本在記錄結束前遞增：回應快取依最後同步時間失效，失效後讀到的一定是新版本
+			if gen, err := database.NextDataGeneration(s.DB); err != nil {
+				log.Printf("[WARN] 無法更新資料版本: %v", err)
+			} else if err := s.SaveDataGeneration(logID, gen); err != nil {
+				log.Printf("[WARN] 無法記錄資料版本: %v", err)
+			} else {
+				log.Printf("[INFO] 資料版本: %d", gen)
+			}
+		}
+		s.LogSyncEnd(logID, endTime, status, summary.String())
+		if summary.SheetLastModified != nil {
+			if err := s.SaveSheetLastModified(logID, *summary.SheetLastModified); err != nil {
+				log.Printf("[WARN] 無法記錄試算表修改時間: %v", err)
+			}
+		}
+
+		// 同步後交叉比對店家營業狀態與出貨紀錄
+		sync.StartStage(s.Progress, StageStatusCheck)
+		checks, err := database.RunStoreStatusCheck(s.DB)
+		if err != nil {
+			log.Printf("[WARN] 店家狀態檢查失敗: %v", err)
+		}
+		sync.FinishStage(s.Progress, StageStatusCheck, checks, err)
+
+		// 同步快照與開放資料檔保存在 BLOB_STORE
+		blobs, blobErr := storage.Default()
+		if blobErr != nil {
+			log.Printf("[WARN] 無法使用 BLOB_STORE，不保存同步快照與開放資料: %v", blobErr)
+		}
+
+		// 保存同步後的快照，供比較兩次同步的差異
+		if blobErr == nil {
+			sync.StartStage(s.Progress, StageSnapshot)
+			snap, err := database.CaptureSnapshot(s.DB)
+			if err != nil {
+				log.Printf("[WARN] 無法建立同步快照: %v", err)
+			} else if err = database.SaveSyncSnapshot(s.DB, blobs, logID, snap); err != nil {
+				log.Printf("[WARN] 無法保存同步快照: %v", err)
+			}
+			sync.FinishStage(s.Progress, StageSnapshot, nil, err)
+		}
+
+		// 更新區域每日彙總（開放資料等統計端點讀取此表，不必每次彙總原始出貨）
+		sync.StartStage(s.Progress, StageDistrictTotals)
+		n, err := database.RefreshDistrictDailyTotals(s.DB)
+		if err != nil {
+			log.Printf("[WARN] 更新區域每日彙總失敗: %v", err)
+		} else {
+			log.Printf("[INFO] 已更新區域每日彙總（%d 筆）", n)
+		}
+		sync.FinishStage(s.Progress, StageDistrictTotals, map[string]int{"rows": n}, err)
+
+		// 產生當天的開放資料檔
+		if blobErr == nil {
+			sync.StartStage(s.Progress, StageOpenData)
+			file, err := opendata.Generate(s.DB, blobs, endTime)
+			if err != nil {
+				log.Printf("[WARN] 產生開放資料失敗: %v", err)
+			}
+			sync.FinishStage(s.Progress, StageOpenData, map[string]string{"file": file}, err)
+		}
+
+		if s.Progress != nil {
+			s.Progress.SyncFinished(status, summary, nil)
+		}
 	}
 
 	log.Println(strings.Repeat("=", 50))
+
+	// 保存本次執行的日誌（平台的日誌保存期限很短）
+	if err := s.SaveSyncOutput(logID, capture.Stop()); err != nil {
+		log.Printf("[WARN] 無法保存同步日誌: %v", err)
+	}
+
+	return syncErr
+}
+
+// WaitIdle 等待執行中的排程工作與同步結束（最多 timeout），之後不再開始新的工作；程序結束前呼叫。
+// 同步在時限內結束時回傳 true
+func WaitIdle(timeout time.Duration) bool {
+	idle := make(chan struct{})
+	go func() {
+		stopJobs()
+		runLock.Lock() // 不釋放：程序即將結束，避免排程或手動同步在關閉途中開始
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
 
 // LogSyncStart 記錄同步開始
@@ -217,6 +350,47 @@ func (s *Scheduler) LogSyncEnd(id int, endTime time.Time, status, message string
 	return err
 }
 
+// SaveSyncOutput 保存同步執行日誌
+func (s *Scheduler) SaveSyncOutput(id int, output string) error {
+	_, err := s.DB.Exec(`UPDATE sync_logs SET output = $1 WHERE id = $2`, output, id)
+	return err
+}
+
+// SaveDataGeneration 記錄本次同步產生的資料版本
+func (s *Scheduler) SaveDataGeneration(id int, generation int64) error {
+	_, err := s.DB.Exec(`UPDATE sync_logs SET data_generation = $1 WHERE id = $2`, generation, id)
+	return err
+}
+
+// SaveSheetLastModified 記錄本次同步取得的試算表最後修改時間
+func (s *Scheduler) SaveSheetLastModified(id int, modified time.Time) error {
+	_, err := s.DB.Exec(`UPDATE sync_logs SET sheet_last_modified = $1 WHERE id = $2`, modified.Local(), id)
+	return err
+}
+
+// GetSheetLastModified 取得最近一次記錄的試算表最後修改時間，沒有任何記錄時回傳零值
+func (s *Scheduler) GetSheetLastModified() (time.Time, error) {
+	var modified time.Time
+	err := s.DB.QueryRow(`
+		SELECT sheet_last_modified
+		FROM sync_logs
+		WHERE sheet_last_modified IS NOT NULL
+		ORDER BY start_time DESC
+		LIMIT 1
+	`).Scan(&modified)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return modified, err
+}
+
+// GetSyncOutput 取得同步執行日誌，不存在時回傳 sql.ErrNoRows
+func (s *Scheduler) GetSyncOutput(id int) (string, error) {
+	var output sql.NullString
+	err := s.DB.QueryRow(`SELECT output FROM sync_logs WHERE id = $1`, id).Scan(&output)
+	return output.String, err
+}
+
 // GetLastSyncTime 取得上次同步時間
 func (s *Scheduler) GetLastSyncTime() (time.Time, error) {
 	var lastSync time.Time
@@ -234,15 +408,42 @@ func (s *Scheduler) GetLastSyncTime() (time.Time, error) {
 	return lastSync, err
 }
 
+// GetLatestSync 取得最近一次同步記錄（可能仍在執行中），沒有任何記錄時回傳 nil
+func (s *Scheduler) GetLatestSync() (*SyncLog, error) {
+	var l SyncLog
+	var message sql.NullString
+	query := `
+		SELECT id, start_time, end_time, status, message, sheet_last_modified, data_generation
+		FROM sync_logs
+		ORDER BY start_time DESC
+		LIMIT 1
+	`
+	err := s.DB.QueryRow(query).Scan(&l.ID, &l.StartTime, &l.EndTime, &l.Status, &message, &l.SheetLastModified, &l.DataGeneration)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.Message = message.String
+	return &l, nil
+}
+
 // GetSyncHistory 取得同步歷史記錄
 func (s *Scheduler) GetSyncHistory(limit int) ([]SyncLog, error) {
+	return s.GetSyncHistoryByStatus("", limit)
+}
+
+// GetSyncHistoryByStatus 取得指定狀態的同步歷史記錄，status 為空時不限狀態
+func (s *Scheduler) GetSyncHistoryByStatus(status string, limit int) ([]SyncLog, error) {
 	query := `
-		SELECT id, start_time, end_time, status, message
+		SELECT id, start_time, end_time, status, message, sheet_last_modified, data_generation
 		FROM sync_logs
+		WHERE ($1 = '' OR status = $1)
 		ORDER BY start_time DESC
-		LIMIT $1
+		LIMIT $2
 	`
-	rows, err := s.DB.Query(query, limit)
+	rows, err := s.DB.Query(query, status, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -251,12 +452,14 @@ func (s *Scheduler) GetSyncHistory(limit int) ([]SyncLog, error) {
 	var logs []SyncLog
 	for rows.Next() {
 		var log SyncLog
-		err := rows.Scan(&log.ID, &log.StartTime, &log.EndTime, &log.Status, &log.Message)
+		var message sql.NullString
+		err := rows.Scan(&log.ID, &log.StartTime, &log.EndTime, &log.Status, &message, &log.SheetLastModified, &log.DataGeneration)
 		if err != nil {
 			return nil, err
 		}
+		log.Message = message.String
 		logs = append(logs, log)
 	}
 
-	return logs, nil
+	return logs, rows.Err()
 }
\ No newline at end of file
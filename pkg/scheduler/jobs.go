@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Schedule 計算 now 之後下一次執行的時間
+type Schedule interface {
+	Next(now time.Time) time.Time
+}
+
+// DailyAt 每天 Hour:Minute 執行
+type DailyAt struct {
+	Hour, Minute int
+}
+
+// Next 實作 Schedule
+func (d DailyAt) Next(now time.Time) time.Time {
+	return NextDailyRun(now, d.Hour, d.Minute)
+}
+
+// MonthlyAt 每月 Day 號 Hour:Minute 執行
+type MonthlyAt struct {
+	Day, Hour, Minute int
+}
+
+// Next 實作 Schedule
+func (m MonthlyAt) Next(now time.Time) time.Time {
+	return NextMonthlyRun(now, m.Day, m.Hour, m.Minute)
+}
+
+// Every 每隔固定時間執行（第一次在註冊後經過一個間隔）
+type Every time.Duration
+
+// Next 實作 Schedule
+func (e Every) Next(now time.Time) time.Time {
+	return now.Add(time.Duration(e))
+}
+
+// Job 排程工作：依 Schedule 在背景執行 Run，完成後呼叫 OnComplete（可為 nil）
+type Job struct {
+	Name       string
+	Schedule   Schedule
+	Run        func() error
+	OnComplete func(JobResult)
+}
+
+// JobResult 單次執行的結果（Run panic 時 Err 為 panic 內容）
+type JobResult struct {
+	Name       string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        error
+}
+
+var (
+	jobsMu      sync.Mutex
+	jobsStopped bool // WaitIdle 之後不再開始新的工作
+	jobsRunning sync.WaitGroup
+	listeners   []func(JobResult)
+)
+
+// OnComplete 註冊所有排程工作共用的完成通知（在 Job.OnComplete 之後呼叫）
+func OnComplete(fn func(JobResult)) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Register 在背景依排程執行 job（由 Supervise 監督，排程迴圈 panic 時自動重新啟動）
+func Register(job Job) {
+	log.Printf("[INFO] 已註冊排程工作 %s，下次執行: %s", job.Name, job.Schedule.Next(time.Now()).Format("2006-01-02 15:04:05"))
+	Supervise(job.Name, func() {
+		for {
+			nextRun := job.Schedule.Next(time.Now())
+			time.Sleep(time.Until(nextRun))
+			RunJob(job)
+			log.Printf("[INFO] 排程工作 %s 下次執行: %s", job.Name, job.Schedule.Next(time.Now()).Format("2006-01-02 15:04:05"))
+		}
+	})
+}
+
+// RunJob 立即執行一次 job 並通知完成；程序停止中（WaitIdle 之後）不執行，回傳 false
+func RunJob(job Job) bool {
+	jobsMu.Lock()
+	if jobsStopped {
+		jobsMu.Unlock()
+		log.Printf("[INFO] 程序停止中，略過排程工作 %s", job.Name)
+		return false
+	}
+	jobsRunning.Add(1)
+	notify := append([]func(JobResult){}, listeners...)
+	jobsMu.Unlock()
+	defer jobsRunning.Done()
+
+	result := JobResult{Name: job.Name, StartedAt: time.Now()}
+	result.Err = runJobRecovered(job)
+	result.FinishedAt = time.Now()
+
+	if result.Err != nil {
+		log.Printf("[ERROR] 排程工作 %s 失敗（%v）: %v", job.Name, result.FinishedAt.Sub(result.StartedAt).Round(time.Second), result.Err)
+	} else {
+		log.Printf("[INFO] 排程工作 %s 完成（%v）", job.Name, result.FinishedAt.Sub(result.StartedAt).Round(time.Second))
+	}
+
+	if job.OnComplete != nil {
+		job.OnComplete(result)
+	}
+	for _, fn := range notify {
+		fn(result)
+	}
+	return true
+}
+
+// runJobRecovered 執行 job.Run，panic 時轉成錯誤，不讓單次失敗中斷排程迴圈
+func runJobRecovered(job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] 排程工作 %s panic: %v\n%s", job.Name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run()
+}
+
+// stopJobs 不再開始新的排程工作，並等待執行中的工作結束
+func stopJobs() {
+	jobsMu.Lock()
+	jobsStopped = true
+	jobsMu.Unlock()
+	jobsRunning.Wait()
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+)
+
+// maxRunLogBytes 每次同步保存的日誌上限，避免異常時寫入過大的資料
+const maxRunLogBytes = 512 * 1024
+
+// runLock 同一時間只執行一個同步任務（日誌擷取會替換全域 log 輸出）
+var runLock sync.Mutex
+
+// runLogCapture 擷取同步期間的日誌，同時照常輸出到原本的位置
+type runLogCapture struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	prev      io.Writer
+	truncated bool
+}
+
+// startLogCapture 開始擷取日誌
+func startLogCapture() *runLogCapture {
+	c := &runLogCapture{prev: log.Writer()}
+	log.SetOutput(io.MultiWriter(c.prev, c))
+	return c
+}
+
+func (c *runLogCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.buf.Len()+len(p) > maxRunLogBytes {
+		c.truncated = true
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
+
+// Stop 停止擷取並回傳擷取到的日誌
+func (c *runLogCapture) Stop() string {
+	log.SetOutput(c.prev)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.truncated {
+		c.buf.WriteString("... (日誌超過上限，已截斷)\n")
+	}
+	return c.buf.String()
+}
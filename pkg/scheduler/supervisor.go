@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	restartInitialBackoff = time.Second
+	restartMaxBackoff     = 5 * time.Minute
+)
+
+// LoopHealth 受監督的背景迴圈狀態
+type LoopHealth struct {
+	Name        string     `json:"name"`
+	Running     bool       `json:"running"`
+	Restarts    int        `json:"restarts"` // 因 panic 重新啟動的次數
+	LastPanic   string     `json:"lastPanic,omitempty"`
+	LastPanicAt *time.Time `json:"lastPanicAt,omitempty"`
+}
+
+var (
+	loops   = make(map[string]*LoopHealth)
+	loopsMu sync.Mutex
+)
+
+// Supervise 在背景執行 loop，panic 時記錄並以指數退避重新啟動；loop 正常結束則不再重啟
+func Supervise(name string, loop func()) {
+	loopsMu.Lock()
+	health := &LoopHealth{Name: name}
+	loops[name] = health
+	loopsMu.Unlock()
+
+	go func() {
+		backoff := restartInitialBackoff
+		for {
+			setRunning(health, true)
+			if !runRecovered(name, health, loop) {
+				setRunning(health, false)
+				log.Printf("[INFO] 背景迴圈 %s 已結束", name)
+				return
+			}
+
+			setRunning(health, false)
+			log.Printf("[WARN] 背景迴圈 %s 將於 %v 後重新啟動", name, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > restartMaxBackoff {
+				backoff = restartMaxBackoff
+			}
+		}
+	}()
+}
+
+// runRecovered 執行 loop，發生 panic 時回傳 true
+func runRecovered(name string, health *LoopHealth, loop func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			now := time.Now()
+
+			loopsMu.Lock()
+			health.Restarts++
+			health.LastPanic = fmt.Sprint(r)
+			health.LastPanicAt = &now
+			loopsMu.Unlock()
+
+			log.Printf("[ERROR] 背景迴圈 %s panic: %v\n%s", name, r, debug.Stack())
+		}
+	}()
+	loop()
+	return false
+}
+
+func setRunning(health *LoopHealth, running bool) {
+	loopsMu.Lock()
+	health.Running = running
+	loopsMu.Unlock()
+}
+
+// LoopStatuses 所有受監督背景迴圈的狀態
+func LoopStatuses() []LoopHealth {
+	loopsMu.Lock()
+	defer loopsMu.Unlock()
+
+	statuses := []LoopHealth{}
+	for _, h := range loops {
+		statuses = append(statuses, *h)
+	}
+	return statuses
+}
+
+// LoopsHealthy 所有受監督的背景迴圈是否都在執行中
+func LoopsHealthy() bool {
+	for _, h := range LoopStatuses() {
+		if !h.Running {
+			return false
+		}
+	}
+	return true
+}
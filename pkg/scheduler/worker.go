@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+)
+
+// leaderLockKey worker 選主用的 PostgreSQL advisory lock 鍵值
+const leaderLockKey = 0x50584d4b // "PXMK"
+
+const (
+	leaderRetryInterval = 30 * time.Second
+	jobPollInterval     = 10 * time.Second
+)
+
+const (
+	// SyncJobHeartbeatInterval 執行中的同步工作更新 heartbeat_at 的間隔
+	SyncJobHeartbeatInterval = time.Minute
+	// SyncJobStaleAfter 超過此時間沒有心跳的執行中工作視為執行的程序已中斷
+	SyncJobStaleAfter = 5 * SyncJobHeartbeatInterval
+	// StaleSyncJobMessage 中斷的 API 程序內同步記錄的錯誤訊息
+	StaleSyncJobMessage = "執行的程序已中斷，同步未完成"
+)
+
+// KeepSyncJobAlive 在背景定期更新工作的心跳，直到呼叫回傳的 stop
+func KeepSyncJobAlive(db *sql.DB, id int) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(SyncJobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := database.TouchSyncJob(db, id); err != nil {
+					log.Printf("[WARN] 無法更新同步工作 #%d 的心跳: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ReclaimStaleSyncJobs 處理沒有心跳的執行中工作（不論 owner），讓換了主機名稱的程序留下的工作不會永遠擋住新的同步
+func ReclaimStaleSyncJobs(db *sql.DB) {
+	if n, err := database.ReclaimStaleSyncJobs(db, SyncJobStaleAfter, StaleSyncJobMessage); err != nil {
+		log.Printf("[WARN] 無法處理中斷的同步工作: %v", err)
+	} else if n > 0 {
+		log.Printf("[WARN] %d 個超過 %v 沒有心跳的同步工作已重新排隊或標記為失敗", n, SyncJobStaleAfter)
+	}
+}
+
+// AcquireLeadership 取得 worker 主控權（advisory lock），已有其他 worker 持有時持續等待；
+// 回傳的連線必須保持開啟，關閉即釋放主控權
+func AcquireLeadership(ctx context.Context, db *sql.DB) (*sql.Conn, error) {
+	for {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, leaderLockKey).Scan(&acquired); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if acquired {
+			log.Println("[INFO] 已取得 worker 主控權")
+			return conn, nil
+		}
+		conn.Close()
+
+		log.Printf("[INFO] 已有其他 worker 執行中，%v 後重試", leaderRetryInterval)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(leaderRetryInterval):
+		}
+	}
+}
+
+// WatchLeadership 定期確認持有 lock 的連線仍有效，失效時呼叫 onLost
+func WatchLeadership(ctx context.Context, conn *sql.Conn, onLost func()) {
+	ticker := time.NewTicker(leaderRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.PingContext(ctx); err != nil {
+				log.Printf("[ERROR] worker 主控權連線中斷: %v", err)
+				onLost()
+				return
+			}
+		}
+	}
+}
+
+// ProcessJobs 持續取出 sync_jobs 中排隊的手動同步並執行，直到 ctx 結束；
+// 只能由取得 worker 主控權的程序呼叫，開始前先將之前的 worker 未完成的工作（不論 owner）重新排隊
+func (s *Scheduler) ProcessJobs(ctx context.Context) {
+	if n, err := database.ResetRunningSyncJobs(s.DB); err != nil {
+		log.Printf("[WARN] 無法重設未完成的同步工作: %v", err)
+	} else if n > 0 {
+		log.Printf("[INFO] 已將 %d 個未完成的同步工作重新排隊", n)
+	}
+
+	log.Printf("[INFO] 開始處理同步工作佇列（每 %v 檢查一次）", jobPollInterval)
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ReclaimStaleSyncJobs(s.DB)
+		for s.runNextJob() {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNextJob 執行一個排隊的工作，沒有工作時回傳 false
+func (s *Scheduler) runNextJob() bool {
+	job, err := database.ClaimSyncJob(s.DB, s.InstanceID)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		log.Printf("[WARN] 無法取得同步工作: %v", err)
+		return false
+	}
+
+	log.Printf("[INFO] 執行同步工作 #%d（%s）", job.ID, job.Type)
+	runner := NewScheduler(s.DB, 0)
+	runner.Priority = google.PriorityManual
+	if job.CallbackURL != "" {
+		runner.Progress = NewSyncCallback(job.CallbackURL, s.CallbackSecret, job.Type)
+	}
+	stop := KeepSyncJobAlive(s.DB, job.ID)
+	syncErr := runner.RunSync(job.Type == "monthly")
+	stop()
+
+	if err := database.FinishSyncJob(s.DB, job.ID, syncErr); err != nil {
+		log.Printf("[WARN] 無法記錄同步工作 #%d 的結果: %v", job.ID, err)
+	}
+	return true
+}
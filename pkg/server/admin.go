@@ -0,0 +1,383 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"PXMarkMapBackEnd/pkg/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReviewLimit = 100
+	maxReviewLimit     = 500
+)
+
+// GeocodeBatchRequest 批次地點查詢請求
+type GeocodeBatchRequest struct {
+	StoreIDs []int `json:"storeIds"`
+}
+
+// GeocodeProgress 批次地點查詢進度事件
+type GeocodeProgress struct {
+	Index     int     `json:"index"`
+	Total     int     `json:"total"`
+	StoreID   int     `json:"storeId"`
+	StoreName string  `json:"storeName"`
+	Status    string  `json:"status"` // 'success', 'failed'
+	Address   string  `json:"address,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// GeocodeSummary 批次地點查詢結果摘要
+type GeocodeSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	NotFound  int `json:"notFound"` // 資料庫中不存在的店家 ID
+}
+
+// RegisterAdminRoutes 註冊管理端點（需要 X-Admin-Secret 驗證）
+func RegisterAdminRoutes(r gin.IRouter, db, syncDB *sql.DB, cfg *config.Config) {
+	admin := r.Group("/admin", adminAuth(cfg.AdminSecret))
+	admin.GET("/config", handleConfig(cfg))
+	admin.GET("/overview", handleOverview(db))
+	admin.GET("/runtime", handleRuntime(db, syncDB))
+	admin.GET("/indexAdvisor", handleIndexAdvisor(db))
+	admin.POST("/geocode/batch", handleGeocodeBatch(db))
+	// 店家維護另外接受有 admin:stores 權限的 API 金鑰
+	stores := r.Group("/admin/stores", adminAuthOrScope(db, cfg, database.ScopeAdminStores))
+	stores.GET("", handleListStores(db))
+	stores.POST("", handleCreateStore(db))
+	stores.GET("/collisions", handleCoordinateCollisions(db))
+	stores.POST("/gc", handleStoreGC(db))
+	stores.GET("/:id", handleGetStore(db))
+	stores.DELETE("/:id", handleDeleteStore(db))
+	stores.PUT("/:id", handlePatchStore(db))
+	stores.PATCH("/:id", handlePatchStore(db))
+	stores.POST("/bulkUpdate", handleBulkUpdateStores(db))
+	stores.GET("/bulkUpdate/:id", handleGetBulkStoreJob(db))
+	admin.GET("/sources", handleListSources(db))
+	admin.DELETE("/sources/:id/data", handleAdminPurgeSource(db))
+	admin.GET("/syncRuns/:id/log", handleSyncRunLog(db))
+	admin.GET("/syncRuns/:id/diff/:other", handleSyncRunDiff(db))
+	admin.GET("/links", handleListLinks(db))
+	admin.POST("/links", handleCreateLink(db, cfg.URLPath(cfg.MapBaseURL), cfg.BasePath))
+	admin.POST("/previewLinks", handleCreatePreviewLink(cfg))
+	admin.POST("/regions", handleCreateRegion(db))
+	admin.DELETE("/regions/:id", handleDeleteRegion(db))
+	admin.GET("/webhooks", handleListWebhooks(db))
+	admin.POST("/webhooks", handleCreateWebhook(db))
+	admin.DELETE("/webhooks/:id", handleDeleteWebhook(db))
+	admin.GET("/webhooks/:id/deliveries", handleWebhookDeliveries(db))
+	admin.GET("/exports", handleListExports(db))
+	admin.GET("/exports/:id/download", handleDownloadExport(db))
+	admin.GET("/review/shipments", handleFlaggedShipments(db))
+	admin.GET("/shipments/:id", handleGetShipment(db))
+	admin.PATCH("/shipments/:id", handleUpdateShipment(db))
+	admin.DELETE("/shipments/:id", handleDeleteShipment(db))
+	admin.GET("/products", handleListProducts(db))
+	admin.PUT("/products/:product", handleSaveProduct(db))
+	admin.GET("/products/aliases", handleListProductAliases(db))
+	admin.POST("/products/rename", handleRenameProduct(db))
+	admin.GET("/apiKeys", handleListAPIKeys(db))
+	admin.POST("/apiKeys", handleCreateAPIKey(db, cfg.APIKeyMonthlyQuota))
+	admin.GET("/apiKeys/usage", handleAPIKeyUsage(db))
+	admin.PATCH("/apiKeys/:id", handleUpdateAPIKey(db))
+	admin.DELETE("/apiKeys/:id", handleDeleteAPIKey(db))
+
+	log.Println("[INFO] 管理端點已啟用: /api/v1/admin")
+}
+
+// adminAuth 驗證管理密鑰
+func adminAuth(adminSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Secret")), []byte(adminSecret)) != 1 {
+			logf(c, "[WARN] 管理請求被拒絕：密鑰錯誤 (%s %s)", c.Request.Method, c.Request.URL.Path)
+			AbortWithError(c, http.StatusUnauthorized, "Invalid admin secret")
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminAuthOrScope 帶 X-API-Key 時以金鑰驗證且必須有 scope 權限，否則驗證管理密鑰
+func adminAuthOrScope(db *sql.DB, cfg *config.Config, scope string) gin.HandlerFunc {
+	secretAuth := adminAuth(cfg.AdminSecret)
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			secretAuth(c)
+			return
+		}
+		if apiKey, ok := authenticateAPIKey(c, db, ParseList(cfg.APIKeys), key); ok {
+			if !apiKey.HasScope(scope) {
+				logf(c, "[WARN] API 金鑰 %s 沒有 %s 權限 (%s %s)", apiKey.Name, scope, c.Request.Method, c.Request.URL.Path)
+				AbortWithError(c, http.StatusForbidden, "API key lacks scope "+scope)
+				return
+			}
+			c.Next()
+		}
+	}
+}
+
+// auditActor 稽核紀錄中的操作者：以 API 金鑰存取時為 api-key:<名稱>，否則為 fallback
+func auditActor(c *gin.Context, fallback string) string {
+	if apiKey := requestAPIKey(c); apiKey != nil {
+		return "api-key:" + apiKey.Name
+	}
+	return fallback
+}
+
+// handleConfig 回傳目前生效的設定（密鑰已隱藏）
+func handleConfig(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, cfg.Redacted())
+	}
+}
+
+// handleOverview 回傳店家統計與狀態檢查結果
+func handleOverview(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		overview, err := database.GetStoreOverview(db)
+		if err != nil {
+			logf(c, "[ERROR] 查詢管理總覽失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, overview)
+	}
+}
+
+// handleIndexAdvisor 回傳資料表掃描統計、缺少的索引與慢查詢（有安裝 pg_stat_statements 時）
+func handleIndexAdvisor(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := database.AdviseIndexes(db)
+		if err != nil {
+			logf(c, "[ERROR] 產生索引建議失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// handleSyncRunLog 回傳單次同步的執行日誌（純文字）
+func handleSyncRunLog(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid sync run id")
+			return
+		}
+
+		output, err := scheduler.NewScheduler(db, 0).GetSyncOutput(id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "sync run not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.String(http.StatusOK, output)
+	}
+}
+
+// handleListExports 列出每月出貨封存
+func handleListExports(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		exports, err := database.ListExports(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, exports)
+	}
+}
+
+// handleFlaggedShipments 列出數量異常、未顯示在公開地圖上的出貨（?limit= 預設 100，最多 500）；
+// 修正表單數量後，下次同步會重新檢查並清除標記
+func handleFlaggedShipments(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultReviewLimit
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 || n > maxReviewLimit {
+				RespondError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+				return
+			}
+			limit = n
+		}
+
+		shipments, err := database.GetFlaggedShipments(db, limit)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, shipments)
+	}
+}
+
+// handleDownloadExport 下載封存的 CSV
+func handleDownloadExport(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid export id")
+			return
+		}
+
+		blobs, err := storage.Default()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		fileName, data, err := database.GetExportData(db, blobs, id)
+		if err == sql.ErrNoRows || err == storage.ErrNotFound {
+			RespondError(c, http.StatusNotFound, "export not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	}
+}
+
+// handleSyncRunDiff 比較兩次同步後的快照，回傳店家與出貨的新增、移除與修改
+func handleSyncRunDiff(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fromID, err1 := strconv.Atoi(c.Param("id"))
+		toID, err2 := strconv.Atoi(c.Param("other"))
+		if err1 != nil || err2 != nil {
+			RespondError(c, http.StatusBadRequest, "invalid sync run id")
+			return
+		}
+
+		blobs, err := storage.Default()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		var snaps [2]*database.Snapshot
+		for i, id := range []int{fromID, toID} {
+			snap, err := database.GetSyncSnapshot(db, blobs, id)
+			if err == sql.ErrNoRows || err == storage.ErrNotFound {
+				RespondError(c, http.StatusNotFound, "no snapshot for sync run "+strconv.Itoa(id))
+				return
+			}
+			if err != nil {
+				RespondError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			snaps[i] = snap
+		}
+
+		c.JSON(http.StatusOK, database.DiffSnapshots(fromID, toID, snaps[0], snaps[1]))
+	}
+}
+
+// handleGeocodeBatch 依店家 ID 逐筆查詢 Places API，並以 SSE 回報進度
+func handleGeocodeBatch(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GeocodeBatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.StoreIDs) == 0 {
+			RespondError(c, http.StatusBadRequest, "storeIds is required")
+			return
+		}
+
+		stores, err := database.GetStoresByIDs(db, req.StoreIDs)
+		if err != nil {
+			logf(c, "[ERROR] 查詢店家失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		summary := GeocodeSummary{
+			Total:    len(stores),
+			NotFound: len(req.StoreIDs) - len(stores),
+		}
+		log.Printf("[INFO] 開始批次地點查詢，共 %d 個店家", len(stores))
+
+		ctx := c.Request.Context()
+		index := 0
+		c.Stream(func(w io.Writer) bool {
+			if index >= len(stores) {
+				c.SSEvent("done", summary)
+				log.Printf("[INFO] 批次地點查詢完成: 成功 %d，失敗 %d", summary.Succeeded, summary.Failed)
+				return false
+			}
+
+			// 客戶端中斷連線時停止查詢，避免浪費 API 配額
+			select {
+			case <-ctx.Done():
+				log.Printf("[WARN] 批次地點查詢已中斷，完成 %d/%d", index, len(stores))
+				return false
+			default:
+			}
+
+			store := stores[index]
+			index++
+
+			progress := geocodeStore(db, store)
+			progress.Index = index
+			progress.Total = len(stores)
+			if progress.Status == "success" {
+				summary.Succeeded++
+			} else {
+				summary.Failed++
+			}
+
+			c.SSEvent("progress", progress)
+			return true
+		})
+	}
+}
+
+// geocodeStore 查詢單一店家的地點並寫回資料庫
+func geocodeStore(db *sql.DB, store database.StoreRecord) GeocodeProgress {
+	progress := GeocodeProgress{
+		StoreID:   store.ID,
+		StoreName: store.StoreName,
+		Status:    "failed",
+	}
+
+	placeRes, err := google.SearchPlace(google.StoreSearchQuery(store.StoreName, store.Region),
+		google.RegionBias(store.Region, google.PriorityManual), google.PriorityManual)
+	if err != nil {
+		log.Printf("⚠ 無法找到 %s 的地點資訊: %v", store.StoreName, err)
+		progress.Error = err.Error()
+		return progress
+	}
+
+	place := placeRes.Places[0]
+	err = database.UpdateStoreLocation(db, store.ID, place.ID, place.FormattedAddress,
+		place.Location.Latitude, place.Location.Longitude, place.BusinessStatus)
+	if err != nil {
+		log.Printf("[ERROR] 更新 %s 地點失敗: %v", store.StoreName, err)
+		progress.Error = err.Error()
+		return progress
+	}
+
+	progress.Status = "success"
+	progress.Address = place.FormattedAddress
+	progress.Latitude = place.Location.Latitude
+	progress.Longitude = place.Location.Longitude
+	return progress
+}
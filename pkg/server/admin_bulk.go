@@ -0,0 +1,141 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// BulkUpdateStoresRequest 批次店家操作請求
+type BulkUpdateStoresRequest struct {
+	Filter database.StoreFilter `json:"filter"`
+	Action string               `json:"action"` // 'deactivate', 'reactivate', 're-geocode'
+}
+
+// handleBulkUpdateStores 依條件選取店家並在背景執行停用 / 重新啟用 / 重新查詢地點，
+// 立即回傳工作 ID，結果報告以 GET /api/v1/admin/stores/bulkUpdate/:id 查詢
+func handleBulkUpdateStores(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BulkUpdateStoresRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		switch req.Action {
+		case database.BulkActionDeactivate, database.BulkActionReactivate, database.BulkActionRegeocode:
+		default:
+			RespondError(c, http.StatusBadRequest, "action must be deactivate, reactivate or re-geocode")
+			return
+		}
+		if req.Filter.IsEmpty() {
+			RespondError(c, http.StatusBadRequest, "filter must include region, noShipmentSince or sourceId")
+			return
+		}
+		if req.Filter.NoShipmentSince != "" {
+			if _, err := time.Parse("2006-01-02", req.Filter.NoShipmentSince); err != nil {
+				RespondError(c, http.StatusBadRequest, "noShipmentSince must be YYYY-MM-DD")
+				return
+			}
+		}
+
+		jobID, err := database.CreateBulkStoreJob(db, req.Action, req.Filter)
+		if err != nil {
+			logf(c, "[ERROR] 建立批次店家操作失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// 透過排程器的工作執行，程序關閉時會等待批次操作完成
+		go func() {
+			job := scheduler.Job{
+				Name: fmt.Sprintf("bulk-store-%d", jobID),
+				Run:  func() error { return runBulkStoreJob(db, jobID, req) },
+			}
+			if !scheduler.RunJob(job) {
+				database.FinishBulkStoreJob(db, jobID, []database.BulkStoreResult{}, fmt.Errorf("程序停止中，未執行"))
+			}
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"jobId":  jobID,
+			"status": database.JobStatusRunning,
+		})
+	}
+}
+
+// runBulkStoreJob 執行批次操作並保存結果報告
+func runBulkStoreJob(db *sql.DB, jobID int, req BulkUpdateStoresRequest) error {
+	stores, err := database.FindStoresByFilter(db, req.Filter)
+	if err != nil {
+		database.FinishBulkStoreJob(db, jobID, []database.BulkStoreResult{}, err)
+		return err
+	}
+	log.Printf("[INFO] 批次店家操作 #%d（%s）開始，共 %d 個店家", jobID, req.Action, len(stores))
+
+	results := make([]database.BulkStoreResult, 0, len(stores))
+	for _, store := range stores {
+		results = append(results, applyBulkAction(db, store, req.Action))
+	}
+
+	if err := database.FinishBulkStoreJob(db, jobID, results, nil); err != nil {
+		log.Printf("[ERROR] 無法保存批次店家操作 #%d 的結果: %v", jobID, err)
+		return err
+	}
+	log.Printf("[INFO] 批次店家操作 #%d 完成", jobID)
+	return nil
+}
+
+// applyBulkAction 對單一店家執行批次操作
+func applyBulkAction(db *sql.DB, store database.StoreRecord, action string) database.BulkStoreResult {
+	result := database.BulkStoreResult{StoreID: store.ID, StoreName: store.StoreName, Status: "failed"}
+
+	if action == database.BulkActionRegeocode {
+		progress := geocodeStore(db, store)
+		result.Status, result.Error = "updated", progress.Error
+		if progress.Status != "success" {
+			result.Status = "failed"
+		}
+		return result
+	}
+
+	active := action == database.BulkActionReactivate
+	applied, err := database.PatchStore(db, store.ID, map[string]interface{}{"is_active": active}, "admin-bulk")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = "unchanged"
+	if len(applied) > 0 {
+		result.Status = "updated"
+	}
+	return result
+}
+
+// handleGetBulkStoreJob 取得批次店家操作的狀態與結果報告
+func handleGetBulkStoreJob(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid job id")
+			return
+		}
+
+		job, err := database.GetBulkStoreJob(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "job not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
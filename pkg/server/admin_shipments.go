@@ -0,0 +1,172 @@
+package server
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateShipmentRequest PATCH /api/v1/admin/shipments/:id 的請求內容，未提供的欄位不修改；note 必填
+type UpdateShipmentRequest struct {
+	ProductType *string `json:"productType"`
+	Date        *string `json:"date"` // YYYY-MM-DD
+	Quantity    *string `json:"quantity"`
+	Note        string  `json:"note"`
+}
+
+// DeleteShipmentRequest DELETE /api/v1/admin/shipments/:id 的請求內容（也可改用 ?note=）
+type DeleteShipmentRequest struct {
+	Note string `json:"note"`
+}
+
+// ShipmentDetailResponse GET /api/v1/admin/shipments/:id 的回應；出貨已刪除時 shipment 為 null，仍回傳修正紀錄
+type ShipmentDetailResponse struct {
+	Shipment *database.ShipmentRecord    `json:"shipment"`
+	History  []database.ShipmentAuditLog `json:"history"`
+}
+
+// handleGetShipment 取得出貨與修正紀錄
+func handleGetShipment(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid shipment id")
+			return
+		}
+
+		shipment, err := database.GetShipmentByID(db, id)
+		if err != nil && err != sql.ErrNoRows {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		history, err := database.GetShipmentAuditLogs(db, id)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if shipment == nil && len(history) == 0 {
+			RespondError(c, http.StatusNotFound, "shipment not found")
+			return
+		}
+		c.JSON(http.StatusOK, ShipmentDetailResponse{Shipment: shipment, History: history})
+	}
+}
+
+// handleUpdateShipment 修正出貨的產品、日期或數量（例如工作表曾有錯字、之後已修正），並記錄附註
+func handleUpdateShipment(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid shipment id")
+			return
+		}
+
+		var req UpdateShipmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "request body must be a JSON object")
+			return
+		}
+		note := strings.TrimSpace(req.Note)
+		if note == "" {
+			RespondError(c, http.StatusBadRequest, "note is required")
+			return
+		}
+
+		var update database.ShipmentUpdate
+		if req.ProductType != nil {
+			productType := strings.TrimSpace(*req.ProductType)
+			if productType == "" {
+				RespondError(c, http.StatusBadRequest, "productType must not be empty")
+				return
+			}
+			update.ProductType = &productType
+		}
+		if req.Date != nil {
+			date, err := time.Parse("2006-01-02", strings.TrimSpace(*req.Date))
+			if err != nil {
+				RespondError(c, http.StatusBadRequest, "date must be YYYY-MM-DD")
+				return
+			}
+			update.ShipmentDate = &date
+		}
+		if req.Quantity != nil {
+			quantity := strings.TrimSpace(*req.Quantity)
+			update.Quantity = &quantity
+		}
+		if update == (database.ShipmentUpdate{}) {
+			RespondError(c, http.StatusBadRequest, "no fields to update")
+			return
+		}
+
+		shipment, err := database.UpdateShipment(db, id, update, note, "admin-api")
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "shipment not found")
+			return
+		}
+		if isUniqueViolation(err) {
+			RespondError(c, http.StatusConflict, "the store already has a shipment of this product on that date")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 修正出貨 #%d 失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		refreshAfterShipmentCorrection(db)
+		c.JSON(http.StatusOK, shipment)
+	}
+}
+
+// handleDeleteShipment 刪除出貨（例如工作表中已移除的錯誤列），並記錄附註
+func handleDeleteShipment(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid shipment id")
+			return
+		}
+
+		note := c.Query("note")
+		if note == "" && c.Request.ContentLength != 0 {
+			var req DeleteShipmentRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				RespondError(c, http.StatusBadRequest, "request body must be a JSON object")
+				return
+			}
+			note = req.Note
+		}
+		note = strings.TrimSpace(note)
+		if note == "" {
+			RespondError(c, http.StatusBadRequest, "note is required")
+			return
+		}
+
+		shipment, err := database.DeleteShipment(db, id, note, "admin-api")
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "shipment not found")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 刪除出貨 #%d 失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		refreshAfterShipmentCorrection(db)
+		c.JSON(http.StatusOK, shipment)
+	}
+}
+
+// refreshAfterShipmentCorrection 重新計算區域每日彙總，開放資料統計不必等到下次同步
+func refreshAfterShipmentCorrection(db *sql.DB) {
+	if _, err := database.RefreshDistrictDailyTotals(db); err != nil {
+		log.Printf("[WARN] 出貨修正後無法更新區域每日彙總: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// defaultStoreListLimit GET /api/v1/admin/stores 未指定 limit 時每頁筆數
+const defaultStoreListLimit = 50
+
+// CreateStoreRequest POST /api/v1/admin/stores 的請求內容
+type CreateStoreRequest struct {
+	StoreName        string   `json:"storeName"`
+	PlaceID          string   `json:"placeId"`
+	FormattedAddress string   `json:"formattedAddress"`
+	Latitude         *float64 `json:"latitude"`
+	Longitude        *float64 `json:"longitude"`
+	Region           string   `json:"region"`
+}
+
+// StoreListResponse GET /api/v1/admin/stores 的回應
+type StoreListResponse struct {
+	Data  []database.StoreRecord `json:"data"`
+	Total int                    `json:"total"`
+}
+
+// isUniqueViolation 是否為 unique 限制衝突（例如店名重複）
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// handleListStores 依 ?q=（店名或地址）與 ?active=true|false 列出店家，支援 limit / offset
+func handleListStores(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, err := parsePagination(c)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if limit == 0 {
+			limit = defaultStoreListLimit
+		}
+
+		var active *bool
+		if s := c.Query("active"); s != "" {
+			v, err := strconv.ParseBool(s)
+			if err != nil {
+				RespondError(c, http.StatusBadRequest, "active must be true or false")
+				return
+			}
+			active = &v
+		}
+
+		stores, total, err := database.ListStores(db, strings.TrimSpace(c.Query("q")), active, limit, offset)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, StoreListResponse{Data: stores, Total: total})
+	}
+}
+
+// handleGetStore 取得單一店家
+func handleGetStore(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid store id")
+			return
+		}
+
+		store, err := database.GetStoreByID(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "store not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, store)
+	}
+}
+
+// handleCoordinateCollisions 列出座標完全相同的啟用店家（地圖上以固定的小幅偏移分開顯示），
+// 確認是否同一棟建築或需要以 PATCH /api/v1/admin/stores/:id 修正座標
+func handleCoordinateCollisions(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collisions, err := database.GetCoordinateCollisions(db)
+		if err != nil {
+			logf(c, "[ERROR] 查詢座標重疊的店家失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, collisions)
+	}
+}
+
+// handleStoreGC 清理沒有出貨、且 days 天內（預設 30）未出現在工作表的孤兒店家；
+// action 預設 dry-run 只回傳清單，確認後以 deactivate 停用或 delete 刪除
+func handleStoreGC(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		action := c.DefaultQuery("action", database.StoreGCDryRun)
+		if !database.IsValidStoreGCAction(action) {
+			RespondError(c, http.StatusBadRequest, "action must be dry-run, deactivate or delete")
+			return
+		}
+		days, err := queryInt(c, "days", database.DefaultStoreGCDays, 1, 3650)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		report, err := database.CollectOrphanedStores(db, days, action, auditActor(c, "admin-api"))
+		if err != nil {
+			logf(c, "[ERROR] 清理孤兒店家失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// handleCreateStore 新增店家（例如工作表漏填、需要先建立再由同步補上出貨）
+func handleCreateStore(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateStoreRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "request body must be a JSON object")
+			return
+		}
+		req.StoreName = strings.TrimSpace(req.StoreName)
+		if req.StoreName == "" {
+			RespondError(c, http.StatusBadRequest, "storeName is required")
+			return
+		}
+		if (req.Latitude == nil) != (req.Longitude == nil) {
+			RespondError(c, http.StatusBadRequest, "latitude and longitude must be provided together")
+			return
+		}
+		if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+			RespondError(c, http.StatusBadRequest, "latitude or longitude out of range")
+			return
+		}
+
+		store, err := database.CreateStore(db, database.NewStore{
+			StoreName:        req.StoreName,
+			PlaceID:          strings.TrimSpace(req.PlaceID),
+			FormattedAddress: strings.TrimSpace(req.FormattedAddress),
+			Latitude:         req.Latitude,
+			Longitude:        req.Longitude,
+			Region:           strings.TrimSpace(req.Region),
+		}, auditActor(c, "admin-api"))
+		if isUniqueViolation(err) {
+			RespondError(c, http.StatusConflict, "a store with this name already exists")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 建立店家失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusCreated, store)
+	}
+}
+
+// handleDeleteStore 刪除店家；有出貨紀錄時需加 ?force=true（出貨紀錄會一併刪除），否則建議改為停用
+func handleDeleteStore(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid store id")
+			return
+		}
+
+		if c.Query("force") != "true" {
+			n, err := database.CountStoreShipments(db, id)
+			if err != nil {
+				RespondError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if n > 0 {
+				RespondError(c, http.StatusConflict, fmt.Sprintf("store has %d shipments; deactivate it with PATCH isActive=false or delete with force=true", n))
+				return
+			}
+		}
+
+		shipments, err := database.DeleteStore(db, id, auditActor(c, "admin-api"))
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "store not found")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 刪除店家 #%d 失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": id, "deletedShipments": shipments})
+	}
+}
+
+// storePatchFields JSON 欄位名稱與資料庫欄位的對應
+var storePatchFields = map[string]string{
+	"storeName":        "store_name",
+	"placeId":          "place_id",
+	"formattedAddress": "formatted_address",
+	"latitude":         "latitude",
+	"longitude":        "longitude",
+	"isActive":         "is_active",
+}
+
+// handlePatchStore 以 JSON Merge Patch (RFC 7396) 部分更新店家資料
+// 只會修改請求中出現的欄位，值為 null 表示清除該欄位
+func handlePatchStore(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid store id")
+			return
+		}
+
+		var patch map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			RespondError(c, http.StatusBadRequest, "request body must be a JSON object")
+			return
+		}
+
+		changes, err := parseStorePatch(patch)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		applied, err := database.PatchStore(db, id, changes, auditActor(c, "admin-api"))
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "store not found")
+			return
+		}
+		if isUniqueViolation(err) {
+			RespondError(c, http.StatusConflict, "a store with this name already exists")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 更新店家 #%d 失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		store, err := database.GetStoreByID(db, id)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if applied == nil {
+			applied = []database.FieldChange{}
+		}
+		// v1 的 changes[].field 是資料庫欄位名稱（store_name），?v=2 改用與請求相同的 camelCase 名稱
+		if c.Query("v") == "2" {
+			for i := range applied {
+				applied[i].Field = storePatchFieldName(applied[i].Field)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"store":   store,
+			"changes": applied,
+		})
+	}
+}
+
+// storePatchFieldName 資料庫欄位對應的 JSON 欄位名稱
+func storePatchFieldName(column string) string {
+	for field, col := range storePatchFields {
+		if col == column {
+			return field
+		}
+	}
+	return column
+}
+
+// parseStorePatch 驗證 patch 內容並轉換為資料庫欄位
+func parseStorePatch(patch map[string]json.RawMessage) (map[string]interface{}, error) {
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	changes := make(map[string]interface{})
+	for field, raw := range patch {
+		column, ok := storePatchFields[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %s", field)
+		}
+
+		isNull := strings.TrimSpace(string(raw)) == "null"
+
+		switch field {
+		case "storeName":
+			var name string
+			if isNull || json.Unmarshal(raw, &name) != nil || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("storeName must be a non-empty string")
+			}
+			changes[column] = strings.TrimSpace(name)
+
+		case "isActive":
+			var active bool
+			if isNull || json.Unmarshal(raw, &active) != nil {
+				return nil, fmt.Errorf("isActive must be a boolean")
+			}
+			changes[column] = active
+
+		case "latitude", "longitude":
+			if isNull {
+				changes[column] = nil
+				continue
+			}
+			var v float64
+			if json.Unmarshal(raw, &v) != nil {
+				return nil, fmt.Errorf("%s must be a number or null", field)
+			}
+			if (field == "latitude" && (v < -90 || v > 90)) || (field == "longitude" && (v < -180 || v > 180)) {
+				return nil, fmt.Errorf("%s out of range", field)
+			}
+			changes[column] = v
+
+		default:
+			if isNull {
+				changes[column] = nil
+				continue
+			}
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				return nil, fmt.Errorf("%s must be a string or null", field)
+			}
+			changes[column] = s
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyContextKey 通過驗證的 API 金鑰（*database.APIKey）在 gin.Context 中的 key
+const apiKeyContextKey = "apiKey"
+
+// CreateAPIKeyRequest 建立 API 金鑰請求（Scopes 未指定時為 read:map、read:stats，
+// MonthlyQuota 未指定時為 API_KEY_MONTHLY_QUOTA，0 表示不限）
+type CreateAPIKeyRequest struct {
+	Name         string   `json:"name"`
+	Scopes       []string `json:"scopes"`
+	MonthlyQuota *int     `json:"monthlyQuota"`
+}
+
+// UpdateAPIKeyRequest 啟用或停用 API 金鑰、更新權限範圍或每月請求上限（至少指定一項）
+type UpdateAPIKeyRequest struct {
+	IsActive     *bool     `json:"isActive"`
+	Scopes       *[]string `json:"scopes"`
+	MonthlyQuota *int      `json:"monthlyQuota"`
+}
+
+// APIKeyAuth 驗證 X-API-Key：符合 envKeys（API_KEYS 環境變數）或資料庫中啟用中的金鑰才放行，
+// 金鑰記錄在 context 中，各路由再以 RequireScope 檢查權限範圍
+func APIKeyAuth(db *sql.DB, envKeys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			AbortWithError(c, http.StatusUnauthorized, "API key required")
+			return
+		}
+		if _, ok := authenticateAPIKey(c, db, envKeys, key); ok {
+			c.Next()
+		}
+	}
+}
+
+// authenticateAPIKey 查詢金鑰並記錄在 context 中；無效、已停用或查詢失敗時回應錯誤並回傳 false。
+// API_KEYS 環境變數的金鑰只有 DefaultAPIKeyScopes 的權限
+func authenticateAPIKey(c *gin.Context, db *sql.DB, envKeys []string, key string) (*database.APIKey, bool) {
+	for _, k := range envKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			apiKey := &database.APIKey{Name: "API_KEYS", Scopes: database.DefaultAPIKeyScopes, IsActive: true}
+			c.Set(apiKeyContextKey, apiKey)
+			return apiKey, true
+		}
+	}
+
+	apiKey, err := database.FindActiveAPIKey(db, key)
+	if err == sql.ErrNoRows {
+		logf(c, "[WARN] API 金鑰無效或已停用 (%s %s)", c.Request.Method, c.Request.URL.Path)
+		AbortWithError(c, http.StatusUnauthorized, "Invalid API key")
+		return nil, false
+	}
+	if err != nil {
+		logf(c, "[ERROR] 查詢 API 金鑰失敗: %v", err)
+		AbortWithError(c, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if !checkAPIKeyQuota(c, db, apiKey) {
+		return nil, false
+	}
+	c.Set(apiKeyContextKey, apiKey)
+	return apiKey, true
+}
+
+// checkAPIKeyQuota 記錄金鑰本月的用量；超過每月上限時回應 429，Retry-After 為距離下個月的秒數
+func checkAPIKeyQuota(c *gin.Context, db *sql.DB, apiKey *database.APIKey) bool {
+	now := time.Now()
+	month := database.MonthStart(now)
+	ok, err := database.RecordAPIKeyUsage(db, apiKey, month)
+	if err != nil {
+		logf(c, "[ERROR] 記錄 API 金鑰用量失敗: %v", err)
+		AbortWithError(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !ok {
+		retryAfter := int(month.AddDate(0, 1, 0).Sub(now).Seconds()) + 1
+		logf(c, "[WARN] API 金鑰 %s 已達本月上限 %d 次 (%s %s)", apiKey.Name, apiKey.MonthlyQuota, c.Request.Method, c.Request.URL.Path)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		AbortWithError(c, http.StatusTooManyRequests, "API key monthly quota exceeded")
+		return false
+	}
+	return true
+}
+
+// requestAPIKey 本次請求通過驗證的 API 金鑰，沒有經過 API 金鑰驗證時回傳 nil
+func requestAPIKey(c *gin.Context) *database.APIKey {
+	if v, ok := c.Get(apiKeyContextKey); ok {
+		return v.(*database.APIKey)
+	}
+	return nil
+}
+
+// RequireScope 以 API 金鑰存取時，金鑰必須有 scope 權限（否則 403）；
+// 沒有經過 API 金鑰驗證的請求（REQUIRE_API_KEY=false）直接放行
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := requestAPIKey(c); apiKey != nil && !apiKey.HasScope(scope) {
+			logf(c, "[WARN] API 金鑰 %s 沒有 %s 權限 (%s %s)", apiKey.Name, scope, c.Request.Method, c.Request.URL.Path)
+			AbortWithError(c, http.StatusForbidden, "API key lacks scope "+scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+// validateScopes 檢查權限範圍，回傳第一個不支援的值
+func validateScopes(scopes []string) (string, bool) {
+	for _, s := range scopes {
+		if !database.IsValidAPIKeyScope(s) {
+			return s, false
+		}
+	}
+	return "", true
+}
+
+// handleCreateAPIKey 建立 API 金鑰，回應中包含金鑰本身（只會顯示這一次）；未指定每月上限時使用 defaultQuota
+func handleCreateAPIKey(db *sql.DB, defaultQuota int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Name == "" {
+			RespondError(c, http.StatusBadRequest, "name is required")
+			return
+		}
+		scopes := req.Scopes
+		if len(scopes) == 0 {
+			scopes = database.DefaultAPIKeyScopes
+		}
+		if s, ok := validateScopes(scopes); !ok {
+			RespondError(c, http.StatusBadRequest, "unknown scope: "+s)
+			return
+		}
+		quota := defaultQuota
+		if req.MonthlyQuota != nil {
+			quota = *req.MonthlyQuota
+		}
+		if quota < 0 {
+			RespondError(c, http.StatusBadRequest, "monthlyQuota must not be negative")
+			return
+		}
+
+		key := randomSecret()
+		apiKey, err := database.CreateAPIKey(db, req.Name, key, scopes, quota)
+		if err != nil {
+			logf(c, "[ERROR] 建立 API 金鑰失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已建立 API 金鑰 #%d (%s)，權限: %s，每月上限: %d", apiKey.ID, apiKey.Name, strings.Join(apiKey.Scopes, ", "), apiKey.MonthlyQuota)
+		c.JSON(http.StatusCreated, gin.H{
+			"apiKey": apiKey,
+			"key":    key,
+		})
+	}
+}
+
+// handleListAPIKeys 列出所有 API 金鑰（只顯示前綴）
+func handleListAPIKeys(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := database.ListAPIKeys(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// handleUpdateAPIKey 啟用或停用 API 金鑰、更新權限範圍或每月請求上限
+func handleUpdateAPIKey(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid api key id")
+			return
+		}
+		var req UpdateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.IsActive == nil && req.Scopes == nil && req.MonthlyQuota == nil) {
+			RespondError(c, http.StatusBadRequest, "isActive, scopes or monthlyQuota is required")
+			return
+		}
+		if req.MonthlyQuota != nil && *req.MonthlyQuota < 0 {
+			RespondError(c, http.StatusBadRequest, "monthlyQuota must not be negative")
+			return
+		}
+		if req.Scopes != nil {
+			if len(*req.Scopes) == 0 {
+				RespondError(c, http.StatusBadRequest, "scopes must not be empty")
+				return
+			}
+			if s, ok := validateScopes(*req.Scopes); !ok {
+				RespondError(c, http.StatusBadRequest, "unknown scope: "+s)
+				return
+			}
+		}
+
+		var apiKey *database.APIKey
+		if req.Scopes != nil {
+			apiKey, err = database.SetAPIKeyScopes(db, id, *req.Scopes)
+		}
+		if err == nil && req.MonthlyQuota != nil {
+			apiKey, err = database.SetAPIKeyQuota(db, id, *req.MonthlyQuota)
+		}
+		if err == nil && req.IsActive != nil {
+			apiKey, err = database.SetAPIKeyActive(db, id, *req.IsActive)
+		}
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "api key not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] API 金鑰 #%d (%s) 啟用狀態: %v，權限: %s，每月上限: %d", apiKey.ID, apiKey.Name, apiKey.IsActive, strings.Join(apiKey.Scopes, ", "), apiKey.MonthlyQuota)
+		c.JSON(http.StatusOK, apiKey)
+	}
+}
+
+// handleAPIKeyUsage 回傳各金鑰的每月用量（?month=YYYY-MM，未指定時為本月）
+func handleAPIKeyUsage(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		month := database.MonthStart(time.Now())
+		if m := c.Query("month"); m != "" {
+			t, err := time.ParseInLocation("2006-01", m, time.Local)
+			if err != nil {
+				RespondError(c, http.StatusBadRequest, "month must be YYYY-MM")
+				return
+			}
+			month = t
+		}
+
+		usage, err := database.GetAPIKeyUsage(db, month)
+		if err != nil {
+			logf(c, "[ERROR] 查詢 API 金鑰用量失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"month": month.Format("2006-01"),
+			"keys":  usage,
+		})
+	}
+}
+
+// handleDeleteAPIKey 刪除 API 金鑰
+func handleDeleteAPIKey(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid api key id")
+			return
+		}
+
+		err = database.DeleteAPIKey(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "api key not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已刪除 API 金鑰 #%d", id)
+		c.Status(http.StatusNoContent)
+	}
+}
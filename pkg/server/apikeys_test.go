@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIKeyMonthlyQuota 超過每月上限的請求回應 429 與 Retry-After，且不計入用量
+func TestAPIKeyMonthlyQuota(t *testing.T) {
+	db := openTestDB(t)
+	key := fmt.Sprintf("quota-test-%d", time.Now().UnixNano())
+	apiKey, err := database.CreateAPIKey(db, key, key, database.DefaultAPIKeyScopes, 2)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	t.Cleanup(func() { database.DeleteAPIKey(db, apiKey.ID) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/data", APIKeyAuth(db, nil), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get(); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200: %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	w := get()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over quota = %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > 31*24*3600 {
+		t.Errorf("Retry-After = %q, want seconds until next month", w.Header().Get("Retry-After"))
+	}
+
+	usage, err := database.GetAPIKeyUsage(db, database.MonthStart(time.Now()))
+	if err != nil {
+		t.Fatalf("GetAPIKeyUsage: %v", err)
+	}
+	for _, u := range usage {
+		if u.APIKeyID == apiKey.ID {
+			if u.Requests != 2 || u.Rejected != 1 {
+				t.Errorf("usage = %d requests, %d rejected, want 2 and 1", u.Requests, u.Rejected)
+			}
+			return
+		}
+	}
+	t.Errorf("key #%d missing from usage report", apiKey.ID)
+}
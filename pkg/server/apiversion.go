@@ -0,0 +1,58 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentAPIVersion 目前的 API 版本，沒有版本的舊路徑（/api/shopeMap）轉址到此版本
+const CurrentAPIVersion = "v1"
+
+// versionedPath 已帶版本的 API 路徑（/api/v1/...、/api/v2/...）
+var versionedPath = regexp.MustCompile(`^/api/v\d+(/|$)`)
+
+// APIVersion 一個 API 版本（/api/<版本>）的路由群組：Public 不需要金鑰，Data 在 REQUIRE_API_KEY=true 時需要 X-API-Key。
+// 回應格式有不相容的變更時新增下一個版本（newAPIVersion(base, "v2", ...) 加上 registerAPIV2），
+// 重用 registerAPIV1 的 Register 函式並只替換有變更的端點，舊版本維持原本的格式，已部署的前端不受影響
+type APIVersion struct {
+	Name   string
+	Public *gin.RouterGroup
+	Data   *gin.RouterGroup
+}
+
+// newAPIVersion 建立 /api/<name> 的路由群組，回應附上 X-API-Version 標頭（Data 另外附上 X-Data-Generation）
+func newAPIVersion(base *gin.RouterGroup, name string, db *sql.DB, cfg *config.Config) *APIVersion {
+	public := base.Group("/api/"+name, func(c *gin.Context) {
+		c.Header("X-API-Version", name)
+		c.Next()
+	})
+	data := public.Group("")
+	if cfg.RequireAPIKey {
+		data.Use(APIKeyAuth(db, ParseList(cfg.APIKeys)))
+	}
+	data.Use(DataGeneration(db))
+	return &APIVersion{Name: name, Public: public, Data: data}
+}
+
+// legacyAPIRedirect 將沒有版本的舊路徑（BASE_PATH/api/...）以 308 轉址到目前版本（保留方法、內容與查詢參數），
+// 並以 Deprecation / Link 標頭提示改用新路徑；不是舊路徑時回傳 false
+func legacyAPIRedirect(c *gin.Context, basePath string) bool {
+	path := strings.TrimPrefix(c.Request.URL.Path, basePath)
+	if !strings.HasPrefix(path, "/api/") || versionedPath.MatchString(path) {
+		return false
+	}
+
+	target := basePath + "/api/" + CurrentAPIVersion + strings.TrimPrefix(path, "/api")
+	c.Header("Deprecation", "true")
+	c.Header("Link", "<"+target+`>; rel="successor-version"`)
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusPermanentRedirect, target)
+	return true
+}
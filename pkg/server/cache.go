@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCacheEntries 快取的回應數量上限（bbox 等查詢參數組合很多，超過時整個清空）
+const maxCacheEntries = 256
+
+// CachedResponse 快取的回應內容
+type CachedResponse struct {
+	ContentType string
+	Header      http.Header // 額外要回傳的標頭（例如 Surrogate-Key）
+	Body        []byte
+
+	version time.Time
+	expires time.Time
+}
+
+// Write 將快取的回應寫出
+func (r *CachedResponse) Write(c *gin.Context) {
+	for k, v := range r.Header {
+		c.Writer.Header()[k] = v
+	}
+	c.Data(http.StatusOK, r.ContentType, r.Body)
+}
+
+// ResponseCache 以資料版本（最後同步時間）區分的回應快取：同步後版本改變即失效，另有 TTL 上限
+type ResponseCache struct {
+	name    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*CachedResponse
+}
+
+// CacheStats 回應快取目前的大小（/api/v1/admin/runtime）
+type CacheStats struct {
+	Name       string `json:"name"`
+	Entries    int    `json:"entries"`
+	Bytes      int    `json:"bytes"` // 快取回應內容的總大小
+	MaxEntries int    `json:"maxEntries"`
+	TTLSeconds int    `json:"ttlSeconds"` // 0 = 停用
+}
+
+var (
+	responseCachesMu sync.Mutex
+	responseCaches   []*ResponseCache
+)
+
+// NewResponseCache 建立回應快取，ttl <= 0 時停用（Get 永遠不命中）；name 用於 /api/v1/admin/runtime 的統計
+func NewResponseCache(name string, ttl time.Duration) *ResponseCache {
+	rc := &ResponseCache{name: name, ttl: ttl, entries: make(map[string]*CachedResponse)}
+	responseCachesMu.Lock()
+	responseCaches = append(responseCaches, rc)
+	responseCachesMu.Unlock()
+	return rc
+}
+
+// Stats 目前的快取筆數與大小
+func (rc *ResponseCache) Stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	stats := CacheStats{Name: rc.name, Entries: len(rc.entries), MaxEntries: maxCacheEntries, TTLSeconds: int(rc.ttl.Seconds())}
+	for _, entry := range rc.entries {
+		stats.Bytes += len(entry.Body)
+	}
+	return stats
+}
+
+// allCacheStats 所有已建立的回應快取的統計
+func allCacheStats() []CacheStats {
+	responseCachesMu.Lock()
+	defer responseCachesMu.Unlock()
+
+	stats := make([]CacheStats, 0, len(responseCaches))
+	for _, rc := range responseCaches {
+		stats = append(stats, rc.Stats())
+	}
+	return stats
+}
+
+// Get 取得 key 在 version 版本的快取回應
+func (rc *ResponseCache) Get(key string, version time.Time) (*CachedResponse, bool) {
+	if rc.ttl <= 0 {
+		return nil, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[key]
+	if !ok || !entry.version.Equal(version) || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set 保存 key 在 version 版本的回應
+func (rc *ResponseCache) Set(key string, version time.Time, resp *CachedResponse) {
+	if rc.ttl <= 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if len(rc.entries) >= maxCacheEntries {
+		rc.entries = make(map[string]*CachedResponse)
+	}
+	resp.version = version
+	resp.expires = time.Now().Add(rc.ttl)
+	rc.entries[key] = resp
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl 回應附上 Cache-Control: max-age 與 Expires，讓瀏覽器與 CDN 在兩次同步之間直接使用快取；
+// maxAge <= 0 時不設定。REQUIRE_API_KEY=true 時內容依金鑰而定，只允許瀏覽器快取（private）。
+// handler 自行設定的 Cache-Control（例如預覽的 no-store）優先，錯誤回應由 RespondError 改為 no-store
+func CacheControl(maxAge int, private bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxAge > 0 {
+			scope := "public"
+			if private {
+				scope = "private"
+			}
+			c.Header("Cache-Control", scope+", max-age="+strconv.Itoa(maxAge))
+			c.Header("Expires", time.Now().Add(time.Duration(maxAge)*time.Second).UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
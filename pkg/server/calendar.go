@@ -0,0 +1,59 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCalendarRoutes 註冊店家出貨日曆端點（店家詳細頁的日曆檢視，不列入隱藏的產品）
+func RegisterCalendarRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/stores/:id/calendar", handleStoreCalendar(db, newHiddenProducts(cfg)))
+}
+
+// handleStoreCalendar 回傳店家某個月份每天、每個產品的出貨數量（?month=2025-06，預設本月）
+func handleStoreCalendar(db *sql.DB, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid store id")
+			return
+		}
+
+		month := time.Now()
+		if s := c.Query("month"); s != "" {
+			month, err = time.Parse("2006-01", s)
+			if err != nil {
+				RespondError(c, http.StatusBadRequest, "month must be YYYY-MM")
+				return
+			}
+		}
+
+		cal, err := database.GetStoreCalendar(db, id, month)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "store not found")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 查詢店家 #%d 出貨日曆失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		products := make([]string, 0, len(cal.Products))
+		for product := range cal.Products {
+			if hidden[product] {
+				delete(cal.Products, product)
+			} else {
+				products = append(products, product)
+			}
+		}
+		cal.ProductNames = loadProductLabels(c, db, requestLanguage(c)).names(products)
+		c.JSON(http.StatusOK, cal)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotModified 設定 ETag 與 Last-Modified；請求的 If-None-Match / If-Modified-Since 仍然有效時回應 304 並回傳 true
+// ETag 由 lastModified 與請求路徑、查詢參數組成，不同查詢條件的回應不會共用 ETag
+func NotModified(c *gin.Context, lastModified time.Time) bool {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	sum := sha256.Sum256([]byte(strconv.FormatInt(lastModified.Unix(), 10) + "|" + c.Request.URL.Path + "?" + c.Request.URL.RawQuery))
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	// If-None-Match 優先於 If-Modified-Since
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				c.Status(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
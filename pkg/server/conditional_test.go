@@ -0,0 +1,148 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+)
+
+// openTestDB 連接 TEST_DATABASE_URL 指定的 PostgreSQL 並套用資料表版本，未設定時略過測試
+// （CI 設定 REQUIRE_TEST_DATABASE 時改為失敗）
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		if os.Getenv("REQUIRE_TEST_DATABASE") != "" {
+			t.Fatal("REQUIRE_TEST_DATABASE is set but TEST_DATABASE_URL is not")
+		}
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// lastModifiedRouter 與資料端點相同，以 GetDataLastModified 回應條件式請求
+func lastModifiedRouter(db *sql.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/data", func(c *gin.Context) {
+		lastModified, err := database.GetDataLastModified(db)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func getData(t *testing.T, r *gin.Engine, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK && w.Code != http.StatusNotModified {
+		t.Fatalf("GET /data = %d: %s", w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lastModified := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	r := gin.New()
+	r.GET("/data", func(c *gin.Context) {
+		if NotModified(c, lastModified) {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	first := getData(t, r, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first request = %d, ETag %q", first.Code, etag)
+	}
+	if got := getData(t, r, etag).Code; got != http.StatusNotModified {
+		t.Errorf("matching If-None-Match = %d, want 304", got)
+	}
+
+	lastModified = lastModified.Add(time.Second)
+	if got := getData(t, r, etag).Code; got != http.StatusOK {
+		t.Errorf("If-None-Match after a change = %d, want 200", got)
+	}
+}
+
+// TestDeleteStoreChangesETag 刪除店家後 stores 中已沒有該列，ETag 仍必須改變
+func TestDeleteStoreChangesETag(t *testing.T) {
+	db := openTestDB(t)
+	r := lastModifiedRouter(db)
+
+	store, err := database.CreateStore(db, database.NewStore{StoreName: fmt.Sprintf("etag-test-%d", time.Now().UnixNano())}, "test")
+	if err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	before := getData(t, r, "").Header().Get("ETag")
+
+	// ETag 以秒為單位
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := database.DeleteStore(db, store.ID, "test"); err != nil {
+		t.Fatalf("DeleteStore: %v", err)
+	}
+
+	w := getData(t, r, before)
+	if w.Code != http.StatusOK {
+		t.Errorf("If-None-Match after DeleteStore = %d, want 200", w.Code)
+	}
+	if after := w.Header().Get("ETag"); after == before {
+		t.Errorf("ETag did not change after DeleteStore: %s", after)
+	}
+}
+
+// TestSaveProductChangesETag 修改產品顯示名稱後地圖與 /products 的 ETag 必須改變
+func TestSaveProductChangesETag(t *testing.T) {
+	db := openTestDB(t)
+	r := lastModifiedRouter(db)
+
+	product := database.Product{ProductType: fmt.Sprintf("etag-test-%d", time.Now().UnixNano()), NameZhTW: "測試", NameEn: "Test"}
+	if _, err := database.SaveProduct(db, product); err != nil {
+		t.Fatalf("SaveProduct: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM products WHERE product_type = $1`, product.ProductType) })
+	before := getData(t, r, "").Header().Get("ETag")
+
+	time.Sleep(1100 * time.Millisecond)
+	product.NameEn = "Renamed"
+	if _, err := database.SaveProduct(db, product); err != nil {
+		t.Fatalf("SaveProduct: %v", err)
+	}
+
+	w := getData(t, r, before)
+	if w.Code != http.StatusOK {
+		t.Errorf("If-None-Match after SaveProduct = %d, want 200", w.Code)
+	}
+	if after := w.Header().Get("ETag"); after == before {
+		t.Errorf("ETag did not change after SaveProduct: %s", after)
+	}
+}
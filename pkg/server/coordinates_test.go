@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+)
+
+// TestFullSyncKeepsManualCoordinates 匯入或以管理 API 建立的座標（manual_import）不被之後完整同步的 Places 結果覆蓋
+func TestFullSyncKeepsManualCoordinates(t *testing.T) {
+	db := openTestDB(t)
+	suffix := time.Now().UnixNano()
+	imported := fmt.Sprintf("manual-import-test-%d", suffix)
+	created := fmt.Sprintf("manual-create-test-%d", suffix)
+	synced := fmt.Sprintf("places-test-%d", suffix)
+
+	// 第一次同步建立店家，之後人工匯入修正座標
+	if _, err := database.SaveStores(db, []database.StoreInfo{
+		{StoreName: imported, PlaceID: "ChIJoriginal", FormattedAddress: "原地址", Latitude: 23.0, Longitude: 120.0},
+		{StoreName: synced, PlaceID: "ChIJsynced", Latitude: 23.0, Longitude: 120.0},
+	}); err != nil {
+		t.Fatalf("SaveStores: %v", err)
+	}
+	if _, err := database.ImportCoordinates(db, []database.CoordinateFix{{StoreName: imported, Latitude: 23.1, Longitude: 120.1}}, "test"); err != nil {
+		t.Fatalf("ImportCoordinates: %v", err)
+	}
+	lat, lng := 22.9, 120.2
+	store, err := database.CreateStore(db, database.NewStore{StoreName: created, Latitude: &lat, Longitude: &lng}, "test")
+	if err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+
+	// 完整同步重新查詢到不同的地點
+	if _, err := database.SaveStores(db, []database.StoreInfo{
+		{StoreName: imported, PlaceID: "ChIJwrong", FormattedAddress: "錯誤地址", Latitude: 25.0, Longitude: 121.5},
+		{StoreName: created, PlaceID: "ChIJwrong", Latitude: 25.0, Longitude: 121.5},
+		{StoreName: synced, PlaceID: "ChIJmoved", Latitude: 23.2, Longitude: 120.3},
+	}); err != nil {
+		t.Fatalf("SaveStores: %v", err)
+	}
+
+	stores, _, err := database.ListStores(db, fmt.Sprintf("-test-%d", suffix), nil, 10, 0)
+	if err != nil {
+		t.Fatalf("ListStores: %v", err)
+	}
+	got := map[string]database.StoreRecord{}
+	for _, s := range stores {
+		got[s.StoreName] = s
+		id := s.ID
+		t.Cleanup(func() { database.DeleteStore(db, id, "test") })
+	}
+	if len(got) != 3 {
+		t.Fatalf("ListStores = %+v, want the 3 test stores", stores)
+	}
+
+	if s := got[imported]; s.Latitude != 23.1 || s.Longitude != 120.1 || s.PlaceID != "ChIJoriginal" || s.FormattedAddress != "原地址" {
+		t.Errorf("imported store = %+v, want the imported coordinates and the original place", s)
+	}
+	if s := got[created]; s.ID != store.ID || s.Latitude != lat || s.Longitude != lng || s.PlaceID != "" {
+		t.Errorf("created store = %+v, want the coordinates given to the admin API", s)
+	}
+	if s := got[synced]; s.Latitude != 23.2 || s.Longitude != 120.3 || s.PlaceID != "ChIJmoved" {
+		t.Errorf("synced store = %+v, want the new Places result", s)
+	}
+}
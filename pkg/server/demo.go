@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// NewDemoRouter 示範模式（不需要資料庫）的 Gin engine：靜態前端與 /api/v1/shopeMap、/api/v1/shopeMap.geojson，
+// 資料來自 store（demo.Memory）；其他需要資料庫的端點回應 404
+func NewDemoRouter(store MapStore, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(), Recovery(), CORS(cfg.CORSOrigins))
+	router.NoRoute(func(c *gin.Context) {
+		RespondError(c, http.StatusNotFound, "not found")
+	})
+
+	base := router.Group(cfg.BasePath)
+	RegisterStaticRoutes(base, cfg)
+
+	api := base.Group("/api/v1", func(c *gin.Context) {
+		c.Header("X-API-Version", "v1")
+		c.Next()
+	})
+	registerShopeMap(api.Group("", CacheControl(cfg.CacheMaxAgeMap, false)), store, cfg)
+	return router
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/demo"
+	"github.com/gin-gonic/gin"
+)
+
+// TestDemoRouter 示範模式不需要資料庫即可提供前端與地圖端點
+func TestDemoRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RecentDays: demo.Days, MaxRecentDays: 30, MaxRangeDays: 92, CORSOrigins: "*"}
+	r := NewDemoRouter(demo.NewMemory(time.Now()), cfg)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/"); w.Code != http.StatusOK {
+		t.Errorf("GET / = %d, want 200", w.Code)
+	}
+
+	w := get("/api/v1/shopeMap")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/shopeMap = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []map[string]interface{} `json:"data"`
+		Meta struct {
+			Total   int `json:"total"`
+			Sources []struct {
+				SourceID string `json:"sourceId"`
+			} `json:"sources"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Meta.Total == 0 || len(body.Data) != body.Meta.Total {
+		t.Errorf("data = %d stores, meta.total = %d", len(body.Data), body.Meta.Total)
+	}
+	if len(body.Meta.Sources) != 1 || body.Meta.Sources[0].SourceID != demo.SourceID {
+		t.Errorf("meta.sources = %+v, want the demo source", body.Meta.Sources)
+	}
+
+	// 條件式請求與正式環境相同
+	if etag := w.Header().Get("ETag"); etag == "" {
+		t.Errorf("no ETag")
+	} else {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shopeMap", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match = %d, want 304", w.Code)
+		}
+	}
+
+	if w := get("/api/v1/shopeMap.geojson?include=sparkline"); w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/shopeMap.geojson = %d: %s", w.Code, w.Body.String())
+	}
+	// 需要資料庫的端點不註冊
+	if w := get("/api/v1/stores/nearby"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/v1/stores/nearby = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse 所有錯誤回應的格式：code 供程式判斷，message 說明原因，requestId 對照伺服器日誌
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// 錯誤代碼（依 HTTP 狀態碼決定）
+const (
+	ErrCodeInvalidRequest  = "invalid_request" // 參數或請求內容不正確（含查詢參數驗證失敗）
+	ErrCodeUnauthorized    = "unauthorized"
+	ErrCodeForbidden       = "forbidden"
+	ErrCodeNotFound        = "not_found"
+	ErrCodeConflict        = "conflict"
+	ErrCodeTooManyRequests = "too_many_requests"
+	ErrCodeInternal        = "internal_error"
+	ErrCodeUnavailable     = "unavailable"
+)
+
+// errorCode 狀態碼對應的錯誤代碼
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return ErrCodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeInvalidRequest
+}
+
+// RespondError 以統一格式回傳錯誤（錯誤回應不可被快取，覆寫 CacheControl 設定的標頭）
+func RespondError(c *gin.Context, status int, message string) {
+	noStore(c)
+	c.JSON(status, newErrorResponse(c, status, message))
+}
+
+// AbortWithError 同 RespondError，並中止後續的 handler（middleware 使用）
+func AbortWithError(c *gin.Context, status int, message string) {
+	noStore(c)
+	c.AbortWithStatusJSON(status, newErrorResponse(c, status, message))
+}
+
+func newErrorResponse(c *gin.Context, status int, message string) ErrorResponse {
+	return ErrorResponse{Code: errorCode(status), Message: message, RequestID: GetRequestID(c)}
+}
+
+// noStore 移除快取標頭並設定 Cache-Control: no-store
+func noStore(c *gin.Context) {
+	c.Writer.Header().Del("Expires")
+	c.Header("Cache-Control", "no-store")
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/xlsx"
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumns 匯出活頁簿每張工作表的欄位（依語言）
+func exportColumns(lang string) []string {
+	keys := []string{"export.date", "export.timeSlot", "export.storeId", "export.storeName", "export.quantity", "export.source"}
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = message(lang, key)
+	}
+	return columns
+}
+
+// RegisterExportRoutes 註冊出貨匯出端點（給合作社會計使用的 Excel 活頁簿，不列入隱藏的產品）
+func RegisterExportRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/export.xlsx", handleExportXLSX(db, cfg, newHiddenProducts(cfg)))
+}
+
+// handleExportXLSX 將近 N 天（或 ?from=&to=）的出貨匯出成 .xlsx，每個產品一張工作表；
+// 日期為 Excel 日期、可解析的數量為數字儲存格（其餘保留原本的文字）；工作表名稱與欄位依 Accept-Language
+func handleExportXLSX(db *sql.DB, cfg *config.Config, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, hasRange, err := parseDateRange(c, cfg)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !hasRange {
+			today := time.Now()
+			to = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+			from = to.AddDate(0, 0, -cfg.RecentDays)
+		}
+
+		lang := requestLanguage(c)
+		lastModified, err := database.GetDataLastModified(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+
+		shipments, err := database.QueryShipments(db, database.ShipmentQuery{
+			From:    from.Format("2006-01-02"),
+			To:      to.Format("2006-01-02"),
+			Exclude: hidden.list(),
+		})
+		if err != nil {
+			logf(c, "[ERROR] 匯出出貨失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := buildShipmentWorkbook(shipments, loadProductLabels(c, db, lang)).Write(&buf); err != nil {
+			logf(c, "[ERROR] 產生出貨活頁簿失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		fileName := "shipments-" + from.Format("2006-01-02") + "-" + to.Format("2006-01-02") + ".xlsx"
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		c.Data(http.StatusOK, xlsx.ContentType, buf.Bytes())
+	}
+}
+
+// buildShipmentWorkbook 依產品分工作表（依產品名稱排序，工作表名稱為顯示名稱），每張表依日期、時段、店名由舊到新排列
+func buildShipmentWorkbook(shipments []database.ShipmentRecord, labels productLabels) *xlsx.Workbook {
+	byProduct := map[string][]database.ShipmentRecord{}
+	for _, s := range shipments {
+		byProduct[s.ProductType] = append(byProduct[s.ProductType], s)
+	}
+	products := make([]string, 0, len(byProduct))
+	for p := range byProduct {
+		products = append(products, p)
+	}
+	sort.Strings(products)
+
+	columns := exportColumns(labels.lang)
+	wb := xlsx.New()
+	if len(products) == 0 {
+		wb.AddSheet(message(labels.lang, "export.sheet")).SetHeader(columns...)
+		return wb
+	}
+	for _, product := range products {
+		rows := byProduct[product]
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.ShipmentDate != b.ShipmentDate {
+				return a.ShipmentDate < b.ShipmentDate
+			}
+			if a.TimeSlot != b.TimeSlot {
+				return a.TimeSlot < b.TimeSlot
+			}
+			return a.StoreName < b.StoreName
+		})
+
+		sheet := wb.AddSheet(labels.name(product))
+		sheet.SetHeader(columns...)
+		for _, s := range rows {
+			date := xlsx.String(s.ShipmentDate)
+			if t, err := time.Parse("2006-01-02", s.ShipmentDate); err == nil {
+				date = xlsx.Date(t)
+			}
+			sheet.AddRow(date, xlsx.String(s.TimeSlot), xlsx.Number(float64(s.StoreID)),
+				xlsx.String(s.StoreName), quantityCell(s.Quantity), xlsx.String(s.SourceID))
+		}
+	}
+	return wb
+}
+
+// quantityCell 數量可解析為數字（允許千分位逗號）時為數字儲存格，否則保留原本的文字（例如「少量」）
+func quantityCell(quantity string) xlsx.Cell {
+	if n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(quantity), ",", ""), 64); err == nil {
+		return xlsx.Number(n)
+	}
+	return xlsx.String(quantity)
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// dataGenerationKey 資料版本在 gin.Context 中的鍵值
+const dataGenerationKey = "dataGeneration"
+
+// DataGeneration 查詢目前的資料版本（每次成功同步加一），寫入 X-Data-Generation 標頭並保存在 context 供 meta.generation 使用；
+// 前端分頁時比對版本即可發現中途資料已更新，客服也能以截圖中的版本對照同步紀錄。查詢失敗時只記錄警告
+func DataGeneration(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		generation, err := database.GetDataGeneration(db)
+		if err != nil {
+			log.Printf("[WARN] 查詢資料版本失敗: %v", err)
+		} else {
+			c.Set(dataGenerationKey, generation)
+			c.Header("X-Data-Generation", strconv.FormatInt(generation, 10))
+		}
+		c.Next()
+	}
+}
+
+// dataGeneration 目前請求的資料版本（查詢失敗時為 0）
+func dataGeneration(c *gin.Context) int64 {
+	return c.GetInt64(dataGenerationKey)
+}
@@ -0,0 +1,435 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/graphql"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// graphqlMaxQueryBytes 查詢內容（含變數）的大小上限
+	graphqlMaxQueryBytes = 16 << 10
+	// graphqlMaxDepth 巢狀物件層數上限，例如 stores → shipments → store 為 3 層
+	graphqlMaxDepth = 4
+
+	graphqlDefaultLimit = 100
+	graphqlMaxLimit     = 1000
+	graphqlMaxPerStore  = 200 // Store.shipments 每家店的上限
+)
+
+// RegisterGraphQLRoutes 註冊 /graphql：以 GraphQL 查詢店家與出貨，前端一次取得剛好需要的欄位（不列入隱藏的產品）；
+// 掛在公開的資料路由上，同步紀錄只由需要管理密鑰的 /syncHistory 提供
+func RegisterGraphQLRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	schema := newGraphQLSchema()
+	hidden := newHiddenProducts(cfg)
+	r.POST("/graphql", handleGraphQL(db, schema, hidden))
+	r.GET("/graphql", handleGraphQL(db, schema, hidden))
+}
+
+// handleGraphQL 執行 GraphQL 查詢：POST 為 JSON {query, variables, operationName}，
+// GET 為 ?query=&variables=&operationName=；語法或驗證錯誤時回傳 400，欄位取值錯誤時回傳 200 與部分資料
+func handleGraphQL(db *sql.DB, schema *graphql.Schema, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req graphql.Request
+		if c.Request.Method == http.MethodPost {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, graphqlMaxQueryBytes+1))
+			if err != nil {
+				RespondError(c, http.StatusBadRequest, "cannot read request body")
+				return
+			}
+			if len(body) > graphqlMaxQueryBytes {
+				RespondError(c, http.StatusRequestEntityTooLarge, "query is too large")
+				return
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				RespondError(c, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+		} else {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if v := c.Query("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					RespondError(c, http.StatusBadRequest, "variables must be a JSON object")
+					return
+				}
+			}
+			if len(req.Query) > graphqlMaxQueryBytes {
+				RespondError(c, http.StatusRequestEntityTooLarge, "query is too large")
+				return
+			}
+		}
+		if req.Query == "" {
+			RespondError(c, http.StatusBadRequest, "query is required")
+			return
+		}
+
+		loader := newGraphQLLoader(db, hidden.list(), requestLanguage(c))
+		ctx := context.WithValue(c.Request.Context(), graphqlLoaderKey{}, loader)
+		result := graphql.Execute(ctx, schema, req)
+		for _, e := range result.Errors {
+			logf(c, "[WARN] GraphQL 查詢錯誤: %s %v", e.Message, e.Path)
+		}
+
+		status := http.StatusOK
+		if result.Data == nil {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, result)
+	}
+}
+
+// newGraphQLSchema 查詢的型別定義：
+//
+//	type Query {
+//	  stores(ids: [Int], q: String, region: String, active: Boolean, limit: Int, offset: Int): [Store]
+//	  store(id: Int!): Store
+//	  shipments(storeId: Int, from: String, to: String, product: String, limit: Int): [Shipment]
+//	}
+//	type Store {
+//	  id, storeName, placeId, formattedAddress, latitude, longitude, businessStatus, isActive, region
+//	  latestShipmentDate(product: String): String
+//	  shipments(from: String, to: String, product: String, limit: Int): [Shipment]
+//	}
+//	type Shipment { id, storeId, storeName, productType, productName, shipmentDate, timeSlot, quantity, qualityFlag, store: Store }
+func newGraphQLSchema() *graphql.Schema {
+	shipmentArgs := map[string]string{"from": "String", "to": "String", "product": "String", "limit": "Int"}
+
+	return &graphql.Schema{
+		Query:    "Query",
+		MaxDepth: graphqlMaxDepth,
+		Types: map[string]*graphql.Object{
+			"Query": {Name: "Query", Fields: map[string]*graphql.FieldDef{
+				"stores": {
+					Type:    "[Store]",
+					Args:    map[string]string{"ids": "[Int]", "q": "String", "region": "String", "active": "Boolean", "limit": "Int", "offset": "Int"},
+					Resolve: resolveStores,
+				},
+				"store": {
+					Type:    "Store",
+					Args:    map[string]string{"id": "Int!"},
+					Resolve: resolveStore,
+				},
+				"shipments": {
+					Type:    "[Shipment]",
+					Args:    map[string]string{"storeId": "Int", "from": "String", "to": "String", "product": "String", "limit": "Int"},
+					Resolve: resolveShipments,
+				},
+			}},
+			"Store": {Name: "Store", Fields: map[string]*graphql.FieldDef{
+				"id":                 {Type: "Int"},
+				"storeName":          {Type: "String"},
+				"placeId":            {Type: "String"},
+				"formattedAddress":   {Type: "String"},
+				"latitude":           {Type: "Float"},
+				"longitude":          {Type: "Float"},
+				"businessStatus":     {Type: "String"},
+				"isActive":           {Type: "Boolean"},
+				"region":             {Type: "String"},
+				"latestShipmentDate": {Type: "String", Args: map[string]string{"product": "String"}, Resolve: resolveLatestShipmentDate},
+				"shipments":          {Type: "[Shipment]", Args: shipmentArgs, Resolve: resolveStoreShipments},
+			}},
+			"Shipment": {Name: "Shipment", Fields: map[string]*graphql.FieldDef{
+				"id":           {Type: "Int"},
+				"storeId":      {Type: "Int"},
+				"storeName":    {Type: "String"},
+				"productType":  {Type: "String"},
+				"productName":  {Type: "String", Resolve: resolveShipmentProductName},
+				"shipmentDate": {Type: "String"},
+				"timeSlot":     {Type: "String"},
+				"quantity":     {Type: "String"},
+				"qualityFlag":  {Type: "String"},
+				"store":        {Type: "Store", Resolve: resolveShipmentStore},
+			}},
+		},
+	}
+}
+
+func resolveStores(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := graphqlLimit(p.Args, graphqlDefaultLimit, graphqlMaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	offset := p.Args.Int("offset", 0)
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	l := loaderFrom(p.Context)
+	stores, err := database.QueryStores(l.db, database.StoreQuery{
+		IDs:    p.Args.Ints("ids"),
+		Query:  p.Args.String("q"),
+		Region: p.Args.String("region"),
+		Active: p.Args.Bool("active"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.addStores(stores)
+	return stores, nil
+}
+
+func resolveStore(p graphql.ResolveParams) (interface{}, error) {
+	l := loaderFrom(p.Context)
+	store, err := database.GetStoreByID(l.db, p.Args.Int("id", 0))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.addStores([]database.StoreRecord{*store})
+	return store, nil
+}
+
+func resolveShipments(p graphql.ResolveParams) (interface{}, error) {
+	q, err := shipmentQueryArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit, err = graphqlLimit(p.Args, graphqlDefaultLimit, graphqlMaxLimit); err != nil {
+		return nil, err
+	}
+	if id := p.Args.Int("storeId", 0); p.Args["storeId"] != nil {
+		q.StoreIDs = []int{id}
+	}
+	l := loaderFrom(p.Context)
+	q.Exclude = l.exclude
+	shipments, err := database.QueryShipments(l.db, q)
+	if err != nil {
+		return nil, err
+	}
+	l.addShipmentStores(shipments)
+	return shipments, nil
+}
+
+// resolveStoreShipments Store.shipments：同一次查詢中所有店家的出貨以一個查詢取得，limit 為每家店的筆數
+func resolveStoreShipments(p graphql.ResolveParams) (interface{}, error) {
+	q, err := shipmentQueryArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if q.PerStoreLimit, err = graphqlLimit(p.Args, graphqlMaxPerStore, graphqlMaxPerStore); err != nil {
+		return nil, err
+	}
+	return loaderFrom(p.Context).storeShipments(storeIDOf(p.Source), q)
+}
+
+func resolveLatestShipmentDate(p graphql.ResolveParams) (interface{}, error) {
+	date, ok, err := loaderFrom(p.Context).latestShipmentDate(storeIDOf(p.Source), p.Args.String("product"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	return date, nil
+}
+
+func resolveShipmentStore(p graphql.ResolveParams) (interface{}, error) {
+	shipment := p.Source.(database.ShipmentRecord)
+	return loaderFrom(p.Context).store(shipment.StoreID)
+}
+
+// resolveShipmentProductName Shipment.productName：依 Accept-Language 選擇的產品顯示名稱
+func resolveShipmentProductName(p graphql.ResolveParams) (interface{}, error) {
+	shipment := p.Source.(database.ShipmentRecord)
+	return loaderFrom(p.Context).productName(shipment.ProductType)
+}
+
+// graphqlLimit limit 參數，未提供時為 def，需介於 1 與 max 之間
+func graphqlLimit(args graphql.Args, def, max int) (int, error) {
+	limit := args.Int("limit", def)
+	if limit < 1 || limit > max {
+		return 0, fmt.Errorf("limit must be between 1 and %d", max)
+	}
+	return limit, nil
+}
+
+// shipmentQueryArgs 出貨的 from / to / product 參數
+func shipmentQueryArgs(args graphql.Args) (database.ShipmentQuery, error) {
+	q := database.ShipmentQuery{From: args.String("from"), To: args.String("to"), Product: args.String("product")}
+	for _, d := range []string{q.From, q.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return q, fmt.Errorf("from and to must be YYYY-MM-DD")
+		}
+	}
+	return q, nil
+}
+
+func storeIDOf(source interface{}) int {
+	switch s := source.(type) {
+	case database.StoreRecord:
+		return s.ID
+	case *database.StoreRecord:
+		return s.ID
+	}
+	return 0
+}
+
+type graphqlLoaderKey struct{}
+
+func loaderFrom(ctx context.Context) *graphqlLoader {
+	return ctx.Value(graphqlLoaderKey{}).(*graphqlLoader)
+}
+
+// graphqlLoader 單一 GraphQL 請求內的批次載入：記錄查詢結果中出現過的店家，
+// 巢狀欄位第一次取值時一併載入所有這些店家的資料，避免每家店各查一次（N+1）
+type graphqlLoader struct {
+	db      *sql.DB
+	exclude []string       // 隱藏的產品
+	labels  *productLabels // 第一次查詢 productName 時載入
+	lang    string
+
+	storeIDs []int        // 出現過的店家（依出現順序）
+	seen     map[int]bool // storeIDs 的集合
+	stores   map[int]*database.StoreRecord
+	latest   map[string]*storeBatch // 產品 → 各店家最近出貨日
+	ships    map[string]*storeBatch // 出貨查詢條件 → 各店家的出貨
+}
+
+// storeBatch 依店家分組的批次結果，loaded 為已查詢過的店家
+type storeBatch struct {
+	loaded map[int]bool
+	values map[int]interface{}
+}
+
+func newGraphQLLoader(db *sql.DB, exclude []string, lang string) *graphqlLoader {
+	return &graphqlLoader{
+		db:      db,
+		exclude: exclude,
+		lang:    lang,
+		seen:    map[int]bool{},
+		stores:  map[int]*database.StoreRecord{},
+		latest:  map[string]*storeBatch{},
+		ships:   map[string]*storeBatch{},
+	}
+}
+
+// productName 產品的顯示名稱（同一次查詢只讀取一次 products）
+func (l *graphqlLoader) productName(product string) (string, error) {
+	if l.labels == nil {
+		products, err := database.GetProducts(l.db)
+		if err != nil {
+			return "", err
+		}
+		l.labels = &productLabels{lang: l.lang, products: products}
+	}
+	return l.labels.name(product), nil
+}
+
+func (l *graphqlLoader) addStoreID(id int) {
+	if !l.seen[id] {
+		l.seen[id] = true
+		l.storeIDs = append(l.storeIDs, id)
+	}
+}
+
+func (l *graphqlLoader) addStores(stores []database.StoreRecord) {
+	for i := range stores {
+		l.addStoreID(stores[i].ID)
+		l.stores[stores[i].ID] = &stores[i]
+	}
+}
+
+func (l *graphqlLoader) addShipmentStores(shipments []database.ShipmentRecord) {
+	for _, s := range shipments {
+		l.addStoreID(s.StoreID)
+	}
+}
+
+// pending 尚未在 batch 中查詢過的店家（含 id）
+func (l *graphqlLoader) pending(batch *storeBatch, id int) []int {
+	l.addStoreID(id)
+	var ids []int
+	for _, storeID := range l.storeIDs {
+		if !batch.loaded[storeID] {
+			ids = append(ids, storeID)
+		}
+	}
+	return ids
+}
+
+func (l *graphqlLoader) batch(batches map[string]*storeBatch, key string) *storeBatch {
+	b := batches[key]
+	if b == nil {
+		b = &storeBatch{loaded: map[int]bool{}, values: map[int]interface{}{}}
+		batches[key] = b
+	}
+	return b
+}
+
+func (l *graphqlLoader) store(id int) (*database.StoreRecord, error) {
+	if s, ok := l.stores[id]; ok {
+		return s, nil
+	}
+	l.addStoreID(id)
+	var ids []int
+	for _, storeID := range l.storeIDs {
+		if _, ok := l.stores[storeID]; !ok {
+			ids = append(ids, storeID)
+		}
+	}
+	stores, err := database.GetStoresByIDs(l.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, storeID := range ids {
+		l.stores[storeID] = nil // 已刪除的店家
+	}
+	for i := range stores {
+		l.stores[stores[i].ID] = &stores[i]
+	}
+	return l.stores[id], nil
+}
+
+func (l *graphqlLoader) latestShipmentDate(id int, product string) (string, bool, error) {
+	b := l.batch(l.latest, product)
+	if !b.loaded[id] {
+		ids := l.pending(b, id)
+		dates, err := database.LatestShipmentDates(l.db, ids, product, l.exclude)
+		if err != nil {
+			return "", false, err
+		}
+		for _, storeID := range ids {
+			b.loaded[storeID] = true
+		}
+		for storeID, date := range dates {
+			b.values[storeID] = date
+		}
+	}
+	date, ok := b.values[id].(string)
+	return date, ok, nil
+}
+
+func (l *graphqlLoader) storeShipments(id int, q database.ShipmentQuery) ([]database.ShipmentRecord, error) {
+	b := l.batch(l.ships, fmt.Sprintf("%s|%s|%s|%d", q.From, q.To, q.Product, q.PerStoreLimit))
+	if !b.loaded[id] {
+		q.StoreIDs = l.pending(b, id)
+		q.Exclude = l.exclude
+		sort.Ints(q.StoreIDs)
+		shipments, err := database.QueryShipments(l.db, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, storeID := range q.StoreIDs {
+			b.loaded[storeID] = true
+			b.values[storeID] = []database.ShipmentRecord{}
+		}
+		for _, s := range shipments {
+			b.values[s.StoreID] = append(b.values[s.StoreID].([]database.ShipmentRecord), s)
+		}
+	}
+	return b.values[id].([]database.ShipmentRecord), nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+// Readiness 啟動是否完成（開始接受連線後才標記為完成）
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// MarkReady 標記啟動完成
+func (r *Readiness) MarkReady() {
+	r.ready.Store(true)
+}
+
+// MarkNotReady 標記為停止中（關閉時先讓 /readyz 回 503，負載平衡器不再導入流量）
+func (r *Readiness) MarkNotReady() {
+	r.ready.Store(false)
+}
+
+// IsReady 是否已啟動完成
+func (r *Readiness) IsReady() bool {
+	return r.ready.Load()
+}
+
+// RegisterHealthRoutes 註冊健康檢查端點（負載平衡器、監控與 Kubernetes probe 使用）
+func RegisterHealthRoutes(r gin.IRouter, db *sql.DB, startedAt time.Time, readiness *Readiness) {
+	r.GET("/healthz", handleHealthz(db, startedAt))
+	r.GET("/livez", handleLivez)
+	r.GET("/readyz", handleReadyz(db, readiness))
+}
+
+// handleLivez 程序還在運作就回應 200（不檢查資料庫，避免資料庫故障時被重啟）
+func handleLivez(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadyz 啟動完成、資料庫可連線且排程迴圈都在執行時回應 200，否則 503（讓流量暫時不要導到這個程序）
+func handleReadyz(db *sql.DB, readiness *Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		if !readiness.IsReady() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "starting"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": err.Error()})
+			return
+		}
+
+		loops := scheduler.LoopStatuses()
+		if !scheduler.LoopsHealthy() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "scheduler": loops})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "scheduler": loops})
+	}
+}
+
+// handleHealthz 檢查資料庫連線，回傳狀態、運行時間與上次成功同步時間；資料庫無法連線時回傳 503
+func handleHealthz(db *sql.DB, startedAt time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+
+		status, code := "ok", http.StatusOK
+		dbStatus := "ok"
+		if err := db.PingContext(ctx); err != nil {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			dbStatus = err.Error()
+		}
+
+		result := gin.H{
+			"status":        status,
+			"database":      dbStatus,
+			"uptimeSeconds": int(time.Since(startedAt).Seconds()),
+			"startedAt":     startedAt,
+		}
+		if stats := database.GetQueryStats(); stats.SlowThresholdMs > 0 {
+			result["queries"] = stats
+		}
+		if dbStatus == "ok" {
+			if lastSync, err := scheduler.NewScheduler(db, 0).GetLastSyncTime(); err == nil && !lastSync.IsZero() {
+				result["lastSuccessfulSync"] = lastSync
+			}
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(code, result)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"database/sql"
+	"strings"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// 回應支援的語言：沒有 Accept-Language 時為繁體中文，要求其他語言（非中文）時使用英文
+const (
+	LangZhTW = "zh-TW"
+	LangEn   = "en"
+)
+
+// messages API 層的固定文字（匯出活頁簿的欄位名稱等），依語言選擇
+var messages = map[string]map[string]string{
+	LangZhTW: {
+		"export.sheet":     "出貨",
+		"export.date":      "出貨日期",
+		"export.timeSlot":  "時段",
+		"export.storeId":   "店家編號",
+		"export.storeName": "店家名稱",
+		"export.quantity":  "數量",
+		"export.source":    "資料來源",
+	},
+	LangEn: {
+		"export.sheet":     "Shipments",
+		"export.date":      "Shipment date",
+		"export.timeSlot":  "Time slot",
+		"export.storeId":   "Store ID",
+		"export.storeName": "Store name",
+		"export.quantity":  "Quantity",
+		"export.source":    "Source",
+	},
+}
+
+// negotiateLanguage 依 Accept-Language（含 q 值）選擇回應語言：中文（不分繁簡）為 zh-TW，
+// 英文或其他不支援的語言為 en，沒有或無法解析時為 zh-TW
+func negotiateLanguage(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return LangZhTW
+	}
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return LangZhTW
+	}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		switch base.String() {
+		case "zh":
+			return LangZhTW
+		case "en":
+			return LangEn
+		case "mul":
+			// *：任何語言皆可，使用預設語言
+			return LangZhTW
+		}
+	}
+	return LangEn
+}
+
+// requestLanguage 目前請求的回應語言，並設定 Content-Language 與 Vary（CDN 依語言分開快取）
+func requestLanguage(c *gin.Context) string {
+	lang := negotiateLanguage(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	return lang
+}
+
+// message 固定文字的翻譯，找不到時使用繁體中文
+func message(lang, key string) string {
+	if s, ok := messages[lang][key]; ok {
+		return s
+	}
+	return messages[LangZhTW][key]
+}
+
+// productLabels 產品的顯示名稱（products 資料表），依語言選擇
+type productLabels struct {
+	lang     string
+	products map[string]database.Product
+}
+
+// loadProductLabels 讀取產品顯示名稱；查詢失敗時只記錄警告，顯示名稱改用產品名稱本身
+func loadProductLabels(c *gin.Context, db *sql.DB, lang string) productLabels {
+	return newProductLabels(c, lang, func() (map[string]database.Product, error) { return database.GetProducts(db) })
+}
+
+// newProductLabels 以 load 讀取產品顯示名稱，失敗時與 loadProductLabels 相同改用產品名稱本身
+func newProductLabels(c *gin.Context, lang string, load func() (map[string]database.Product, error)) productLabels {
+	products, err := load()
+	if err != nil {
+		logf(c, "[WARN] 讀取產品顯示名稱失敗，改用產品名稱: %v", err)
+	}
+	return productLabels{lang: lang, products: products}
+}
+
+// name 產品的顯示名稱：英文優先使用英文名稱，沒有設定時依序使用中文名稱、產品名稱
+func (l productLabels) name(product string) string {
+	p, ok := l.products[product]
+	if !ok {
+		return product
+	}
+	if l.lang == LangEn && p.NameEn != "" {
+		return p.NameEn
+	}
+	if p.NameZhTW != "" {
+		return p.NameZhTW
+	}
+	return product
+}
+
+// names 產品名稱 → 顯示名稱
+func (l productLabels) names(products []string) map[string]string {
+	names := make(map[string]string, len(products))
+	for _, p := range products {
+		names[p] = l.name(p)
+	}
+	return names
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/graphql"
+	"PXMarkMapBackEnd/pkg/opendata"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"PXMarkMapBackEnd/pkg/sync"
+	"PXMarkMapBackEnd/pkg/webhook"
+)
+
+// JSON 命名規則：所有回應與請求的欄位一律使用 camelCase（例如 storeName、lastSyncAt）
+var camelCaseName = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// apiTypes 會被編碼成 API 回應、webhook 或開放資料、或從請求解碼的型別；新增型別時請加入此列表
+var apiTypes = []interface{}{
+	config.Config{},
+	database.APIKey{},
+	database.BulkStoreJob{},
+	database.DeliveryRegion{},
+	database.ShortLink{},
+	database.NewShipment{},
+	database.UpsertStats{},
+	database.StoreCalendar{},
+	database.StoreTimeseries{},
+	database.SnapshotDiff{},
+	database.NearbyStore{},
+	database.TopStore{},
+	database.ProductType{},
+	database.Export{},
+	database.QueryStats{},
+	database.Product{},
+	database.ProductAlias{},
+	database.ProductRenameResult{},
+	database.StoreOverview{},
+	database.Webhook{},
+	database.WebhookDelivery{},
+	database.DistrictAggregate{},
+	database.StoreRecord{},
+	database.FieldChange{},
+	database.FlaggedShipment{},
+	database.ShipmentRecord{},
+	database.ShipmentAuditLog{},
+	database.IndexReport{},
+	database.SourceFreshness{},
+	database.SyncJob{},
+	database.CoordinateCollision{},
+	database.StoreGCReport{},
+	google.DataSource{},
+	graphql.Request{},
+	graphql.Result{},
+	opendata.Dump{},
+	scheduler.LoopHealth{},
+	scheduler.SyncEvent{},
+	sync.Summary{},
+	webhook.Payload{},
+	BulkUpdateStoresRequest{},
+	CreateStoreRequest{},
+	StoreListResponse{},
+	PreviewClaims{},
+	CreatePreviewLinkRequest{},
+	PreviewLinkResponse{},
+	UpdateShipmentRequest{},
+	SaveProductRequest{},
+	DeleteShipmentRequest{},
+	ShipmentDetailResponse{},
+	WSEvent{},
+	CreateAPIKeyRequest{},
+	UpdateAPIKeyRequest{},
+	CreateRegionRequest{},
+	CreateLinkRequest{},
+	CreateWebhookRequest{},
+	ErrorResponse{},
+	GeocodeBatchRequest{},
+	GeocodeProgress{},
+	GeocodeSummary{},
+	RenameProductRequest{},
+	SourceInfo{},
+	SyncHistoryEntry{},
+	RuntimeInfo{},
+	CacheStats{},
+}
+
+// CheckJSONNaming 檢查 apiTypes 中所有欄位（含巢狀結構）的 json 標籤是否符合 camelCase，
+// 回傳違規的欄位；沒有 json 標籤的匯出欄位會以 Go 欄位名稱（PascalCase）輸出，也視為違規
+func CheckJSONNaming() []string {
+	var violations []string
+	seen := make(map[reflect.Type]bool)
+	for _, v := range apiTypes {
+		violations = append(violations, checkJSONType(reflect.TypeOf(v), seen)...)
+	}
+	return violations
+}
+
+func checkJSONType(t reflect.Type, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+	seen[t] = true
+
+	var violations []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" && f.Anonymous {
+			violations = append(violations, checkJSONType(f.Type, seen)...)
+			continue
+		}
+		if !camelCaseName.MatchString(name) {
+			violations = append(violations, fmt.Sprintf("%s.%s: json 名稱 %q 不是 camelCase", t, f.Name, name))
+		}
+		violations = append(violations, checkJSONType(f.Type, seen)...)
+	}
+	return violations
+}
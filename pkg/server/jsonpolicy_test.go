@@ -0,0 +1,34 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestJSONNamingPolicy 所有 API 型別的 json 欄位名稱都必須是 camelCase
+func TestJSONNamingPolicy(t *testing.T) {
+	if violations := CheckJSONNaming(); len(violations) > 0 {
+		t.Errorf("%d 個欄位不符合 JSON 命名規則:\n%s", len(violations), strings.Join(violations, "\n"))
+	}
+}
+
+func TestCheckJSONTypeReportsViolations(t *testing.T) {
+	type nested struct {
+		SnakeCase string `json:"snake_case"`
+	}
+	type sample struct {
+		StoreName string   `json:"storeName"`
+		Untagged  string   // 沒有標籤時以 PascalCase 輸出
+		Ignored   string   `json:"-"`
+		Nested    []nested `json:"nested,omitempty"`
+		private   string
+	}
+	violations := checkJSONType(reflect.TypeOf(sample{}), map[reflect.Type]bool{})
+	if len(violations) != 2 {
+		t.Fatalf("violations = %q, want Untagged and snake_case", violations)
+	}
+	if !strings.Contains(violations[0], "Untagged") || !strings.Contains(violations[1], "snake_case") {
+		t.Errorf("violations = %q", violations)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	shortCodeLength   = 6
+	shortCodeAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789" // 去除容易混淆的字元
+	shortCodeRetries  = 5
+)
+
+// CreateLinkRequest 建立短網址請求（篩選條件皆為選填）
+type CreateLinkRequest struct {
+	Product string `json:"product"`
+	Region  string `json:"region"`
+	Date    string `json:"date"` // 單日 2006-01-02
+}
+
+// RegisterLinkRoutes 註冊短網址轉址端點
+func RegisterLinkRoutes(r gin.IRouter, db *sql.DB) {
+	r.GET("/s/:code", handleResolveLink(db))
+}
+
+// handleResolveLink 短網址轉址並累計點擊
+func handleResolveLink(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, err := database.ResolveShortLink(db, c.Param("code"))
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "link not found")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 查詢短網址失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Redirect(http.StatusFound, target)
+	}
+}
+
+// handleCreateLink 建立指向篩選後地圖的短網址（管理端點），回傳的 path 含 BASE_PATH
+func handleCreateLink(db *sql.DB, mapBaseURL, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Date != "" {
+			if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+				RespondError(c, http.StatusBadRequest, "date must be YYYY-MM-DD")
+				return
+			}
+		}
+
+		link := database.ShortLink{
+			Target:  mapViewURL(mapBaseURL, req),
+			Product: req.Product,
+			Region:  req.Region,
+			Date:    req.Date,
+		}
+
+		for i := 0; i < shortCodeRetries; i++ {
+			link.Code = randomShortCode()
+			created, err := database.CreateShortLink(db, link)
+			if err == nil {
+				log.Printf("[INFO] 已建立短網址 /s/%s → %s", created.Code, created.Target)
+				c.JSON(http.StatusCreated, gin.H{
+					"link": created,
+					"path": basePath + "/s/" + created.Code,
+				})
+				return
+			}
+			if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != "23505" {
+				logf(c, "[ERROR] 建立短網址失敗: %v", err)
+				RespondError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			// 代碼重複，重新產生
+		}
+
+		RespondError(c, http.StatusInternalServerError, "could not allocate a unique code")
+	}
+}
+
+// handleListLinks 列出短網址與點擊次數（管理端點）
+func handleListLinks(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		links, err := database.ListShortLinks(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, links)
+	}
+}
+
+// mapViewURL 組合帶篩選條件的地圖網址
+func mapViewURL(base string, req CreateLinkRequest) string {
+	params := url.Values{}
+	if req.Product != "" {
+		params.Set("product", req.Product)
+	}
+	if req.Region != "" {
+		params.Set("region", req.Region)
+	}
+	if req.Date != "" {
+		params.Set("date", req.Date)
+	}
+	if len(params) == 0 {
+		return base
+	}
+	return base + "?" + params.Encode()
+}
+
+// randomShortCode 產生隨機短網址代碼
+func randomShortCode() string {
+	code := make([]byte, shortCodeLength)
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, max)
+		code[i] = shortCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMetaRoutes 註冊資料新鮮度端點（前端顯示「資料更新至 X 月 X 日」）
+func RegisterMetaRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/meta", handleMeta(db, cfg, newHiddenProducts(cfg)))
+}
+
+// handleMeta 回傳資料庫中最新的出貨日期（不含隱藏的產品）、上次成功同步時間、資料版本、RECENT_DAYS 與 ?days= 的上限
+func handleMeta(db *sql.DB, cfg *config.Config, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lastModified, err := database.GetDataLastModified(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+
+		latest, err := database.GetLatestShipmentDate(db, hidden.list())
+		if err != nil {
+			logf(c, "[ERROR] 查詢最新出貨日期失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		generation, err := database.GetDataGeneration(db)
+		if err != nil {
+			logf(c, "[ERROR] 查詢資料版本失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		lastSuccess, err := scheduler.NewScheduler(db, 0).GetLastSyncTime()
+		if err != nil {
+			logf(c, "[ERROR] 查詢上次成功同步時間失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		result := gin.H{
+			"recentDays":    cfg.RecentDays,
+			"maxRecentDays": maxRecentDays(cfg),
+			"maxRangeDays":  cfg.MaxRangeDays,
+			"timezone":      cfg.DisplayTimezone,
+			"generation":    generation,
+		}
+		if latest != "" {
+			result["latestShipmentDate"] = latest
+		}
+		if !lastSuccess.IsZero() {
+			result["lastSuccessfulSync"] = lastSuccess
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"PXMarkMapBackEnd/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMetricsRoutes 註冊 Prometheus 指標端點（每個程序各自記錄，worker 同步的指標要抓 worker 的 /metrics）
+func RegisterMetricsRoutes(r gin.IRouter) {
+	r.GET("/metrics", handleMetrics)
+}
+
+// handleMetrics 以 Prometheus 文字格式輸出指標
+func handleMetrics(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.WriteText(c.Writer); err != nil {
+		logf(c, "[ERROR] 輸出指標失敗: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNearbyRadiusKm = 5.0
+	maxNearbyRadiusKm     = 50.0
+)
+
+// RegisterNearbyRoutes 註冊附近店家查詢端點（近期出貨的天數為 RECENT_DAYS，不列入隱藏的產品）
+func RegisterNearbyRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/stores/nearby", handleNearbyStores(db, cfg.RecentDays, newHiddenProducts(cfg)))
+}
+
+// handleNearbyStores 回傳附近有近期出貨的店家（?lat=&lng=&radius= 公里，可加 &product=；
+// ?sort=distance|name|latestShipment|totalQty&order=asc|desc，預設依距離由近到遠）
+func handleNearbyStores(db *sql.DB, recentDays int, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lat, err1 := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, err2 := strconv.ParseFloat(c.Query("lng"), 64)
+		if err1 != nil || err2 != nil || math.Abs(lat) > 90 || math.Abs(lng) > 180 {
+			RespondError(c, http.StatusBadRequest, "lat and lng are required")
+			return
+		}
+
+		radius := defaultNearbyRadiusKm
+		if s := c.Query("radius"); s != "" {
+			r, err := strconv.ParseFloat(s, 64)
+			if err != nil || r <= 0 || r > maxNearbyRadiusKm {
+				RespondError(c, http.StatusBadRequest, "radius must be between 0 and 50 km")
+				return
+			}
+			radius = r
+		}
+		sort, err := parseStoreSort(c, database.SortDistance, database.SortName, database.SortLatestShipment, database.SortTotalQuantity)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		stores, err := database.GetNearbyStores(db, lat, lng, radius, recentDays, c.Query("product"), hidden.list(), sort)
+		if err != nil {
+			logf(c, "[ERROR] 查詢附近店家失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		labels := loadProductLabels(c, db, requestLanguage(c))
+		for i := range stores {
+			stores[i].ProductNames = labels.names(stores[i].Products)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": stores,
+			"meta": gin.H{"lat": lat, "lng": lng, "radiusKm": radius, "total": len(stores), "generation": dataGeneration(c)},
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec 所有端點的 OpenAPI 3 說明；新增或修改端點時請一併更新 openapi.json
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIPage 載入 CDN 上的 Swagger UI 並讀取同一層的 openapi.json（相對路徑，BASE_PATH 底下也能用）
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+  <meta charset="utf-8">
+  <title>PXMarkMap API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// RegisterOpenAPIRoutes 註冊 API 說明文件端點，basePath 不為空時加到 spec 的 servers
+func RegisterOpenAPIRoutes(r gin.IRouter, basePath string) {
+	spec := openAPISpec
+	if basePath != "" {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+			log.Printf("[ERROR] openapi.json 格式錯誤: %v", err)
+		} else {
+			doc["servers"] = []map[string]string{{"url": basePath}}
+			spec, _ = json.MarshalIndent(doc, "", "  ")
+		}
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs", handleSwaggerUI)
+}
+
+// handleSwaggerUI 回傳 Swagger UI 頁面
+func handleSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+
+	"PXMarkMapBackEnd/pkg/opendata"
+	"PXMarkMapBackEnd/pkg/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOpenDataRoutes 註冊開放資料檔案端點（同步後產生、保存在 BLOB_STORE 的靜態 JSON）
+func RegisterOpenDataRoutes(r gin.IRouter) {
+	r.GET("/opendata/:file", handleOpenDataFile)
+}
+
+// handleOpenDataFile 回傳開放資料檔案（支援 If-Modified-Since）
+func handleOpenDataFile(c *gin.Context) {
+	name := c.Param("file")
+	if !opendata.IsValidFileName(name) {
+		RespondError(c, http.StatusNotFound, "file not found")
+		return
+	}
+
+	blobs, err := storage.Default()
+	if err != nil {
+		logf(c, "[ERROR] 無法使用 BLOB_STORE: %v", err)
+		RespondError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	obj, err := blobs.Get(opendata.Key(name))
+	if err == storage.ErrNotFound {
+		RespondError(c, http.StatusNotFound, "file not found")
+		return
+	}
+	if err != nil {
+		logf(c, "[ERROR] 讀取開放資料 %s 失敗: %v", name, err)
+		RespondError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if name == opendata.LatestFile {
+		c.Header("Cache-Control", "public, max-age=3600")
+	} else {
+		// 每日檔案產生後不再變動（除非同一天重新同步）
+		c.Header("Cache-Control", "public, max-age=86400")
+	}
+	c.Header("Content-Type", "application/json")
+	http.ServeContent(c.Writer, c.Request, name, obj.ModTime, bytes.NewReader(obj.Data))
+}
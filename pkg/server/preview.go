@@ -0,0 +1,200 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewTTLHours = 72
+	maxPreviewTTLHours     = 30 * 24
+)
+
+// PreviewClaims 預覽連結的內容：在 ExpiresAt 之前可在地圖上看到 Products 中尚未公開的產品
+type PreviewClaims struct {
+	Products  []string `json:"products"`
+	ExpiresAt int64    `json:"expiresAt"` // Unix 秒數
+	Label     string   `json:"label,omitempty"`
+}
+
+// CreatePreviewLinkRequest 建立預覽連結請求
+type CreatePreviewLinkRequest struct {
+	Products []string `json:"products"`
+	TTLHours int      `json:"ttlHours"` // 預設 72 小時，最多 30 天
+	Label    string   `json:"label"`    // 給誰的連結（只用於紀錄）
+}
+
+// PreviewLinkResponse 建立的預覽連結
+type PreviewLinkResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	Products  []string  `json:"products"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+var errInvalidPreview = errors.New("invalid or expired preview token")
+
+// SignPreviewToken 產生預覽 token：base64url(JSON 內容) + "." + base64url(HMAC-SHA256(key, 內容))
+func SignPreviewToken(key string, claims PreviewClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + previewSignature(key, encoded), nil
+}
+
+// ParsePreviewToken 驗證預覽 token 的簽章與期限
+func ParsePreviewToken(key, token string, now time.Time) (*PreviewClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if key == "" || !ok || !hmac.Equal([]byte(sig), []byte(previewSignature(key, encoded))) {
+		return nil, errInvalidPreview
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidPreview
+	}
+	var claims PreviewClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidPreview
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, errInvalidPreview
+	}
+	return &claims, nil
+}
+
+func previewSignature(key, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("preview." + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hiddenProducts 尚未公開的產品（HIDDEN_PRODUCTS），公開端點不顯示，只能透過預覽連結在地圖上查看
+type hiddenProducts map[string]bool
+
+func newHiddenProducts(cfg *config.Config) hiddenProducts {
+	hidden := hiddenProducts{}
+	for _, p := range ParseList(cfg.HiddenProducts) {
+		hidden[p] = true
+	}
+	return hidden
+}
+
+// list 隱藏的產品名稱（供 SQL 條件使用）
+func (h hiddenProducts) list() []string {
+	products := make([]string, 0, len(h))
+	for p := range h {
+		products = append(products, p)
+	}
+	return products
+}
+
+// filterRows 去掉隱藏產品的出貨（preview 中的產品除外）
+func (h hiddenProducts) filterRows(rows []map[string]interface{}, preview map[string]bool) []map[string]interface{} {
+	if len(h) == 0 {
+		return rows
+	}
+	visible := rows[:0]
+	for _, row := range rows {
+		if product, _ := row["product_type"].(string); h[product] && !preview[product] {
+			continue
+		}
+		visible = append(visible, row)
+	}
+	return visible
+}
+
+// filterSparklines 去掉隱藏產品的走勢（preview 中的產品除外）
+func (h hiddenProducts) filterSparklines(sparklines map[string][]float64, preview map[string]bool) map[string][]float64 {
+	if len(h) == 0 || sparklines == nil {
+		return sparklines
+	}
+	visible := make(map[string][]float64, len(sparklines))
+	for product, values := range sparklines {
+		if !h[product] || preview[product] {
+			visible[product] = values
+		}
+	}
+	return visible
+}
+
+// previewAllowed 依 ?preview= 回傳這次請求可以看到的隱藏產品；token 無效或過期時回傳 errInvalidPreview
+func previewAllowed(c *gin.Context, cfg *config.Config) (map[string]bool, error) {
+	token := c.Query("preview")
+	if token == "" {
+		return nil, nil
+	}
+	claims, err := ParsePreviewToken(cfg.PreviewSigningKey, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(claims.Products))
+	for _, p := range claims.Products {
+		allowed[p] = true
+	}
+	return allowed, nil
+}
+
+// handleCreatePreviewLink 產生限時的預覽連結（管理端點），產品必須在 HIDDEN_PRODUCTS 中
+func handleCreatePreviewLink(cfg *config.Config) gin.HandlerFunc {
+	hidden := newHiddenProducts(cfg)
+	return func(c *gin.Context) {
+		if cfg.PreviewSigningKey == "" {
+			RespondError(c, http.StatusServiceUnavailable, "PREVIEW_SIGNING_KEY is not configured")
+			return
+		}
+
+		var req CreatePreviewLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Products) == 0 {
+			RespondError(c, http.StatusBadRequest, "products is required")
+			return
+		}
+		for _, p := range req.Products {
+			if !hidden[p] {
+				RespondError(c, http.StatusBadRequest, fmt.Sprintf("product %q is not in HIDDEN_PRODUCTS", p))
+				return
+			}
+		}
+		if req.TTLHours == 0 {
+			req.TTLHours = defaultPreviewTTLHours
+		}
+		if req.TTLHours < 1 || req.TTLHours > maxPreviewTTLHours {
+			RespondError(c, http.StatusBadRequest, fmt.Sprintf("ttlHours must be between 1 and %d", maxPreviewTTLHours))
+			return
+		}
+
+		expiresAt := time.Now().Add(time.Duration(req.TTLHours) * time.Hour).Truncate(time.Second)
+		token, err := SignPreviewToken(cfg.PreviewSigningKey, PreviewClaims{
+			Products:  req.Products,
+			ExpiresAt: expiresAt.Unix(),
+			Label:     req.Label,
+		})
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		link := cfg.URLPath(cfg.MapBaseURL)
+		sep := "?"
+		if strings.Contains(link, "?") {
+			sep = "&"
+		}
+		link += sep + "preview=" + url.QueryEscape(token)
+
+		log.Printf("[INFO] 已建立預覽連結（%s，產品: %s，到期: %s）", req.Label, strings.Join(req.Products, "、"), expiresAt.Format(time.RFC3339))
+		c.JSON(http.StatusCreated, PreviewLinkResponse{Token: token, URL: link, Products: req.Products, ExpiresAt: expiresAt})
+	}
+}
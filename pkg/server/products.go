@@ -0,0 +1,157 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterProductRoutes 註冊產品列表端點（前端的產品篩選，不列入隱藏的產品）
+func RegisterProductRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/products", handleProductTypes(db, newHiddenProducts(cfg)))
+}
+
+// handleProductTypes 列出資料庫中有出貨的產品與依 Accept-Language 選擇的顯示名稱
+func handleProductTypes(db *sql.DB, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := requestLanguage(c)
+		lastModified, err := database.GetDataLastModified(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+
+		products, err := database.ListProductTypes(db, hidden.list())
+		if err != nil {
+			logf(c, "[ERROR] 查詢產品列表失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		labels := loadProductLabels(c, db, lang)
+		for i := range products {
+			products[i].Name = labels.name(products[i].ProductType)
+		}
+		c.JSON(http.StatusOK, products)
+	}
+}
+
+// SaveProductRequest 設定產品的顯示名稱（nameEn 為空時英文回應也使用中文名稱）與工作表比對規則
+// （sheetPatterns 省略時保留原本的規則）
+type SaveProductRequest struct {
+	NameZhTW      string   `json:"nameZhTw"`
+	NameEn        string   `json:"nameEn"`
+	SheetPatterns []string `json:"sheetPatterns"`
+}
+
+// maxProductNameLength 顯示名稱的長度上限（字元數，對應 products 資料表的 VARCHAR(100)）
+const maxProductNameLength = 100
+
+// RenameProductRequest 產品改名 / 合併請求
+type RenameProductRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// handleRenameProduct 將產品改名（新名稱已存在時合併），歷史出貨、webhook 訂閱條件一併更新，
+// 並記錄別名讓之後的同步以新名稱寫入
+func handleRenameProduct(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RenameProductRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.From, req.To = strings.TrimSpace(req.From), strings.TrimSpace(req.To)
+		if req.From == "" || req.To == "" || req.From == req.To {
+			RespondError(c, http.StatusBadRequest, "from and to must be different product names")
+			return
+		}
+
+		result, err := database.RenameProduct(db, req.From, req.To)
+		if err != nil {
+			logf(c, "[ERROR] 產品 %s 改名為 %s 失敗: %v", req.From, req.To, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 產品 %s 已改名為 %s：出貨 %d 筆，合併 %d 筆，webhook %d 個",
+			result.From, result.To, result.Renamed, result.Merged, result.WebhooksUpdated)
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// handleListProductAliases 列出產品別名（舊名稱 → 目前名稱）
+func handleListProductAliases(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		aliases, err := database.ListProductAliases(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, aliases)
+	}
+}
+
+// handleListProducts 列出產品的顯示名稱
+func handleListProducts(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		products, err := database.ListProducts(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, products)
+	}
+}
+
+// handleSaveProduct 新增或更新產品（地圖回應快取最多 MAP_CACHE_TTL_SECONDS 秒後更新）；
+// 新增的產品在下次同步時開始讀取名稱相同或符合 sheetPatterns 的工作表
+func handleSaveProduct(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SaveProductRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		product := strings.TrimSpace(c.Param("product"))
+		req.NameZhTW, req.NameEn = strings.TrimSpace(req.NameZhTW), strings.TrimSpace(req.NameEn)
+		if utf8.RuneCountInString(product) > 50 {
+			RespondError(c, http.StatusBadRequest, "product must not exceed 50 characters")
+			return
+		}
+		if req.NameZhTW == "" {
+			RespondError(c, http.StatusBadRequest, "nameZhTw is required")
+			return
+		}
+		if utf8.RuneCountInString(req.NameZhTW) > maxProductNameLength || utf8.RuneCountInString(req.NameEn) > maxProductNameLength {
+			RespondError(c, http.StatusBadRequest, "names must not exceed 100 characters")
+			return
+		}
+		for _, p := range req.SheetPatterns {
+			if _, err := regexp.Compile(p); err != nil || p == "" {
+				RespondError(c, http.StatusBadRequest, fmt.Sprintf("invalid sheet pattern %q", p))
+				return
+			}
+		}
+
+		saved, err := database.SaveProduct(db, database.Product{ProductType: product, NameZhTW: req.NameZhTW, NameEn: req.NameEn, SheetPatterns: req.SheetPatterns})
+		if err != nil {
+			logf(c, "[ERROR] 設定產品 %s 的顯示名稱失敗: %v", product, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		log.Printf("[INFO] 產品 %s 的顯示名稱已設定為 %s / %s", product, saved.NameZhTW, saved.NameEn)
+		c.JSON(http.StatusOK, saved)
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"PXMarkMapBackEnd/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery 取代 gin.Recovery：handler panic 時記錄堆疊與請求資訊（method、path、請求 ID），
+// 回傳不含內部細節的 JSON 500，避免單一請求的資料問題（例如 NULL 地址）讓回應中斷
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// net/http 用來中斷回應的 panic，交回給 http.Server 處理
+				panic(r)
+			}
+
+			logf(c, "[ERROR] %s %s panic: %v\n%s", c.Request.Method, c.Request.URL.Path, r, debug.Stack())
+			metrics.AddCounter("pxmark_http_panics_total", "HTTP handler panic 次數",
+				metrics.Labels{"route": c.FullPath()}, 1)
+
+			if c.Writer.Written() {
+				// 回應已經開始送出，無法再改狀態碼
+				c.Abort()
+				return
+			}
+			AbortWithError(c, http.StatusInternalServerError, "internal server error")
+		}()
+		c.Next()
+	}
+}
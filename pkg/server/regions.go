@@ -0,0 +1,132 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/geo"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateRegionRequest 建立配送區域請求
+type CreateRegionRequest struct {
+	Name    string          `json:"name"`
+	GeoJSON json.RawMessage `json:"geojson"` // Polygon、MultiPolygon 或包含它們的 Feature
+}
+
+// RegisterRegionRoutes 註冊配送區域查詢端點
+func RegisterRegionRoutes(r gin.IRouter, db *sql.DB) {
+	r.GET("/regions", handleListRegions(db))
+	r.GET("/regions/:id/stores", handleRegionStores(db))
+}
+
+// handleListRegions 列出所有配送區域
+func handleListRegions(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		regions, err := database.ListDeliveryRegions(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, regions)
+	}
+}
+
+// handleRegionStores 回傳座標落在配送區域內的店家
+func handleRegionStores(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid region id")
+			return
+		}
+
+		region, err := database.GetDeliveryRegion(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "region not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		area, err := geo.ParseGeoJSON(region.GeoJSON)
+		if err != nil {
+			logf(c, "[ERROR] 配送區域 #%d 的 GeoJSON 無效: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		stores, err := database.GetLocatedStores(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		inside := []database.StoreRecord{}
+		for _, store := range stores {
+			if area.Contains(store.Longitude, store.Latitude) {
+				inside = append(inside, store)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"region": gin.H{"id": region.ID, "name": region.Name},
+			"stores": inside,
+		})
+	}
+}
+
+// handleCreateRegion 建立配送區域（管理端點）
+func handleCreateRegion(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateRegionRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			RespondError(c, http.StatusBadRequest, "name and geojson are required")
+			return
+		}
+		if _, err := geo.ParseGeoJSON(req.GeoJSON); err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		region, err := database.CreateDeliveryRegion(db, req.Name, req.GeoJSON)
+		if err != nil {
+			logf(c, "[ERROR] 建立配送區域失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已建立配送區域 #%d (%s)", region.ID, region.Name)
+		c.JSON(http.StatusCreated, region)
+	}
+}
+
+// handleDeleteRegion 刪除配送區域（管理端點）
+func handleDeleteRegion(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid region id")
+			return
+		}
+
+		err = database.DeleteDeliveryRegion(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "region not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已刪除配送區域 #%d", id)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDKey 請求 ID 在 gin.Context 中的鍵值
+const requestIDKey = "requestId"
+
+// validRequestID 接受上游（反向代理、前端）帶來的 X-Request-ID，格式不符時重新產生
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// accessLogEntry 每個請求一筆的存取日誌（JSON）
+type accessLogEntry struct {
+	Type      string  `json:"type"`
+	RequestID string  `json:"requestId"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Bytes     int     `json:"bytes"`
+	ClientIP  string  `json:"clientIp"`
+}
+
+// RequestID 為每個請求產生 X-Request-ID（或沿用上游帶來的），寫入回應標頭；
+// 錯誤回應（ErrorResponse）的 requestId 欄位也使用同一個值
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+// AccessLog 每個請求結束後寫一筆 JSON 存取日誌（method、path、status、latency）
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry, _ := json.Marshal(accessLogEntry{
+			Type:      "access",
+			RequestID: GetRequestID(c),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Query:     c.Request.URL.RawQuery,
+			Status:    c.Writer.Status(),
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:     c.Writer.Size(),
+			ClientIP:  c.ClientIP(),
+		})
+		log.Println(string(entry))
+	}
+}
+
+// GetRequestID 目前請求的 ID（不在請求中時為空字串）
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// logf 同 log.Printf，並在結尾加上請求 ID，方便對照存取日誌與前端回報的錯誤
+func logf(c *gin.Context, format string, args ...interface{}) {
+	log.Printf(format+" [requestId=%s]", append(args, GetRequestID(c))...)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter 建立包含所有路由的 Gin engine（請求 ID、存取日誌、panic 處理、CORS、靜態檔案、資料 / 同步 / 管理端點）；
+// db 供查詢使用，syncDB 供同步寫入使用，readiness 由呼叫端在開始接受連線後標記
+func NewRouter(db, syncDB *sql.DB, cfg *config.Config, readiness *Readiness) *gin.Engine {
+	// gin.Default() 的文字日誌改為附請求 ID 的 JSON 存取日誌，panic 時回傳 JSON 500
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(), Recovery(), CORS(cfg.CORSOrigins))
+	router.NoRoute(func(c *gin.Context) {
+		// 舊的 /api/... 路徑轉址到 /api/v1/...
+		if legacyAPIRedirect(c, cfg.BasePath) {
+			return
+		}
+		RespondError(c, http.StatusNotFound, "not found")
+	})
+
+	// 所有路由掛在 BASE_PATH 底下（未設定時為根路徑），方便放在共用的反向代理路徑後面
+	base := router.Group(cfg.BasePath)
+
+	// /healthz 健康檢查、/livez 與 /readyz probe
+	RegisterHealthRoutes(base, db, time.Now(), readiness)
+
+	// 靜態 HTML（嵌入執行檔，STATIC_DIR 設定時改讀磁碟）
+	RegisterStaticRoutes(base, cfg)
+
+	// 資料端點：設定 REQUIRE_API_KEY=true 時需要 X-API-Key
+	data := base.Group("")
+	if cfg.RequireAPIKey {
+		data.Use(APIKeyAuth(db, ParseList(cfg.APIKeys)), RequireScope(database.ScopeReadMap))
+		log.Println("[INFO] 資料端點需要 API 金鑰")
+	}
+
+	// /api/v1 的端點
+	registerAPIV1(newAPIVersion(base, "v1", db, cfg), db, syncDB, cfg)
+
+	// /metrics Prometheus 指標
+	RegisterMetricsRoutes(base)
+
+	// /ws 同步完成時推送 dataRefreshed 事件
+	RegisterWebSocketRoutes(base, db, cfg)
+
+	// /s/:code 短網址
+	RegisterLinkRoutes(base, db)
+
+	// /graphql 店家、出貨與同步紀錄的 GraphQL 查詢
+	RegisterGraphQLRoutes(data, db, cfg)
+
+	// /opendata/shipments-YYYY-MM-DD.json、/opendata/latest.json
+	RegisterOpenDataRoutes(data)
+
+	return router
+}
+
+// registerAPIV1 註冊 /api/v1 底下的端點（以下路徑皆省略 /api/v1 前綴）
+func registerAPIV1(api *APIVersion, db, syncDB *sql.DB, cfg *config.Config) {
+	// 資料端點依 API 金鑰的權限範圍分組，並依分類附上 Cache-Control（CACHE_MAX_AGE_*）
+	mapData := api.Data.Group("", RequireScope(database.ScopeReadMap))
+	statsData := api.Data.Group("", RequireScope(database.ScopeReadStats))
+	cached := func(r *gin.RouterGroup, maxAge int) gin.IRouter {
+		return r.Group("", CacheControl(maxAge, cfg.RequireAPIKey))
+	}
+
+	// /shopeMap、/shopeMap.geojson 店家地圖
+	RegisterShopeMapRoutes(cached(mapData, cfg.CacheMaxAgeMap), db, cfg)
+
+	// /triggerSync 手動同步（需設定 ENABLE_SYNC 與 SYNC_SECRET）
+	if cfg.EnableSync {
+		RegisterTriggerSyncRoutes(api.Public, syncDB, cfg)
+	}
+
+	// /openapi.json 與 /docs（Swagger UI）
+	RegisterOpenAPIRoutes(api.Public, cfg.BasePath)
+
+	// /syncStatus 同步狀態與下次排程時間
+	RegisterSyncStatusRoutes(api.Public, db, cfg)
+
+	// /meta 資料新鮮度（最新出貨日期、上次成功同步時間、RECENT_DAYS）
+	RegisterMetaRoutes(api.Public, db, cfg)
+
+	// /stores/nearby 附近店家
+	RegisterNearbyRoutes(cached(mapData, cfg.CacheMaxAgeStores), db, cfg)
+
+	// /stores/:id/calendar 店家出貨日曆
+	RegisterCalendarRoutes(cached(mapData, cfg.CacheMaxAgeStores), db, cfg)
+
+	// /stores/:id/timeseries 店家出貨時間序列
+	RegisterTimeseriesRoutes(cached(mapData, cfg.CacheMaxAgeStores), db, cfg)
+
+	// /products 產品列表（前端的產品篩選）
+	RegisterProductRoutes(cached(mapData, cfg.CacheMaxAgeCatalog), db, cfg)
+
+	// /regions 配送區域
+	RegisterRegionRoutes(cached(mapData, cfg.CacheMaxAgeCatalog), db)
+
+	// /export.xlsx 出貨匯出（Excel，每個產品一張工作表）
+	RegisterExportRoutes(mapData, db, cfg)
+
+	// /stats/topStores 出貨量排行
+	RegisterStatsRoutes(cached(statsData, cfg.CacheMaxAgeStats), db, cfg)
+
+	// /sources/:id（只有設定了密鑰的資料來源可使用）
+	if sources, err := google.LoadDataSources(); err != nil {
+		log.Printf("[WARN] 無法載入資料來源設定: %v", err)
+	} else {
+		RegisterSourceRoutes(api.Public, syncDB, sources)
+	}
+
+	// /admin（未設定 ADMIN_SECRET 時不啟用）
+	if cfg.AdminSecret != "" {
+		RegisterAdminRoutes(api.Public, db, syncDB, cfg)
+	}
+}
+
+// CORS 依 CORS_ORIGINS（* 或逗號分隔的來源）設定跨來源標頭，並直接回應 OPTIONS preflight
+func CORS(corsOrigins string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if corsOrigins == "*" {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			allowed := false
+			for _, o := range strings.Split(corsOrigins, ",") {
+				if strings.TrimSpace(o) == origin {
+					allowed = true
+					break
+				}
+			}
+			if allowed {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+				c.Writer.Header().Set("Vary", "Origin")
+			}
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Sync-Secret, X-Admin-Secret, X-API-Key, X-Source-Secret, X-PXMark-Timestamp, X-PXMark-Signature, If-None-Match, If-Modified-Since, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-API-Version, X-Data-Generation, Deprecation, Link")
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(200)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeInfo 程序目前的資源使用狀況（GET /api/v1/admin/runtime），讓免費方案等無法登入主機的環境也能看到記憶體與連線壓力
+type RuntimeInfo struct {
+	GoVersion  string                 `json:"goVersion"`
+	OS         string                 `json:"os"`
+	Arch       string                 `json:"arch"`
+	NumCPU     int                    `json:"numCpu"`
+	GOMAXPROCS int                    `json:"gomaxprocs"`
+	Goroutines int                    `json:"goroutines"`
+	Memory     RuntimeMemory          `json:"memory"`
+	Database   map[string]DBPoolStats `json:"database"` // query（API 查詢）與 sync（同步寫入）兩個連線池
+	Queries    *database.QueryStats   `json:"queries,omitempty"`
+	Caches     []CacheStats           `json:"caches"`
+}
+
+// RuntimeMemory runtime.MemStats 中較常用的欄位
+type RuntimeMemory struct {
+	AllocBytes      uint64     `json:"allocBytes"`     // 目前使用中的 heap
+	HeapInuseBytes  uint64     `json:"heapInuseBytes"` // heap 已使用的 span
+	HeapObjects     uint64     `json:"heapObjects"`
+	SysBytes        uint64     `json:"sysBytes"` // 向作業系統取得的記憶體總量
+	TotalAllocBytes uint64     `json:"totalAllocBytes"`
+	NumGC           uint32     `json:"numGc"`
+	GCPauseTotalMs  float64    `json:"gcPauseTotalMs"`
+	LastGC          *time.Time `json:"lastGc,omitempty"`
+}
+
+// DBPoolStats sql.DBStats 的連線池狀態
+type DBPoolStats struct {
+	MaxOpen        int     `json:"maxOpen"` // 0 = 不限制
+	Open           int     `json:"open"`
+	InUse          int     `json:"inUse"`
+	Idle           int     `json:"idle"`
+	WaitCount      int64   `json:"waitCount"` // 因連線池已滿而等待的次數
+	WaitDurationMs float64 `json:"waitDurationMs"`
+}
+
+// handleRuntime 回傳 Go 版本、GOMAXPROCS、記憶體、goroutine 數、資料庫連線與回應快取大小
+func handleRuntime(db, syncDB *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		info := RuntimeInfo{
+			GoVersion:  runtime.Version(),
+			OS:         runtime.GOOS,
+			Arch:       runtime.GOARCH,
+			NumCPU:     runtime.NumCPU(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: runtime.NumGoroutine(),
+			Memory: RuntimeMemory{
+				AllocBytes:      mem.Alloc,
+				HeapInuseBytes:  mem.HeapInuse,
+				HeapObjects:     mem.HeapObjects,
+				SysBytes:        mem.Sys,
+				TotalAllocBytes: mem.TotalAlloc,
+				NumGC:           mem.NumGC,
+				GCPauseTotalMs:  float64(mem.PauseTotalNs) / float64(time.Millisecond),
+			},
+			Database: map[string]DBPoolStats{
+				"query": poolStats(db),
+				"sync":  poolStats(syncDB),
+			},
+			Caches: allCacheStats(),
+		}
+		if mem.LastGC > 0 {
+			lastGC := time.Unix(0, int64(mem.LastGC))
+			info.Memory.LastGC = &lastGC
+		}
+		if stats := database.GetQueryStats(); stats.SlowThresholdMs > 0 {
+			info.Queries = &stats
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+func poolStats(db *sql.DB) DBPoolStats {
+	s := db.Stats()
+	return DBPoolStats{
+		MaxOpen:        s.MaxOpenConnections,
+		Open:           s.OpenConnections,
+		InUse:          s.InUse,
+		Idle:           s.Idle,
+		WaitCount:      s.WaitCount,
+		WaitDurationMs: float64(s.WaitDuration) / float64(time.Millisecond),
+	}
+}
@@ -0,0 +1,489 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/cdn"
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// MapStore 店家地圖端點讀取的資料：正式環境查詢 PostgreSQL，示範模式改用記憶體中的假資料（demo.Memory）
+type MapStore interface {
+	LastModified() (time.Time, error)
+	RecentShipments(days int, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error)
+	ShipmentsBetween(from, to time.Time, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error)
+	SourceFreshness() ([]database.SourceFreshness, error)
+	Products() (map[string]database.Product, error)
+	Sparklines() (map[string]map[string][]float64, error)
+}
+
+// dbMapStore 查詢 PostgreSQL 的 MapStore
+type dbMapStore struct{ db *sql.DB }
+
+func (s dbMapStore) LastModified() (time.Time, error) { return database.GetDataLastModified(s.db) }
+
+func (s dbMapStore) RecentShipments(days int, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error) {
+	return database.GetRecentShipments(s.db, days, bbox, bySlot, sort)
+}
+
+func (s dbMapStore) ShipmentsBetween(from, to time.Time, bbox *database.BBox, bySlot bool, sort database.StoreSort) ([]map[string]interface{}, error) {
+	return database.GetShipmentsBetween(s.db, from, to, bbox, bySlot, sort)
+}
+
+func (s dbMapStore) SourceFreshness() ([]database.SourceFreshness, error) {
+	return database.GetSourceFreshness(s.db)
+}
+
+func (s dbMapStore) Products() (map[string]database.Product, error) {
+	return database.GetProducts(s.db)
+}
+
+func (s dbMapStore) Sparklines() (map[string]map[string][]float64, error) {
+	return database.GetSparklines(s.db)
+}
+
+// RegisterShopeMapRoutes 註冊店家地圖端點（/api/v1/shopeMap.geojson 或 ?format=geojson 回傳 GeoJSON FeatureCollection）
+func RegisterShopeMapRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	registerShopeMap(r, dbMapStore{db}, cfg)
+}
+
+func registerShopeMap(r gin.IRouter, store MapStore, cfg *config.Config) {
+	// 回應快取：同步後（資料版本改變）或超過 TTL 才重新查詢
+	mapCache := NewResponseCache("shopeMap", time.Duration(cfg.MapCacheTTLSeconds)*time.Second)
+
+	h := handleShopeMap(store, cfg, mapCache)
+	r.GET("/shopeMap", h)
+	r.GET("/shopeMap.geojson", h)
+}
+
+// handleShopeMap 回傳近 N 天（?days=，預設 RECENT_DAYS；或 ?from=&to=）的店家與出貨，支援 bbox、分頁與 ?include=sparkline；
+// 隱藏的產品只在帶有效的 ?preview= 時回傳。同一天多個時段的出貨預設合併為一筆，?granularity=slot 時分開回傳；
+// 店家預設依店名排序，?sort=name|latestShipment|totalQty&order=asc|desc 在 SQL 中排序（分頁依排序後的順序）；
+// ?fields=storeId,latitude,longitude 只回傳指定的店家欄位（第一次繪製標記用，出貨明細再以店家端點查詢）
+func handleShopeMap(store MapStore, cfg *config.Config, mapCache *ResponseCache) gin.HandlerFunc {
+	hidden := newHiddenProducts(cfg)
+	return func(c *gin.Context) {
+		var data []map[string]interface{}
+		from, to, hasRange, err := parseDateRange(c, cfg)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		days, err := parseRecentDays(c, cfg, hasRange)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit, offset, err := parsePagination(c)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		bbox, err := parseBBox(c.Query("bbox"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		bySlot, err := parseGranularity(c.Query("granularity"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sort, err := parseStoreSort(c, database.SortName, database.SortLatestShipment, database.SortTotalQuantity)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		fields, err := parseFields(c.Query("fields"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		geoJSON := c.Query("format") == "geojson" || strings.HasSuffix(c.Request.URL.Path, ".geojson")
+		if geoJSON && fields != nil {
+			// GeoJSON 的 geometry 一定需要座標
+			fields["latitude"], fields["longitude"] = true, true
+		}
+		preview, err := previewAllowed(c, cfg)
+		if err != nil {
+			RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if preview != nil {
+			// 預覽內容不可被 CDN 或共用快取保存
+			c.Header("Cache-Control", "private, no-store")
+		}
+		lang := requestLanguage(c)
+		// 資料只在同步時變動，條件式請求未過期時直接回 304，不重新查詢
+		lastModified, err := store.LastModified()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+		cacheKey := lang + " " + c.Request.URL.Path + "?" + shopeMapCacheQuery(c, days)
+		if cached, ok := mapCache.Get(cacheKey, lastModified); ok {
+			cached.Write(c)
+			return
+		}
+		if hasRange {
+			data, err = store.ShipmentsBetween(from, to, bbox, bySlot, sort)
+		} else {
+			data, err = store.RecentShipments(days, bbox, bySlot, sort)
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sources, err := store.SourceFreshness()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		data = hidden.filterRows(data, preview)
+		stores := formatResponse(data, newProductLabels(c, lang, store.Products))
+		meta := gin.H{"sources": sources, "total": len(stores), "generation": dataGeneration(c)}
+		if hasInclude(c, "sparkline") {
+			sparklines, err := store.Sparklines()
+			if err != nil {
+				RespondError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, store := range stores {
+				store["sparklines"] = hidden.filterSparklines(sparklines[store["storeName"].(string)], preview)
+			}
+			meta["sparklineDays"] = database.SparklineDays
+		}
+		if hasRange {
+			meta["from"] = from.Format("2006-01-02")
+			meta["to"] = to.Format("2006-01-02")
+		} else {
+			meta["days"] = days
+		}
+		if limit > 0 {
+			stores = paginate(stores, limit, offset)
+			meta["limit"] = limit
+			meta["offset"] = offset
+		}
+		if fields != nil {
+			selectFields(stores, fields)
+		}
+		extraHeader := http.Header{}
+		cdn.SetHeaders(extraHeader, surrogateKeys(data))
+
+		var response interface{} = gin.H{
+			"data": stores,
+			"meta": meta,
+		}
+		contentType := "application/json; charset=utf-8"
+		if geoJSON {
+			response = formatGeoJSON(stores, meta)
+			contentType = "application/geo+json; charset=utf-8"
+		}
+		body, err := json.Marshal(response)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		cached := &CachedResponse{ContentType: contentType, Header: extraHeader, Body: body}
+		mapCache.Set(cacheKey, lastModified, cached)
+		cached.Write(c)
+	}
+}
+
+// parseDateRange 解析 ?from=&to=（YYYY-MM-DD），都沒有時回傳 hasRange = false 使用近 N 天；
+// 只給 from 時 to 為今天，只給 to 時往前取 RecentDays 天
+func parseDateRange(c *gin.Context, cfg *config.Config) (from, to time.Time, hasRange bool, err error) {
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" && toStr == "" {
+		return from, to, false, nil
+	}
+
+	today := time.Now()
+	to = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return from, to, false, fmt.Errorf("to must be YYYY-MM-DD")
+		}
+	}
+
+	from = to.AddDate(0, 0, -cfg.RecentDays)
+	if fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return from, to, false, fmt.Errorf("from must be YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to) {
+		return from, to, false, fmt.Errorf("from must not be after to")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > cfg.MaxRangeDays {
+		return from, to, false, fmt.Errorf("date range must not exceed %d days", cfg.MaxRangeDays)
+	}
+	return from, to, true, nil
+}
+
+// parseRecentDays 解析 ?days=（近幾天，預設 RecentDays，最多 MaxRecentDays），不可與 ?from=&to= 同時使用
+func parseRecentDays(c *gin.Context, cfg *config.Config, hasRange bool) (int, error) {
+	s := c.Query("days")
+	if s == "" {
+		return cfg.RecentDays, nil
+	}
+	if hasRange {
+		return 0, fmt.Errorf("days cannot be combined with from/to")
+	}
+	max := maxRecentDays(cfg)
+	days, err := strconv.Atoi(s)
+	if err != nil || days < 1 || days > max {
+		return 0, fmt.Errorf("days must be between 1 and %d", max)
+	}
+	return days, nil
+}
+
+// maxRecentDays ?days= 的上限（MAX_RECENT_DAYS 小於 RECENT_DAYS 時以 RECENT_DAYS 為準）
+func maxRecentDays(cfg *config.Config) int {
+	if cfg.MaxRecentDays < cfg.RecentDays {
+		return cfg.RecentDays
+	}
+	return cfg.MaxRecentDays
+}
+
+// shopeMapCacheQuery 快取鍵的查詢字串：days 以實際天數取代，
+// 未指定與 ?days=RECENT_DAYS 共用同一份快取
+func shopeMapCacheQuery(c *gin.Context, days int) string {
+	query := c.Request.URL.Query()
+	if query.Get("from") == "" && query.Get("to") == "" {
+		query.Set("days", strconv.Itoa(days))
+	}
+	return query.Encode()
+}
+
+// parseBBox 解析 ?bbox=minLng,minLat,maxLng,maxLat，未指定時回傳 nil
+func parseBBox(s string) (*database.BBox, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+		}
+		v[i] = f
+	}
+
+	bbox := &database.BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if bbox.MinLng > bbox.MaxLng || bbox.MinLat > bbox.MaxLat ||
+		bbox.MinLat < -90 || bbox.MaxLat > 90 || bbox.MinLng < -180 || bbox.MaxLng > 180 {
+		return nil, fmt.Errorf("bbox is out of range")
+	}
+	return bbox, nil
+}
+
+// parseGranularity 解析 ?granularity=day|slot（預設 day），回傳是否依時段分開
+func parseGranularity(s string) (bool, error) {
+	switch s {
+	case "", "day":
+		return false, nil
+	case "slot":
+		return true, nil
+	}
+	return false, fmt.Errorf("granularity must be day or slot")
+}
+
+// storeFields ?fields= 可選擇的店家欄位
+var storeFields = []string{"storeId", "storeName", "address", "latitude", "longitude", "shipments", "totalQuantityByProduct", "sparklines"}
+
+// parseFields 解析 ?fields=（逗號分隔的店家欄位），未指定時回傳 nil 表示回傳所有欄位
+func parseFields(s string) (map[string]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := make(map[string]bool)
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		valid := false
+		for _, name := range storeFields {
+			if f == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("fields must be a comma-separated list of %s", strings.Join(storeFields, ", "))
+		}
+		fields[f] = true
+	}
+	return fields, nil
+}
+
+// selectFields 只保留 fields 中的店家欄位（第一次繪製地圖標記時不需要出貨明細，縮小回應）
+func selectFields(stores []map[string]interface{}, fields map[string]bool) {
+	for _, store := range stores {
+		for k := range store {
+			if !fields[k] {
+				delete(store, k)
+			}
+		}
+	}
+}
+
+// parseStoreSort 解析 ?sort=&order=（order 預設 asc），sort 必須是 allowed 之一；未指定 sort 時使用查詢的預設排序
+func parseStoreSort(c *gin.Context, allowed ...string) (database.StoreSort, error) {
+	var sort database.StoreSort
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		return sort, fmt.Errorf("order must be asc or desc")
+	}
+
+	field := c.Query("sort")
+	if field == "" {
+		return sort, nil
+	}
+	for _, a := range allowed {
+		if field == a {
+			sort.Field = field
+			return sort, nil
+		}
+	}
+	return sort, fmt.Errorf("sort must be one of %s", strings.Join(allowed, ", "))
+}
+
+// hasInclude ?include= 是否包含指定項目（逗號分隔）
+func hasInclude(c *gin.Context, name string) bool {
+	for _, v := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPageSize /api/v1/shopeMap 每頁最多幾個店家
+const maxPageSize = 500
+
+// parsePagination 解析 ?limit=&offset=，未指定 limit 時回傳 0（不分頁）
+func parsePagination(c *gin.Context) (limit, offset int, err error) {
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if s := c.Query("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// paginate 取出 [offset, offset+limit) 的店家
+func paginate(stores []map[string]interface{}, limit, offset int) []map[string]interface{} {
+	if offset >= len(stores) {
+		return []map[string]interface{}{}
+	}
+	end := offset + limit
+	if end > len(stores) {
+		end = len(stores)
+	}
+	return stores[offset:end]
+}
+
+// surrogateKeys 依回傳的店家與產品產生 CDN surrogate keys
+func surrogateKeys(data []map[string]interface{}) []string {
+	keys := []string{cdn.MapKey}
+	for _, record := range data {
+		keys = append(keys,
+			cdn.StoreKey(record["store_id"].(int)),
+			cdn.ProductKey(record["product_type"].(string)))
+	}
+	return keys
+}
+
+// formatResponse 將資料整理成前端需要格式（productName 為依 Accept-Language 選擇的產品顯示名稱；
+// totalQuantityByProduct 為期間內各產品數量開頭數字的總和，前端不必再解析 "3箱" 之類的數量）
+func formatResponse(data []map[string]interface{}, labels productLabels) []map[string]interface{} {
+	// 依查詢結果的順序（店名）排列，分頁時每頁內容才會固定
+	storeMap := make(map[string]map[string]interface{})
+	var order []string
+	for _, record := range data {
+		name := record["store_name"].(string)
+		if _, exists := storeMap[name]; !exists {
+			order = append(order, name)
+			storeMap[name] = map[string]interface{}{
+				"storeId":                record["store_id"].(int),
+				"storeName":              name,
+				"address":                record["address"].(string),
+				"latitude":               record["latitude"].(float64),
+				"longitude":              record["longitude"].(float64),
+				"shipments":              []map[string]string{},
+				"totalQuantityByProduct": map[string]float64{},
+			}
+		}
+		store := storeMap[name]
+		shipments := store["shipments"].([]map[string]string)
+		product := record["product_type"].(string)
+		store["totalQuantityByProduct"].(map[string]float64)[product] = record["product_total"].(float64)
+		shipment := map[string]string{
+			"productType": product,
+			"productName": labels.name(product),
+			"date":        record["shipment_date"].(string),
+			"quantity":    record["quantity"].(string),
+		}
+		if slot, _ := record["time_slot"].(string); slot != "" {
+			shipment["timeSlot"] = slot
+		}
+		shipments = append(shipments, shipment)
+		store["shipments"] = shipments
+	}
+	response := []map[string]interface{}{}
+	for _, name := range order {
+		response = append(response, storeMap[name])
+	}
+	return response
+}
+
+// formatGeoJSON 將店家資料轉成 GeoJSON FeatureCollection（座標為 [經度, 緯度]）
+func formatGeoJSON(stores []map[string]interface{}, meta gin.H) gin.H {
+	features := []gin.H{}
+	for _, store := range stores {
+		properties := gin.H{}
+		for k, v := range store {
+			if k != "latitude" && k != "longitude" {
+				properties[k] = v
+			}
+		}
+		features = append(features, gin.H{
+			"type": "Feature",
+			"geometry": gin.H{
+				"type":        "Point",
+				"coordinates": []float64{store["longitude"].(float64), store["latitude"].(float64)},
+			},
+			"properties": properties,
+		})
+	}
+	return gin.H{
+		"type":     "FeatureCollection",
+		"features": features,
+		"meta":     meta,
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 請求簽章標頭：簽章 = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + path + "." + body))，
+// path 包含 query string
+const (
+	SignatureHeader = "X-PXMark-Signature"
+	TimestampHeader = "X-PXMark-Timestamp"
+)
+
+// signatureTolerance 時間戳與伺服器時間的最大誤差，超過視為重送
+const signatureTolerance = 5 * time.Minute
+
+// SignRequest 計算請求簽章（不含 "sha256=" 前綴）
+func SignRequest(secret, timestamp, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + path + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HasSignature 請求是否帶有簽章標頭
+func HasSignature(c *gin.Context) bool {
+	return c.GetHeader(SignatureHeader) != ""
+}
+
+// ValidSignature 以 secret 驗證請求簽章，讀取 body 後會放回去供後續使用
+func ValidSignature(c *gin.Context, secret string) bool {
+	if secret == "" || !HasSignature(c) {
+		return false
+	}
+
+	timestamp := c.GetHeader(TimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if math.Abs(time.Since(time.Unix(sec, 0)).Seconds()) > signatureTolerance.Seconds() {
+		return false
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := SignRequest(secret, timestamp, c.Request.URL.RequestURI(), body)
+	given := strings.TrimPrefix(c.GetHeader(SignatureHeader), "sha256=")
+	return hmac.Equal([]byte(given), []byte(expected))
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/sync"
+	"github.com/gin-gonic/gin"
+)
+
+// SourceInfo 資料來源資訊（不含密鑰）
+type SourceInfo struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Sheets    []string `json:"sheets"`
+	HasSecret bool     `json:"hasSecret"`
+	Stores    int      `json:"stores"`
+	Shipments int      `json:"shipments"`
+}
+
+// RegisterSourceRoutes 註冊資料來源端點，各來源以自己的密鑰（X-Source-Secret）驗證，
+// 只能同步或清除自己來源的資料
+func RegisterSourceRoutes(r gin.IRouter, syncDB *sql.DB, sources []google.DataSource) {
+	group := r.Group("/sources/:id", sourceAuth(sources))
+	group.POST("/sync", handleSourceSync(syncDB))
+	group.DELETE("/data", handleSourcePurge(syncDB))
+
+	log.Println("[INFO] 資料來源端點已啟用: /api/v1/sources/:id")
+}
+
+// sourceAuth 驗證資料來源密鑰（或以該密鑰簽章的請求），通過後將來源放入 context
+func sourceAuth(sources []google.DataSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := google.FindDataSource(sources, c.Param("id"))
+		if !ok {
+			AbortWithError(c, http.StatusNotFound, "source not found")
+			return
+		}
+		if HasSignature(c) {
+			if !ValidSignature(c, source.Secret) {
+				logf(c, "[WARN] 資料來源 %s 的請求被拒絕：簽章錯誤", source.ID)
+				AbortWithError(c, http.StatusUnauthorized, "Invalid signature")
+				return
+			}
+		} else if source.Secret == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Source-Secret")), []byte(source.Secret)) != 1 {
+			logf(c, "[WARN] 資料來源 %s 的請求被拒絕：密鑰錯誤", source.ID)
+			AbortWithError(c, http.StatusUnauthorized, "Invalid source secret")
+			return
+		}
+		c.Set("source", *source)
+		c.Next()
+	}
+}
+
+// handleSourceSync 只同步單一資料來源（背景執行）
+func handleSourceSync(syncDB *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source := c.MustGet("source").(google.DataSource)
+
+		go func() {
+			log.Printf("[INFO] 觸發資料來源 %s 的同步", source.ID)
+			summary, err := sync.SyncSourcesDaily(syncDB, []google.DataSource{source}, google.PriorityManual, nil)
+			if err != nil {
+				log.Printf("[ERROR] 資料來源 %s 同步失敗: %v", source.ID, err)
+				return
+			}
+			log.Printf("[INFO] 資料來源 %s 同步完成: %s", source.ID, summary)
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":  "triggered",
+			"source":  source.ID,
+			"message": "同步任務已觸發，正在背景執行",
+		})
+	}
+}
+
+// handleSourcePurge 清除單一資料來源的資料
+func handleSourcePurge(syncDB *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source := c.MustGet("source").(google.DataSource)
+
+		shipments, stores, err := database.PurgeSourceData(syncDB, source.ID)
+		if err != nil {
+			logf(c, "[ERROR] 清除資料來源 %s 失敗: %v", source.ID, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"source":           source.ID,
+			"deletedShipments": shipments,
+			"deletedStores":    stores,
+		})
+	}
+}
+
+// handleListSources 列出所有資料來源與資料量（管理端點）
+func handleListSources(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sources, err := google.LoadDataSources()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		stats, err := database.GetSourceStats(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		result := []SourceInfo{}
+		for _, s := range sources {
+			result = append(result, SourceInfo{
+				ID:        s.ID,
+				Name:      s.Name,
+				Sheets:    s.Names,
+				HasSecret: s.Secret != "",
+				Stores:    stats[s.ID].Stores,
+				Shipments: stats[s.ID].Shipments,
+			})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// handleAdminPurgeSource 以管理密鑰清除任一資料來源的資料
+func handleAdminPurgeSource(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sourceID := c.Param("id")
+		shipments, stores, err := database.PurgeSourceData(db, sourceID)
+		if err != nil {
+			logf(c, "[ERROR] 清除資料來源 %s 失敗: %v", sourceID, err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"source":           sourceID,
+			"deletedShipments": shipments,
+			"deletedStores":    stores,
+		})
+	}
+}
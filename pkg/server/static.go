@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/static"
+	"github.com/gin-gonic/gin"
+)
+
+// staticModTime 嵌入檔案沒有修改時間，以程序啟動時間作為 Last-Modified
+var staticModTime = time.Now()
+
+// RegisterStaticRoutes 註冊前端頁面（/ 與 /static/*），預設使用嵌入執行檔的檔案，設定 STATIC_DIR 時改讀磁碟
+func RegisterStaticRoutes(r gin.IRouter, cfg *config.Config) {
+	fs := http.FS(static.FS)
+	if cfg.StaticDir != "" {
+		fs = http.Dir(cfg.StaticDir)
+	}
+
+	r.StaticFS("/static", fs)
+	r.GET("/", handleIndex(fs))
+}
+
+// handleIndex 回傳 index.html（不經過 http.FileServer，否則 /index.html 會被轉址到目錄）
+func handleIndex(fs http.FileSystem) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := fs.Open("index.html")
+		if err != nil {
+			RespondError(c, http.StatusNotFound, "index.html not found")
+			return
+		}
+		defer f.Close()
+
+		modTime := staticModTime
+		if info, err := f.Stat(); err == nil && !info.ModTime().IsZero() {
+			modTime = info.ModTime()
+		}
+		http.ServeContent(c.Writer, c.Request, "index.html", modTime, f)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTopStoresDays  = 30
+	defaultTopStoresLimit = 10
+	maxTopStoresLimit     = 100
+)
+
+// RegisterStatsRoutes 註冊統計端點（不列入隱藏的產品）
+func RegisterStatsRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/stats/topStores", handleTopStores(db, cfg, newHiddenProducts(cfg)))
+}
+
+// handleTopStores 依近 N 天的出貨總量排列店家（?product=&days=&limit=，days 預設 30、最多 MAX_RANGE_DAYS，limit 預設 10、最多 100）
+func handleTopStores(db *sql.DB, cfg *config.Config, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := queryInt(c, "days", defaultTopStoresDays, 1, cfg.MaxRangeDays)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit, err := queryInt(c, "limit", defaultTopStoresLimit, 1, maxTopStoresLimit)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		product := c.Query("product")
+		lang := requestLanguage(c)
+
+		lastModified, err := database.GetDataLastModified(db)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if NotModified(c, lastModified) {
+			return
+		}
+
+		stores, err := database.GetTopStores(db, product, days, limit, hidden.list())
+		if err != nil {
+			logf(c, "[ERROR] 查詢出貨排行失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		today := time.Now()
+		meta := gin.H{
+			"days":       days,
+			"from":       today.AddDate(0, 0, -days).Format("2006-01-02"),
+			"to":         today.Format("2006-01-02"),
+			"limit":      limit,
+			"total":      len(stores),
+			"generation": dataGeneration(c),
+		}
+		if product != "" {
+			meta["product"] = product
+			meta["productName"] = loadProductLabels(c, db, lang).name(product)
+		}
+		c.JSON(http.StatusOK, gin.H{"data": stores, "meta": meta})
+	}
+}
+
+// queryInt 解析整數查詢參數，未提供時為 def，需介於 min 與 max 之間
+func queryInt(c *gin.Context, name string, def, min, max int) (int, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return n, nil
+}
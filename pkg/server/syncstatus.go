@@ -0,0 +1,146 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSyncHistoryLimit = 20
+	maxSyncHistoryLimit     = 100
+)
+
+// SyncHistoryEntry 同步歷史記錄中的一次執行
+type SyncHistoryEntry struct {
+	ID              int        `json:"id"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	Status          string     `json:"status"`
+	DurationSeconds *float64   `json:"durationSeconds,omitempty"` // 尚未結束時不回傳
+	Message         string     `json:"message"`
+	// SheetLastModified 同步時試算表的最後修改時間（Drive API，取得不到時不回傳）
+	SheetLastModified *time.Time `json:"sheetLastModified,omitempty"`
+	// Generation 成功同步產生的資料版本（對照使用者回報的 meta.generation）
+	Generation *int64 `json:"generation,omitempty"`
+}
+
+// RegisterSyncStatusRoutes 註冊同步狀態端點（前端顯示「資料更新時間」、維運確認同步狀態）；
+// 設定 ADMIN_SECRET 時另外啟用需要 X-Admin-Secret 的 /api/v1/syncHistory
+func RegisterSyncStatusRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/syncStatus", handleSyncStatus(db, cfg))
+	if cfg.AdminSecret != "" {
+		r.GET("/syncHistory", adminAuth(cfg.AdminSecret), handleSyncHistory(db))
+	}
+}
+
+// handleSyncHistory 回傳最近的同步記錄（?limit= 預設 20，最多 100）
+func handleSyncHistory(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultSyncHistoryLimit
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 || n > maxSyncHistoryLimit {
+				RespondError(c, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = n
+		}
+
+		logs, err := scheduler.NewScheduler(db, 0).GetSyncHistory(limit)
+		if err != nil {
+			logf(c, "[ERROR] 查詢同步歷史失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		entries := make([]SyncHistoryEntry, 0, len(logs))
+		for _, l := range logs {
+			entry := SyncHistoryEntry{
+				ID:        l.ID,
+				StartedAt: l.StartTime,
+				Status:    l.Status,
+				Message:   l.Message,
+			}
+			if l.EndTime.Valid {
+				finished := l.EndTime.Time
+				duration := finished.Sub(l.StartTime).Seconds()
+				entry.FinishedAt = &finished
+				entry.DurationSeconds = &duration
+			}
+			if l.SheetLastModified.Valid {
+				entry.SheetLastModified = &l.SheetLastModified.Time
+			}
+			if l.DataGeneration.Valid {
+				entry.Generation = &l.DataGeneration.Int64
+			}
+			entries = append(entries, entry)
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// handleSyncStatus 回傳目前是否同步中、最近一次同步的結果、上次成功同步時間、試算表最後修改時間與下次排程時間；
+// 試算表修改時間早於上次成功同步時，表示同步正常但表單沒有新資料
+func handleSyncStatus(db *sql.DB, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := scheduler.NewScheduler(db, 0)
+
+		latest, err := s.GetLatestSync()
+		if err != nil {
+			logf(c, "[ERROR] 查詢同步記錄失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		lastSuccess, err := s.GetLastSyncTime()
+		if err != nil {
+			logf(c, "[ERROR] 查詢上次成功同步時間失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		sheetModified, err := s.GetSheetLastModified()
+		if err != nil {
+			logf(c, "[ERROR] 查詢試算表修改時間失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		result := gin.H{
+			"running": latest != nil && latest.Status == "running",
+		}
+		if latest != nil {
+			lastSync := gin.H{
+				"id":        latest.ID,
+				"startedAt": latest.StartTime,
+				"status":    latest.Status,
+				"message":   latest.Message,
+			}
+			if latest.EndTime.Valid {
+				lastSync["finishedAt"] = latest.EndTime.Time
+			}
+			result["lastSync"] = lastSync
+		}
+		if !lastSuccess.IsZero() {
+			result["lastSuccessfulSync"] = lastSuccess
+		}
+		if !sheetModified.IsZero() {
+			result["sheetLastModified"] = sheetModified
+		}
+
+		now := time.Now()
+		result["nextRuns"] = gin.H{
+			"daily":   scheduler.NextDailyRun(now, cfg.DailySyncHour, cfg.DailySyncMinute),
+			"monthly": scheduler.NextMonthlyRun(now, cfg.MonthlySyncDay, cfg.MonthlySyncHour, cfg.MonthlySyncMinute),
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// timeseriesPeriods 各區間單位的預設與最多區間數
+var timeseriesPeriods = map[string]struct{ def, max int }{
+	database.BucketDay:   {30, 366},
+	database.BucketWeek:  {12, 104},
+	database.BucketMonth: {12, 36},
+}
+
+// RegisterTimeseriesRoutes 註冊店家出貨時間序列端點（店家詳細頁的圖表，不列入隱藏的產品）
+func RegisterTimeseriesRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	r.GET("/stores/:id/timeseries", handleStoreTimeseries(db, newHiddenProducts(cfg)))
+}
+
+// handleStoreTimeseries 回傳店家依日、週或月加總的出貨量（?product=&granularity=day|week|month&periods=，
+// granularity 預設 day，periods 預設近 30 天 / 12 週 / 12 個月）
+func handleStoreTimeseries(db *sql.DB, hidden hiddenProducts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid store id")
+			return
+		}
+
+		granularity := c.DefaultQuery("granularity", database.BucketDay)
+		limits, ok := timeseriesPeriods[granularity]
+		if !ok {
+			RespondError(c, http.StatusBadRequest, "granularity must be day, week or month")
+			return
+		}
+		periods, err := queryInt(c, "periods", limits.def, 1, limits.max)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		product := c.Query("product")
+
+		ts, err := database.GetStoreTimeseries(db, id, product, granularity, periods, hidden.list())
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "store not found")
+			return
+		}
+		if err != nil {
+			logf(c, "[ERROR] 查詢店家 #%d 出貨時間序列失敗: %v", id, err)
+			RespondError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		products := make([]string, 0, len(ts.Products))
+		for product := range ts.Products {
+			products = append(products, product)
+		}
+		ts.ProductNames = loadProductLabels(c, db, requestLanguage(c)).names(products)
+		c.JSON(http.StatusOK, ts)
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewAutocertManager 建立 Let's Encrypt 自動憑證管理：只替 domains 申請憑證，憑證保存在 cacheDir（重新啟動不必重新申請）
+func NewAutocertManager(domains []string, cacheDir string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+}
+
+// NewACMEChallengeServer 在 addr 上回應 Let's Encrypt 的 HTTP-01 驗證，其餘請求轉址到 HTTPS
+func NewACMEChallengeServer(addr string, m *autocert.Manager) *http.Server {
+	return &http.Server{Addr: addr, Handler: m.HTTPHandler(nil)}
+}
+
+// ParseList 解析逗號分隔的設定值（網域、API 金鑰等），忽略空白項目
+func ParseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync/atomic"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTriggerSyncRoutes 註冊手動同步與同步工作查詢端點（X-Sync-Secret、簽章或有 write:sync 權限的 API 金鑰）
+func RegisterTriggerSyncRoutes(r gin.IRouter, syncDB *sql.DB, cfg *config.Config) {
+	r.POST("/triggerSync", handleTriggerSync(syncDB, cfg))
+	r.GET("/syncJobs/:id", handleSyncJob(syncDB, cfg))
+}
+
+// checkSyncAuth 驗證有 write:sync 權限的 X-API-Key、簽章或 X-Sync-Secret（也接受 ?secret=），
+// 失敗時回應 401（權限不足時 403）並回傳 false
+func checkSyncAuth(c *gin.Context, db *sql.DB, cfg *config.Config) bool {
+	// 觸發同步的排程服務可持有只有 write:sync 權限的金鑰，不必知道 SYNC_SECRET
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		apiKey, ok := authenticateAPIKey(c, db, ParseList(cfg.APIKeys), key)
+		if !ok {
+			return false
+		}
+		if !apiKey.HasScope(database.ScopeWriteSync) {
+			logf(c, "[WARN] API 金鑰 %s 沒有 %s 權限 (%s %s)", apiKey.Name, database.ScopeWriteSync, c.Request.Method, c.Request.URL.Path)
+			RespondError(c, http.StatusForbidden, "API key lacks scope "+database.ScopeWriteSync)
+			return false
+		}
+		return true
+	}
+
+	// 伺服器間整合可改用簽章，不必在請求中傳送密鑰
+	if HasSignature(c) {
+		if !ValidSignature(c, cfg.SyncSecret) {
+			RespondError(c, http.StatusUnauthorized, "Invalid signature")
+			return false
+		}
+		return true
+	}
+	secret := c.GetHeader("X-Sync-Secret")
+	if secret == "" {
+		secret = c.Query("secret")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(cfg.SyncSecret)) != 1 {
+		RespondError(c, http.StatusUnauthorized, "Invalid secret")
+		return false
+	}
+	return true
+}
+
+// syncJobLocation 同步工作查詢端點的路徑（與 triggerSync 在同一層，保留 BASE_PATH 與版本前綴）
+func syncJobLocation(c *gin.Context, jobID int) string {
+	return path.Join(path.Dir(c.Request.URL.Path), "syncJobs", strconv.Itoa(jobID))
+}
+
+// handleTriggerSync 觸發每日或完整同步；SYNC_MODE=queue 時排入佇列交給 worker，否則在背景執行。
+// 兩種模式都記錄在 sync_jobs，回傳的 jobId 可以 GET /syncJobs/:id 查詢結果（Location 標頭）。
+// 指定 ?callbackUrl= 時，同步各階段的開始、結束與統計會 POST 到該網址（以 SYNC_SECRET 簽章）
+func handleTriggerSync(syncDB *sql.DB, cfg *config.Config) gin.HandlerFunc {
+	// 同一時間只允許一個手動同步，避免重複觸發造成資料庫負載堆積
+	var manualSyncRunning atomic.Bool
+
+	return func(c *gin.Context) {
+		if !checkSyncAuth(c, syncDB, cfg) {
+			return
+		}
+
+		syncType := c.Query("type")
+		if syncType == "" {
+			syncType = "daily" // 預設每日同步
+		}
+
+		if syncType != "daily" && syncType != "monthly" {
+			RespondError(c, http.StatusBadRequest, "type must be daily or monthly")
+			return
+		}
+
+		callbackURL := c.Query("callbackUrl")
+		if callbackURL != "" {
+			if u, err := url.Parse(callbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				RespondError(c, http.StatusBadRequest, "callbackUrl must be an absolute http(s) URL")
+				return
+			}
+		}
+
+		// 佇列模式：交給 worker 程序執行（web / worker 分開部署）
+		if cfg.SyncMode == "queue" {
+			// 先處理中斷的工作（例如換了主機名稱的 worker 留下的），避免永遠回應 429
+			scheduler.ReclaimStaleSyncJobs(syncDB)
+			jobID, ok, err := database.EnqueueSyncJob(syncDB, syncType, callbackURL)
+			if err != nil {
+				RespondError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !ok {
+				c.Header("Retry-After", "60")
+				RespondError(c, http.StatusTooManyRequests, "A sync is already queued or running")
+				return
+			}
+			c.Header("Location", syncJobLocation(c, jobID))
+			c.JSON(http.StatusAccepted, gin.H{
+				"status":  "queued",
+				"type":    syncType,
+				"jobId":   jobID,
+				"message": "同步任務已排入佇列，將由 worker 執行",
+			})
+			return
+		}
+
+		if !manualSyncRunning.CompareAndSwap(false, true) {
+			c.Header("Retry-After", "60")
+			RespondError(c, http.StatusTooManyRequests, "A sync is already running")
+			return
+		}
+
+		// 已取得本程序的執行權，本程序名下仍是執行中的工作必定是重新啟動前未完成的；
+		// 其他程序（包含換了主機名稱的舊副本）的工作依心跳判斷是否已中斷
+		if n, err := database.AbandonRunningSyncJobs(syncDB, cfg.InstanceID, "伺服器重新啟動，同步未完成"); err != nil {
+			logf(c, "[WARN] 無法更新未完成的同步工作: %v", err)
+		} else if n > 0 {
+			logf(c, "[WARN] %d 個未完成的同步工作已標記為失敗", n)
+		}
+		scheduler.ReclaimStaleSyncJobs(syncDB)
+		jobID, err := database.StartSyncJob(syncDB, syncType, callbackURL, cfg.InstanceID)
+		if err != nil {
+			manualSyncRunning.Store(false)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		go func() {
+			defer manualSyncRunning.Store(false)
+			log.Printf("[INFO] 觸發手動 %s 同步", syncType)
+
+			// 透過排程器執行，才會寫入 sync_logs 並保存執行日誌
+			s := scheduler.NewScheduler(syncDB, 0)
+			s.Priority = google.PriorityManual
+			if callbackURL != "" {
+				s.Progress = scheduler.NewSyncCallback(callbackURL, cfg.SyncSecret, syncType)
+			}
+			stop := scheduler.KeepSyncJobAlive(syncDB, jobID)
+			syncErr := s.RunSync(syncType == "monthly")
+			stop()
+			if syncErr != nil {
+				log.Printf("[ERROR] %s 同步失敗: %v", syncType, syncErr)
+			} else {
+				log.Printf("[INFO] %s 同步完成", syncType)
+			}
+			if err := database.FinishSyncJob(syncDB, jobID, syncErr); err != nil {
+				log.Printf("[WARN] 無法記錄同步工作 #%d 的結果: %v", jobID, err)
+			}
+		}()
+
+		c.Header("Location", syncJobLocation(c, jobID))
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":  "triggered",
+			"type":    syncType,
+			"jobId":   jobID,
+			"message": "同步任務已觸發，正在背景執行",
+		})
+	}
+}
+
+// handleSyncJob 查詢 triggerSync 回傳的同步工作：queued、running、success 或 failed（失敗時附錯誤訊息）
+func handleSyncJob(syncDB *sql.DB, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkSyncAuth(c, syncDB, cfg) {
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid sync job id")
+			return
+		}
+
+		job, err := database.GetSyncJob(syncDB, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "sync job not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// 狀態會隨同步進行改變，不可快取
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, job)
+	}
+}
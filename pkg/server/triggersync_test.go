@@ -0,0 +1,138 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// insertRunningJob 建立其他程序留下的執行中工作（origin 可為 NULL），heartbeatAgo 為最後一次心跳距今的時間
+func insertRunningJob(t *testing.T, db *sql.DB, origin sql.NullString, owner, heartbeatAgo string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO sync_jobs (type, status, requested_at, started_at, heartbeat_at, origin, owner)
+		VALUES ('daily', $1, CURRENT_TIMESTAMP - $2::interval, CURRENT_TIMESTAMP - $2::interval, CURRENT_TIMESTAMP - $2::interval, $3, NULLIF($4, ''))
+		RETURNING id
+	`, database.JobStatusRunning, heartbeatAgo, origin, owner).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert sync job: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM sync_jobs WHERE id = $1`, id) })
+	return id
+}
+
+func jobStatus(t *testing.T, db *sql.DB, id int) *database.SyncJob {
+	t.Helper()
+	job, err := database.GetSyncJob(db, id)
+	if err != nil {
+		t.Fatalf("GetSyncJob(%d): %v", id, err)
+	}
+	return job
+}
+
+// TestResetRunningSyncJobsOtherOwner 取得主控權的 worker 重新排隊所有執行中的佇列工作，不論 owner 是否為自己
+func TestResetRunningSyncJobsOtherOwner(t *testing.T) {
+	db := openTestDB(t)
+	queued := insertRunningJob(t, db, sql.NullString{String: database.JobOriginQueue, Valid: true}, "worker-old-pod", "1 minute")
+	legacy := insertRunningJob(t, db, sql.NullString{}, "", "1 minute")
+	inline := insertRunningJob(t, db, sql.NullString{String: database.JobOriginInline, Valid: true}, "api-old-pod", "1 minute")
+
+	if _, err := database.ResetRunningSyncJobs(db); err != nil {
+		t.Fatalf("ResetRunningSyncJobs: %v", err)
+	}
+	for _, id := range []int{queued, legacy} {
+		if job := jobStatus(t, db, id); job.Status != database.JobStatusQueued || job.Owner != "" {
+			t.Errorf("job #%d = %s (owner %q), want queued without an owner", id, job.Status, job.Owner)
+		}
+	}
+	// API 程序內執行的工作不會交給 worker
+	if job := jobStatus(t, db, inline); job.Status != database.JobStatusRunning {
+		t.Errorf("inline job = %s, want running", job.Status)
+	}
+}
+
+// TestTriggerSyncReclaimsStaleJob 換了主機名稱的程序留下、沒有心跳的工作不會讓之後的觸發永遠回應 429
+func TestTriggerSyncReclaimsStaleJob(t *testing.T) {
+	db := openTestDB(t)
+	stale := insertRunningJob(t, db, sql.NullString{String: database.JobOriginInline, Valid: true}, "api-old-pod", "10 minutes")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	cfg := &config.Config{SyncSecret: "secret", SyncMode: "queue", InstanceID: "api-new-pod"}
+	RegisterTriggerSyncRoutes(r, db, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/triggerSync", nil)
+	req.Header.Set("X-Sync-Secret", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /triggerSync = %d, want 202: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		JobID int `json:"jobId"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM sync_jobs WHERE id = $1`, body.JobID) })
+
+	if job := jobStatus(t, db, stale); job.Status != database.JobStatusFailed || job.Error == "" {
+		t.Errorf("stale job = %s (%q), want failed with a message", job.Status, job.Error)
+	}
+}
+
+// TestReclaimStaleSyncJobsKeepsLiveJobs 仍有心跳的工作（其他程序正在執行）不受影響
+func TestReclaimStaleSyncJobsKeepsLiveJobs(t *testing.T) {
+	db := openTestDB(t)
+	live := insertRunningJob(t, db, sql.NullString{String: database.JobOriginQueue, Valid: true}, "worker-other", "30 seconds")
+	stale := insertRunningJob(t, db, sql.NullString{String: database.JobOriginQueue, Valid: true}, "worker-gone", "10 minutes")
+
+	if _, err := database.ReclaimStaleSyncJobs(db, 5*time.Minute, "stale"); err != nil {
+		t.Fatalf("ReclaimStaleSyncJobs: %v", err)
+	}
+	if job := jobStatus(t, db, live); job.Status != database.JobStatusRunning {
+		t.Errorf("live job = %s, want running", job.Status)
+	}
+	if job := jobStatus(t, db, stale); job.Status != database.JobStatusQueued {
+		t.Errorf("stale queue job = %s, want queued", job.Status)
+	}
+}
+
+func TestCheckSyncAuthSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{SyncSecret: "secret"}
+	tests := []struct {
+		name, header, query string
+		want                bool
+	}{
+		{"header", "secret", "", true},
+		{"query", "", "secret", true},
+		{"wrong secret", "secreT", "", false},
+		{"prefix", "secre", "", false},
+		{"missing", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/triggerSync?secret="+tt.query, nil)
+			if tt.header != "" {
+				c.Request.Header.Set("X-Sync-Secret", tt.header)
+			}
+			if got := checkSyncAuth(c, nil, cfg); got != tt.want {
+				t.Errorf("checkSyncAuth = %v, want %v", got, tt.want)
+			}
+			if !tt.want && w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+const webhookDeliveryLimit = 50
+
+// CreateWebhookRequest 建立 webhook 請求（products / regions 留空表示全部）
+type CreateWebhookRequest struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret"` // 留空時自動產生
+	Products []string `json:"products"`
+	Regions  []string `json:"regions"`
+}
+
+// handleCreateWebhook 建立 webhook，回應中包含簽章密鑰（只會顯示這一次）
+func handleCreateWebhook(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Name == "" {
+			RespondError(c, http.StatusBadRequest, "name is required")
+			return
+		}
+		if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			RespondError(c, http.StatusBadRequest, "url must be an absolute http(s) URL")
+			return
+		}
+		if req.Secret == "" {
+			req.Secret = randomSecret()
+		}
+
+		hook, err := database.CreateWebhook(db, database.Webhook{
+			Name:     req.Name,
+			URL:      req.URL,
+			Secret:   req.Secret,
+			Products: req.Products,
+			Regions:  req.Regions,
+		})
+		if err != nil {
+			logf(c, "[ERROR] 建立 webhook 失敗: %v", err)
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已建立 webhook #%d (%s) → %s", hook.ID, hook.Name, hook.URL)
+		c.JSON(http.StatusCreated, gin.H{
+			"webhook": hook,
+			"secret":  hook.Secret,
+		})
+	}
+}
+
+// handleListWebhooks 列出所有 webhook（不含密鑰）
+func handleListWebhooks(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := database.ListWebhooks(db, false)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, hooks)
+	}
+}
+
+// handleDeleteWebhook 刪除 webhook
+func handleDeleteWebhook(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid webhook id")
+			return
+		}
+
+		err = database.DeleteWebhook(db, id)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Printf("[INFO] 已刪除 webhook #%d", id)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// handleWebhookDeliveries 回傳 webhook 最近的投遞紀錄
+func handleWebhookDeliveries(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid webhook id")
+			return
+		}
+
+		deliveries, err := database.GetWebhookDeliveries(db, id, webhookDeliveryLimit)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// randomSecret 產生 webhook 簽章密鑰
+func randomSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/config"
+	"PXMarkMapBackEnd/pkg/metrics"
+	"PXMarkMapBackEnd/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// wsKeepAliveInterval 送出 ping 的間隔，避免反向代理關閉閒置連線
+	wsKeepAliveInterval = 30 * time.Second
+	// wsWriteTimeout 單次寫入逾時，過慢的用戶端會被斷線
+	wsWriteTimeout = 10 * time.Second
+	// wsSendBuffer 每個用戶端待送出的事件數，滿了表示用戶端跟不上，直接斷線
+	wsSendBuffer = 4
+)
+
+// 推送給地圖前端的事件類型
+const (
+	WSEventHello         = "hello"         // 連線時送出目前的同步時間，前端可與已載入的資料比對
+	WSEventDataRefreshed = "dataRefreshed" // 同步完成，前端應重新載入地圖資料
+)
+
+// WSEvent /ws 推送的事件
+type WSEvent struct {
+	Type       string     `json:"type"`
+	LastSyncAt *time.Time `json:"lastSyncAt,omitempty"`
+}
+
+// RegisterWebSocketRoutes 註冊 /ws：同步完成時推送 dataRefreshed 事件，前端不必輪詢
+func RegisterWebSocketRoutes(r gin.IRouter, db *sql.DB, cfg *config.Config) {
+	hub := newRefreshHub(db, time.Duration(cfg.WSPollSeconds)*time.Second)
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, req *http.Request) error {
+			if !wsOriginAllowed(cfg.CORSOrigins, req.Header.Get("Origin")) {
+				return fmt.Errorf("origin not allowed")
+			}
+			return nil
+		},
+		Handler: hub.serve,
+	}
+	r.GET("/ws", func(c *gin.Context) {
+		server.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// wsOriginAllowed 依 CORS_ORIGINS 檢查瀏覽器的 Origin；非瀏覽器用戶端沒有 Origin，一律允許
+func wsOriginAllowed(corsOrigins, origin string) bool {
+	if origin == "" || corsOrigins == "*" {
+		return true
+	}
+	for _, o := range strings.Split(corsOrigins, ",") {
+		if strings.TrimSpace(o) == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshHub 管理 /ws 的連線；有連線時定期檢查最後成功同步的時間，改變時廣播 dataRefreshed。
+// 以資料庫為準，worker 程序或 cmd/sync 執行的同步也會通知到
+type refreshHub struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu       sync.Mutex
+	clients  map[chan WSEvent]struct{}
+	lastSync time.Time
+	polling  bool
+}
+
+func newRefreshHub(db *sql.DB, interval time.Duration) *refreshHub {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &refreshHub{db: db, interval: interval, clients: make(map[chan WSEvent]struct{})}
+}
+
+// serve 處理一個 WebSocket 連線：送出 hello 後持續推送事件，直到用戶端斷線
+func (h *refreshHub) serve(ws *websocket.Conn) {
+	defer ws.Close()
+
+	lastSync, err := scheduler.NewScheduler(h.db, 0).GetLastSyncTime()
+	if err != nil {
+		log.Printf("[WARN] /ws 無法取得最後同步時間: %v", err)
+	}
+	events := h.subscribe(lastSync)
+	defer h.unsubscribe(events)
+
+	if err := wsSend(ws, WSEvent{Type: WSEventHello, LastSyncAt: timePtr(lastSync)}); err != nil {
+		return
+	}
+
+	// 用戶端不需要送資料；讀取只用來偵測斷線（並讓套件回應 ping / close）
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(wsKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := wsSend(ws, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			_, err := ws.Write(nil)
+			ws.PayloadType = websocket.TextFrame
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func wsSend(ws *websocket.Conn, event WSEvent) error {
+	ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return websocket.JSON.Send(ws, event)
+}
+
+// subscribe 加入一個用戶端，第一個用戶端連線時開始檢查同步時間
+func (h *refreshHub) subscribe(lastSync time.Time) chan WSEvent {
+	events := make(chan WSEvent, wsSendBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[events] = struct{}{}
+	if lastSync.After(h.lastSync) {
+		h.lastSync = lastSync
+	}
+	if !h.polling {
+		h.polling = true
+		go h.poll()
+	}
+	metrics.SetGauge("pxmark_ws_clients", "Connected /ws clients", nil, float64(len(h.clients)))
+	return events
+}
+
+func (h *refreshHub) unsubscribe(events chan WSEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[events]; ok {
+		delete(h.clients, events)
+		close(events)
+	}
+	metrics.SetGauge("pxmark_ws_clients", "Connected /ws clients", nil, float64(len(h.clients)))
+}
+
+// poll 定期檢查最後成功同步的時間，沒有用戶端時停止
+func (h *refreshHub) poll() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.mu.Lock()
+		if len(h.clients) == 0 {
+			h.polling = false
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Unlock()
+
+		lastSync, err := scheduler.NewScheduler(h.db, 0).GetLastSyncTime()
+		if err != nil {
+			log.Printf("[WARN] /ws 無法取得最後同步時間: %v", err)
+			continue
+		}
+		h.broadcastIfNewer(lastSync)
+	}
+}
+
+// broadcastIfNewer 同步時間比上次通知的新時推送 dataRefreshed；跟不上的用戶端直接斷線，重新連線後會收到 hello
+func (h *refreshHub) broadcastIfNewer(lastSync time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !lastSync.After(h.lastSync) {
+		return
+	}
+	h.lastSync = lastSync
+
+	event := WSEvent{Type: WSEventDataRefreshed, LastSyncAt: timePtr(lastSync)}
+	for events := range h.clients {
+		select {
+		case events <- event:
+		default:
+			delete(h.clients, events)
+			close(events)
+		}
+	}
+	log.Printf("[INFO] 已通知 %d 個 /ws 用戶端資料更新（%s）", len(h.clients), lastSync.Format(time.RFC3339))
+	metrics.SetGauge("pxmark_ws_clients", "Connected /ws clients", nil, float64(len(h.clients)))
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
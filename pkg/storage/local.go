@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Local 將檔案保存在本機目錄（key 對應到目錄下的相對路徑），適合開發或掛載了永久磁碟的主機
+type Local struct {
+	Dir string
+}
+
+// NewLocal 建立以 dir 為根目錄的儲存空間（目錄在第一次寫入時建立）
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先寫入暫存檔再改名，避免讀到寫到一半的檔案
+func (l *Local) Put(key string, data []byte, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Get 讀取檔案，Content-Type 依副檔名判斷
+func (l *Local) Get(key string) (*Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj := &Object{Data: data, ContentType: mime.TypeByExtension(path.Ext(key))}
+	if info, err := os.Stat(p); err == nil {
+		obj.ModTime = info.ModTime()
+	}
+	return obj, nil
+}
+
+// Delete 刪除檔案
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) String() string {
+	return "local:" + l.Dir
+}
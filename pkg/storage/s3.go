@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GCSEndpoint Cloud Storage 與 S3 相容的 XML API
+const GCSEndpoint = "https://storage.googleapis.com"
+
+// S3Config S3（或相容服務：Cloud Storage、MinIO、R2）的連線設定
+type S3Config struct {
+	Endpoint  string // 空字串 = AWS（https://<bucket>.s3.<region>.amazonaws.com），否則以 <endpoint>/<bucket>/<key> 存取
+	Region    string
+	Bucket    string
+	Prefix    string // 所有 key 前面加上的路徑（例如 pxmark/），同一個 bucket 給多個環境使用時區分
+	AccessKey string
+	SecretKey string
+}
+
+// S3 以 AWS Signature Version 4 簽章的 REST API 存取 bucket（不依賴 AWS SDK）
+type S3 struct {
+	cfg    S3Config
+	base   string // bucket 的網址（不含結尾的 /）
+	client *http.Client
+}
+
+// NewS3 建立 S3 儲存空間，bucket 與金鑰為必填
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("未設定 BLOB_BUCKET")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("未設定 BLOB_ACCESS_KEY_ID 或 BLOB_SECRET_ACCESS_KEY")
+	}
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+
+	base := "https://" + cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com"
+	if cfg.Endpoint != "" {
+		base = strings.TrimSuffix(cfg.Endpoint, "/") + "/" + uriEncode(cfg.Bucket)
+	}
+	return &S3{
+		cfg:    cfg,
+		base:   base,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Put 以單一 PUT 上傳（封存與快照都在數十 MB 以內，不需要分段上傳）
+func (s *S3) Put(key string, data []byte, contentType string) error {
+	headers := http.Header{}
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(http.MethodPut, key, data, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.errorFromResponse("PUT", key, resp)
+	}
+	return nil
+}
+
+// Get 下載，404 時回傳 ErrNotFound
+func (s *S3) Get(key string) (*Object, error) {
+	resp, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.errorFromResponse("GET", key, resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	obj := &Object{Data: data, ContentType: resp.Header.Get("Content-Type")}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = t
+	}
+	return obj, nil
+}
+
+// Delete 刪除（S3 刪除不存在的 key 也回傳 204）
+func (s *S3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.errorFromResponse("DELETE", key, resp)
+	}
+	return nil
+}
+
+func (s *S3) String() string {
+	return "s3:" + s.base + "/" + s.cfg.Prefix
+}
+
+// do 送出簽章後的請求
+func (s *S3) do(method, key string, body []byte, headers http.Header) (*http.Response, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, s.base+"/"+uriEncode(s.cfg.Prefix+key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign 加上 S3 要求的 X-Amz-Content-Sha256 與 AWS Signature Version 4 的 Authorization 標頭
+// （https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html）
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, payloadHash, s.cfg.AccessKey, s.cfg.SecretKey, s.cfg.Region, "s3", now)
+}
+
+// signV4 以 Signature Version 4 簽署請求：除了 Authorization 與 User-Agent 之外的標頭都納入簽章
+// （https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html）；
+// 路徑與查詢字串必須已經依 S3 的規則編碼（不再正規化），與 S3 範例及 aws-sig-v4-test-suite 的測試向量比對
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	signed := map[string]string{"host": host}
+	for k, v := range req.Header {
+		lower := strings.ToLower(k)
+		if lower == "authorization" || lower == "user-agent" {
+			continue
+		}
+		values := make([]string, len(v))
+		for i, value := range v {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		signed[lower] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + signed[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// errorFromResponse 組成包含狀態碼與回應內容（S3 的 XML 錯誤訊息）的錯誤
+func (s *S3) errorFromResponse(method, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s 失敗: HTTP %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// uriEncode 依 SigV4 的規則編碼路徑（只保留 RFC 3986 的 unreserved 字元與 /）
+func uriEncode(p string) string {
+	var b strings.Builder
+	for _, c := range []byte(p) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,126 @@
+// Package storage 保存較大的檔案（每月出貨封存、同步與工作表快照、開放資料），
+// 不放在資料庫，也不放在容器重新部署後就會消失的檔案系統。
+// 依 BLOB_STORE 選擇本機目錄（local）、Amazon S3 或相容服務（s3）、Google Cloud Storage（gcs），
+// 設定由 config.Config 轉換後以 Configure 傳入，不直接讀取環境變數
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 指定的 key 不存在
+var ErrNotFound = errors.New("blob not found")
+
+// Object 讀取到的檔案內容
+type Object struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore 以 key（以 / 分隔的相對路徑，例如 exports/shipments-2025-06.csv）保存與讀取檔案
+type BlobStore interface {
+	// Put 寫入（已存在時覆蓋），讀取端不會看到寫到一半的內容
+	Put(key string, data []byte, contentType string) error
+	// Get 讀取，不存在時回傳 ErrNotFound
+	Get(key string) (*Object, error)
+	// Delete 刪除，不存在時不視為錯誤
+	Delete(key string) error
+	// String 顯示用的位置（不含密鑰）
+	String() string
+}
+
+// Settings 儲存空間設定（BLOB_*；s3 未設定金鑰與區域時 config 沿用 AWS_*）
+type Settings struct {
+	Kind      string // local / s3 / gcs，空字串 = local
+	Dir       string // local 的根目錄，空字串 = ./data
+	Endpoint  string // S3 相容服務的網址，gcs 預設 GCSEndpoint
+	Region    string // 空字串時 s3 為 us-east-1，gcs 為 auto
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultStore    BlobStore
+	defaultSettings Settings
+)
+
+// Configure 設定 Default 使用的儲存空間（在第一次呼叫 Default 之前呼叫，之後呼叫會重新建立）
+func Configure(s Settings) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultSettings = s
+	defaultStore = nil
+}
+
+// Default 依 Configure 的設定建立的共用儲存空間（第一次成功建立後重複使用）
+func Default() (BlobStore, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultStore != nil {
+		return defaultStore, nil
+	}
+	store, err := New(defaultSettings)
+	if err != nil {
+		return nil, err
+	}
+	defaultStore = store
+	return store, nil
+}
+
+// New 依 s.Kind（local / s3 / gcs，預設 local）建立儲存空間
+func New(s Settings) (BlobStore, error) {
+	kind := strings.ToLower(strings.TrimSpace(s.Kind))
+	switch kind {
+	case "", "local":
+		return NewLocal(orDefault(s.Dir, "./data")), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  s.Endpoint,
+			Region:    orDefault(s.Region, "us-east-1"),
+			Bucket:    s.Bucket,
+			Prefix:    s.Prefix,
+			AccessKey: s.AccessKey,
+			SecretKey: s.SecretKey,
+		})
+	case "gcs":
+		// 使用 Cloud Storage 的 XML API（與 S3 相容），以 HMAC 金鑰簽章
+		return NewS3(S3Config{
+			Endpoint:  orDefault(s.Endpoint, GCSEndpoint),
+			Region:    orDefault(s.Region, "auto"),
+			Bucket:    s.Bucket,
+			Prefix:    s.Prefix,
+			AccessKey: s.AccessKey,
+			SecretKey: s.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("未知的 BLOB_STORE: %s（可用 local、s3、gcs）", kind)
+	}
+}
+
+// validKey 檢查 key 是否為不會跳出根目錄的相對路徑
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return fmt.Errorf("無效的 blob key: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("無效的 blob key: %q", key)
+		}
+	}
+	return nil
+}
+
+func orDefault(val, def string) string {
+	if val != "" {
+		return val
+	}
+	return def
+}
@@ -0,0 +1,30 @@
+package sync
+
+// 同步的階段（scheduler 在同步後另有維護階段）
+const (
+	StageSheets = "sheets" // 讀取 Google Sheets
+	StagePlaces = "places" // 地點資訊
+	StageSave   = "save"   // 寫入資料庫
+)
+
+// Progress 接收同步各階段的開始與結束（手動觸發時指定 callbackUrl 才有），
+// stats 為該階段的統計，需可序列化為 JSON
+type Progress interface {
+	StageStarted(stage string)
+	StageFinished(stage string, stats interface{}, err error)
+	SyncFinished(status string, summary *Summary, err error)
+}
+
+// StartStage 回報階段開始，progress 為 nil 時不做任何事
+func StartStage(progress Progress, stage string) {
+	if progress != nil {
+		progress.StageStarted(stage)
+	}
+}
+
+// FinishStage 回報階段結束，progress 為 nil 時不做任何事
+func FinishStage(progress Progress, stage string, stats interface{}, err error) {
+	if progress != nil {
+		progress.StageFinished(stage, stats, err)
+	}
+}
@@ -2,76 +2,310 @@ package sync
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"PXMarkMapBackEnd/pkg/cdn"
 	"PXMarkMapBackEnd/pkg/database"
 	"PXMarkMapBackEnd/pkg/google"
+	"PXMarkMapBackEnd/pkg/storage"
+	"PXMarkMapBackEnd/pkg/webhook"
 )
 
-// SyncData 完整同步（包含 Places API）- 每月執行
-func SyncData(db *sql.DB) error {
+// Summary 同步執行結果摘要
+type Summary struct {
+	Type      string                `json:"type"` // 'full', 'daily'
+	Stores    int                   `json:"stores"`
+	Shipments *database.UpsertStats `json:"shipments,omitempty"`
+	Sheets    *google.LoadReport    `json:"sheets"`
+	Warnings  []string              `json:"warnings"`
+	// StaleSources 下載失敗、改用上次快照同步的資料來源（不為空時同步記錄為 stale_source）
+	StaleSources []string `json:"staleSources,omitempty"`
+	// SheetLastModified 所有資料來源中最晚的試算表修改時間（Drive API，取得不到時沒有）
+	SheetLastModified *time.Time `json:"sheetLastModified,omitempty"`
+}
+
+// String 產生寫入 sync_logs 的摘要文字
+func (s *Summary) String() string {
+	typeText := "每日"
+	if s.Type == "full" {
+		typeText = "完整"
+	}
+
+	msg := fmt.Sprintf("%s同步成功：%d 個店家", typeText, s.Stores)
+	if len(s.StaleSources) > 0 {
+		msg = fmt.Sprintf("%s同步以快照完成（%s 下載失敗）：%d 個店家", typeText, strings.Join(s.StaleSources, "、"), s.Stores)
+	}
+	if s.Sheets != nil {
+		msg += "；工作表共 " + google.FormatBytes(s.Sheets.TotalBytes())
+	}
+	if s.SheetLastModified != nil {
+		msg += "；試算表最後修改於 " + s.SheetLastModified.Local().Format("2006-01-02 15:04")
+	}
+	if s.Shipments != nil {
+		msg += "；出貨" + s.Shipments.String()
+	}
+	if len(s.Warnings) > 0 {
+		msg += fmt.Sprintf("；%d 個警告：%s", len(s.Warnings), strings.Join(s.Warnings, "；"))
+	}
+	return msg
+}
+
+// SyncData 完整同步（包含 Places API）- 每月執行，priority 決定 Places API 查詢的排隊順序，
+// progress 不為 nil 時回報各階段進度
+func SyncData(db *sql.DB, priority google.Priority, progress Progress) (*Summary, error) {
 	log.Println("=== 開始完整同步（含地點資訊） ===")
+	summary := &Summary{Type: "full"}
+
+	sources, err := google.LoadDataSources()
+	if err != nil {
+		return nil, err
+	}
 
 	// 步驟 1: 從 Google Sheets 讀取資料
 	log.Println("[INFO] 讀取 Google Sheets 資料...")
-	storeMap, err := google.LoadAndOrganizeSheets()
+	StartStage(progress, StageSheets)
+	if err := loadProducts(db); err != nil {
+		FinishStage(progress, StageSheets, nil, err)
+		return nil, err
+	}
+	loadProductAliases(db)
+	useSheetSnapshotStore(db)
+	storeMap, report, err := google.LoadAndOrganizeSources(sources)
 	if err != nil {
-		return err
+		FinishStage(progress, StageSheets, nil, err)
+		return nil, err
 	}
 	log.Printf("[INFO] 成功讀取 %d 個店家\n", len(storeMap))
+	summary.Stores = len(storeMap)
+	summary.Sheets = report
+	summary.StaleSources = report.StaleSources()
+	summary.SheetLastModified = report.LastModified()
+	summary.Warnings = append(summary.Warnings, report.Warnings()...)
+	FinishStage(progress, StageSheets, map[string]interface{}{
+		"stores":            summary.Stores,
+		"sheets":            len(report.Sheets),
+		"bytes":             report.TotalBytes(),
+		"staleSources":      summary.StaleSources,
+		"sheetLastModified": summary.SheetLastModified,
+		"warnings":          len(summary.Warnings),
+	}, nil)
 
 	// 步驟 2: 使用 Places API 搜尋地點資訊
 	log.Println("[INFO] 搜尋店家地點資訊...")
-	if err := google.EnrichStoresWithPlaceData(storeMap); err != nil {
+	StartStage(progress, StagePlaces)
+	err = enrichPlaceDataExceptManual(db, storeMap, priority)
+	if err != nil {
 		log.Printf("[WARN] 搜尋地點資訊時發生錯誤: %v", err)
 	}
+	FinishStage(progress, StagePlaces, nil, err)
 
 	// 步驟 3: 轉換資料格式
 	stores := convertToStoreInfo(storeMap)
 
 	// 步驟 4: 儲存到資料庫
 	log.Println("[INFO] 儲存資料到資料庫...")
-	if err := database.SaveStores(db, stores); err != nil {
-		return err
+	StartStage(progress, StageSave)
+	result, err := database.SaveStores(db, stores)
+	if err != nil {
+		recordSourceStatus(db, sources, report, err)
+		FinishStage(progress, StageSave, nil, err)
+		return nil, err
 	}
+	recordSourceStatus(db, sources, report, nil)
+	summary.Shipments = &result.Shipments
+	FinishStage(progress, StageSave, map[string]interface{}{
+		"stores":       len(stores),
+		"shipments":    result.Shipments,
+		"newShipments": len(result.NewShipments),
+	}, nil)
+	purgeCDN(stores, summary.Type)
+	notifyWebhooks(db, result)
 
 	log.Println("[INFO] 完整同步完成")
-	return nil
+	return summary, nil
 }
 
 // SyncDataDaily 每日同步（只更新出貨資料，缺少地點的才查詢）
-func SyncDataDaily(db *sql.DB) error {
+func SyncDataDaily(db *sql.DB, priority google.Priority, progress Progress) (*Summary, error) {
+	sources, err := google.LoadDataSources()
+	if err != nil {
+		return nil, err
+	}
+	return SyncSourcesDaily(db, sources, priority, progress)
+}
+
+// SyncSourcesDaily 只同步指定資料來源的每日同步，不影響其他來源的資料
+func SyncSourcesDaily(db *sql.DB, sources []google.DataSource, priority google.Priority, progress Progress) (*Summary, error) {
 	log.Println("=== 開始每日同步（優先使用現有地點資訊） ===")
+	summary := &Summary{Type: "daily"}
 
 	// 步驟 1: 從 Google Sheets 讀取資料
 	log.Println("[INFO] 讀取 Google Sheets 資料...")
-	storeMap, err := google.LoadAndOrganizeSheets()
+	StartStage(progress, StageSheets)
+	if err := loadProducts(db); err != nil {
+		FinishStage(progress, StageSheets, nil, err)
+		return nil, err
+	}
+	loadProductAliases(db)
+	useSheetSnapshotStore(db)
+	storeMap, report, err := google.LoadAndOrganizeSources(sources)
 	if err != nil {
-		return err
+		FinishStage(progress, StageSheets, nil, err)
+		return nil, err
 	}
 	log.Printf("[INFO] 成功讀取 %d 個店家\n", len(storeMap))
+	summary.Stores = len(storeMap)
+	summary.Sheets = report
+	summary.StaleSources = report.StaleSources()
+	summary.SheetLastModified = report.LastModified()
+	summary.Warnings = append(summary.Warnings, report.Warnings()...)
+	FinishStage(progress, StageSheets, map[string]interface{}{
+		"stores":            summary.Stores,
+		"sheets":            len(report.Sheets),
+		"bytes":             report.TotalBytes(),
+		"staleSources":      summary.StaleSources,
+		"sheetLastModified": summary.SheetLastModified,
+		"warnings":          len(summary.Warnings),
+	}, nil)
 
 	// 步驟 2: 檢查並補充缺少的地點資訊
 	log.Println("[INFO] 檢查店家地點資訊...")
-	if err := enrichMissingPlaceData(db, storeMap); err != nil {
+	StartStage(progress, StagePlaces)
+	err = enrichMissingPlaceData(db, storeMap, priority)
+	if err != nil {
 		log.Printf("[WARN] 補充地點資訊時發生錯誤: %v", err)
 	}
+	FinishStage(progress, StagePlaces, nil, err)
 
 	// 步驟 3: 轉換資料格式
 	stores := convertToStoreInfo(storeMap)
 
 	// 步驟 4: 儲存到資料庫（會自動更新或插入）
 	log.Println("[INFO] 儲存資料到資料庫...")
-	if err := database.SaveStores(db, stores); err != nil {
-		return err
+	StartStage(progress, StageSave)
+	result, err := database.SaveStores(db, stores)
+	if err != nil {
+		recordSourceStatus(db, sources, report, err)
+		FinishStage(progress, StageSave, nil, err)
+		return nil, err
 	}
+	recordSourceStatus(db, sources, report, nil)
+	summary.Shipments = &result.Shipments
+	FinishStage(progress, StageSave, map[string]interface{}{
+		"stores":       len(stores),
+		"shipments":    result.Shipments,
+		"newShipments": len(result.NewShipments),
+	}, nil)
+	purgeCDN(stores, summary.Type)
+	notifyWebhooks(db, result)
 
 	log.Println("[INFO] 每日同步完成")
+	return summary, nil
+}
+
+// loadProducts 載入要同步的產品（products 資料表），讀取失敗時不同步（避免所有工作表都被略過）
+func loadProducts(db *sql.DB) error {
+	products, err := database.ListProducts(db)
+	if err != nil {
+		return fmt.Errorf("無法載入產品: %v", err)
+	}
+	rules := make([]google.ProductRule, len(products))
+	for i, p := range products {
+		rules[i] = google.ProductRule{ProductType: p.ProductType, SheetPatterns: p.SheetPatterns}
+	}
+	google.SetProducts(rules)
 	return nil
 }
 
+// loadProductAliases 載入產品改名的別名，讓工作表使用新名稱或舊名稱都能辨識
+func loadProductAliases(db *sql.DB) {
+	aliases, err := database.GetProductAliases(db)
+	if err != nil {
+		log.Printf("[WARN] 無法載入產品別名: %v", err)
+		return
+	}
+	google.SetProductAliases(aliases)
+}
+
+// sheetSnapshotStore 工作表快照的內容保存在 BLOB_STORE，sheet_snapshots 資料表記錄 key 與下載時間
+type sheetSnapshotStore struct {
+	db    *sql.DB
+	blobs storage.BlobStore
+}
+
+// useSheetSnapshotStore 設定工作表快照的保存位置，BLOB_STORE 設定錯誤時不保存快照（不影響同步）
+func useSheetSnapshotStore(db *sql.DB) {
+	blobs, err := storage.Default()
+	if err != nil {
+		log.Printf("[WARN] 無法使用 BLOB_STORE，本次同步不保存工作表快照: %v", err)
+		google.SetSheetSnapshotStore(nil)
+		return
+	}
+	google.SetSheetSnapshotStore(sheetSnapshotStore{db, blobs})
+}
+
+func (s sheetSnapshotStore) SaveSheetSnapshot(sourceID, gid, sheetName string, records [][]string) error {
+	return database.SaveSheetSnapshot(s.db, s.blobs, sourceID, gid, sheetName, records)
+}
+
+func (s sheetSnapshotStore) LoadSheetSnapshot(sourceID, gid string) ([][]string, time.Time, error) {
+	return database.GetSheetSnapshot(s.db, s.blobs, sourceID, gid)
+}
+
+// purgeCDN 同步完成後通知 CDN 清除地圖資料與有更新的產品
+func purgeCDN(stores []database.StoreInfo, syncType string) {
+	keys := []string{cdn.MapKey}
+	for _, store := range stores {
+		for product, shipments := range store.Shipments {
+			if len(shipments) > 0 {
+				keys = append(keys, cdn.ProductKey(google.ProductDisplayName(product)))
+			}
+		}
+	}
+
+	if err := cdn.Purge(keys, syncType+" sync"); err != nil {
+		log.Printf("[WARN] CDN 快取清除失敗: %v", err)
+	}
+}
+
+// notifyWebhooks 將新出現的出貨通知訂閱的 webhook
+func notifyWebhooks(db *sql.DB, result *database.SaveResult) {
+	if len(result.NewShipments) == 0 {
+		return
+	}
+	log.Printf("[INFO] 本次同步新增 %d 筆出貨，通知 webhook...", len(result.NewShipments))
+	webhook.Dispatch(db, result.NewShipments)
+}
+
+// recordSourceStatus 記錄每個資料來源這次同步的結果（儲存失敗時全部標記為失敗）
+func recordSourceStatus(db *sql.DB, sources []google.DataSource, report *google.LoadReport, saveErr error) {
+	for _, source := range sources {
+		status := report.SourceStatus(source.ID)
+		message := ""
+		if saveErr != nil {
+			status = google.SourceStatusFailed
+			message = saveErr.Error()
+		} else if status == google.SourceStatusStale {
+			message = "工作表下載失敗，改用上次的快照同步"
+		} else if status != google.SourceStatusSuccess {
+			message = "部分或全部工作表讀取失敗"
+		}
+
+		var sheetModified *time.Time
+		if t, ok := report.SheetModified[source.ID]; ok {
+			sheetModified = &t
+		}
+		if err := database.RecordSourceSync(db, source.ID, source.Name, status, message, sheetModified); err != nil {
+			log.Printf("[WARN] 無法記錄資料來源 %s 的同步狀態: %v", source.ID, err)
+		}
+	}
+}
+
 // enrichMissingPlaceData 只為缺少地點資訊的店家查詢 Places API
-func enrichMissingPlaceData(db *sql.DB, storeMap map[string]*google.StoreData) error {
+func enrichMissingPlaceData(db *sql.DB, storeMap map[string]*google.StoreData, priority google.Priority) error {
 	// 從資料庫查詢已有地點資訊的店家
 	existingStores, err := database.GetExistingStoresWithLocation(db)
 	if err != nil {
@@ -82,6 +316,7 @@ func enrichMissingPlaceData(db *sql.DB, storeMap map[string]*google.StoreData) e
 
 	// 標記需要查詢的店家
 	needPlaceAPI := make(map[string]*google.StoreData)
+	needStatus := make(map[string]*google.StoreData)
 
 	for storeName, storeData := range storeMap {
 		if existingStore, exists := existingStores[storeName]; exists {
@@ -90,7 +325,11 @@ func enrichMissingPlaceData(db *sql.DB, storeMap map[string]*google.StoreData) e
 			storeData.FormattedAddress = existingStore.FormattedAddress
 			storeData.Latitude = existingStore.Latitude
 			storeData.Longitude = existingStore.Longitude
+			storeData.BusinessStatus = existingStore.BusinessStatus
 			log.Printf("[INFO] 使用現有地點: %s", storeName)
+			if storeData.BusinessStatus == "" && storeData.PlaceID != "" {
+				needStatus[storeName] = storeData
+			}
 		} else {
 			// 標記為需要查詢
 			needPlaceAPI[storeName] = storeData
@@ -100,37 +339,65 @@ func enrichMissingPlaceData(db *sql.DB, storeMap map[string]*google.StoreData) e
 	// 只為缺少地點的店家查詢 Places API
 	if len(needPlaceAPI) > 0 {
 		log.Printf("[INFO] 需要查詢 %d 個新店家的地點資訊", len(needPlaceAPI))
-		if err := google.EnrichStoresWithPlaceData(needPlaceAPI); err != nil {
+		if err := google.EnrichStoresWithPlaceData(needPlaceAPI, priority); err != nil {
 			return err
 		}
 	} else {
 		log.Println("[INFO] 所有店家都已有地點資訊，跳過 Places API 查詢")
 	}
 
+	// 補上沒有營業狀態的店家（每家只需要查詢一次，之後沿用資料庫的狀態）
+	if len(needStatus) > 0 {
+		log.Printf("[INFO] 需要補上 %d 個店家的營業狀態", len(needStatus))
+		google.RefreshBusinessStatus(needStatus, priority)
+	}
+
 	return nil
 }
 
+// enrichPlaceDataExceptManual 完整同步時重新查詢所有店家的地點，座標為人工修正（manual_import）的店家除外，
+// 這些店家沿用資料庫中的地點資訊
+func enrichPlaceDataExceptManual(db *sql.DB, storeMap map[string]*google.StoreData, priority google.Priority) error {
+	manual, err := database.GetManualImportStores(db)
+	if err != nil {
+		return err
+	}
+
+	lookup := make(map[string]*google.StoreData, len(storeMap))
+	for storeName, storeData := range storeMap {
+		existing, ok := manual[storeName]
+		if !ok {
+			lookup[storeName] = storeData
+			continue
+		}
+		storeData.PlaceID = existing.PlaceID
+		storeData.FormattedAddress = existing.FormattedAddress
+		storeData.Latitude = existing.Latitude
+		storeData.Longitude = existing.Longitude
+		storeData.BusinessStatus = existing.BusinessStatus
+	}
+	if skipped := len(storeMap) - len(lookup); skipped > 0 {
+		log.Printf("[INFO] %d 個店家的座標為人工修正，不重新查詢地點", skipped)
+	}
+	return google.EnrichStoresWithPlaceData(lookup, priority)
+}
+
 // convertToStoreInfo 將 google.StoreData 轉換為 database.StoreInfo
 func convertToStoreInfo(storeMap map[string]*google.StoreData) []database.StoreInfo {
 	var stores []database.StoreInfo
 
 	for _, data := range storeMap {
-		// 轉換秋葵出貨紀錄
-		var okraShipments []database.ShipmentInfo
-		for _, s := range data.OkraShipments {
-			okraShipments = append(okraShipments, database.ShipmentInfo{
-				Date: s.Date,
-				Qty:  s.Qty,
-			})
-		}
-
-		// 轉換絲瓜出貨紀錄
-		var gourdShipments []database.ShipmentInfo
-		for _, s := range data.SpongeGourdShipments {
-			gourdShipments = append(gourdShipments, database.ShipmentInfo{
-				Date: s.Date,
-				Qty:  s.Qty,
-			})
+		// 轉換各產品的出貨紀錄
+		shipments := make(map[string][]database.ShipmentInfo, len(data.Shipments))
+		for product, list := range data.Shipments {
+			for _, s := range list {
+				shipments[product] = append(shipments[product], database.ShipmentInfo{
+					Date:     s.Date,
+					TimeSlot: s.TimeSlot,
+					Qty:      s.Qty,
+					SourceID: s.SourceID,
+				})
+			}
 		}
 
 		stores = append(stores, database.StoreInfo{
@@ -139,8 +406,10 @@ func convertToStoreInfo(storeMap map[string]*google.StoreData) []database.StoreI
 			FormattedAddress: data.FormattedAddress,
 			Latitude:         data.Latitude,
 			Longitude:        data.Longitude,
-			OkraShipments:    okraShipments,
-			GourdShipments:   gourdShipments,
+			BusinessStatus:   data.BusinessStatus,
+			SourceID:         data.SourceID,
+			Region:           data.Region,
+			Shipments:        shipments,
 		})
 	}
 
@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"PXMarkMapBackEnd/pkg/database"
+	"PXMarkMapBackEnd/pkg/fault"
+)
+
+// EventShipmentsCreated 同步後出現新出貨時觸發
+const EventShipmentsCreated = "shipments.created"
+
+const (
+	maxAttempts    = 5
+	initialBackoff = 2 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Payload 送到 webhook 的內容
+type Payload struct {
+	Event     string                 `json:"event"`
+	SentAt    time.Time              `json:"sentAt"`
+	Shipments []database.NewShipment `json:"shipments"`
+}
+
+// Sign 計算簽章：HMAC-SHA256(secret, timestamp + "." + body)，以 hex 表示
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch 將新出貨依各 webhook 的產品/區域篩選後送出，失敗時以指數退避重試，
+// 等所有投遞結束才返回
+func Dispatch(db *sql.DB, shipments []database.NewShipment) {
+	if len(shipments) == 0 {
+		return
+	}
+
+	hooks, err := database.ListWebhooks(db, true)
+	if err != nil {
+		log.Printf("[WARN] 無法讀取 webhook 設定: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		matched := filterShipments(hook, shipments)
+		if len(matched) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(hook database.Webhook, matched []database.NewShipment) {
+			defer wg.Done()
+			deliver(db, hook, matched)
+		}(hook, matched)
+	}
+	wg.Wait()
+}
+
+// deliver 投遞到單一 webhook 並記錄結果
+func deliver(db *sql.DB, hook database.Webhook, shipments []database.NewShipment) {
+	body, _ := json.Marshal(Payload{
+		Event:     EventShipmentsCreated,
+		SentAt:    time.Now(),
+		Shipments: shipments,
+	})
+
+	deliveryID, err := database.CreateWebhookDelivery(db, hook.ID, EventShipmentsCreated, string(body))
+	if err != nil {
+		log.Printf("[WARN] 無法建立 webhook #%d 投遞紀錄: %v", hook.ID, err)
+		return
+	}
+
+	backoff := initialBackoff
+	var statusCode int
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		statusCode, err = send(hook, deliveryID, body)
+		if err == nil {
+			log.Printf("[INFO] webhook #%d 投遞成功（%d 筆新出貨）", hook.ID, len(shipments))
+			database.UpdateWebhookDelivery(db, deliveryID, "success", attempt, statusCode, "")
+			return
+		}
+
+		log.Printf("[WARN] webhook #%d 第 %d 次投遞失敗: %v", hook.ID, attempt, err)
+		database.UpdateWebhookDelivery(db, deliveryID, "pending", attempt, statusCode, err.Error())
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	log.Printf("[ERROR] webhook #%d 投遞失敗，已重試 %d 次", hook.ID, maxAttempts)
+	database.UpdateWebhookDelivery(db, deliveryID, "failed", maxAttempts, statusCode, err.Error())
+}
+
+// send 送出一次請求，非 2xx 視為失敗
+func send(hook database.Webhook, deliveryID int, body []byte) (int, error) {
+	if err := fault.WebhookError(); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PXMark-Event", EventShipmentsCreated)
+	req.Header.Set("X-PXMark-Delivery", strconv.Itoa(deliveryID))
+	req.Header.Set("X-PXMark-Timestamp", timestamp)
+	req.Header.Set("X-PXMark-Signature", "sha256="+Sign(hook.Secret, timestamp, body))
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// filterShipments 依 webhook 訂閱的產品與區域篩選
+func filterShipments(hook database.Webhook, shipments []database.NewShipment) []database.NewShipment {
+	var matched []database.NewShipment
+	for _, s := range shipments {
+		if len(hook.Products) > 0 && !contains(hook.Products, s.ProductType) {
+			continue
+		}
+		if len(hook.Regions) > 0 && !contains(hook.Regions, s.Region) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	return matched
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
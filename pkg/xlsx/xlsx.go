@@ -0,0 +1,332 @@
+// Package xlsx 產生簡單的 Excel 活頁簿（Office Open XML），只支援寫入文字、數字與日期儲存格；
+// 字串直接寫在儲存格內（inlineStr），不產生 sharedStrings.xml
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType .xlsx 的 MIME 類型
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// maxSheetName Excel 工作表名稱的長度上限
+const maxSheetName = 31
+
+// 儲存格樣式（對應 styles.xml 的 cellXfs 順序）
+const (
+	styleDefault = 0
+	styleDate    = 1 // yyyy-mm-dd
+	styleHeader  = 2 // 粗體
+)
+
+// excelEpoch Excel 日期序號的起點（1900 日期系統，已包含 1900/2/29 的差異）
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type cellKind int
+
+const (
+	kindEmpty cellKind = iota
+	kindString
+	kindNumber
+	kindDate
+)
+
+// Cell 一個儲存格，以 String、Number、Date 建立，零值為空白儲存格
+type Cell struct {
+	kind  cellKind
+	str   string
+	num   float64
+	style int
+}
+
+// String 文字儲存格
+func String(s string) Cell {
+	return Cell{kind: kindString, str: s}
+}
+
+// Number 數字儲存格
+func Number(n float64) Cell {
+	return Cell{kind: kindNumber, num: n}
+}
+
+// Date 日期儲存格（只取年月日，以 yyyy-mm-dd 顯示）
+func Date(t time.Time) Cell {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return Cell{kind: kindDate, num: day.Sub(excelEpoch).Hours() / 24, style: styleDate}
+}
+
+// Workbook 活頁簿
+type Workbook struct {
+	sheets []*Sheet
+	names  map[string]bool
+}
+
+// Sheet 工作表
+type Sheet struct {
+	name      string
+	rows      [][]Cell
+	header    bool
+	colWidths []int
+}
+
+// New 建立空的活頁簿
+func New() *Workbook {
+	return &Workbook{names: map[string]bool{}}
+}
+
+// AddSheet 新增工作表；name 中 Excel 不允許的字元改為底線、超過 31 字截斷，與既有名稱重複時加上編號
+func (w *Workbook) AddSheet(name string) *Sheet {
+	name = sheetName(name)
+	unique := name
+	for i := 2; w.names[strings.ToLower(unique)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		unique = truncateRunes(name, maxSheetName-len(suffix)) + suffix
+	}
+	w.names[strings.ToLower(unique)] = true
+
+	s := &Sheet{name: unique}
+	w.sheets = append(w.sheets, s)
+	return s
+}
+
+// Name 工作表名稱（可能因不允許的字元或重複而與 AddSheet 的參數不同）
+func (s *Sheet) Name() string {
+	return s.name
+}
+
+// SetHeader 設定第一列為表頭（粗體並凍結），必須在 AddRow 之前呼叫
+func (s *Sheet) SetHeader(titles ...string) {
+	row := make([]Cell, len(titles))
+	for i, t := range titles {
+		row[i] = Cell{kind: kindString, str: t, style: styleHeader}
+	}
+	s.rows = append(s.rows, row)
+	s.header = true
+	s.fitWidths(row)
+}
+
+// AddRow 新增一列
+func (s *Sheet) AddRow(cells ...Cell) {
+	s.rows = append(s.rows, cells)
+	s.fitWidths(cells)
+}
+
+// fitWidths 依內容估計欄寬（全形字算兩格）
+func (s *Sheet) fitWidths(cells []Cell) {
+	for i, cell := range cells {
+		width := 0
+		switch cell.kind {
+		case kindString:
+			for _, r := range cell.str {
+				if r > 0x2E80 {
+					width += 2
+				} else {
+					width++
+				}
+			}
+		case kindNumber:
+			width = len(strconv.FormatFloat(cell.num, 'f', -1, 64))
+		case kindDate:
+			width = len("2006-01-02")
+		}
+		for len(s.colWidths) <= i {
+			s.colWidths = append(s.colWidths, 0)
+		}
+		if width > s.colWidths[i] {
+			s.colWidths[i] = width
+		}
+	}
+}
+
+// Write 將活頁簿寫成 .xlsx；沒有工作表時會產生一張空白的 Sheet1（Excel 不接受沒有工作表的活頁簿）
+func (w *Workbook) Write(out io.Writer) error {
+	sheets := w.sheets
+	if len(sheets) == 0 {
+		sheets = []*Sheet{{name: "Sheet1"}}
+	}
+
+	z := zip.NewWriter(out)
+	files := []part{
+		{"[Content_Types].xml", func(f io.Writer) error { return writeContentTypes(f, len(sheets)) }},
+		{"_rels/.rels", writeString(rootRels)},
+		{"xl/workbook.xml", func(f io.Writer) error { return writeWorkbook(f, sheets) }},
+		{"xl/_rels/workbook.xml.rels", func(f io.Writer) error { return writeWorkbookRels(f, len(sheets)) }},
+		{"xl/styles.xml", writeString(styles)},
+	}
+	for i, s := range sheets {
+		files = append(files, part{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.write})
+	}
+
+	for _, file := range files {
+		f, err := z.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if err := file.body(f); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// part zip 中的一個檔案
+type part struct {
+	name string
+	body func(io.Writer) error
+}
+
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const rootRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const styles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func writeContentTypes(w io.Writer, sheets int) error {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeWorkbook(w io.Writer, sheets []*Sheet) error {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeWorkbookRels 工作表為 rId1..N，樣式為 rIdN+1
+func writeWorkbookRels(w io.Writer, sheets int) error {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// write 輸出工作表 XML（sheetViews、cols、sheetData 的順序為規格要求）
+func (s *Sheet) write(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if s.header {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(s.colWidths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range s.colWidths {
+			width = min(max(width+2, 8), 60)
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch {
+			case cell.kind == kindString && cell.str != "":
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr(cell.style), escape(cell.str))
+			case cell.kind == kindNumber, cell.kind == kindDate:
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr(cell.style), strconv.FormatFloat(cell.num, 'f', -1, 64))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func styleAttr(style int) string {
+	if style == styleDefault {
+		return ""
+	}
+	return ` s="` + strconv.Itoa(style) + `"`
+}
+
+// columnName 欄位索引（從 0 開始）轉為 A、B、…、Z、AA…
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// escape XML 跳脫（無效的 XML 字元會換成 U+FFFD）
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// sheetName 去掉 Excel 工作表名稱不允許的字元並限制長度
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+	return truncateRunes(name, maxSheetName)
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,267 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// workbookFile 重新開啟 .xlsx 讀出的內容：工作表名稱依順序，儲存格以 A1 參照為 key
+type workbookFile struct {
+	names  []string
+	sheets map[string]map[string]readCell
+	parts  map[string][]byte
+}
+
+type readCell struct {
+	Value string
+	Type  string // 空字串 = 數字
+	Style int
+}
+
+// readWorkbook 依 OOXML 的關聯（[Content_Types].xml → _rels/.rels → workbook.xml.rels）讀回活頁簿，
+// 與 Excel 一樣只透過關聯找到工作表，檔名或關聯錯誤時測試會失敗
+func readWorkbook(t *testing.T, data []byte) workbookFile {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = b
+	}
+	decode := func(name string, v interface{}) {
+		t.Helper()
+		b, ok := parts[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		if err := xml.Unmarshal(b, v); err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+	}
+
+	var types struct {
+		Overrides []struct {
+			PartName    string `xml:"PartName,attr"`
+			ContentType string `xml:"ContentType,attr"`
+		} `xml:"Override"`
+	}
+	decode("[Content_Types].xml", &types)
+	for _, o := range types.Overrides {
+		if _, ok := parts[o.PartName[1:]]; !ok {
+			t.Errorf("[Content_Types].xml lists %s, which is not in the zip", o.PartName)
+		}
+	}
+
+	type relationships struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	var root relationships
+	decode("_rels/.rels", &root)
+	if len(root.Rels) != 1 || root.Rels[0].Target != "xl/workbook.xml" {
+		t.Fatalf("_rels/.rels = %+v, want xl/workbook.xml", root.Rels)
+	}
+	var rels relationships
+	decode("xl/_rels/workbook.xml.rels", &rels)
+	targets := map[string]string{}
+	for _, r := range rels.Rels {
+		targets[r.ID] = path.Join("xl", r.Target)
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	decode("xl/workbook.xml", &workbook)
+
+	// 共用字串（目前只寫 inlineStr，若改為 sharedStrings.xml 也能讀）
+	var shared []string
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []struct {
+				T string `xml:"t"`
+			} `xml:"si"`
+		}
+		decode("xl/sharedStrings.xml", &sst)
+		for _, si := range sst.Items {
+			shared = append(shared, si.T)
+		}
+	}
+
+	wb := workbookFile{sheets: map[string]map[string]readCell{}, parts: parts}
+	for _, s := range workbook.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			t.Fatalf("sheet %q refers to missing relationship %s", s.Name, s.RID)
+		}
+		var sheet struct {
+			Rows []struct {
+				R     int `xml:"r,attr"`
+				Cells []struct {
+					R      string `xml:"r,attr"`
+					T      string `xml:"t,attr"`
+					S      int    `xml:"s,attr"`
+					V      string `xml:"v"`
+					Inline string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		decode(target, &sheet)
+		cells := map[string]readCell{}
+		for i, row := range sheet.Rows {
+			if row.R != i+1 {
+				t.Errorf("sheet %q row %d has r=%d", s.Name, i+1, row.R)
+			}
+			for _, c := range row.Cells {
+				cell := readCell{Value: c.V, Type: c.T, Style: c.S}
+				switch c.T {
+				case "inlineStr":
+					cell.Value = c.Inline
+				case "s":
+					n, _ := strconv.Atoi(c.V)
+					cell.Value = shared[n]
+				}
+				if c.R[len(c.R)-len(strconv.Itoa(row.R)):] != strconv.Itoa(row.R) {
+					t.Errorf("cell %s is in row %d", c.R, row.R)
+				}
+				cells[c.R] = cell
+			}
+		}
+		wb.names = append(wb.names, s.Name)
+		wb.sheets[s.Name] = cells
+	}
+	return wb
+}
+
+func write(t *testing.T, wb *Workbook) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteReopen(t *testing.T) {
+	wb := New()
+	s := wb.AddSheet("秋葵")
+	s.SetHeader("出貨日期", "店家編號", "店名", "數量")
+	s.AddRow(Date(time.Date(2025, 6, 1, 23, 30, 0, 0, time.UTC)), Number(12), String("全聯安南店"), Number(3.5))
+	s.AddRow(Date(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)), Number(-7), String(" <A&B> \"店\" "), String(""))
+	wb.AddSheet("產銷/絲瓜")
+
+	file := readWorkbook(t, write(t, wb))
+	if want := []string{"秋葵", "產銷_絲瓜"}; !reflect.DeepEqual(file.names, want) {
+		t.Errorf("sheets = %q, want %q", file.names, want)
+	}
+
+	want := map[string]readCell{
+		"A1": {"出貨日期", "inlineStr", styleHeader},
+		"B1": {"店家編號", "inlineStr", styleHeader},
+		"C1": {"店名", "inlineStr", styleHeader},
+		"D1": {"數量", "inlineStr", styleHeader},
+		"A2": {"45809", "", styleDate}, // 2025-06-01
+		"B2": {"12", "", styleDefault},
+		"C2": {"全聯安南店", "inlineStr", styleDefault},
+		"D2": {"3.5", "", styleDefault},
+		"A3": {"61", "", styleDate}, // 1900-03-01（Excel 把 1900/2/29 算成一天，序號為 61）
+		"B3": {"-7", "", styleDefault},
+		"C3": {" <A&B> \"店\" ", "inlineStr", styleDefault},
+		// 空字串不輸出儲存格
+	}
+	if got := file.sheets["秋葵"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("cells =\n  %v\nwant\n  %v", got, want)
+	}
+	if got := file.sheets["產銷_絲瓜"]; len(got) != 0 {
+		t.Errorf("empty sheet has cells %v", got)
+	}
+
+	// 表頭凍結第一列
+	if !bytes.Contains(file.parts["xl/worksheets/sheet1.xml"], []byte(`state="frozen"`)) {
+		t.Errorf("header row is not frozen")
+	}
+	if bytes.Contains(file.parts["xl/worksheets/sheet2.xml"], []byte(`<sheetViews>`)) {
+		t.Errorf("sheet without a header has a frozen pane")
+	}
+}
+
+func TestWriteColumnsPastZ(t *testing.T) {
+	wb := New()
+	s := wb.AddSheet("寬")
+	row := make([]Cell, 28)
+	for i := range row {
+		row[i] = Number(float64(i))
+	}
+	s.AddRow(row...)
+
+	cells := readWorkbook(t, write(t, wb)).sheets["寬"]
+	for ref, want := range map[string]string{"A1": "0", "Z1": "25", "AA1": "26", "AB1": "27"} {
+		if cells[ref].Value != want {
+			t.Errorf("%s = %q, want %q", ref, cells[ref].Value, want)
+		}
+	}
+}
+
+func TestWriteEmptyWorkbook(t *testing.T) {
+	file := readWorkbook(t, write(t, New()))
+	if !reflect.DeepEqual(file.names, []string{"Sheet1"}) {
+		t.Errorf("sheets = %q, want [Sheet1]", file.names)
+	}
+}
+
+func TestWriteInvalidXMLCharacters(t *testing.T) {
+	wb := New()
+	wb.AddSheet("a").AddRow(String("x\x00y\x1Fz"))
+	cells := readWorkbook(t, write(t, wb)).sheets["a"]
+	if got := cells["A1"].Value; got != "x�y�z" {
+		t.Errorf("A1 = %q, want control characters replaced with U+FFFD", got)
+	}
+}
+
+func TestAddSheetNames(t *testing.T) {
+	wb := New()
+	tests := []struct {
+		name, want string
+	}{
+		{"出貨", "出貨"},
+		{"出貨", "出貨 (2)"},
+		{"出貨", "出貨 (3)"},
+		{"a[b]:c*d?e/f\\g", "a_b__c_d_e_f_g"},
+		{"'quoted'", "quoted"},
+		{"   ", "Sheet"},
+		{"abcdefghijklmnopqrstuvwxyz0123456789", "abcdefghijklmnopqrstuvwxyz01234"},
+		{"abcdefghijklmnopqrstuvwxyz0123456789", "abcdefghijklmnopqrstuvwxyz0 (2)"},
+	}
+	for _, tt := range tests {
+		if got := wb.AddSheet(tt.name).Name(); got != tt.want {
+			t.Errorf("AddSheet(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 名稱不分大小寫重複
+	if got := wb.AddSheet("SHEET").Name(); got != "SHEET (2)" {
+		t.Errorf("AddSheet(SHEET) = %q, want SHEET (2)", got)
+	}
+}
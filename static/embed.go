@@ -0,0 +1,9 @@
+// Package static 前端靜態檔（編譯時嵌入執行檔，從任何工作目錄執行都能提供）
+package static
+
+import "embed"
+
+// FS 嵌入的靜態檔；新增其他副檔名的檔案時請一併加到下方的 pattern
+//
+//go:embed *.html
+var FS embed.FS